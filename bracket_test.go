@@ -0,0 +1,110 @@
+package hclog
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBracketLevelFirst(t *testing.T) {
+	t.Run("writes the level first", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:            &buf,
+			Level:             Trace,
+			DisableTime:       true,
+			BracketLevelFirst: true,
+		})
+
+		logger.Trace("trace")
+		logger.Debug("debug")
+		logger.Info("info", "a", 1)
+		logger.Named("raft").Warn("heartbeat failed", "peer", "10.0.0.2")
+		logger.Named("raft").Named("fsm").Error("apply failed", "error", errors.New("disk full"))
+		assert.Equal(t,
+			"[TRACE] trace\n"+
+				"[DEBUG] debug\n"+
+				"[INFO] info a=1\n"+
+				"[WARN] raft: heartbeat failed peer=10.0.0.2\n"+
+				"[ERR] raft.fsm: apply failed error=\"disk full\"\n",
+			buf.String())
+	})
+
+	t.Run("writes the time before the level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, BracketLevelFirst: true})
+
+		logger.Named("raft").Info("entry", "a", 1)
+		assert.Regexp(t, `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}(Z|[+-]\d{4}) \[INFO\] raft: entry a=1\n$`, buf.String())
+	})
+
+	t.Run("writes the location after the level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:            &buf,
+			DisableTime:       true,
+			IncludeLocation:   true,
+			BracketLevelFirst: true,
+		})
+
+		logger.Named("raft").Info("entry")
+		assert.Regexp(t, `^\[INFO\] go-hclog/bracket_test.go:\d+: raft: entry\n$`, buf.String())
+	})
+
+	t.Run("writes fields without a colon", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, BracketLevelFirst: true})
+
+		logger.(FieldLogger).InfoF("entry", Int("a", 1))
+		assert.Equal(t, "[INFO] entry a=1\n", buf.String())
+	})
+
+	t.Run("ignores the name padding", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:            &buf,
+			DisableTime:       true,
+			NamePadding:       10,
+			BracketLevelFirst: true,
+		})
+
+		logger.Named("raft").Info("entry")
+		assert.Equal(t, "[INFO] raft: entry\n", buf.String())
+	})
+
+	t.Run("is kept by derived loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, BracketLevelFirst: true})
+
+		logger.With("a", 1).Named("raft").Info("entry")
+		logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.Name = "http"
+		}).Warn("entry")
+		assert.Equal(t, "[INFO] raft: entry a=1\n[WARN] http: entry\n", buf.String())
+	})
+
+	t.Run("is read back by the standard logger", func(t *testing.T) {
+		var src bytes.Buffer
+		New(&LoggerOptions{Output: &src, BracketLevelFirst: true}).Named("raft").Warn("heartbeat failed")
+
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+		stdlog := logger.StandardLogger(&StandardLoggerOptions{InferLevels: true})
+		stdlog.SetFlags(0)
+		stdlog.Print(src.String())
+		assert.Equal(t, "[WARN]  -- raft: heartbeat failed\n", buf.String())
+	})
+
+	t.Run("reads the levels of a log.Logger with timestamps", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+		w := logger.StandardWriter(&StandardLoggerOptions{InferLevels: true})
+
+		std := log.New(w, "", log.LstdFlags)
+		std.Print("[ERR] raft: apply failed")
+		assert.Equal(t, "[ERROR] -- raft: apply failed\n", buf.String())
+	})
+}
@@ -0,0 +1,58 @@
+package hclog
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// CaptureWindow writes the entries of the logger and of the loggers derived
+// from it at level or above to w as well, whatever their level, for d or until
+// stop is called, whichever comes first. A d of zero or less captures until
+// stop is called. This is meant for incident response, to get the next minutes
+// of Trace entries in a file of their own, commonly a fresh LogFile, without
+// changing the configuration: the entries written to the output of the logger
+// are unchanged.
+//
+// The capture is a sink registered on the logger, written in the format of
+// the logger, and is marked by an entry at its start and one at its end,
+// written at level or Info, whichever is higher. Captures can overlap, each
+// with its own level and writer. w is flushed once the capture ends if it
+// implements Flushable, but is left open.
+func (i *interceptLogger) CaptureWindow(level Level, w io.Writer, d time.Duration) (stop func()) {
+	opts := &LoggerOptions{Level: level, Output: w, Color: ColorOff}
+	if l, ok := i.Logger.(*intLogger); ok {
+		opts.JSONFormat = l.json
+		opts.TimeFormat = l.timeFormat
+		opts.DisableTime = l.timeFormat == ""
+		opts.TimeEncoding = l.timeEncoding
+		opts.OrderJSONKeys = l.orderJSON
+	}
+	sink := NewSinkAdapter(opts)
+
+	markerLevel := level
+	if markerLevel < Info {
+		markerLevel = Info
+	}
+	sink.Accept(i.Name(), markerLevel, "capture window started", "capture_level", level.String(), "capture_duration", d.String())
+	i.RegisterSink(sink)
+
+	var once sync.Once
+	end := func() {
+		once.Do(func() {
+			i.DeregisterSink(sink)
+			sink.Accept(i.Name(), markerLevel, "capture window ended", "capture_level", level.String())
+			if f, ok := w.(Flushable); ok {
+				f.Flush()
+			}
+		})
+	}
+	if d <= 0 {
+		return end
+	}
+	timer := time.AfterFunc(d, end)
+	return func() {
+		timer.Stop()
+		end()
+	}
+}
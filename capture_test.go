@@ -0,0 +1,119 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedBuffer is a bytes.Buffer safe to read while a logger writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestCaptureWindow(t *testing.T) {
+	t.Run("captures the entries at its level", func(t *testing.T) {
+		var primary, capture bytes.Buffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: &primary, DisableTime: true})
+
+		stop := logger.(Capturer).CaptureWindow(Trace, &capture, time.Hour)
+		logger.Trace("trace", "a", 1)
+		logger.Named("raft").Debug("debug")
+		logger.Info("info")
+		stop()
+		logger.Trace("after")
+
+		assert.Equal(t, "[INFO]  -- info\n", primary.String())
+		assert.Equal(t,
+			"[INFO]  -- capture window started: capture_level=trace capture_duration=1h0m0s\n"+
+				"[TRACE] -- trace: a=1\n"+
+				"[DEBUG] [module=raft] -- debug\n"+
+				"[INFO]  -- info\n"+
+				"[INFO]  -- capture window ended: capture_level=trace\n",
+			capture.String())
+	})
+
+	t.Run("ends after its duration", func(t *testing.T) {
+		var capture lockedBuffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: new(bytes.Buffer), DisableTime: true})
+
+		stop := logger.(Capturer).CaptureWindow(Debug, &capture, 10*time.Millisecond)
+		defer stop()
+		logger.Debug("during")
+
+		waitFor(t, func() bool {
+			return strings.Contains(capture.String(), "capture window ended")
+		})
+		logger.Debug("after")
+		assert.NotContains(t, capture.String(), "after")
+		assert.Contains(t, capture.String(), "during")
+
+		// The sink is gone, and stopping again is harmless.
+		stop()
+		assert.Equal(t, 1, strings.Count(capture.String(), "capture window ended"))
+		assert.Equal(t, int32(0), *logger.(*interceptLogger).sinkCount)
+	})
+
+	t.Run("runs overlapping captures independently", func(t *testing.T) {
+		var first, second bytes.Buffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: new(bytes.Buffer), DisableTime: true})
+
+		stopFirst := logger.(Capturer).CaptureWindow(Trace, &first, 0)
+		logger.Trace("one")
+		stopSecond := logger.(Capturer).CaptureWindow(Warn, &second, 0)
+		logger.Trace("two")
+		logger.Warn("three")
+		stopFirst()
+		logger.Warn("four")
+		stopSecond()
+
+		assert.Equal(t,
+			"[INFO]  -- capture window started: capture_level=trace capture_duration=0s\n"+
+				"[TRACE] -- one\n"+
+				"[TRACE] -- two\n"+
+				"[WARN]  -- three\n"+
+				"[INFO]  -- capture window ended: capture_level=trace\n",
+			first.String())
+		assert.Equal(t,
+			"[WARN]  -- capture window started: capture_level=warn capture_duration=0s\n"+
+				"[WARN]  -- three\n"+
+				"[WARN]  -- four\n"+
+				"[WARN]  -- capture window ended: capture_level=warn\n",
+			second.String())
+	})
+
+	t.Run("writes in the format of the logger", func(t *testing.T) {
+		var capture bytes.Buffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: new(bytes.Buffer), JSONFormat: true})
+
+		stop := logger.(Capturer).CaptureWindow(Debug, &capture, 0)
+		logger.With("a", 1).Debug("entry")
+		stop()
+
+		lines := strings.Split(strings.TrimSpace(capture.String()), "\n")
+		require.Len(t, lines, 3)
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &raw))
+		assert.Equal(t, "entry", raw["@message"])
+		assert.Equal(t, float64(1), raw["a"])
+	})
+}
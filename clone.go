@@ -0,0 +1,124 @@
+package hclog
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"sync/atomic"
+)
+
+// options returns the options l was created with, as they stand now: the
+// current level and overrides, and the color actually in use.
+func (l *intLogger) options() *LoggerOptions {
+	out := l.writer.output()
+	opts := &LoggerOptions{
+		Name:               l.name,
+		Level:              Level(atomic.LoadInt32(l.level)),
+		Output:             out.w,
+		Mutex:              l.mutex,
+		JSONFormat:         l.json,
+		IncludeLocation:    l.callerOffset > 0,
+		TimeFormat:         l.timeFormat,
+		DisableTime:        l.timeFormat == "",
+		TimeEncoding:       l.timeEncoding,
+		Color:              out.color,
+		Exclude:            l.exclude,
+		IndependentLevels:  l.independentLevels,
+		LevelOverrides:     l.overrides.snapshot(),
+		NormalizeKeys:      l.keyNormalization,
+		KeyCollisionPrefix: l.collisionPrefix,
+		MaxMessageBytes:    l.limits.messageBytes,
+		MaxFieldBytes:      l.limits.fieldBytes,
+		MaxFields:          l.limits.fields,
+		AllowRawNewlines:   l.rawNewlines,
+		AdaptToJournald:    out.journald,
+		Middleware:         l.middleware,
+		ExitHooks:          l.exit.hooks,
+		ExitHookTimeout:    l.exit.timeout,
+		ExitCode:           l.exit.code,
+		SuppressErrors:     l.suppress.errors(),
+		Sequence:           l.seq != nil && l.seq.numbered,
+		EntryIDs:           l.seq != nil && l.seq.ids,
+		seqCounter:         l.seq.counter(),
+		RegisterNamed:      l.registry != nil,
+		StrictArgs:         l.strictArgs,
+		NamePadding:        l.namePadding.width,
+		BracketLevelFirst:  l.bracketLevelFirst,
+		StructuredLocation: l.locationKey != "",
+		LocationKey:        l.locationKey,
+		OrderJSONKeys:      l.orderJSON,
+		DisableScopeChecks: l.noScopeChecks,
+		registry:           l.registry,
+		shutdown:           l.shutdown,
+	}
+	if l.keyFilter != nil {
+		opts.OmitKeys = setKeys(l.keyFilter.omit)
+		opts.OnlyKeys = setKeys(l.keyFilter.only)
+	}
+	if l.formatters != nil {
+		opts.ValueFormatters = make(map[reflect.Type]func(interface{}) interface{}, len(l.formatters.formatters))
+		for t, format := range l.formatters.formatters {
+			opts.ValueFormatters[t] = format
+		}
+	}
+	if l.names != nil {
+		opts.ExcludeNames = append([]string(nil), l.names.exclude...)
+		opts.IncludeOnlyNames = append([]string(nil), l.names.include...)
+	}
+	return opts
+}
+
+// WithOptions returns a new logger with the name, implied arguments and
+// options of l, as changed by modify. The new logger shares nothing else with
+// l but the counter of Sequence and the registry of RegisterNamed: its level,
+// overrides and name filters are copies, so that changing them on one doesn't
+// affect the other or the loggers derived from it.
+//
+// The options given to modify hold the Mutex of l, guarding its output, so
+// that the new logger shares the lock unless modify changes it: to nil for a
+// lock of its own, as suits a new Output, or to another Locker.
+func (l *intLogger) WithOptions(modify func(opts *LoggerOptions)) Logger {
+	frames := 0
+	if l.callerOffset > 0 {
+		frames = l.callerOffset - offsetIntLogger
+	}
+	return l.withOptions(modify, frames)
+}
+
+// withOptions implements WithOptions, frames being the stack frames between
+// the caller and the Log methods of the new logger besides its own.
+func (l *intLogger) withOptions(modify func(opts *LoggerOptions), frames int) *intLogger {
+	opts := l.options()
+	if modify != nil {
+		modify(opts)
+	}
+
+	// AutoColor was resolved against the output of l, and is only kept when
+	// the output is still a file to be checked.
+	if opts.Color == AutoColor {
+		if _, ok := opts.Output.(*os.File); !ok {
+			opts.Color = ColorOff
+		}
+	}
+
+	sl := newLogger(opts)
+	if sl.callerOffset > 0 {
+		sl.callerOffset += frames
+	}
+	sl.implied = append([]interface{}(nil), l.implied...)
+	sl.scope = l.scope
+	return sl
+}
+
+// setKeys returns the keys of set, sorted.
+func setKeys(set map[string]struct{}) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
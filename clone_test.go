@@ -0,0 +1,122 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_WithOptions(t *testing.T) {
+	t.Run("changes the options of the new logger only", func(t *testing.T) {
+		var text, js bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Name:        "test",
+			Output:      &text,
+			DisableTime: true,
+		}).With("a", 1)
+
+		derived := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.Output = &js
+			opts.JSONFormat = true
+		})
+
+		logger.Info("text")
+		derived.Info("json")
+		logger.Named("sub").Info("still text")
+
+		assert.Equal(t, "[INFO]  [module=test] -- text: a=1\n[INFO]  [module=test.sub] -- still text: a=1\n", text.String())
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(js.Bytes(), &entry))
+		delete(entry, "@timestamp")
+		assert.Equal(t, map[string]interface{}{
+			"@level":   "info",
+			"@message": "json",
+			"@module":  "test",
+			"a":        float64(1),
+		}, entry)
+	})
+
+	t.Run("copies the level", func(t *testing.T) {
+		logger := New(&LoggerOptions{Level: Info})
+		derived := logger.(Reconfigurable).WithOptions(nil)
+		assert.True(t, derived.IsInfo())
+
+		derived.SetLevel(Error)
+		assert.True(t, logger.IsInfo())
+
+		logger.SetLevel(Trace)
+		assert.False(t, derived.IsWarn())
+	})
+
+	t.Run("copies the overrides", func(t *testing.T) {
+		logger := New(&LoggerOptions{LevelOverrides: map[string]Level{"raft": Debug}})
+		derived := logger.(Reconfigurable).WithOptions(nil)
+
+		derived.(LevelOverridable).SetLevelOverride("raft", Error)
+		assert.Equal(t, map[string]Level{"raft": Debug}, logger.(LevelOverridable).LevelOverrides())
+	})
+
+	t.Run("shares the mutex unless changed", func(t *testing.T) {
+		mutex := new(sync.Mutex)
+		logger := New(&LoggerOptions{Mutex: mutex})
+
+		shared := logger.(Reconfigurable).WithOptions(nil)
+		assert.True(t, shared.(*intLogger).mutex == Locker(mutex))
+
+		own := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.Output = new(bytes.Buffer)
+			opts.Mutex = nil
+		})
+		assert.False(t, own.(*intLogger).mutex == Locker(mutex))
+
+		var noop NoopLocker
+		other := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.Mutex = noop
+		})
+		assert.Equal(t, Locker(noop), other.(*intLogger).mutex)
+	})
+
+	t.Run("turns AutoColor off for outputs other than files", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{Output: &buf})
+		derived := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.Color = AutoColor
+		})
+		assert.Equal(t, ColorOff, derived.(*intLogger).writer.output().color)
+	})
+
+	t.Run("reports the location of intercepted loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := NewInterceptLogger(&LoggerOptions{Output: &buf, DisableTime: true})
+		sink := &collectSink{}
+		logger.RegisterSink(sink)
+
+		derived := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.IncludeLocation = true
+		})
+
+		_, _, line, _ := runtime.Caller(0)
+		derived.Info("located")
+
+		assert.Equal(t, "[INFO] [go-hclog/clone_test.go:"+strconv.Itoa(line+1)+"] -- located\n", buf.String())
+		require.Len(t, sink.msgs, 1)
+	})
+}
+
+type collectSink struct {
+	msgs []string
+}
+
+func (s *collectSink) Accept(name string, level Level, msg string, args ...interface{}) {
+	s.msgs = append(s.msgs, msg)
+}
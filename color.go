@@ -0,0 +1,36 @@
+package hclog
+
+import (
+	"io"
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// SupportsColor reports whether colored output is wanted on w, as AutoColor
+// decides it: never if the NO_COLOR environment variable is set, always if
+// CLICOLOR_FORCE or FORCE_COLOR is set to a value other than 0 or false, even
+// for pipes, and otherwise only if w is a terminal.
+func SupportsColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if envForcesColor("CLICOLOR_FORCE") || envForcesColor("FORCE_COLOR") {
+		return true
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// envForcesColor reports whether the environment variable name forces colors.
+func envForcesColor(name string) bool {
+	switch os.Getenv(name) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
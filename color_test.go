@@ -0,0 +1,77 @@
+package hclog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportsColor(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	cases := []struct {
+		name   string
+		env    map[string]string
+		output io.Writer
+		color  bool
+	}{
+		{"pipe", nil, w, false},
+		{"buffer", nil, new(bytes.Buffer), false},
+		{"forced on a pipe", map[string]string{"FORCE_COLOR": "1"}, w, true},
+		{"forced by CLICOLOR_FORCE", map[string]string{"CLICOLOR_FORCE": "1"}, new(bytes.Buffer), true},
+		{"not forced", map[string]string{"FORCE_COLOR": "0", "CLICOLOR_FORCE": "false"}, w, false},
+		{"disabled", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, w, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			env := map[string]string{"NO_COLOR": "", "FORCE_COLOR": "", "CLICOLOR_FORCE": ""}
+			for k, v := range c.env {
+				env[k] = v
+			}
+			defer setenv(env)()
+
+			assert.Equal(t, c.color, SupportsColor(c.output))
+		})
+	}
+}
+
+func TestLogger_colorEnvironment(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	t.Run("forces AutoColor on pipes", func(t *testing.T) {
+		defer setenv(map[string]string{"NO_COLOR": "", "FORCE_COLOR": "1"})()
+
+		logger := New(&LoggerOptions{Output: w, Color: AutoColor})
+		assert.Equal(t, AutoColor, logger.(*intLogger).writer.output().color)
+	})
+
+	t.Run("disables AutoColor", func(t *testing.T) {
+		defer setenv(map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"})()
+
+		logger := New(&LoggerOptions{Output: w, Color: AutoColor})
+		assert.Equal(t, ColorOff, logger.(*intLogger).writer.output().color)
+	})
+
+	t.Run("ignores the environment for ForceColor and ColorOff", func(t *testing.T) {
+		defer setenv(map[string]string{"NO_COLOR": "1", "FORCE_COLOR": ""})()
+
+		logger := New(&LoggerOptions{Output: w, Color: ForceColor})
+		assert.Equal(t, ForceColor, logger.(*intLogger).writer.output().color)
+
+		defer setenv(map[string]string{"NO_COLOR": "", "FORCE_COLOR": "1"})()
+
+		logger = New(&LoggerOptions{Output: w, Color: ColorOff})
+		assert.Equal(t, ColorOff, logger.(*intLogger).writer.output().color)
+	})
+}
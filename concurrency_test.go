@@ -0,0 +1,102 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentSubloggers creates subloggers while their parents log, for
+// the race detector to check that the loggers aren't changed once created.
+func TestConcurrentSubloggers(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	const goroutines, entries = 8, 200
+
+	cases := []struct {
+		name string
+		new  func(*LoggerOptions) Logger
+	}{
+		{"logger", func(opts *LoggerOptions) Logger { return New(opts) }},
+		{"intercept logger", func(opts *LoggerOptions) Logger {
+			l := NewInterceptLogger(opts)
+			l.RegisterSink(NewSinkAdapter(&LoggerOptions{Output: ioutil.Discard, JSONFormat: true}))
+			return l
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, format := range []string{"text", "json"} {
+				t.Run(format, func(t *testing.T) {
+					var out lockedBuffer
+					logger := c.new(&LoggerOptions{Output: &out, JSONFormat: format == "json", IncludeLocation: true})
+					// Overriding a key leaves room at the end of the implied
+					// arguments, which logging must not append into.
+					parent := logger.With("a", 1, "b", 2, "a", 3)
+
+					var wg sync.WaitGroup
+					for g := 0; g < goroutines; g++ {
+						wg.Add(2)
+						go func(g int) {
+							defer wg.Done()
+							for i := 0; i < entries; i++ {
+								parent.Info("parent", "g", g)
+							}
+						}(g)
+						go func(g int) {
+							defer wg.Done()
+							for i := 0; i < entries; i++ {
+								sub := parent.With("g", g).Named(fmt.Sprint("sub", g))
+								sub.Debug("hidden", "i", i)
+								sub.With("c", i).Info("sub", WithLocation)
+								if i%50 == 0 {
+									parent.(OutputResettable).ResetOutput(&LoggerOptions{Output: &out})
+								}
+							}
+						}(g)
+					}
+					wg.Wait()
+
+					lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+					assert.Len(t, lines, goroutines*entries*2)
+					for _, line := range lines {
+						if !strings.Contains(line, "parent") {
+							continue
+						}
+						if format == "json" {
+							var raw map[string]interface{}
+							require.NoError(t, json.Unmarshal([]byte(line), &raw), line)
+							assert.EqualValues(t, 3, raw["a"], line)
+							assert.Nil(t, raw["c"], line)
+						} else {
+							assert.Contains(t, line, "a=3 b=2 g=", line)
+							assert.NotContains(t, line, "c=", line)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+// TestImpliedArgsCopy checks that appending to ImpliedArgs doesn't change
+// the logger.
+func TestImpliedArgsCopy(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&LoggerOptions{Output: &out, DisableTime: true}).With("a", 1, "a", 2)
+
+	implied := logger.ImpliedArgs()
+	_ = append(implied, "b", 3)
+
+	logger.With("c", 4)
+	logger.Info("entry", "d", 5)
+	assert.Equal(t, "[INFO]  -- entry: a=2 d=5\n", out.String())
+}
@@ -0,0 +1,254 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigPollInterval is how often WatchConfig checks the config file for
+// changes.
+var ConfigPollInterval = 5 * time.Second
+
+// redactedValue replaces the values of the RedactKeys of a LoggerConfig.
+const redactedValue = "[REDACTED]"
+
+// LoggerConfig holds the settings of a logger which can be changed while it
+// runs, as loaded by LoadConfig from JSON such as:
+//
+//	{
+//	  "level": "info",
+//	  "overrides": {"raft": "debug"},
+//	  "format": "json",
+//	  "redact_keys": ["token"]
+//	}
+//
+// Each setting left out keeps the setting of the logger it is applied to.
+type LoggerConfig struct {
+	// Level is the level of the logger, as accepted by LevelFromString.
+	Level string `json:"level,omitempty"`
+
+	// Overrides are the levels of named loggers, replacing the level
+	// overrides in effect. An empty object removes them all.
+	Overrides map[string]string `json:"overrides,omitempty"`
+
+	// Format is text or json.
+	Format string `json:"format,omitempty"`
+
+	// RedactKeys are keys of which the values are written as "[REDACTED]",
+	// whether given to the logging methods or implied.
+	RedactKeys []string `json:"redact_keys,omitempty"`
+}
+
+// LoadConfig reads the LoggerConfig in the JSON file at path, and rejects it
+// unless it is valid, including fields this package doesn't know, which are
+// likely misspelled.
+func LoadConfig(path string) (LoggerConfig, error) {
+	var c LoggerConfig
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return c, fmt.Errorf("invalid logger config %s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return c, fmt.Errorf("invalid logger config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// validate rejects unknown levels and formats, overrides without a name and
+// empty keys.
+func (c LoggerConfig) validate() error {
+	if c.Level != "" && LevelFromString(c.Level) == NoLevel {
+		return fmt.Errorf("unknown level %q", c.Level)
+	}
+	for name, level := range c.Overrides {
+		if name == "" {
+			return fmt.Errorf("level override without a name")
+		}
+		if LevelFromString(level) == NoLevel {
+			return fmt.Errorf("level override of %q has unknown level %q", name, level)
+		}
+	}
+	switch strings.ToLower(c.Format) {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("unknown format %q, valid formats are text and json", c.Format)
+	}
+	for _, key := range c.RedactKeys {
+		if key == "" {
+			return fmt.Errorf("empty key in redact_keys")
+		}
+	}
+	return nil
+}
+
+// Apply applies c to l, and returns the logger to use from then on. The level
+// and overrides are set on l with SetLevel and SetLevelOverride, unless the
+// format or redacted keys are set, which need a logger derived from l with
+// WithOptions: the level and overrides are set on that logger instead, and l
+// is left unchanged. Apply configs to the same base logger, rather than to the
+// logger returned by the last Apply, so that the keys no longer redacted are
+// written again.
+func (c LoggerConfig) Apply(l Logger) (Logger, error) {
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid logger config: %w", err)
+	}
+
+	if c.Format != "" || len(c.RedactKeys) > 0 {
+		r, ok := l.(Reconfigurable)
+		if !ok {
+			return nil, fmt.Errorf("logger cannot change its format or redacted keys")
+		}
+		l = r.WithOptions(func(opts *LoggerOptions) {
+			if c.Format != "" {
+				opts.JSONFormat = strings.EqualFold(c.Format, "json")
+				if opts.JSONFormat {
+					opts.Color = ColorOff
+				}
+			}
+			if len(c.RedactKeys) > 0 {
+				opts.Middleware = append(opts.Middleware[:len(opts.Middleware):len(opts.Middleware)],
+					redactKeys(c.RedactKeys))
+			}
+		})
+	}
+
+	if c.Level != "" {
+		l.SetLevel(LevelFromString(c.Level))
+	}
+	if c.Overrides != nil {
+		lo, ok := l.(LevelOverridable)
+		if !ok {
+			return nil, fmt.Errorf("logger does not support level overrides")
+		}
+		for name := range lo.LevelOverrides() {
+			if _, ok := c.Overrides[name]; !ok {
+				lo.SetLevelOverride(name, NoLevel)
+			}
+		}
+		for name, level := range c.Overrides {
+			lo.SetLevelOverride(name, LevelFromString(level))
+		}
+	}
+	return l, nil
+}
+
+// redactKeys returns a middleware replacing the values of keys.
+func redactKeys(keys []string) func(e *Entry) bool {
+	set := keySet(keys)
+	return func(e *Entry) bool {
+		for i := 0; i+1 < len(e.Args); i += 2 {
+			if key, ok := e.Args[i].(string); ok {
+				if _, ok := set[key]; ok {
+					e.Args[i+1] = redactedValue
+				}
+			}
+		}
+		return true
+	}
+}
+
+// ConfigWatcher polls a config file for changes, as returned by WatchConfig.
+type ConfigWatcher struct {
+	path  string
+	apply func(LoggerConfig) error
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	err     error
+}
+
+// WatchConfig loads the LoggerConfig in the JSON file at path and passes it
+// to apply, typically calling LoggerConfig.Apply, then checks the file for
+// changes every ConfigPollInterval, by its modification time and size, and
+// passes each new config to apply. The file is polled rather than watched with
+// inotify and the like, which keeps this package free of dependencies, and
+// follows files replaced through symbolic links, as Kubernetes does with
+// config maps.
+//
+// A config which can't be loaded, or which apply returns an error for, is
+// rejected and the config applied before is kept: WatchConfig returns the error
+// for the first config, and Err the error rejecting the last change. Call Stop
+// once done.
+func WatchConfig(path string, apply func(LoggerConfig) error) (*ConfigWatcher, error) {
+	w := &ConfigWatcher{
+		path:  path,
+		apply: apply,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if err := w.check(); err != nil {
+		return nil, err
+	}
+	go w.poll(ConfigPollInterval)
+	return w, nil
+}
+
+// poll checks the file every interval, until Stop is called.
+func (w *ConfigWatcher) poll(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// check applies the config in the file if it changed since the last check,
+// and records the error rejecting it.
+func (w *ConfigWatcher) check() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		// The file is loaded again once it is back, even unchanged.
+		w.modTime, w.size = time.Time{}, 0
+	} else if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return w.err
+	} else {
+		// Changes are only tried once, rather than every interval until the
+		// file is fixed.
+		w.modTime, w.size = fi.ModTime(), fi.Size()
+		var c LoggerConfig
+		if c, err = LoadConfig(w.path); err == nil {
+			err = w.apply(c)
+		}
+	}
+	w.err = err
+	return err
+}
+
+// Err returns the error rejecting the last change of the file, or nil if it
+// was applied.
+func (w *ConfigWatcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Stop stops polling the file, waiting for a change being applied.
+func (w *ConfigWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
@@ -0,0 +1,208 @@
+package hclog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hclog-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	load := func(body string) (LoggerConfig, error) {
+		path := filepath.Join(dir, "log.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(body), 0644))
+		return LoadConfig(path)
+	}
+
+	t.Run("loads a config", func(t *testing.T) {
+		c, err := load(`{"level":"debug","overrides":{"raft":"trace"},"format":"json","redact_keys":["token"]}`)
+		require.NoError(t, err)
+		assert.Equal(t, LoggerConfig{
+			Level:      "debug",
+			Overrides:  map[string]string{"raft": "trace"},
+			Format:     "json",
+			RedactKeys: []string{"token"},
+		}, c)
+	})
+
+	cases := map[string]string{
+		"malformed":        `{"level":`,
+		"unknown field":    `{"levle":"debug"}`,
+		"unknown level":    `{"level":"loud"}`,
+		"unknown override": `{"overrides":{"raft":"loud"}}`,
+		"unnamed override": `{"overrides":{"":"debug"}}`,
+		"unknown format":   `{"format":"xml"}`,
+		"empty key":        `{"redact_keys":[""]}`,
+	}
+	for name, body := range cases {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := load(body)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoggerConfig_Apply(t *testing.T) {
+	t.Run("sets the levels in place", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:         &buf,
+			DisableTime:    true,
+			Level:          Info,
+			LevelOverrides: map[string]Level{"http": Error},
+		})
+
+		got, err := LoggerConfig{Level: "warn", Overrides: map[string]string{"raft": "debug"}}.Apply(logger)
+		require.NoError(t, err)
+		assert.Equal(t, logger, got)
+		assert.Equal(t, Warn, logger.(LevelGetter).GetLevel())
+		assert.Equal(t, map[string]Level{"raft": Debug}, logger.(LevelOverridable).LevelOverrides())
+
+		_, err = LoggerConfig{}.Apply(logger)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]Level{"raft": Debug}, logger.(LevelOverridable).LevelOverrides())
+		_, err = LoggerConfig{Overrides: map[string]string{}}.Apply(logger)
+		require.NoError(t, err)
+		assert.Empty(t, logger.(LevelOverridable).LevelOverrides())
+	})
+
+	t.Run("derives a logger for the format and redacted keys", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, Level: Info})
+
+		got, err := LoggerConfig{Level: "debug", RedactKeys: []string{"token"}}.Apply(logger.With("token", "implied"))
+		require.NoError(t, err)
+		got.Debug("redacted", "token", "secret", "user", "alice")
+		got.(FieldLogger).DebugF("redacted", Str("token", "secret"))
+		assert.Equal(t,
+			"[DEBUG] -- redacted: token=[REDACTED] token=[REDACTED] user=alice\n"+
+				"[DEBUG] -- redacted: token=[REDACTED] token=[REDACTED]\n",
+			buf.String())
+		assert.Equal(t, Info, logger.(LevelGetter).GetLevel())
+
+		buf.Reset()
+		got, err = LoggerConfig{Format: "json"}.Apply(logger)
+		require.NoError(t, err)
+		got.Info("entry")
+		assert.Contains(t, buf.String(), `"@message":"entry"`)
+	})
+
+	t.Run("rejects invalid configs", func(t *testing.T) {
+		logger := New(&LoggerOptions{Output: &bytes.Buffer{}, Level: Info})
+		_, err := LoggerConfig{Level: "loud"}.Apply(logger)
+		assert.EqualError(t, err, `invalid logger config: unknown level "loud"`)
+		assert.Equal(t, Info, logger.(LevelGetter).GetLevel())
+	})
+}
+
+func TestWatchConfig(t *testing.T) {
+	defer func(interval time.Duration) { ConfigPollInterval = interval }(ConfigPollInterval)
+	ConfigPollInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "hclog-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.json")
+
+	mtime := time.Now()
+	write := func(body string) {
+		require.NoError(t, ioutil.WriteFile(path, []byte(body), 0644))
+		// The time of the file is moved on so that each write is seen as a
+		// change, whatever the resolution of the file system.
+		mtime = mtime.Add(time.Second)
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	var buf bytes.Buffer
+	logger := New(&LoggerOptions{Output: &buf, Level: Info})
+	var (
+		mu      sync.Mutex
+		applied []LoggerConfig
+	)
+	current := func() []LoggerConfig {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]LoggerConfig(nil), applied...)
+	}
+	apply := func(c LoggerConfig) error {
+		if _, err := c.Apply(logger); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		applied = append(applied, c)
+		return nil
+	}
+
+	t.Run("rejects an invalid first config", func(t *testing.T) {
+		write(`{"level":"loud"}`)
+		w, err := WatchConfig(path, apply)
+		assert.Error(t, err)
+		assert.Nil(t, w)
+		assert.Empty(t, current())
+	})
+
+	write(`{"level":"debug"}`)
+	w, err := WatchConfig(path, apply)
+	require.NoError(t, err)
+	defer w.Stop()
+	assert.Equal(t, Debug, logger.(LevelGetter).GetLevel())
+
+	t.Run("applies changes", func(t *testing.T) {
+		write(`{"level":"warn"}`)
+		waitFor(t, func() bool { return len(current()) == 2 })
+		assert.Equal(t, Warn, logger.(LevelGetter).GetLevel())
+		assert.NoError(t, w.Err())
+	})
+
+	t.Run("keeps the config applied before an invalid one", func(t *testing.T) {
+		write(`{"level":"loud"}`)
+		waitFor(t, func() bool { return w.Err() != nil })
+		assert.Len(t, current(), 2)
+		assert.Equal(t, Warn, logger.(LevelGetter).GetLevel())
+
+		write(`{"level":"error"}`)
+		waitFor(t, func() bool { return w.Err() == nil })
+		assert.Len(t, current(), 3)
+		assert.Equal(t, Error, logger.(LevelGetter).GetLevel())
+	})
+
+	t.Run("reloads a file put back", func(t *testing.T) {
+		require.NoError(t, os.Rename(path, path+".bak"))
+		waitFor(t, func() bool { return w.Err() != nil })
+		require.NoError(t, os.Rename(path+".bak", path))
+		waitFor(t, func() bool { return w.Err() == nil })
+		assert.Len(t, current(), 4)
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		w.Stop()
+		w.Stop()
+		write(`{"level":"trace"}`)
+		time.Sleep(5 * ConfigPollInterval)
+		assert.Len(t, current(), 4)
+	})
+}
+
+// waitFor polls cond until it holds, failing the test if it doesn't within a
+// second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
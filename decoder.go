@@ -0,0 +1,263 @@
+package hclog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONKeys are the keys of the entries read by an EntryDecoder. Each key left
+// empty is the key of this package: "@timestamp", "@level", "@message" and
+// "@module".
+type JSONKeys struct {
+	Timestamp string
+	Level     string
+	Message   string
+	Module    string
+}
+
+// withDefaults returns k with the empty keys set to the keys of this package.
+func (k JSONKeys) withDefaults() JSONKeys {
+	if k.Timestamp == "" {
+		k.Timestamp = "@timestamp"
+	}
+	if k.Level == "" {
+		k.Level = "@level"
+	}
+	if k.Message == "" {
+		k.Message = "@message"
+	}
+	if k.Module == "" {
+		k.Module = "@module"
+	}
+	return k
+}
+
+// CorruptLineError is returned by EntryDecoder.Next for a line which isn't an
+// entry. The line is skipped, and the next call of Next reads on.
+type CorruptLineError struct {
+	// Line is the number of the line, from 1.
+	Line int
+
+	// Text is the line, or its start when an entry follows it on the line.
+	Text string
+
+	// Err is why the line couldn't be read.
+	Err error
+}
+
+func (e *CorruptLineError) Error() string {
+	return fmt.Sprintf("corrupt log line %d: %v", e.Line, e.Err)
+}
+
+func (e *CorruptLineError) Unwrap() error {
+	return e.Err
+}
+
+// EntryDecoder reads back the entries written with JSONFormat, one per line,
+// as returned by NewEntryDecoder. Set its fields before the first call of
+// Next.
+type EntryDecoder struct {
+	// Keys are the keys of the entries, for logs written with other keys
+	// than those of this package.
+	Keys JSONKeys
+
+	// TimeEncoding is the TimeEncoding the entries were written with.
+	TimeEncoding TimeEncoding
+
+	// TimeFormat is the layout of the timestamps written as strings. RFC3339,
+	// with or without a fraction of a second, is the default.
+	TimeFormat string
+
+	r    *bufio.Reader
+	line int
+
+	// next is the entry found after the corrupt start of the last line.
+	next *Entry
+}
+
+// NewEntryDecoder returns an EntryDecoder reading the entries in r.
+func NewEntryDecoder(r io.Reader) *EntryDecoder {
+	return &EntryDecoder{r: bufio.NewReader(r)}
+}
+
+// Next returns the next entry, with the key/value pairs of any other keys in
+// Args, in the order written. The values are as json.Unmarshal decodes them
+// into an interface{}, except for numbers, which are json.Number values so
+// that integers keep all of their digits. Entries without a time, level or
+// message leave them zero, and NoLevel is the level of those with an unknown
+// level.
+//
+// Next returns a *CorruptLineError for a line which isn't an entry, and reads
+// on from the next line when called again. Blank lines are skipped. A line
+// without its end, as left by a crash, is read up to the next entry written
+// on it, if any, so that the entry is returned by the next call. At the end of
+// r, Next returns io.EOF.
+func (d *EntryDecoder) Next() (Entry, error) {
+	if d.next != nil {
+		e := *d.next
+		d.next = nil
+		return e, nil
+	}
+
+	for {
+		b, err := d.r.ReadBytes('\n')
+		if len(b) == 0 || err != nil && err != io.EOF {
+			return Entry{}, err
+		}
+		d.line++
+
+		b = bytes.TrimSpace(b)
+		if len(b) == 0 {
+			continue
+		}
+
+		e, derr := d.decode(b)
+		if derr == nil {
+			return e, nil
+		}
+
+		corrupt := &CorruptLineError{Line: d.line, Text: string(b), Err: derr}
+		for i := 1; i < len(b); i++ {
+			j := bytes.Index(b[i:], []byte(`{"`))
+			if j < 0 {
+				break
+			}
+			i += j
+			if e, err := d.decode(b[i:]); err == nil {
+				corrupt.Text = string(b[:i])
+				d.next = &e
+				break
+			}
+		}
+		return Entry{}, corrupt
+	}
+}
+
+// decode decodes the entry in line.
+func (d *EntryDecoder) decode(line []byte) (Entry, error) {
+	var e Entry
+	keys := d.Keys.withDefaults()
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil {
+		return e, err
+	} else if tok != json.Delim('{') {
+		return e, fmt.Errorf("entry is not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return e, err
+		}
+		key := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return e, err
+		}
+
+		switch key {
+		case keys.Timestamp:
+			if e.Time, err = d.parseTime(raw); err != nil {
+				return e, fmt.Errorf("invalid %s: %w", key, err)
+			}
+		case keys.Level:
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return e, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			e.Level = LevelFromString(s)
+		case keys.Message:
+			if err := json.Unmarshal(raw, &e.Message); err != nil {
+				return e, fmt.Errorf("invalid %s: %w", key, err)
+			}
+		case keys.Module:
+			if err := json.Unmarshal(raw, &e.Name); err != nil {
+				return e, fmt.Errorf("invalid %s: %w", key, err)
+			}
+		default:
+			vdec := json.NewDecoder(bytes.NewReader(raw))
+			vdec.UseNumber()
+			var val interface{}
+			if err := vdec.Decode(&val); err != nil {
+				return e, err
+			}
+			e.Args = append(e.Args, key, val)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return e, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return e, fmt.Errorf("data after the entry")
+	}
+	return e, nil
+}
+
+// parseTime parses a timestamp or time.Time value written with the
+// TimeEncoding of d.
+func (d *EntryDecoder) parseTime(raw json.RawMessage) (time.Time, error) {
+	if d.TimeEncoding == TimeRFC3339 {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return time.Time{}, err
+		}
+		layout := d.TimeFormat
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		return time.Parse(layout, s)
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return time.Time{}, err
+	}
+	switch d.TimeEncoding {
+	case TimeEpochSeconds:
+		// The fraction is parsed as digits rather than as a float, which
+		// would lose the nanoseconds it holds.
+		s := n.String()
+		frac := ""
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			s, frac = s[:i], s[i+1:]
+		}
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		var nsec int64
+		if frac != "" {
+			if nsec, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64); err != nil {
+				return time.Time{}, err
+			}
+		}
+		if strings.HasPrefix(s, "-") {
+			nsec = -nsec
+		}
+		return time.Unix(sec, nsec), nil
+	case TimeEpochMillis:
+		ms, err := n.Int64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	case TimeEpochNanos:
+		ns, err := n.Int64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, ns), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown time encoding %v", d.TimeEncoding)
+}
@@ -0,0 +1,166 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeAll returns the entries read by d, and the corrupt lines it reports.
+func decodeAll(t *testing.T, d *EntryDecoder) ([]Entry, []*CorruptLineError) {
+	var entries []Entry
+	var corrupt []*CorruptLineError
+	for {
+		e, err := d.Next()
+		if err == io.EOF {
+			return entries, corrupt
+		}
+		var cerr *CorruptLineError
+		if errors.As(err, &cerr) {
+			corrupt = append(corrupt, cerr)
+			continue
+		}
+		require.NoError(t, err)
+		entries = append(entries, e)
+	}
+}
+
+func TestEntryDecoder(t *testing.T) {
+	t.Run("reads back the entries written", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, JSONFormat: true, Level: Trace})
+
+		before := time.Now()
+		logger.Info("first", "a", 1, "b", "two")
+		logger.Named("raft").Warn("second", "big", int64(1)<<60, "nested", map[string]int{"x": 1})
+		logger.Trace("third")
+
+		entries, corrupt := decodeAll(t, NewEntryDecoder(&buf))
+		require.Empty(t, corrupt)
+		require.Len(t, entries, 3)
+
+		assert.Equal(t, Info, entries[0].Level)
+		assert.Equal(t, "first", entries[0].Message)
+		assert.Equal(t, "", entries[0].Name)
+		assert.Equal(t, []interface{}{"a", json.Number("1"), "b", "two"}, entries[0].Args)
+		assert.WithinDuration(t, before, entries[0].Time, time.Second)
+
+		assert.Equal(t, Warn, entries[1].Level)
+		assert.Equal(t, "raft", entries[1].Name)
+		assert.Equal(t, []interface{}{
+			"big", json.Number("1152921504606846976"),
+			"nested", map[string]interface{}{"x": json.Number("1")},
+		}, entries[1].Args)
+
+		assert.Equal(t, Trace, entries[2].Level)
+		assert.Nil(t, entries[2].Args)
+	})
+
+	t.Run("skips and reports corrupt lines", func(t *testing.T) {
+		input := `{"@level":"info","@message":"first","@timestamp":"2024-06-10T06:13:20.000000Z"}
+[INFO]  -- not json
+
+{"@level":"info","@message":"second"} trailing
+{"@level":5}
+{"@level":"info","@message":"third"}
+`
+		entries, corrupt := decodeAll(t, NewEntryDecoder(strings.NewReader(input)))
+		require.Len(t, entries, 2)
+		assert.Equal(t, "first", entries[0].Message)
+		assert.True(t, time.Date(2024, 6, 10, 6, 13, 20, 0, time.UTC).Equal(entries[0].Time))
+		assert.Equal(t, "third", entries[1].Message)
+
+		require.Len(t, corrupt, 3)
+		assert.Equal(t, 2, corrupt[0].Line)
+		assert.Equal(t, "[INFO]  -- not json", corrupt[0].Text)
+		assert.Equal(t, 4, corrupt[1].Line)
+		assert.Equal(t, 5, corrupt[2].Line)
+		assert.Contains(t, corrupt[2].Error(), "corrupt log line 5: invalid @level")
+	})
+
+	t.Run("reads a partial line left by a crash", func(t *testing.T) {
+		input := `{"@level":"info","@message":"first"}
+{"@level":"info","@mess{"@level":"warn","@message":"restarted"}
+{"@level":"info","@message":"last","a":"b`
+
+		d := NewEntryDecoder(strings.NewReader(input))
+		entries, corrupt := decodeAll(t, d)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "first", entries[0].Message)
+		assert.Equal(t, Warn, entries[1].Level)
+		assert.Equal(t, "restarted", entries[1].Message)
+
+		require.Len(t, corrupt, 2)
+		assert.Equal(t, 2, corrupt[0].Line)
+		assert.Equal(t, `{"@level":"info","@mess`, corrupt[0].Text)
+		assert.Equal(t, 3, corrupt[1].Line)
+		assert.True(t, errors.Is(corrupt[1], io.ErrUnexpectedEOF))
+
+		_, err := d.Next()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("reads other keys", func(t *testing.T) {
+		input := `{"ts":"10/06/2024 06:13:20","lvl":"error","msg":"failed","logger":"raft","@message":"kept"}` + "\n"
+
+		d := NewEntryDecoder(strings.NewReader(input))
+		d.Keys = JSONKeys{Timestamp: "ts", Level: "lvl", Message: "msg", Module: "logger"}
+		d.TimeFormat = "02/01/2006 15:04:05"
+		entries, corrupt := decodeAll(t, d)
+		require.Empty(t, corrupt)
+		require.Len(t, entries, 1)
+		assert.Equal(t, Error, entries[0].Level)
+		assert.Equal(t, "failed", entries[0].Message)
+		assert.Equal(t, "raft", entries[0].Name)
+		assert.True(t, time.Date(2024, 6, 10, 6, 13, 20, 0, time.UTC).Equal(entries[0].Time))
+		assert.Equal(t, []interface{}{"@message", "kept"}, entries[0].Args)
+	})
+
+	t.Run("reads epoch times", func(t *testing.T) {
+		at := time.Date(2024, 6, 10, 6, 13, 20, 123456789, time.UTC)
+		cases := []struct {
+			encoding TimeEncoding
+			want     time.Time
+		}{
+			{TimeEpochSeconds, at.Truncate(time.Microsecond)},
+			{TimeEpochMillis, at.Truncate(time.Millisecond)},
+			{TimeEpochNanos, at},
+		}
+		for _, c := range cases {
+			t.Run(c.encoding.String(), func(t *testing.T) {
+				var buf bytes.Buffer
+				logger := New(&LoggerOptions{Output: &buf, JSONFormat: true, TimeEncoding: c.encoding})
+				logger.Info("entry", "at", at)
+
+				d := NewEntryDecoder(&buf)
+				d.TimeEncoding = c.encoding
+				e, err := d.Next()
+				require.NoError(t, err)
+				require.Len(t, e.Args, 2)
+
+				got, err := d.parseTime(json.RawMessage(e.Args[1].(json.Number)))
+				require.NoError(t, err)
+				assert.WithinDuration(t, c.want, got, time.Microsecond)
+				assert.WithinDuration(t, time.Now(), e.Time, time.Second)
+			})
+		}
+	})
+
+	t.Run("reads long lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, JSONFormat: true})
+		long := strings.Repeat("x", 1<<17)
+		logger.Info("entry", "long", long)
+
+		e, err := NewEntryDecoder(&buf).Next()
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{"long", long}, e.Args)
+	})
+}
@@ -0,0 +1,59 @@
+package hclog
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// DeprecatedKey is the key of the field tagging the entries written with
+// Deprecate.
+const DeprecatedKey = "deprecated"
+
+var (
+	// deprecations are the call sites of Deprecate which have written their
+	// entry.
+	deprecations sync.Map
+
+	// deprecationsAsErrors is set by EscalateDeprecations, and accessed
+	// atomically.
+	deprecationsAsErrors int32
+)
+
+// EscalateDeprecations makes Deprecate write its entries at the ERROR level
+// rather than WARN, for example so that CI runs fail on the use of
+// deprecated features.
+func EscalateDeprecations(escalate bool) {
+	var v int32
+	if escalate {
+		v = 1
+	}
+	atomic.StoreInt32(&deprecationsAsErrors, v)
+}
+
+// Deprecate writes a WARN entry to l tagged with the DeprecatedKey field, once
+// per call site of Deprecate in the process, so that a library can warn about
+// the use of a deprecated option without flooding the log. An entry filtered
+// out by the level of l doesn't count, and is written once the level allows
+// it.
+func Deprecate(l Logger, msg string, args ...interface{}) {
+	level := Warn
+	if atomic.LoadInt32(&deprecationsAsErrors) != 0 {
+		level = Error
+	}
+	if lg, ok := l.(LevelGetter); ok && level < lg.GetLevel() {
+		return
+	}
+
+	_, file, line, ok := runtime.Caller(1)
+	if ok {
+		site := file + ":" + strconv.Itoa(line)
+		if _, seen := deprecations.LoadOrStore(site, struct{}{}); seen {
+			return
+		}
+	}
+
+	args = append([]interface{}{DeprecatedKey, true}, args...)
+	withCallerSkip(l, 1).Log(level, msg, args...)
+}
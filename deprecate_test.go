@@ -0,0 +1,72 @@
+package hclog
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecate(t *testing.T) {
+	t.Run("writes once per call site", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		for i := 0; i < 3; i++ {
+			Deprecate(logger, "option foo is deprecated", "i", i)
+		}
+		Deprecate(logger.Named("other"), "option bar is deprecated")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, []string{
+			"[WARN]  -- option foo is deprecated: deprecated=true i=0",
+			"[WARN]  [module=other] -- option bar is deprecated: deprecated=true",
+		}, lines)
+	})
+
+	t.Run("respects the level", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			Level:       Error,
+			DisableTime: true,
+		})
+
+		deprecate := func() { Deprecate(logger, "deprecated") }
+		deprecate()
+		assert.Equal(t, "", buf.String())
+
+		logger.SetLevel(Warn)
+		deprecate()
+		deprecate()
+		assert.Equal(t, "[WARN]  -- deprecated: deprecated=true\n", buf.String())
+	})
+
+	t.Run("escalates to errors and keeps the caller location", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			Level:           Error,
+			DisableTime:     true,
+			IncludeLocation: true,
+		})
+
+		EscalateDeprecations(true)
+		defer EscalateDeprecations(false)
+
+		_, _, line, _ := runtime.Caller(0)
+		Deprecate(logger, "deprecated")
+
+		want := fmt.Sprintf("[ERROR][go-hclog/deprecate_test.go:%d] -- deprecated: deprecated=true\n", line+1)
+		assert.Equal(t, want, buf.String())
+	})
+}
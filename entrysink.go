@@ -0,0 +1,126 @@
+package hclog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// AcceptEntry implements EntrySink, writing e in the format of the logger if
+// its level, once demoted by SuppressErrors, is at least the level of the
+// logger for the name of e. StrictArgs checks the args of e as it does those
+// of the entries of the logger. The middleware of the logger is run on a copy
+// of e, which is left unchanged.
+func (l *intLogger) AcceptEntry(e *Entry) {
+	if l.strictArgs {
+		checkArgs(l.implied, e.Args)
+	}
+	level := e.Level
+	if l.suppress != nil {
+		var keep bool
+		if level, keep = l.suppress.level(level, e.Args, nil); !keep {
+			return
+		}
+	}
+	if level < l.levelFor(e.Name) {
+		return
+	}
+	if l.names.denies(e.Name) {
+		l.names.drop()
+		countDropped()
+		return
+	}
+	if implied := l.impliedArgs(); level != e.Level || l.middleware != nil || len(implied) > 0 {
+		ec := *e
+		ec.Level = level
+		if l.middleware != nil || len(implied) > 0 {
+			ec.Args = make([]interface{}, 0, len(implied)+len(e.Args))
+			ec.Args = append(append(ec.Args, implied...), e.Args...)
+		}
+		e = &ec
+	}
+	l.logEntry(e)
+}
+
+// entry returns the Entry passed to the sinks implementing EntrySink, for an
+// entry logged with args.
+func (i *interceptLogger) entry(level Level, msg string, args []interface{}) *Entry {
+	return &Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Name:    i.Name(),
+		Message: msg,
+		Args:    expandFields(i.retrieveImplied(args...)),
+	}
+}
+
+// acceptEntry passes an entry logged with args to the sinks, building the
+// Entry of the sinks implementing EntrySink once for all of them. i.mu must be
+// held.
+func (i *interceptLogger) acceptEntry(level Level, msg string, args []interface{}) {
+	var e *Entry
+	for s := range i.Sinks {
+		if es, ok := s.(EntrySink); ok {
+			if e == nil {
+				e = i.entry(level, msg, args)
+			}
+			es.AcceptEntry(e)
+			continue
+		}
+		s.Accept(i.Name(), level, msg, i.retrieveImplied(args...)...)
+	}
+}
+
+// EncoderFunc is an Encoder calling a function.
+type EncoderFunc func(w io.Writer, e *Entry) error
+
+// Encode calls f(w, e).
+func (f EncoderFunc) Encode(w io.Writer, e *Entry) error {
+	return f(w, e)
+}
+
+// encoderSink is the sink returned by NewEncoderSink.
+type encoderSink struct {
+	level Level
+	enc   Encoder
+
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// NewEncoderSink returns a sink writing the entries at level or above to w,
+// encoded by enc, for the entries of an InterceptLogger in a format of its
+// own. Each entry is encoded in full before it is written to w, with a single
+// call of Write, and is dropped if enc returns an error. The sink is safe for
+// concurrent use.
+func NewEncoderSink(w io.Writer, level Level, enc Encoder) SinkAdapter {
+	return &encoderSink{level: level, enc: enc, w: w}
+}
+
+// Accept implements SinkAdapter, for the loggers which don't pass their
+// entries to AcceptEntry.
+func (s *encoderSink) Accept(name string, level Level, msg string, args ...interface{}) {
+	if level < s.level {
+		return
+	}
+	s.AcceptEntry(&Entry{Time: time.Now(), Level: level, Name: name, Message: msg, Args: expandFields(args)})
+}
+
+// AcceptEntry implements EntrySink.
+func (s *encoderSink) AcceptEntry(e *Entry) {
+	if e.Level < s.level {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf.Reset()
+	if err := s.enc.Encode(&s.buf, e); err != nil {
+		countDropped()
+		return
+	}
+	s.w.Write(s.buf.Bytes())
+}
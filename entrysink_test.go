@@ -0,0 +1,189 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entryRecorder records the entries passed to AcceptEntry.
+type entryRecorder struct {
+	entries []*Entry
+}
+
+func (r *entryRecorder) Accept(name string, level Level, msg string, args ...interface{}) {
+	panic("Accept called on an EntrySink")
+}
+
+func (r *entryRecorder) AcceptEntry(e *Entry) {
+	r.entries = append(r.entries, e)
+}
+
+func TestEntrySink(t *testing.T) {
+	t.Run("formats the entries per sink", func(t *testing.T) {
+		var primary, jsonOut, textOut bytes.Buffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: &primary, DisableTime: true, Level: Warn})
+		logger.RegisterSink(NewSinkAdapter(&LoggerOptions{Output: &jsonOut, JSONFormat: true, Level: Trace}))
+		logger.RegisterSink(NewSinkAdapter(&LoggerOptions{Output: &textOut, DisableTime: true, Level: Info}))
+
+		raft := logger.Named("raft").With("peer", "10.0.0.2")
+		raft.Trace("heartbeat")
+		raft.Info("elected", "term", 3)
+		raft.Warn("lagging")
+
+		assert.Equal(t, "[WARN]  [module=raft] -- lagging: peer=10.0.0.2\n", primary.String())
+		assert.Equal(t,
+			"[INFO]  [module=raft] -- elected: peer=10.0.0.2 term=3\n"+
+				"[WARN]  [module=raft] -- lagging: peer=10.0.0.2\n",
+			textOut.String())
+
+		lines := strings.Split(strings.TrimSpace(jsonOut.String()), "\n")
+		require.Len(t, lines, 3)
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &raw))
+		assert.Equal(t, "heartbeat", raw["@message"])
+		assert.Equal(t, "trace", raw["@level"])
+		assert.Equal(t, "raft", raw["@module"])
+		assert.Equal(t, "10.0.0.2", raw["peer"])
+	})
+
+	t.Run("shares the entry between sinks", func(t *testing.T) {
+		var redacted bytes.Buffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: ioutil.Discard})
+		first, second := &entryRecorder{}, &entryRecorder{}
+		logger.RegisterSink(first)
+		logger.RegisterSink(NewSinkAdapter(&LoggerOptions{
+			Output:      &redacted,
+			DisableTime: true,
+			Middleware:  []func(*Entry) bool{redactKeys([]string{"token"})},
+		}))
+		logger.RegisterSink(second)
+
+		logger.Info("login", "user", "alice", "token", "secret")
+		logger.(FieldLogger).WarnF("fields", Int("n", 1))
+
+		require.Len(t, first.entries, 2)
+		require.Len(t, second.entries, 2)
+		assert.True(t, first.entries[0] == second.entries[0])
+		assert.Equal(t, []interface{}{"user", "alice", "token", "secret"}, first.entries[0].Args)
+		assert.Equal(t, []interface{}{"n", int64(1)}, first.entries[1].Args)
+		assert.Equal(t, Warn, first.entries[1].Level)
+		assert.Contains(t, redacted.String(), "token=[REDACTED]")
+	})
+
+	t.Run("applies the suppressed errors and strict args of the sink", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: ioutil.Discard})
+		recorder := &entryRecorder{}
+		logger.RegisterSink(NewSinkAdapter(&LoggerOptions{
+			Output:         &out,
+			DisableTime:    true,
+			Level:          Info,
+			SuppressErrors: []error{io.EOF},
+			StrictArgs:     true,
+		}))
+		logger.RegisterSink(recorder)
+
+		logger.Error("read failed", "error", io.EOF)
+		logger.Error("write failed", "error", io.ErrShortWrite)
+		assert.Equal(t, "[ERROR] -- write failed: error=\"short write\"\n", out.String())
+		require.Len(t, recorder.entries, 2)
+		assert.Equal(t, Error, recorder.entries[0].Level)
+
+		assert.Panics(t, func() { logger.Info("odd", "key") })
+	})
+
+	t.Run("deregisters sinks while entries are written", func(t *testing.T) {
+		logger := NewInterceptLogger(&LoggerOptions{Output: ioutil.Discard})
+		var kept, removed lockedBuffer
+		logger.RegisterSink(NewSinkAdapter(&LoggerOptions{Output: &kept, JSONFormat: true}))
+		sink := NewSinkAdapter(&LoggerOptions{Output: &removed, JSONFormat: true})
+		logger.RegisterSink(sink)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					logger.Info("entry", "g", g, "i", i)
+				}
+			}(g)
+		}
+		logger.DeregisterSink(sink)
+		wg.Wait()
+
+		lines := strings.Split(strings.TrimSpace(kept.String()), "\n")
+		assert.Len(t, lines, 400)
+		for _, out := range []string{kept.String(), removed.String()} {
+			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+				if line == "" {
+					continue
+				}
+				var raw map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(line), &raw), line)
+			}
+		}
+	})
+}
+
+func TestEncoderSink(t *testing.T) {
+	var out bytes.Buffer
+	enc := EncoderFunc(func(w io.Writer, e *Entry) error {
+		if e.Message == "unencodable" {
+			return errors.New("cannot encode")
+		}
+		_, err := fmt.Fprintf(w, "%s|%s|%s|%v\n", e.Level, e.Name, e.Message, e.Args)
+		return err
+	})
+	logger := NewInterceptLogger(&LoggerOptions{Output: ioutil.Discard})
+	logger.RegisterSink(NewEncoderSink(&out, Debug, enc))
+
+	logger.Named("raft").Trace("hidden")
+	logger.Named("raft").Debug("shown", "a", 1)
+	logger.Info("unencodable")
+	logger.(FieldLogger).ErrorF("fields", Str("b", "two"))
+	assert.Equal(t, "debug|raft|shown|[a 1]\nerror||fields|[b two]\n", out.String())
+
+	out.Reset()
+	NewEncoderSink(&out, Info, enc).Accept("http", Info, "accepted", "c", 3)
+	assert.Equal(t, "info|http|accepted|[c 3]\n", out.String())
+}
+
+// argsOnlySink is a sink implementing Accept only, for BenchmarkSinks.
+type argsOnlySink struct {
+	SinkAdapter
+}
+
+func BenchmarkSinks(b *testing.B) {
+	cases := []struct {
+		name string
+		wrap func(SinkAdapter) SinkAdapter
+	}{
+		{"accept", func(s SinkAdapter) SinkAdapter { return argsOnlySink{s} }},
+		{"shared entry", func(s SinkAdapter) SinkAdapter { return s }},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			logger := NewInterceptLogger(&LoggerOptions{Output: ioutil.Discard, Level: Error})
+			for i := 0; i < 4; i++ {
+				logger.RegisterSink(c.wrap(NewSinkAdapter(&LoggerOptions{Output: ioutil.Discard, Level: Error})))
+			}
+			logger = logger.With("peer", "10.0.0.2", "term", 3).(InterceptLogger)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				logger.Info("entry", "a", 1, "b", "two")
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package hclog
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultOptionsFromEnv returns the options of the Default logger, with the
+// values of the environment variables below applied over them, each name
+// prefixed with prefix:
+//
+//	LOG_LEVEL              the level, as accepted by LevelFromString
+//	LOG_FORMAT             text or json
+//	LOG_INCLUDE_LOCATION   a boolean, as accepted by strconv.ParseBool
+//	LOG_COLOR              auto, always or never, or a boolean
+//
+// Malformed values are ignored, and reported by a single warning written by
+// the first logger created with the options.
+func DefaultOptionsFromEnv(prefix string) *LoggerOptions {
+	opts := &LoggerOptions{
+		Level:  DefaultLevel,
+		Output: DefaultOutput,
+	}
+
+	var malformed []interface{}
+	lookup := func(name string) (string, bool) {
+		v, ok := os.LookupEnv(prefix + name)
+		return strings.TrimSpace(v), ok && strings.TrimSpace(v) != ""
+	}
+	invalid := func(name, value string) {
+		malformed = append(malformed, prefix+name, value)
+	}
+
+	if v, ok := lookup("LOG_LEVEL"); ok {
+		if level := LevelFromString(v); level != NoLevel {
+			opts.Level = level
+		} else {
+			invalid("LOG_LEVEL", v)
+		}
+	}
+
+	if v, ok := lookup("LOG_FORMAT"); ok {
+		switch strings.ToLower(v) {
+		case "text":
+			opts.JSONFormat = false
+		case "json":
+			opts.JSONFormat = true
+		default:
+			invalid("LOG_FORMAT", v)
+		}
+	}
+
+	if v, ok := lookup("LOG_INCLUDE_LOCATION"); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			opts.IncludeLocation = b
+		} else {
+			invalid("LOG_INCLUDE_LOCATION", v)
+		}
+	}
+
+	if v, ok := lookup("LOG_COLOR"); ok {
+		if color, ok := colorFromString(v); ok {
+			opts.Color = color
+		} else {
+			invalid("LOG_COLOR", v)
+		}
+	}
+
+	if len(malformed) > 0 {
+		opts.envWarning = &envWarning{args: malformed}
+	}
+	return opts
+}
+
+// colorFromString parses the value of LOG_COLOR.
+func colorFromString(s string) (ColorOption, bool) {
+	switch strings.ToLower(s) {
+	case "auto":
+		return AutoColor, true
+	case "always", "force":
+		return ForceColor, true
+	case "never", "off":
+		return ColorOff, true
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		if b {
+			return ForceColor, true
+		}
+		return ColorOff, true
+	}
+	return ColorOff, false
+}
+
+// envWarning holds the malformed environment variables found by
+// DefaultOptionsFromEnv, as key/value pairs, until a logger reports them.
+type envWarning struct {
+	once sync.Once
+	args []interface{}
+}
+
+// report writes the warning through l, the first time it is called.
+func (w *envWarning) report(l Logger) {
+	if w == nil {
+		return
+	}
+	w.once.Do(func() {
+		l.Warn("ignoring malformed logging environment variables", w.args...)
+	})
+}
@@ -0,0 +1,82 @@
+package hclog
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv sets the environment variables in vars, returning a function
+// restoring them.
+func setenv(vars map[string]string) func() {
+	old := make(map[string]*string, len(vars))
+	for k, v := range vars {
+		if prev, ok := os.LookupEnv(k); ok {
+			old[k] = &prev
+		} else {
+			old[k] = nil
+		}
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range old {
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+func TestDefaultOptionsFromEnv(t *testing.T) {
+	t.Run("applies the variables", func(t *testing.T) {
+		defer setenv(map[string]string{
+			"TEST_LOG_LEVEL":            "debug",
+			"TEST_LOG_FORMAT":           "JSON",
+			"TEST_LOG_INCLUDE_LOCATION": "true",
+			"TEST_LOG_COLOR":            "always",
+		})()
+
+		opts := DefaultOptionsFromEnv("TEST_")
+		assert.Equal(t, Debug, opts.Level)
+		assert.True(t, opts.JSONFormat)
+		assert.True(t, opts.IncludeLocation)
+		assert.Equal(t, ForceColor, opts.Color)
+		assert.Nil(t, opts.envWarning)
+	})
+
+	t.Run("uses the defaults without variables", func(t *testing.T) {
+		opts := DefaultOptionsFromEnv("TEST_UNSET_")
+		assert.Equal(t, DefaultLevel, opts.Level)
+		assert.Equal(t, DefaultOutput, opts.Output)
+		assert.False(t, opts.JSONFormat)
+		assert.Equal(t, ColorOff, opts.Color)
+	})
+
+	t.Run("warns once of malformed values", func(t *testing.T) {
+		defer setenv(map[string]string{
+			"TEST_LOG_LEVEL":            "loud",
+			"TEST_LOG_FORMAT":           "json",
+			"TEST_LOG_INCLUDE_LOCATION": "sometimes",
+		})()
+
+		var buf bytes.Buffer
+		opts := DefaultOptionsFromEnv("TEST_")
+		opts.Output = &buf
+		opts.DisableTime = true
+
+		assert.Equal(t, DefaultLevel, opts.Level)
+		assert.True(t, opts.JSONFormat)
+		assert.False(t, opts.IncludeLocation)
+
+		opts.JSONFormat = false
+		New(opts)
+		New(opts)
+
+		expected := "[WARN]  -- ignoring malformed logging environment variables: TEST_LOG_LEVEL=loud TEST_LOG_INCLUDE_LOCATION=sometimes\n"
+		assert.Equal(t, expected, buf.String())
+	})
+}
@@ -0,0 +1,60 @@
+package hclog
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// escape returns s with its control characters escaped, as \n, \r and \t or
+// as \x1b and \u0085 for the others, so that a message or value can't forge
+// a line of its own in the text format. Line and paragraph separators are
+// escaped too. If rawNewlines is set, \n and \r are left as they are.
+func escape(s string, rawNewlines bool) string {
+	i := 0
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7f || c >= utf8.RuneSelf {
+			break
+		}
+	}
+	if i == len(s) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	b.WriteString(s[:i])
+
+	for len(s[i:]) > 0 {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\n' && !rawNewlines:
+			b.WriteString(`\n`)
+		case r == '\r' && !rawNewlines:
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n', r == '\r':
+			b.WriteRune(r)
+		case r < utf8.RuneSelf && unicode.IsControl(r):
+			b.WriteString(`\x`)
+			b.WriteByte(hexDigits[r>>4])
+			b.WriteByte(hexDigits[r&0xf])
+		case unicode.IsControl(r), r == '\u2028', r == '\u2029':
+			b.WriteString(`\u`)
+			for shift := uint(12); ; shift -= 4 {
+				b.WriteByte(hexDigits[(r>>shift)&0xf])
+				if shift == 0 {
+					break
+				}
+			}
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscape(t *testing.T) {
+	cases := []struct {
+		in, out, raw string
+	}{
+		{"plain", "plain", "plain"},
+		{"héllo wörld", "héllo wörld", "héllo wörld"},
+		{"a\nb", `a\nb`, "a\nb"},
+		{"a\r\nb", `a\r\nb`, "a\r\nb"},
+		{"a\tb", `a\tb`, `a\tb`},
+		{"\x1b[31mred", `\x1b[31mred`, `\x1b[31mred`},
+		{"nul\x00", `nul\x00`, `nul\x00`},
+		{"del\x7f", `del\x7f`, `del\x7f`},
+		{"next\u0085line", `next\u0085line`, `next\u0085line`},
+		{"line\u2028sep", `line\u2028sep`, `line\u2028sep`},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.out, escape(c.in, false), "%q", c.in)
+		assert.Equal(t, c.raw, escape(c.in, true), "%q raw", c.in)
+	}
+}
+
+func TestLogger_escaping(t *testing.T) {
+	t.Run("escapes messages and values", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		forged := "bob\n[ERROR] -- admin logged in"
+		logger.Info("login failed\r\n", "user", forged, "error", errors.New("bad\tpassword"))
+		logger.(FieldLogger).InfoF("login failed", Str("user", forged))
+		logger.Info("login failed", "users", []string{"alice", forged})
+
+		expected := "[INFO]  -- login failed\\r\\n: user=\"bob\\n[ERROR] -- admin logged in\" error=bad\\tpassword\n" +
+			"[INFO]  -- login failed: user=\"bob\\n[ERROR] -- admin logged in\"\n" +
+			"[INFO]  -- login failed: users=[alice, \"bob\\n[ERROR] -- admin logged in\"]\n"
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("escapes keys", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.Info("request", "x\n[ERROR] -- forged", 1)
+		assert.Equal(t, "[INFO]  -- request: x\\n[ERROR] -- forged=1\n", buf.String())
+	})
+
+	t.Run("allows raw newlines", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:           &buf,
+			DisableTime:      true,
+			AllowRawNewlines: true,
+		})
+
+		logger.Info("multi\nline", "query", "SELECT *\nFROM t", "color", "\x1b[0m")
+		assert.Equal(t, "[INFO]  -- multi\nline: query=\"SELECT *\nFROM t\" color=\\x1b[0m\n", buf.String())
+	})
+
+	t.Run("keeps stack traces on lines of their own", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.Error("failed", CapturedStacktrace("main.main\n\tmain.go:10"))
+		assert.Equal(t, "[ERROR] -- failed:\nmain.main\n\tmain.go:10\n", buf.String())
+	})
+
+	t.Run("leaves JSON to the encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		})
+
+		logger.Info("multi\nline", "value", "a\x1bb")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "multi\nline", entry["@message"])
+		assert.Equal(t, "a\x1bb", entry["value"])
+		assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+	})
+}
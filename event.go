@@ -0,0 +1,138 @@
+package hclog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventKey is the key of the field naming the event in the entry reporting an
+// event of which required fields are missing.
+const EventKey = "event"
+
+// ErrEventSent is returned by EventBuilder.Send when the builder was already
+// sent.
+var ErrEventSent = errors.New("event already sent")
+
+// EventBuilder builds an entry out of typed fields, for events of which the
+// fields must follow a schema. It is returned by Event, and is not safe for
+// concurrent use.
+type EventBuilder struct {
+	l        Logger
+	name     string
+	fields   []Field
+	required []string
+	sent     bool
+
+	inline [8]Field
+}
+
+// Event returns an EventBuilder writing the entry of the event name to l,
+// with name as the message. Its fields are chained and the entry written
+// with Send:
+//
+//	err := hclog.Event(logger, "order placed").
+//		Require("user", "count").
+//		Str("user", user).
+//		Int("count", int64(n)).
+//		Send(hclog.Info)
+//
+// Fields are held as Field values, like those of FieldLogger, so they are not
+// boxed when l is a FieldLogger. A builder is sent once: the methods of a
+// builder already sent do nothing, and Send returns ErrEventSent.
+func Event(l Logger, name string) *EventBuilder {
+	b := &EventBuilder{l: withCallerSkip(l, 1), name: name}
+	b.fields = b.inline[:0]
+	return b
+}
+
+// Require makes Send reject the event if any of keys has no field.
+func (b *EventBuilder) Require(keys ...string) *EventBuilder {
+	if !b.sent {
+		b.required = append(b.required, keys...)
+	}
+	return b
+}
+
+// Field adds f to the event.
+func (b *EventBuilder) Field(f Field) *EventBuilder {
+	if !b.sent {
+		b.fields = append(b.fields, f)
+	}
+	return b
+}
+
+// Str adds a string field to the event.
+func (b *EventBuilder) Str(key, value string) *EventBuilder {
+	return b.Field(Str(key, value))
+}
+
+// Int adds an integer field to the event.
+func (b *EventBuilder) Int(key string, value int64) *EventBuilder {
+	return b.Field(Int(key, value))
+}
+
+// Bool adds a boolean field to the event.
+func (b *EventBuilder) Bool(key string, value bool) *EventBuilder {
+	return b.Field(Bool(key, value))
+}
+
+// Err adds an error field to the event, under ErrorKey.
+func (b *EventBuilder) Err(err error) *EventBuilder {
+	return b.Field(Err(err))
+}
+
+// Dur adds a duration field to the event.
+func (b *EventBuilder) Dur(key string, value time.Duration) *EventBuilder {
+	return b.Field(Dur(key, value))
+}
+
+// Time adds a time field to the event.
+func (b *EventBuilder) Time(key string, value time.Time) *EventBuilder {
+	return b.Field(Time(key, value))
+}
+
+// Send writes the entry of the event at level. If required fields are
+// missing, the event is not written: an Error entry naming the event and the
+// missing fields is written instead, and returned as an error. Sending a
+// builder again returns ErrEventSent.
+func (b *EventBuilder) Send(level Level) error {
+	if b.sent {
+		return ErrEventSent
+	}
+	b.sent = true
+	fields := b.fields
+	b.fields = nil
+
+	if missing := b.missing(fields); len(missing) > 0 {
+		err := fmt.Errorf("event %q is missing required fields %s", b.name, strings.Join(missing, ", "))
+		b.l.Error("invalid event", EventKey, b.name, "missing", missing)
+		return err
+	}
+
+	if fl, ok := b.l.(FieldLogger); ok {
+		fl.LogF(level, b.name, fields...)
+	} else {
+		b.l.Log(level, b.name, fieldArgs(fields)...)
+	}
+	return nil
+}
+
+// missing returns the required keys of which fields has no field.
+func (b *EventBuilder) missing(fields []Field) []string {
+	var missing []string
+	for _, key := range b.required {
+		found := false
+		for _, f := range fields {
+			if f.Key == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
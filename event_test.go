@@ -0,0 +1,70 @@
+package hclog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent(t *testing.T) {
+	t.Run("writes the fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		err := Event(logger, "order placed").
+			Require("user", "count").
+			Str("user", "alice").
+			Int("count", 3).
+			Bool("gift", true).
+			Dur("took", time.Second).
+			Err(errors.New("retried")).
+			Send(Info)
+		require.NoError(t, err)
+		assert.Equal(t, "[INFO]  -- order placed: user=alice count=3 gift=true took=1s error=retried\n", buf.String())
+	})
+
+	t.Run("rejects missing required fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		err := Event(logger, "order placed").
+			Require("user", "count").
+			Str("user", "alice").
+			Send(Info)
+		require.Error(t, err)
+		assert.Equal(t, `event "order placed" is missing required fields count`, err.Error())
+		assert.Equal(t, "[ERROR] -- invalid event: event=\"order placed\" missing=[count]\n", buf.String())
+	})
+
+	t.Run("rejects a builder sent twice", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		b := Event(logger, "login").Str("user", "alice")
+		require.NoError(t, b.Send(Info))
+		b.Str("user", "bob")
+		assert.Equal(t, ErrEventSent, b.Send(Info))
+		assert.Equal(t, "[INFO]  -- login: user=alice\n", buf.String())
+	})
+
+	t.Run("reports the location of the caller", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, IncludeLocation: true})
+
+		Event(logger, "login").Send(Info)
+		assert.Contains(t, buf.String(), "event_test.go:")
+	})
+
+	t.Run("writes to loggers without fields support", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		err := Event(struct{ Logger }{logger}, "login").Str("user", "alice").Send(Warn)
+		require.NoError(t, err)
+		assert.Equal(t, "[WARN]  -- login: user=alice\n", buf.String())
+	})
+}
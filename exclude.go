@@ -1,8 +1,10 @@
 package hclog
 
 import (
+	"path"
 	"regexp"
 	"strings"
+	"sync/atomic"
 )
 
 // ExcludeByMessage provides a simple way to build a list of log messages that
@@ -69,3 +71,65 @@ func (ff ExcludeFuncs) Exclude(level Level, msg string, args ...interface{}) boo
 
 	return false
 }
+
+// nameFilter drops the entries of loggers by name, as configured with the
+// ExcludeNames and IncludeOnlyNames options. It is shared by a root logger and
+// every logger derived from it.
+type nameFilter struct {
+	// dropped counts the entries dropped because of their logger name. It is
+	// accessed atomically, and kept first for its alignment on 32-bit platforms.
+	dropped uint64
+
+	exclude []string
+	include []string
+}
+
+// newNameFilter returns a filter for the given patterns, or nil if there are
+// none.
+func newNameFilter(exclude, include []string) *nameFilter {
+	if len(exclude) == 0 && len(include) == 0 {
+		return nil
+	}
+	return &nameFilter{exclude: exclude, include: include}
+}
+
+// denies reports whether the entries of the logger named name are dropped:
+// when the name matches one of the excluded patterns, or when there are
+// patterns to include and the name matches none of them.
+func (f *nameFilter) denies(name string) bool {
+	if f == nil {
+		return false
+	}
+	for _, pattern := range f.exclude {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+	if len(f.include) == 0 {
+		return false
+	}
+	for _, pattern := range f.include {
+		if matchName(pattern, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// drop records an entry dropped because of its logger name.
+func (f *nameFilter) drop() {
+	atomic.AddUint64(&f.dropped, 1)
+}
+
+// matchName reports whether the logger named name matches pattern. Patterns
+// with the glob characters of path.Match are matched as globs, so that
+// "noisy-*" matches "noisy-lib" and "noisy-lib.sub". Other patterns match the
+// logger of that name and its subloggers, so "raft" matches "raft.fsm" but
+// not "raftx".
+func matchName(pattern, name string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, err := path.Match(pattern, name)
+		return err == nil && ok
+	}
+	return name == pattern || strings.HasPrefix(name, pattern+".")
+}
@@ -1,7 +1,9 @@
 package hclog
 
 import (
+	"bytes"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -53,3 +55,83 @@ func TestExclude(t *testing.T) {
 
 	})
 }
+
+func TestExcludeNames(t *testing.T) {
+	t.Run("matches names and globs", func(t *testing.T) {
+		assert.True(t, matchName("raft", "raft"))
+		assert.True(t, matchName("raft", "raft.fsm"))
+		assert.False(t, matchName("raft", "raftx"))
+		assert.False(t, matchName("raft.fsm", "raft"))
+		assert.True(t, matchName("noisy-*", "noisy-lib"))
+		assert.True(t, matchName("noisy-*", "noisy-lib.sub"))
+		assert.False(t, matchName("noisy-*", "quiet"))
+		assert.False(t, matchName("[", "["))
+	})
+
+	t.Run("drops and counts excluded loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:       &buf,
+			DisableTime:  true,
+			ExcludeNames: []string{"noisy-lib"},
+		})
+
+		logger.Named("noisy-lib").Info("dropped")
+		logger.Named("noisy-lib").Named("sub").Info("dropped")
+		logger.Named("noisy-lib").Debug("below the level, not counted")
+		logger.Named("app").Info("kept")
+		logger.Info("root kept")
+
+		assert.Equal(t, "[INFO]  [module=app] -- kept\n[INFO]  -- root kept\n", buf.String())
+		assert.Equal(t, uint64(2), logger.(*intLogger).DroppedByName())
+	})
+
+	t.Run("includes only the listed loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:           &buf,
+			DisableTime:      true,
+			ExcludeNames:     []string{"app.secret"},
+			IncludeOnlyNames: []string{"app", "db*"},
+		})
+
+		logger.Info("root dropped")
+		logger.Named("app").Info("app kept")
+		logger.Named("app").Named("secret").Info("excluded first")
+		logger.Named("db-pool").Info("db kept")
+		logger.Named("http").Info("http dropped")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, []string{
+			"[INFO]  [module=app] -- app kept",
+			"[INFO]  [module=db-pool] -- db kept",
+		}, lines)
+		assert.Equal(t, uint64(3), logger.(*intLogger).DroppedByName())
+	})
+
+	t.Run("applies to intercept logger sinks", func(t *testing.T) {
+		var buf bytes.Buffer
+		var sbuf bytes.Buffer
+
+		intercept := NewInterceptLogger(&LoggerOptions{
+			Output:       &buf,
+			DisableTime:  true,
+			ExcludeNames: []string{"noisy-lib"},
+		})
+		sink := NewSinkAdapter(&LoggerOptions{
+			Level:       Debug,
+			Output:      &sbuf,
+			DisableTime: true,
+		})
+		intercept.RegisterSink(sink)
+		defer intercept.DeregisterSink(sink)
+
+		intercept.Named("noisy-lib").Info("dropped")
+		intercept.Named("app").Debug("sink only")
+
+		assert.Equal(t, "", buf.String())
+		assert.Equal(t, "[DEBUG] [module=app] -- sink only\n", sbuf.String())
+	})
+}
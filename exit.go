@@ -0,0 +1,159 @@
+package hclog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultExitHookTimeout is the time Fatal waits for the exit hooks when
+// LoggerOptions.ExitHookTimeout is zero.
+const DefaultExitHookTimeout = 5 * time.Second
+
+// exitOptions are the ExitHooks, ExitHookTimeout and ExitCode of a logger.
+type exitOptions struct {
+	hooks   []func()
+	timeout time.Duration
+	code    int
+}
+
+func newExitOptions(opts *LoggerOptions) exitOptions {
+	e := exitOptions{
+		hooks:   append([]func(){}, opts.ExitHooks...),
+		timeout: opts.ExitHookTimeout,
+		code:    opts.ExitCode,
+	}
+	if len(e.hooks) == 0 {
+		e.hooks = nil
+	}
+	if e.timeout == 0 {
+		e.timeout = DefaultExitHookTimeout
+	}
+	if e.code == 0 {
+		e.code = 1
+	}
+	return e
+}
+
+// checkExit rejects a negative ExitHookTimeout or ExitCode.
+func checkExit(opts *LoggerOptions) error {
+	switch {
+	case opts.ExitHookTimeout < 0:
+		return fmt.Errorf("negative ExitHookTimeout %s", opts.ExitHookTimeout)
+	case opts.ExitCode < 0:
+		return fmt.Errorf("negative ExitCode %d", opts.ExitCode)
+	}
+	return nil
+}
+
+// exitOptionsOf returns the exit options of l, or the defaults for loggers not
+// created by this package.
+func exitOptionsOf(l Logger) exitOptions {
+	if eo, ok := l.(interface{ exitOptions() exitOptions }); ok {
+		return eo.exitOptions()
+	}
+	return newExitOptions(&LoggerOptions{})
+}
+
+var (
+	// exit is os.Exit, replaced in tests.
+	exit = os.Exit
+
+	// exiting runs the exit hooks of the first call to Fatal, the others
+	// waiting for them before exiting too.
+	exiting = new(sync.Once)
+)
+
+// Fatal writes an Error entry to l, runs the ExitHooks of l and exits the
+// process with its ExitCode, 1 by default. The hooks are given up to
+// ExitHookTimeout to return, in case they hang, and run once even if Fatal is
+// called from several goroutines: the other calls wait for them, and exit.
+//
+//	hclog.Fatal(logger, "failed to bind", "addr", addr, "error", err)
+func Fatal(l Logger, msg string, args ...interface{}) {
+	withCallerSkip(l, 1).Error(msg, args...)
+
+	opts := exitOptionsOf(l)
+	exiting.Do(func() { runExitHooks(opts) })
+	exit(opts.code)
+}
+
+// runExitHooks runs the hooks of opts in order, waiting for them up to the
+// timeout of opts. A hook panicking doesn't keep the next ones from running.
+func runExitHooks(opts exitOptions) {
+	if len(opts.hooks) == 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, hook := range opts.hooks {
+			func() {
+				defer func() { recover() }()
+				hook()
+			}()
+		}
+	}()
+
+	timer := time.NewTimer(opts.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
+
+// PanicError is the value Panic panics with, holding the entry written.
+type PanicError struct {
+	Message string
+
+	// Args are the key/value pairs given to Panic.
+	Args []interface{}
+}
+
+// Error returns the message and the key/value pairs, as in
+// "invalid config: path=/etc/app.hcl error=EOF".
+func (e *PanicError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	args := expandFields(e.Args)
+	for i := 0; i < len(args); i += 2 {
+		if i == 0 {
+			b.WriteByte(':')
+		}
+		b.WriteByte(' ')
+		if i+1 == len(args) {
+			fmt.Fprintf(&b, "%s=%v", MissingKey, args[i])
+			break
+		}
+		fmt.Fprintf(&b, "%v=%v", args[i], args[i+1])
+	}
+	return b.String()
+}
+
+// Unwrap returns the error given under ErrorKey, if any.
+func (e *PanicError) Unwrap() error {
+	args := expandFields(e.Args)
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == ErrorKey {
+			if err, ok := args[i+1].(error); ok {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Panic writes an Error entry to l, and panics with a *PanicError holding
+// the message and the key/value pairs, for initializers which can't go on.
+// Error is the most severe level entries are written at, so Panic entries are
+// only told apart by the panic following them.
+//
+//	hclog.Panic(logger, "invalid config", "path", path, "error", err)
+func Panic(l Logger, msg string, args ...interface{}) {
+	withCallerSkip(l, 1).Error(msg, args...)
+	panic(&PanicError{Message: msg, Args: args})
+}
@@ -0,0 +1,130 @@
+package hclog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExit replaces os.Exit for the test, recording the codes exited with.
+func fakeExit() (codes *[]int, restore func()) {
+	var mu sync.Mutex
+	codes = new([]int)
+	exit = func(code int) {
+		mu.Lock()
+		defer mu.Unlock()
+		*codes = append(*codes, code)
+	}
+	exiting = new(sync.Once)
+	return codes, func() { exit = os.Exit }
+}
+
+func TestFatal(t *testing.T) {
+	t.Run("runs the hooks and exits", func(t *testing.T) {
+		codes, restore := fakeExit()
+		defer restore()
+
+		var buf bytes.Buffer
+		var ran []string
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			ExitHooks: []func(){
+				func() { ran = append(ran, "flush") },
+				func() { panic("boom") },
+				func() { ran = append(ran, "marker") },
+			},
+			ExitCode: 3,
+		})
+
+		Fatal(logger.Named("init"), "failed to bind", "addr", ":80")
+		assert.Equal(t, "[ERROR] [module=init] -- failed to bind: addr=:80\n", buf.String())
+		assert.Equal(t, []string{"flush", "marker"}, ran)
+		assert.Equal(t, []int{3}, *codes)
+	})
+
+	t.Run("gives up on hanging hooks", func(t *testing.T) {
+		codes, restore := fakeExit()
+		defer restore()
+
+		block := make(chan struct{})
+		defer close(block)
+		logger := New(&LoggerOptions{
+			Output:          &bytes.Buffer{},
+			ExitHooks:       []func(){func() { <-block }},
+			ExitHookTimeout: 10 * time.Millisecond,
+		})
+
+		Fatal(logger, "hung")
+		assert.Equal(t, []int{1}, *codes)
+	})
+
+	t.Run("runs the hooks once", func(t *testing.T) {
+		codes, restore := fakeExit()
+		defer restore()
+
+		var runs int32
+		logger := NewInterceptLogger(&LoggerOptions{
+			Output: &bytes.Buffer{},
+			ExitHooks: []func(){func() {
+				atomic.AddInt32(&runs, 1)
+				time.Sleep(10 * time.Millisecond)
+			}},
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				Fatal(logger, "concurrent")
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+		assert.Len(t, *codes, 5)
+	})
+
+	t.Run("reports the location of the caller", func(t *testing.T) {
+		_, restore := fakeExit()
+		defer restore()
+
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, IncludeLocation: true})
+		Fatal(logger, "located")
+		assert.Contains(t, buf.String(), "exit_test.go:")
+	})
+}
+
+func TestPanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+	cause := errors.New("EOF")
+
+	defer func() {
+		v := recover()
+		pe, ok := v.(*PanicError)
+		require.True(t, ok, "expected a *PanicError, got %T", v)
+		assert.Equal(t, "invalid config", pe.Message)
+		assert.Equal(t, []interface{}{"path", "/etc/app.hcl", "error", cause}, pe.Args)
+		assert.Equal(t, "invalid config: path=/etc/app.hcl error=EOF", pe.Error())
+		assert.True(t, errors.Is(pe, cause))
+		assert.Equal(t, "[ERROR] -- invalid config: path=/etc/app.hcl error=EOF\n", buf.String())
+	}()
+	Panic(logger, "invalid config", "path", "/etc/app.hcl", "error", cause)
+}
+
+func TestExitOptions(t *testing.T) {
+	_, err := NewWithError(&LoggerOptions{ExitCode: -1})
+	assert.Error(t, err)
+
+	_, err = NewWithError(&LoggerOptions{ExitHookTimeout: -time.Second})
+	assert.Error(t, err)
+}
@@ -0,0 +1,200 @@
+package hclog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldType is the type of the value held by a Field.
+type fieldType uint8
+
+const (
+	stringField fieldType = iota + 1
+	intField
+	boolField
+	errorField
+	durationField
+	timeField
+)
+
+// Field is a key/value pair of which the value is typed, so that logging it
+// doesn't box the value into an interface{}. Fields are made with Str, Int,
+// Bool, Err, Dur and Time, and written with the methods of FieldLogger, or
+// passed in place of a key/value pair to the usual logging methods:
+//
+//	logger.InfoF("request served", hclog.Str("path", path), hclog.Int("status", 200))
+//	logger.Info("request served", "path", path, hclog.Int("status", 200))
+type Field struct {
+	Key string
+
+	typ     fieldType
+	integer int64
+	str     string
+	iface   interface{}
+}
+
+// Str returns a Field holding a string.
+func Str(key, value string) Field {
+	return Field{Key: key, typ: stringField, str: value}
+}
+
+// Int returns a Field holding an integer.
+func Int(key string, value int64) Field {
+	return Field{Key: key, typ: intField, integer: value}
+}
+
+// Bool returns a Field holding a boolean.
+func Bool(key string, value bool) Field {
+	var i int64
+	if value {
+		i = 1
+	}
+	return Field{Key: key, typ: boolField, integer: i}
+}
+
+// Err returns a Field holding an error, under the "error" key like the errors
+// usually logged.
+func Err(err error) Field {
+	return Field{Key: ErrorKey, typ: errorField, iface: err}
+}
+
+// Dur returns a Field holding a duration.
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, typ: durationField, integer: int64(value)}
+}
+
+// Time returns a Field holding a time.
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, typ: timeField, integer: value.UnixNano(), iface: value.Location()}
+}
+
+// Value returns the value of the field.
+func (f Field) Value() interface{} {
+	switch f.typ {
+	case stringField:
+		return f.str
+	case intField:
+		return f.integer
+	case boolField:
+		return f.integer == 1
+	case errorField:
+		return f.iface
+	case durationField:
+		return time.Duration(f.integer)
+	case timeField:
+		return f.time()
+	}
+	return nil
+}
+
+// textTimeFormat is the layout of time values in the text format, whether
+// they are Time fields or time.Time values of key/value pairs.
+const textTimeFormat = time.RFC3339Nano
+
+func (f Field) time() time.Time {
+	t := time.Unix(0, f.integer)
+	if loc, ok := f.iface.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// FieldLogger is implemented by loggers which write Field values without
+// boxing them. The loggers returned by New and NewInterceptLogger implement
+// it.
+type FieldLogger interface {
+	LogF(level Level, msg string, fields ...Field)
+	TraceF(msg string, fields ...Field)
+	DebugF(msg string, fields ...Field)
+	InfoF(msg string, fields ...Field)
+	WarnF(msg string, fields ...Field)
+	ErrorF(msg string, fields ...Field)
+}
+
+// expandFields replaces the Field values given in place of key/value pairs
+// in args by their key and value, for the code handling key/value pairs.
+// args is returned as is if it holds no Field.
+func expandFields(args []interface{}) []interface{} {
+	found := false
+	for i := 0; i < len(args); i += 2 {
+		if _, ok := args[i].(Field); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return args
+	}
+
+	expanded := make([]interface{}, 0, len(args)+4)
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(Field); ok {
+			expanded = append(expanded, f.Key, f.Value())
+			i++
+			continue
+		}
+		expanded = append(expanded, args[i])
+		if i+1 < len(args) {
+			expanded = append(expanded, args[i+1])
+		}
+		i += 2
+	}
+	return expanded
+}
+
+// fieldArgs returns fields as key/value pairs.
+func fieldArgs(fields []Field) []interface{} {
+	args := make([]interface{}, 0, len(fields)*2)
+	for _, f := range fields {
+		args = append(args, f.Key, f.Value())
+	}
+	return args
+}
+
+// writeFields writes fields in the text format, without going through
+// interface{} values or reflection.
+func (l *intLogger) writeFields(fields []Field) {
+	var scratch [64]byte
+	for _, f := range fields {
+		l.writer.WriteByte(' ')
+		l.writer.WriteString(escape(l.fieldKey(f.Key), false))
+		l.writer.WriteByte('=')
+
+		switch f.typ {
+		case stringField:
+			l.writeFieldString(f.str)
+		case intField:
+			l.writer.Write(strconv.AppendInt(scratch[:0], f.integer, 10))
+		case boolField:
+			l.writer.Write(strconv.AppendBool(scratch[:0], f.integer == 1))
+		case errorField:
+			if err, ok := f.iface.(error); ok && err != nil {
+				if errs := joinedErrors(err); errs != nil {
+					l.writer.WriteString(l.renderErrors(errs))
+					break
+				}
+				l.writeFieldString(err.Error())
+			} else {
+				l.writer.WriteString("<nil>")
+			}
+		case durationField:
+			l.writer.WriteString(time.Duration(f.integer).String())
+		case timeField:
+			l.writer.Write(f.time().AppendFormat(scratch[:0], textTimeFormat))
+		}
+	}
+}
+
+// writeFieldString writes a string value, truncated, escaped and quoted like
+// the values of key/value pairs.
+func (l *intLogger) writeFieldString(s string) {
+	s = escape(l.limits.value(s), l.rawNewlines)
+	if strings.ContainsAny(s, " \t\n\r") {
+		l.writer.WriteByte('"')
+		l.writer.WriteString(s)
+		l.writer.WriteByte('"')
+	} else {
+		l.writer.WriteString(s)
+	}
+}
@@ -0,0 +1,145 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFields(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	t.Run("writes typed fields", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.(FieldLogger).InfoF("request served",
+			Str("path", "/v1/kv"),
+			Str("agent", "curl 7.0"),
+			Int("status", 200),
+			Bool("cached", true),
+			Dur("took", 1500*time.Millisecond),
+			Time("at", at),
+			Err(errors.New("partial content")),
+		)
+
+		assert.Equal(t, "[INFO]  -- request served: path=/v1/kv agent=\"curl 7.0\" status=200 cached=true took=1.5s at=2020-01-02T03:04:05.000000006Z error=\"partial content\"\n", buf.String())
+	})
+
+	t.Run("accepts fields in place of key/value pairs", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.With(Int("id", 7)).Info("request served", "path", "/v1/kv", Int("status", 200), "cached", false)
+
+		assert.Equal(t, "[INFO]  -- request served: id=7 path=/v1/kv status=200 cached=false\n", buf.String())
+	})
+
+	t.Run("writes time fields and time values alike", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.Info("request served", "started", at, Time("at", at))
+
+		assert.Equal(t, "[INFO]  -- request served: started=2020-01-02T03:04:05.000000006Z at=2020-01-02T03:04:05.000000006Z\n", buf.String())
+	})
+
+	t.Run("writes fields with implied args", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.With("id", 7).(FieldLogger).WarnF("slow", Dur("took", time.Second))
+		logger.(FieldLogger).DebugF("filtered", Int("n", 1))
+
+		assert.Equal(t, "[WARN]  -- slow: id=7 took=1s\n", buf.String())
+	})
+
+	t.Run("writes typed fields in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		})
+
+		logger.(FieldLogger).ErrorF("request failed",
+			Str("path", "/v1/kv"),
+			Int("status", 500),
+			Bool("retry", false),
+			Time("at", at),
+			Err(errors.New("timeout")),
+		)
+
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		assert.Equal(t, "/v1/kv", raw["path"])
+		assert.Equal(t, float64(500), raw["status"])
+		assert.Equal(t, false, raw["retry"])
+		assert.Equal(t, "2020-01-02T03:04:05.000000006Z", raw["at"])
+		assert.Equal(t, "timeout", raw["error"])
+	})
+
+	t.Run("sends fields to sinks", func(t *testing.T) {
+		var buf, sbuf bytes.Buffer
+
+		logger := NewInterceptLogger(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+		sink := NewSinkAdapter(&LoggerOptions{
+			Output:      &sbuf,
+			DisableTime: true,
+		})
+		logger.RegisterSink(sink)
+		defer logger.DeregisterSink(sink)
+
+		logger.(FieldLogger).InfoF("served", Int("status", 200))
+
+		assert.Equal(t, "[INFO]  -- served: status=200\n", buf.String())
+		assert.Equal(t, buf.String(), sbuf.String())
+	})
+}
+
+func BenchmarkFields(b *testing.B) {
+	logger := New(&LoggerOptions{
+		Output:      ioutil.Discard,
+		DisableTime: true,
+	})
+
+	b.Run("boxed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Info("request served", "path", "/v1/kv", "status", 200+i, "cached", true, "took", time.Duration(i))
+		}
+	})
+
+	b.Run("typed", func(b *testing.B) {
+		fl := logger.(FieldLogger)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fl.InfoF("request served", Str("path", "/v1/kv"), Int("status", int64(200+i)), Bool("cached", true), Dur("took", time.Duration(i)))
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package hclog
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// levelNames are the level names accepted by LevelVar, in the order of the
+// levels.
+const levelNames = "trace, debug, info, warn, error, off"
+
+// LevelVar is a flag.Value and flag.Getter for a Level. Given to a logger with
+// the LevelVar option, it holds the level of the logger itself, so that
+// setting the flag changes the level of the logger, even at runtime, and
+// SetLevel changes the value of the flag.
+//
+//	var level hclog.LevelVar
+//	flag.Var(&level, "log-level", "log level")
+//	flag.Parse()
+//	logger := hclog.New(&hclog.LoggerOptions{LevelVar: &level})
+type LevelVar struct {
+	level int32
+}
+
+var (
+	_ flag.Getter = (*LevelVar)(nil)
+	_ flag.Getter = (*FormatVar)(nil)
+)
+
+// NewLevelVar returns a LevelVar holding level.
+func NewLevelVar(level Level) *LevelVar {
+	return &LevelVar{level: int32(level)}
+}
+
+// Level returns the level held by v.
+func (v *LevelVar) Level() Level {
+	return Level(atomic.LoadInt32(&v.level))
+}
+
+// SetLevel changes the level held by v.
+func (v *LevelVar) SetLevel(level Level) {
+	atomic.StoreInt32(&v.level, int32(level))
+}
+
+// String implements flag.Value.
+func (v *LevelVar) String() string {
+	if v == nil {
+		return NoLevel.String()
+	}
+	return v.Level().String()
+}
+
+// Set implements flag.Value, parsing s with LevelFromString.
+func (v *LevelVar) Set(s string) error {
+	level := LevelFromString(s)
+	if level == NoLevel {
+		return fmt.Errorf("invalid log level %q, accepted levels are %s", s, levelNames)
+	}
+	v.SetLevel(level)
+	return nil
+}
+
+// Get implements flag.Getter, returning the Level held by v.
+func (v *LevelVar) Get() interface{} {
+	return v.Level()
+}
+
+// FormatVar is a flag.Value and flag.Getter for the output format of a
+// logger, "text" or "json", which sets the JSONFormat option of the
+// LoggerOptions it is made for.
+type FormatVar struct {
+	opts *LoggerOptions
+}
+
+// NewFormatVar returns a FormatVar setting the format of opts.
+func NewFormatVar(opts *LoggerOptions) *FormatVar {
+	return &FormatVar{opts: opts}
+}
+
+// String implements flag.Value.
+func (v *FormatVar) String() string {
+	if v != nil && v.opts != nil && v.opts.JSONFormat {
+		return "json"
+	}
+	return "text"
+}
+
+// Set implements flag.Value.
+func (v *FormatVar) Set(s string) error {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "text":
+		v.opts.JSONFormat = false
+	case "json":
+		v.opts.JSONFormat = true
+	default:
+		return fmt.Errorf("invalid log format %q, accepted formats are text and json", s)
+	}
+	return nil
+}
+
+// Get implements flag.Getter, returning the name of the format.
+func (v *FormatVar) Get() interface{} {
+	return v.String()
+}
+
+// RegisterFlags defines flags setting opts on fs:
+//
+//	-log-level             the level, see LevelVar
+//	-log-format            the format, text or json
+//	-log-include-location  IncludeLocation
+//	-log-time-format       TimeFormat
+//	-log-disable-time      DisableTime
+//
+// The level flag is bound to opts.LevelVar, which is created if nil with the
+// current level of opts, so that the loggers created with opts follow it.
+func RegisterFlags(fs *flag.FlagSet, opts *LoggerOptions) {
+	if opts.LevelVar == nil {
+		level := opts.Level
+		if level == NoLevel {
+			level = DefaultLevel
+		}
+		opts.LevelVar = NewLevelVar(level)
+	}
+
+	fs.Var(opts.LevelVar, "log-level", "log level, one of "+levelNames)
+	fs.Var(NewFormatVar(opts), "log-format", "log format, text or json")
+	fs.BoolVar(&opts.IncludeLocation, "log-include-location", opts.IncludeLocation, "include the file and line of the caller in log entries")
+	fs.StringVar(&opts.TimeFormat, "log-time-format", opts.TimeFormat, "time format of the log entries")
+	fs.BoolVar(&opts.DisableTime, "log-disable-time", opts.DisableTime, "leave the time out of log entries")
+}
@@ -0,0 +1,105 @@
+package hclog
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelVar(t *testing.T) {
+	t.Run("parses levels", func(t *testing.T) {
+		var v LevelVar
+		assert.Equal(t, "none", v.String())
+
+		require.NoError(t, v.Set("DEBUG"))
+		assert.Equal(t, Debug, v.Get())
+		assert.Equal(t, "debug", v.String())
+
+		err := v.Set("verbose")
+		assert.EqualError(t, err, `invalid log level "verbose", accepted levels are trace, debug, info, warn, error, off`)
+		assert.Equal(t, Debug, v.Level())
+	})
+
+	t.Run("shares the level with the logger", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		v := NewLevelVar(Warn)
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			Level:       Info,
+			LevelVar:    v,
+			DisableTime: true,
+		})
+
+		logger.Info("filtered")
+		require.NoError(t, v.Set("info"))
+		logger.Info("written")
+		logger.SetLevel(Error)
+		assert.Equal(t, Error, v.Level())
+
+		assert.Equal(t, "[INFO]  -- written\n", buf.String())
+	})
+
+	t.Run("gets the level of the options when unset", func(t *testing.T) {
+		var v LevelVar
+		New(&LoggerOptions{Level: Trace, LevelVar: &v})
+		assert.Equal(t, Trace, v.Level())
+	})
+}
+
+func TestFormatVar(t *testing.T) {
+	var opts LoggerOptions
+	v := NewFormatVar(&opts)
+
+	assert.Equal(t, "text", v.String())
+	require.NoError(t, v.Set("json"))
+	assert.True(t, opts.JSONFormat)
+	assert.Equal(t, "json", v.Get())
+	require.NoError(t, v.Set("Text"))
+	assert.False(t, opts.JSONFormat)
+
+	assert.EqualError(t, v.Set("xml"), `invalid log format "xml", accepted formats are text and json`)
+}
+
+func TestRegisterFlags(t *testing.T) {
+	t.Run("sets the options", func(t *testing.T) {
+		opts := LoggerOptions{Level: Warn}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		RegisterFlags(fs, &opts)
+
+		assert.Equal(t, Warn, opts.LevelVar.Level())
+
+		err := fs.Parse([]string{
+			"-log-level=debug",
+			"-log-format=json",
+			"-log-include-location",
+			"-log-time-format=15:04",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, Debug, opts.LevelVar.Level())
+		assert.True(t, opts.JSONFormat)
+		assert.True(t, opts.IncludeLocation)
+		assert.Equal(t, "15:04", opts.TimeFormat)
+		assert.False(t, opts.DisableTime)
+
+		logger := New(&opts)
+		assert.True(t, logger.IsDebug())
+		fs.Set("log-level", "error")
+		assert.False(t, logger.IsWarn())
+	})
+
+	t.Run("reports invalid values", func(t *testing.T) {
+		var opts LoggerOptions
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		RegisterFlags(fs, &opts)
+
+		err := fs.Parse([]string{"-log-level=loud"})
+		assert.EqualError(t, err, `invalid value "loud" for flag -log-level: invalid log level "loud", accepted levels are trace, debug, info, warn, error, off`)
+	})
+}
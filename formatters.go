@@ -0,0 +1,83 @@
+package hclog
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// maxFormatDepth bounds how many times the value returned by a value formatter
+// is formatted again, when it is of a type with a formatter of its own, so that
+// formatters returning each other's types can't loop forever.
+const maxFormatDepth = 8
+
+// valueFormatters holds the ValueFormatters of a logger, shared by the
+// loggers derived from it.
+type valueFormatters struct {
+	formatters map[reflect.Type]func(interface{}) interface{}
+
+	// interfaces are the registered interface types, sorted by name so that
+	// a value implementing several of them is always formatted by the same.
+	interfaces []reflect.Type
+
+	// resolved caches the formatter of each concrete type seen, nil if none
+	// applies.
+	resolved sync.Map
+}
+
+// newValueFormatters returns the formatters of m, or nil if there are none.
+func newValueFormatters(m map[reflect.Type]func(interface{}) interface{}) *valueFormatters {
+	if len(m) == 0 {
+		return nil
+	}
+
+	f := &valueFormatters{formatters: make(map[reflect.Type]func(interface{}) interface{}, len(m))}
+	for t, format := range m {
+		if t == nil || format == nil {
+			continue
+		}
+		f.formatters[t] = format
+		if t.Kind() == reflect.Interface {
+			f.interfaces = append(f.interfaces, t)
+		}
+	}
+	sort.Slice(f.interfaces, func(i, j int) bool {
+		return f.interfaces[i].String() < f.interfaces[j].String()
+	})
+	return f
+}
+
+// lookup returns the formatter for values of type t: the one registered for t
+// itself, or else for the first interface t implements.
+func (f *valueFormatters) lookup(t reflect.Type) func(interface{}) interface{} {
+	if cached, ok := f.resolved.Load(t); ok {
+		return cached.(func(interface{}) interface{})
+	}
+
+	format := f.formatters[t]
+	if format == nil {
+		for _, it := range f.interfaces {
+			if t.Implements(it) {
+				format = f.formatters[it]
+				break
+			}
+		}
+	}
+	f.resolved.Store(t, format)
+	return format
+}
+
+// format returns v as formatted by the formatter of its type, if any.
+func (f *valueFormatters) format(v interface{}) interface{} {
+	if f == nil {
+		return v
+	}
+	for depth := 0; depth < maxFormatDepth && v != nil; depth++ {
+		format := f.lookup(reflect.TypeOf(v))
+		if format == nil {
+			break
+		}
+		v = format(v)
+	}
+	return v
+}
@@ -0,0 +1,123 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testMoney struct {
+	cents    int64
+	currency string
+}
+
+type testID [4]byte
+
+type testAlias struct{ id testID }
+
+func TestLogger_ValueFormatters(t *testing.T) {
+	formatters := map[reflect.Type]func(interface{}) interface{}{
+		reflect.TypeOf(testMoney{}): func(v interface{}) interface{} {
+			m := v.(testMoney)
+			return fmt.Sprintf("%d.%02d%s", m.cents/100, m.cents%100, m.currency)
+		},
+		reflect.TypeOf(testID{}): func(v interface{}) interface{} {
+			return fmt.Sprintf("%x", v.(testID))
+		},
+		reflect.TypeOf(testAlias{}): func(v interface{}) interface{} {
+			return v.(testAlias).id
+		},
+		reflect.TypeOf((*error)(nil)).Elem(): func(v interface{}) interface{} {
+			return "error: " + v.(error).Error()
+		},
+	}
+
+	t.Run("formats values in text", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			ValueFormatters: formatters,
+		})
+
+		logger.Info("charged", "amount", testMoney{cents: 1250, currency: "EUR"}, "id", testID{0xde, 0xad, 0xbe, 0xef}, "other", 1)
+		assert.Equal(t, "[INFO]  -- charged: amount=12.50EUR id=deadbeef other=1\n", buf.String())
+	})
+
+	t.Run("formats values in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			JSONFormat:      true,
+			ValueFormatters: formatters,
+		}).With("amount", testMoney{cents: 5, currency: "USD"})
+
+		logger.Info("charged")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "0.05USD", entry["amount"])
+	})
+
+	t.Run("matches interfaces", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			ValueFormatters: formatters,
+		})
+
+		logger.Info("failed", "err", &net.AddrError{Err: "bad", Addr: "x"})
+		assert.Equal(t, "[INFO]  -- failed: err=\"error: address x: bad\"\n", buf.String())
+	})
+
+	t.Run("formats the values returned by formatters", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			ValueFormatters: formatters,
+		})
+
+		logger.Info("lookup", "alias", testAlias{id: testID{1, 2, 3, 4}})
+		assert.Equal(t, "[INFO]  -- lookup: alias=01020304\n", buf.String())
+	})
+
+	t.Run("limits the depth", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		calls := 0
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			ValueFormatters: map[reflect.Type]func(interface{}) interface{}{
+				reflect.TypeOf(testID{}): func(v interface{}) interface{} {
+					calls++
+					return v
+				},
+			},
+		})
+
+		logger.Info("loop", "id", testID{})
+		assert.Equal(t, maxFormatDepth, calls)
+	})
+}
+
+func TestValueFormatters_empty(t *testing.T) {
+	assert.Nil(t, newValueFormatters(nil))
+
+	var f *valueFormatters
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		f.format(42)
+	}))
+}
@@ -10,11 +10,9 @@ var (
 
 	// DefaultOptions is used to create the Default logger. These are read
 	// only when the Default logger is created, so set them as soon as the
-	// process starts.
-	DefaultOptions = &LoggerOptions{
-		Level:  DefaultLevel,
-		Output: DefaultOutput,
-	}
+	// process starts. They default to DefaultOptionsFromEnv with no prefix,
+	// so LOG_LEVEL and the like configure the Default logger.
+	DefaultOptions = DefaultOptionsFromEnv("")
 )
 
 // Default returns a globally held logger. This can be a good starting
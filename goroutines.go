@@ -0,0 +1,91 @@
+package hclog
+
+import (
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+)
+
+// GoroutinesKey is the key of the stacks written by DumpGoroutines, and
+// GoroutineCountKey the key of their number.
+const (
+	GoroutinesKey     = "goroutines"
+	GoroutineCountKey = "goroutine_count"
+)
+
+// DumpGoroutines writes the stacks of all the goroutines to l as one entry at
+// level, as a hung process is debugged. In JSON the stacks are an array of
+// strings under GoroutinesKey, one per goroutine, and in text an indented
+// block following the entry, like a CapturedStacktrace. Either way they are
+// written whole, whatever MaxMessageBytes and MaxFieldBytes.
+func DumpGoroutines(l Logger, level Level) {
+	stacks := goroutineStacks()
+	var dump interface{}
+	if jf, ok := l.(interface{ jsonFormat() bool }); ok && jf.jsonFormat() {
+		dump = stacks
+	} else {
+		dump = indentStacks(stacks)
+	}
+	l.Log(level, "goroutine dump", GoroutineCountKey, len(stacks), GoroutinesKey, dump)
+}
+
+// DumpGoroutinesOnSignal calls DumpGoroutines each time the process receives
+// one of sigs, SIGUSR1 if none are given, until the function returned is
+// called. On Windows, which has no SIGUSR1, nothing is dumped unless sigs are
+// given.
+func DumpGoroutinesOnSignal(l Logger, level Level, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = defaultDumpSignals
+	}
+	if len(sigs) == 0 {
+		return func() {}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				DumpGoroutines(l, level)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// goroutineStacks returns the stacks of all the goroutines, growing the buffer
+// given to runtime.Stack until they fit.
+func goroutineStacks() []string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+	return strings.Split(strings.TrimRight(string(buf), "\n"), "\n\n")
+}
+
+// indentStacks returns stacks as one block, each line indented, the stacks
+// separated by empty lines as runtime.Stack does.
+func indentStacks(stacks []string) CapturedStacktrace {
+	var b strings.Builder
+	for i, stack := range stacks {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString("  ")
+		b.WriteString(strings.Replace(stack, "\n", "\n  ", -1))
+	}
+	return CapturedStacktrace(b.String())
+}
@@ -0,0 +1,106 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parkGoroutines starts n goroutines blocked in parkedGoroutine until the
+// function returned is called.
+func parkGoroutines(n int) (release func()) {
+	var started sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < n; i++ {
+		started.Add(1)
+		go parkedGoroutine(&started, done)
+	}
+	started.Wait()
+	return func() { close(done) }
+}
+
+func parkedGoroutine(started *sync.WaitGroup, done chan struct{}) {
+	started.Done()
+	<-done
+}
+
+func TestDumpGoroutines(t *testing.T) {
+	t.Run("writes an indented block in text", func(t *testing.T) {
+		release := parkGoroutines(3)
+		defer release()
+
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			MaxMessageBytes: 8,
+			MaxFieldBytes:   8,
+		})
+		DumpGoroutines(logger, Warn)
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		assert.Regexp(t, `^\[WARN\]  -- goroutin \(truncated 6 bytes\): goroutine_count=\d+$`, lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "  goroutine "), "Expected the stacks to follow, got %q", lines[1])
+		for _, line := range lines[1:] {
+			if line != "" {
+				assert.True(t, strings.HasPrefix(line, "  "), "Expected an indented line, got %q", line)
+			}
+		}
+		assert.Equal(t, 3, strings.Count(buf.String(), "hclog.parkedGoroutine("))
+		assert.Contains(t, buf.String(), "hclog.TestDumpGoroutines")
+	})
+
+	t.Run("writes an array in JSON", func(t *testing.T) {
+		release := parkGoroutines(3)
+		defer release()
+
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, JSONFormat: true, MaxFieldBytes: 8})
+		DumpGoroutines(logger.Named("debug"), Info)
+
+		var entry struct {
+			Level      string   `json:"@level"`
+			Module     string   `json:"@module"`
+			Count      int      `json:"goroutine_count"`
+			Goroutines []string `json:"goroutines"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "info", entry.Level)
+		assert.Equal(t, "debug", entry.Module)
+		assert.Equal(t, len(entry.Goroutines), entry.Count)
+		parked := 0
+		for _, stack := range entry.Goroutines {
+			assert.True(t, strings.HasPrefix(stack, "goroutine "), "Expected a goroutine, got %q", stack)
+			if strings.Contains(stack, "hclog.parkedGoroutine(") {
+				parked++
+			}
+		}
+		assert.Equal(t, 3, parked)
+	})
+
+	t.Run("grows the buffer until the stacks fit", func(t *testing.T) {
+		release := parkGoroutines(1000)
+		defer release()
+
+		var buf bytes.Buffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: &buf, JSONFormat: true})
+		DumpGoroutines(logger, Info)
+
+		require.True(t, buf.Len() > 64<<10, "Expected a dump beyond the first buffer, got %d bytes", buf.Len())
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, 1000, strings.Count(buf.String(), "hclog.parkedGoroutine("))
+	})
+
+	t.Run("skips the entry below the level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, Level: Info})
+		DumpGoroutines(logger, Debug)
+		assert.Empty(t, buf.String())
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package hclog
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultDumpSignals are the signals of DumpGoroutinesOnSignal when none are
+// given.
+var defaultDumpSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package hclog
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpGoroutinesOnSignal(t *testing.T) {
+	var out lockedBuffer
+	logger := New(&LoggerOptions{Output: &out, DisableTime: true})
+	stop := DumpGoroutinesOnSignal(logger, Info)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	waitFor(t, func() bool {
+		return strings.Contains(out.String(), "goroutine dump")
+	})
+
+	stop()
+	assert.Equal(t, 1, strings.Count(out.String(), "-- goroutine dump"))
+}
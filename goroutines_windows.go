@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package hclog
+
+import "os"
+
+// defaultDumpSignals are the signals of DumpGoroutinesOnSignal when none are
+// given, none on Windows.
+var defaultDumpSignals []os.Signal
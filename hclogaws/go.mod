@@ -0,0 +1,17 @@
+module github.com/varnson/go-hclog/hclogaws
+
+go 1.24
+
+require (
+	github.com/aws/smithy-go v1.28.2
+	github.com/varnson/go-hclog v0.0.0
+)
+
+require (
+	github.com/fatih/color v1.7.0 // indirect
+	github.com/mattn/go-colorable v0.1.4 // indirect
+	github.com/mattn/go-isatty v0.0.10 // indirect
+	golang.org/x/sys v0.0.0-20191008105621-543471e840be // indirect
+)
+
+replace github.com/varnson/go-hclog => ../
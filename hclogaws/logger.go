@@ -0,0 +1,60 @@
+// Package hclogaws adapts hclog loggers to the logging.Logger interface of the
+// AWS SDK for Go v2, so that the retry and debug output of the SDK reaches an
+// hclog logger.
+//
+// It is a module of its own, so that the go-hclog module doesn't depend on the
+// SDK.
+package hclogaws
+
+import (
+	"fmt"
+
+	"github.com/aws/smithy-go/logging"
+	hclog "github.com/varnson/go-hclog"
+)
+
+// SourceKey and Source are implied by the loggers returned by NewLogger, to
+// tell the entries of the SDK apart.
+const (
+	SourceKey = "source"
+	Source    = "aws-sdk"
+)
+
+// ClassificationKey is the key under which the classification of entries is
+// written, when it is neither Warn nor Debug.
+const ClassificationKey = "classification"
+
+// sdkLogger is the logging.Logger returned by NewLogger.
+type sdkLogger struct {
+	l hclog.Logger
+}
+
+// NewLogger returns a logging.Logger writing the entries of the SDK to l, with
+// source=aws-sdk implied:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx, config.WithLogger(hclogaws.NewLogger(logger)))
+//
+// The Warn and Debug classifications map onto the Warn and Debug levels, and
+// others onto Info, with the classification written under ClassificationKey.
+// Entries are only formatted if the level of l lets them through.
+func NewLogger(l hclog.Logger) logging.Logger {
+	return &sdkLogger{l: l.With(SourceKey, Source)}
+}
+
+// Logf implements logging.Logger.
+func (s *sdkLogger) Logf(classification logging.Classification, format string, v ...interface{}) {
+	switch classification {
+	case logging.Warn:
+		if s.l.IsWarn() {
+			s.l.Warn(fmt.Sprintf(format, v...))
+		}
+	case logging.Debug:
+		if s.l.IsDebug() {
+			s.l.Debug(fmt.Sprintf(format, v...))
+		}
+	default:
+		if s.l.IsInfo() {
+			s.l.Info(fmt.Sprintf(format, v...), ClassificationKey, string(classification))
+		}
+	}
+}
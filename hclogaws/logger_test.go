@@ -0,0 +1,60 @@
+package hclogaws
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aws/smithy-go/logging"
+	hclog "github.com/varnson/go-hclog"
+)
+
+// countingArg counts how many times it is formatted.
+type countingArg struct {
+	calls *int
+}
+
+func (a countingArg) String() string {
+	*a.calls++
+	return "formatted"
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Run("maps classifications onto levels", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Output:      &buf,
+			Level:       hclog.Debug,
+			DisableTime: true,
+		})
+
+		var sdk logging.Logger = NewLogger(logger)
+		sdk.Logf(logging.Warn, "retrying request, attempt %d", 2)
+		sdk.Logf(logging.Debug, "request %s", "GetObject")
+		sdk.Logf(logging.Classification("NOTICE"), "deprecated endpoint")
+
+		expected := "[WARN]  -- retrying request, attempt 2: source=aws-sdk\n" +
+			"[DEBUG] -- request GetObject: source=aws-sdk\n" +
+			"[INFO]  -- deprecated endpoint: source=aws-sdk classification=NOTICE\n"
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("formats after the level check", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Output: &buf,
+			Level:  hclog.Warn,
+		})
+
+		calls := 0
+		sdk := NewLogger(logger)
+		sdk.Logf(logging.Debug, "request %s", countingArg{&calls})
+
+		if calls != 0 || buf.Len() != 0 {
+			t.Errorf("Expected filtered entries not to be formatted, got %d calls and %q", calls, buf.String())
+		}
+	})
+}
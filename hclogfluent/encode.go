@@ -0,0 +1,211 @@
+package hclogfluent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// eventTimeExt is the msgpack extension type of the EventTime of the Forward
+// protocol, holding the seconds and nanoseconds of the time as big endian
+// 32-bit integers.
+const eventTimeExt = 0
+
+// Keys of the record written for every entry, as in the JSON format.
+const (
+	levelKey   = "@level"
+	messageKey = "@message"
+	moduleKey  = "@module"
+)
+
+// encodeEntry returns the EventTime and record of an entry, encoded one after
+// the other, to be wrapped into a Message or a PackedForward entry.
+func encodeEntry(t time.Time, name string, level hclog.Level, msg string, args []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+
+	if err := enc.EncodeExtHeader(eventTimeExt, 8); err != nil {
+		return nil, err
+	}
+	var stamp [8]byte
+	binary.BigEndian.PutUint32(stamp[:4], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(stamp[4:], uint32(t.Nanosecond()))
+	buf.Write(stamp[:])
+
+	pairs := make([]interface{}, 0, len(args)+6)
+	pairs = append(pairs, levelKey, level.String(), messageKey, msg)
+	if name != "" {
+		pairs = append(pairs, moduleKey, name)
+	}
+	pairs = appendArgs(pairs, args)
+
+	if err := enc.EncodeMapLen(len(pairs) / 2); err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		if err := enc.EncodeString(pairs[i].(string)); err != nil {
+			return nil, err
+		}
+		if err := encodeValue(enc, pairs[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// appendArgs appends the key/value pairs of args to pairs, with string keys.
+func appendArgs(pairs, args []interface{}) []interface{} {
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(hclog.Field); ok {
+			pairs = append(pairs, f.Key, f.Value())
+			i++
+			continue
+		}
+		if i+1 == len(args) {
+			pairs = append(pairs, hclog.MissingKey, args[i])
+			break
+		}
+		pairs = append(pairs, fmt.Sprint(args[i]), args[i+1])
+		i += 2
+	}
+	return pairs
+}
+
+// encodeValue encodes value, as a string for the types msgpack has no
+// representation for, so that an entry always encodes.
+func encodeValue(enc *msgpack.Encoder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return enc.EncodeNil()
+	case string:
+		return enc.EncodeString(v)
+	case bool:
+		return enc.EncodeBool(v)
+	case int:
+		return enc.EncodeInt(int64(v))
+	case int8:
+		return enc.EncodeInt(int64(v))
+	case int16:
+		return enc.EncodeInt(int64(v))
+	case int32:
+		return enc.EncodeInt(int64(v))
+	case int64:
+		return enc.EncodeInt(v)
+	case uint:
+		return enc.EncodeUint(uint64(v))
+	case uint8:
+		return enc.EncodeUint(uint64(v))
+	case uint16:
+		return enc.EncodeUint(uint64(v))
+	case uint32:
+		return enc.EncodeUint(uint64(v))
+	case uint64:
+		return enc.EncodeUint(v)
+	case float32:
+		return enc.EncodeFloat64(float64(v))
+	case float64:
+		return enc.EncodeFloat64(v)
+	case []byte:
+		return enc.EncodeBytes(v)
+	case time.Duration:
+		return enc.EncodeString(v.String())
+	case time.Time:
+		return enc.EncodeString(v.Format(time.RFC3339Nano))
+	case error:
+		return enc.EncodeString(v.Error())
+	case fmt.Stringer:
+		return enc.EncodeString(v.String())
+	}
+	return enc.EncodeString(fmt.Sprintf("%+v", value))
+}
+
+// encodeMessage encodes an entry in the Message mode, as in
+// [tag, time, record, option].
+func encodeMessage(tag string, body []byte, chunk string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+
+	n := 3
+	if chunk != "" {
+		n = 4
+	}
+	if err := enc.EncodeArrayLen(n); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeString(tag); err != nil {
+		return nil, err
+	}
+	buf.Write(body)
+	if chunk != "" {
+		if err := encodeOption(enc, -1, chunk); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// encodePackedForward encodes entries of the same tag in the PackedForward
+// mode, as in [tag, entries, option], the entries being a string of
+// concatenated [time, record] arrays.
+func encodePackedForward(tag string, bodies [][]byte, chunk string) ([]byte, error) {
+	var entries bytes.Buffer
+	enc := msgpack.NewEncoder(&entries)
+	for _, body := range bodies {
+		if err := enc.EncodeArrayLen(2); err != nil {
+			return nil, err
+		}
+		entries.Write(body)
+	}
+
+	var buf bytes.Buffer
+	enc = msgpack.NewEncoder(&buf)
+	if err := enc.EncodeArrayLen(3); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeString(tag); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeBytes(entries.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := encodeOption(enc, len(bodies), chunk); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeOption encodes the option map of a message, with the number of
+// entries if size isn't negative and the chunk to acknowledge if not empty.
+func encodeOption(enc *msgpack.Encoder, size int, chunk string) error {
+	n := 0
+	if size >= 0 {
+		n++
+	}
+	if chunk != "" {
+		n++
+	}
+	if err := enc.EncodeMapLen(n); err != nil {
+		return err
+	}
+	if size >= 0 {
+		if err := enc.EncodeString("size"); err != nil {
+			return err
+		}
+		if err := enc.EncodeInt(int64(size)); err != nil {
+			return err
+		}
+	}
+	if chunk != "" {
+		if err := enc.EncodeString("chunk"); err != nil {
+			return err
+		}
+		if err := enc.EncodeString(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,440 @@
+// Package hclogfluent sends the entries of hclog loggers to a Fluentd or Fluent
+// Bit collector over the Forward protocol, through a SinkAdapter registered on
+// an InterceptLogger.
+//
+// It is a module of its own, so that the go-hclog module doesn't depend on a
+// msgpack implementation.
+package hclogfluent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Mode is the mode of the Forward protocol entries are sent in.
+type Mode int
+
+const (
+	// ModePackedForward sends the entries of the same tag in batches of up
+	// to Options.BatchSize entries.
+	ModePackedForward Mode = iota
+
+	// ModeMessage sends every entry as a message of its own.
+	ModeMessage
+)
+
+// Defaults of the Options left unset.
+const (
+	DefaultTag           = "hclog"
+	DefaultBatchSize     = 64
+	DefaultBufferSize    = 8192
+	DefaultFlushInterval = time.Second
+	DefaultTimeout       = 10 * time.Second
+	DefaultMinBackoff    = 100 * time.Millisecond
+	DefaultMaxBackoff    = 30 * time.Second
+)
+
+// Options configures the Sink returned by NewSink.
+type Options struct {
+	// Address is the host:port of the collector.
+	Address string
+
+	// Tag is the tag of the entries of unnamed loggers, and the prefix of
+	// the tag of named ones, as in "app.raft" for the logger named "raft".
+	// Defaults to DefaultTag.
+	Tag string
+
+	// Level is the minimum level of the entries sent. Defaults to sending
+	// all of them.
+	Level hclog.Level
+
+	// Mode is the mode entries are sent in.
+	Mode Mode
+
+	// BatchSize is the maximum number of entries sent at once in the
+	// PackedForward mode. Defaults to DefaultBatchSize.
+	BatchSize int
+
+	// FlushInterval is the time after which entries are sent even if there
+	// are fewer than BatchSize. Defaults to DefaultFlushInterval.
+	FlushInterval time.Duration
+
+	// BufferSize is the number of entries held while they can't be sent,
+	// beyond which new entries are dropped. Defaults to DefaultBufferSize.
+	BufferSize int
+
+	// RequireAck asks the collector to acknowledge every message, which is
+	// sent again on another connection if no acknowledgement comes within
+	// Timeout. Without it, entries written to a connection that breaks may
+	// be lost.
+	RequireAck bool
+
+	// Timeout bounds connecting, writing, waiting for acknowledgements and
+	// for heartbeat responses. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// HeartbeatInterval is the interval at which heartbeats are sent over
+	// UDP to the same address, the connection being closed when one isn't
+	// answered, so that a collector gone silent is noticed before entries
+	// pile up. Zero disables heartbeats.
+	HeartbeatInterval time.Duration
+
+	// MinBackoff and MaxBackoff bound the time waited before connecting
+	// again after a failure, doubling with every failure. They default to
+	// DefaultMinBackoff and DefaultMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError is called with the errors sending entries, from the goroutine
+	// of the Sink.
+	OnError func(error)
+}
+
+// Sink is an hclog.SinkAdapter sending entries to a Forward collector:
+//
+//	sink, err := hclogfluent.NewSink(hclogfluent.Options{Address: "127.0.0.1:24224", Tag: "app"})
+//	defer sink.Close(ctx)
+//	logger.RegisterSink(sink)
+//
+// Entries are encoded as msgpack records from the level, message, name and
+// key/value pairs given to Accept, with the @level, @message and @module keys
+// of the JSON format. They are sent by a goroutine of the Sink, so that
+// loggers never wait on the collector, and held in a bounded buffer until
+// sent, surviving reconnections.
+type Sink struct {
+	opts Options
+
+	mu      sync.Mutex
+	pending []entry
+	closed  bool
+
+	wake  chan struct{}
+	stop  chan struct{}
+	abort chan struct{}
+	done  chan struct{}
+
+	dropped uint64
+
+	// conn and dec are only used by the goroutine of the Sink.
+	conn    net.Conn
+	dec     *msgpack.Decoder
+	backoff time.Duration
+}
+
+var _ hclog.SinkAdapter = (*Sink)(nil)
+
+// entry is an entry waiting to be sent.
+type entry struct {
+	tag  string
+	body []byte
+}
+
+// NewSink returns a Sink sending to the collector at opts.Address. It connects
+// in the background, so that an unavailable collector doesn't fail it.
+func NewSink(opts Options) (*Sink, error) {
+	switch {
+	case opts.Address == "":
+		return nil, errors.New("fluent address is empty")
+	case opts.Mode != ModePackedForward && opts.Mode != ModeMessage:
+		return nil, fmt.Errorf("unknown fluent forward mode %d", opts.Mode)
+	case opts.BatchSize < 0 || opts.BufferSize < 0:
+		return nil, errors.New("negative fluent batch or buffer size")
+	case opts.FlushInterval < 0 || opts.Timeout < 0 || opts.HeartbeatInterval < 0:
+		return nil, errors.New("negative fluent flush interval, timeout or heartbeat interval")
+	case opts.MinBackoff < 0 || opts.MaxBackoff < 0:
+		return nil, errors.New("negative fluent backoff")
+	}
+	if opts.Tag == "" {
+		opts.Tag = DefaultTag
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Mode == ModeMessage {
+		opts.BatchSize = 1
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = DefaultMinBackoff
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = opts.MinBackoff
+	}
+
+	s := &Sink{
+		opts:    opts,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		abort:   make(chan struct{}),
+		done:    make(chan struct{}),
+		backoff: opts.MinBackoff,
+	}
+	go s.run()
+	return s, nil
+}
+
+// Accept implements hclog.SinkAdapter, encoding the entry and queuing it to
+// be sent.
+func (s *Sink) Accept(name string, level hclog.Level, msg string, args ...interface{}) {
+	if level < s.opts.Level || level == hclog.Off {
+		return
+	}
+
+	tag := s.opts.Tag
+	if name != "" {
+		tag += "." + name
+	}
+	body, err := encodeEntry(time.Now(), name, level, msg, args)
+	if err != nil {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || len(s.pending) >= s.opts.BufferSize {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	s.pending = append(s.pending, entry{tag: tag, body: body})
+	if len(s.pending) >= s.opts.BatchSize {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Dropped returns the number of entries dropped because the buffer was full
+// or the Sink closed.
+func (s *Sink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close sends the entries still buffered and closes the connection. It gives
+// up once ctx is done, dropping the entries left.
+func (s *Sink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		close(s.abort)
+		<-s.done
+		return ctx.Err()
+	}
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	defer s.disconnect()
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var heartbeat <-chan time.Time
+	if s.opts.HeartbeatInterval > 0 {
+		t := time.NewTicker(s.opts.HeartbeatInterval)
+		defer t.Stop()
+		heartbeat = t.C
+	}
+
+	for {
+		select {
+		case <-s.wake:
+		case <-ticker.C:
+		case <-heartbeat:
+			if err := s.heartbeat(); err != nil {
+				s.reportError(err)
+				s.disconnect()
+			}
+			continue
+		case <-s.stop:
+			s.flush()
+			return
+		}
+		if !s.flush() {
+			return
+		}
+	}
+}
+
+// flush sends the buffered entries, until they're all sent or the Sink is
+// stopped while waiting to retry. It returns false if the Sink was aborted.
+func (s *Sink) flush() bool {
+	for {
+		tag, bodies := s.batch()
+		if len(bodies) == 0 {
+			return true
+		}
+		if err := s.send(tag, bodies); err != nil {
+			s.reportError(err)
+			s.disconnect()
+			if !s.wait() {
+				return false
+			}
+			continue
+		}
+		s.backoff = s.opts.MinBackoff
+
+		s.mu.Lock()
+		s.pending = s.pending[len(bodies):]
+		s.mu.Unlock()
+	}
+}
+
+// batch returns the oldest buffered entries of the same tag, up to BatchSize.
+func (s *Sink) batch() (string, [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return "", nil
+	}
+	tag := s.pending[0].tag
+	var bodies [][]byte
+	for _, e := range s.pending {
+		if e.tag != tag || len(bodies) == s.opts.BatchSize {
+			break
+		}
+		bodies = append(bodies, e.body)
+	}
+	return tag, bodies
+}
+
+// wait waits for the backoff before the next attempt, and doubles it. It
+// returns false if the Sink was aborted meanwhile.
+func (s *Sink) wait() bool {
+	timer := time.NewTimer(s.backoff)
+	defer timer.Stop()
+	if s.backoff *= 2; s.backoff > s.opts.MaxBackoff {
+		s.backoff = s.opts.MaxBackoff
+	}
+	select {
+	case <-timer.C:
+		return true
+	case <-s.abort:
+		return false
+	}
+}
+
+// send sends the entries of tag as one message, waiting for its
+// acknowledgement if required.
+func (s *Sink) send(tag string, bodies [][]byte) error {
+	if err := s.connect(); err != nil {
+		return err
+	}
+
+	var chunk string
+	if s.opts.RequireAck {
+		chunk = newChunkID()
+	}
+	var msg []byte
+	var err error
+	if s.opts.Mode == ModeMessage {
+		msg, err = encodeMessage(tag, bodies[0], chunk)
+	} else {
+		msg, err = encodePackedForward(tag, bodies, chunk)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode fluent message: %w", err)
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(s.opts.Timeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to send fluent message: %w", err)
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	s.conn.SetReadDeadline(time.Now().Add(s.opts.Timeout))
+	resp, err := s.dec.DecodeMap()
+	if err != nil {
+		return fmt.Errorf("failed to read fluent acknowledgement: %w", err)
+	}
+	if ack, _ := resp["ack"].(string); ack != chunk {
+		return fmt.Errorf("fluent acknowledgement %q doesn't match chunk %q", ack, chunk)
+	}
+	return nil
+}
+
+func (s *Sink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", s.opts.Address, s.opts.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to fluent collector: %w", err)
+	}
+	s.conn = conn
+	s.dec = msgpack.NewDecoder(conn)
+	return nil
+}
+
+func (s *Sink) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.dec = nil
+	}
+}
+
+// heartbeat sends a heartbeat, a msgpack nil, over UDP and waits for the
+// collector to answer it.
+func (s *Sink) heartbeat() error {
+	conn, err := net.DialTimeout("udp", s.opts.Address, s.opts.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to send fluent heartbeat: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(s.opts.Timeout))
+	if _, err := conn.Write([]byte{0xc0}); err != nil {
+		return fmt.Errorf("failed to send fluent heartbeat: %w", err)
+	}
+	var resp [1]byte
+	if _, err := conn.Read(resp[:]); err != nil {
+		return fmt.Errorf("fluent heartbeat not answered: %w", err)
+	}
+	return nil
+}
+
+// newChunkID returns a random chunk id, as the base64 of 16 bytes.
+func newChunkID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return base64.StdEncoding.EncodeToString(id[:])
+}
+
+func (s *Sink) reportError(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
@@ -0,0 +1,327 @@
+package hclogfluent
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// record is an entry received by forwardServer.
+type record struct {
+	tag    string
+	time   time.Time
+	fields map[string]interface{}
+}
+
+// forwardServer is an in-process Forward collector.
+type forwardServer struct {
+	ln      net.Listener
+	records chan record
+
+	// ack acknowledges the chunks of messages.
+	ack bool
+
+	// dropFirst closes the first connection after reading a message,
+	// without acknowledging it.
+	mu        sync.Mutex
+	dropFirst bool
+}
+
+func newForwardServer(t *testing.T, ack, dropFirst bool) *forwardServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s := &forwardServer{ln: ln, records: make(chan record, 100), ack: ack, dropFirst: dropFirst}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *forwardServer) serve(conn net.Conn) {
+	defer conn.Close()
+	dec := msgpack.NewDecoder(conn)
+	enc := msgpack.NewEncoder(conn)
+	for {
+		records, chunk, err := readMessage(dec)
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		drop := s.dropFirst
+		s.dropFirst = false
+		s.mu.Unlock()
+		if drop {
+			return
+		}
+
+		for _, r := range records {
+			s.records <- r
+		}
+		if s.ack && chunk != "" {
+			enc.Encode(map[string]string{"ack": chunk})
+		}
+	}
+}
+
+// readMessage reads a message in the Message or PackedForward mode.
+func readMessage(dec *msgpack.Decoder) ([]record, string, error) {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return nil, "", err
+	}
+	tag, err := dec.DecodeString()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var records []record
+	code, err := dec.PeekCode()
+	if err != nil {
+		return nil, "", err
+	}
+	if code == 0xc4 || code == 0xc5 || code == 0xc6 {
+		entries, err := dec.DecodeBytes()
+		if err != nil {
+			return nil, "", err
+		}
+		edec := msgpack.NewDecoder(bytes.NewReader(entries))
+		for {
+			if _, err := edec.DecodeArrayLen(); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, "", err
+			}
+			r, err := readEntry(edec, tag)
+			if err != nil {
+				return nil, "", err
+			}
+			records = append(records, r)
+		}
+		n -= 2
+	} else {
+		r, err := readEntry(dec, tag)
+		if err != nil {
+			return nil, "", err
+		}
+		records = append(records, r)
+		n -= 3
+	}
+
+	var chunk string
+	if n > 0 {
+		option, err := dec.DecodeMap()
+		if err != nil {
+			return nil, "", err
+		}
+		chunk, _ = option["chunk"].(string)
+		if size, ok := option["size"]; ok && size != int8(len(records)) {
+			return nil, "", errors.New("bad size")
+		}
+	}
+	return records, chunk, nil
+}
+
+func readEntry(dec *msgpack.Decoder, tag string) (record, error) {
+	id, n, err := dec.DecodeExtHeader()
+	if err != nil {
+		return record{}, err
+	}
+	if id != eventTimeExt || n != 8 {
+		return record{}, errors.New("not an EventTime")
+	}
+	var stamp [8]byte
+	if err := dec.ReadFull(stamp[:]); err != nil {
+		return record{}, err
+	}
+	t := time.Unix(int64(binary.BigEndian.Uint32(stamp[:4])), int64(binary.BigEndian.Uint32(stamp[4:])))
+	fields, err := dec.DecodeMap()
+	if err != nil {
+		return record{}, err
+	}
+	return record{tag: tag, time: t, fields: fields}, nil
+}
+
+func (s *forwardServer) next(t *testing.T) record {
+	t.Helper()
+	select {
+	case r := <-s.records:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a record")
+	}
+	return record{}
+}
+
+func TestSink(t *testing.T) {
+	for _, mode := range []Mode{ModePackedForward, ModeMessage} {
+		srv := newForwardServer(t, true, false)
+		defer srv.ln.Close()
+
+		sink, err := NewSink(Options{
+			Address:       srv.ln.Addr().String(),
+			Tag:           "app",
+			Mode:          mode,
+			FlushInterval: 10 * time.Millisecond,
+			RequireAck:    true,
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		logger := hclog.NewInterceptLogger(&hclog.LoggerOptions{Output: io.Discard})
+		logger.RegisterSink(sink)
+
+		before := time.Now().Truncate(time.Second)
+		logger.Named("raft").Warn("heartbeat timeout", "peer", "node-2", "attempt", 3, "elapsed", time.Second)
+		logger.Error("apply failed", "error", errors.New("disk full"))
+		if err := sink.Close(context.Background()); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		r := srv.next(t)
+		if r.tag != "app.raft" || r.time.Before(before) {
+			t.Errorf("Unexpected tag %q or time %s", r.tag, r.time)
+		}
+		expected := map[string]interface{}{
+			"@level":   "warn",
+			"@message": "heartbeat timeout",
+			"@module":  "raft",
+			"peer":     "node-2",
+			"attempt":  int8(3),
+			"elapsed":  "1s",
+		}
+		for k, v := range expected {
+			if r.fields[k] != v {
+				t.Errorf("%d: expected %s=%v (%T), got %v (%T)", mode, k, v, v, r.fields[k], r.fields[k])
+			}
+		}
+
+		r = srv.next(t)
+		if r.tag != "app" || r.fields["error"] != "disk full" {
+			t.Errorf("Unexpected record %+v", r)
+		}
+	}
+}
+
+func TestSink_reconnects(t *testing.T) {
+	srv := newForwardServer(t, true, true)
+	defer srv.ln.Close()
+
+	var errs []error
+	var mu sync.Mutex
+	sink, err := NewSink(Options{
+		Address:       srv.ln.Addr().String(),
+		FlushInterval: 10 * time.Millisecond,
+		RequireAck:    true,
+		Timeout:       time.Second,
+		MinBackoff:    time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer sink.Close(context.Background())
+
+	sink.Accept("", hclog.Info, "kept across connections")
+	r := srv.next(t)
+	if r.fields["@message"] != "kept across connections" {
+		t.Errorf("Unexpected record %+v", r)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) == 0 {
+		t.Errorf("Expected the lost acknowledgement to be reported")
+	}
+}
+
+func TestSink_bufferBound(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	sink, err := NewSink(Options{
+		Address:    addr,
+		BufferSize: 3,
+		MinBackoff: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for i := 0; i < 5; i++ {
+		sink.Accept("", hclog.Info, "unsent")
+	}
+	if sink.Dropped() != 2 {
+		t.Errorf("Expected 2 dropped entries, got %d", sink.Dropped())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := sink.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected Close to give up, got %v", err)
+	}
+}
+
+func TestSink_heartbeat(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer pc.Close()
+
+	beats := make(chan struct{}, 10)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n == 1 && buf[0] == 0xc0 {
+				beats <- struct{}{}
+				pc.WriteTo([]byte{0xc0}, addr)
+			}
+		}
+	}()
+
+	sink, err := NewSink(Options{
+		Address:           pc.LocalAddr().String(),
+		HeartbeatInterval: 10 * time.Millisecond,
+		OnError:           func(err error) { t.Errorf("Unexpected error %s", err) },
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer sink.Close(context.Background())
+
+	select {
+	case <-beats:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a heartbeat")
+	}
+}
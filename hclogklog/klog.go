@@ -0,0 +1,114 @@
+// Package hclogklog redirects the output of klog, used by the Kubernetes
+// client libraries, to an hclog logger.
+//
+// It is a module of its own, so that the go-hclog module doesn't depend on
+// klog.
+package hclogklog
+
+import (
+	"bytes"
+
+	"github.com/go-logr/logr"
+	hclog "github.com/varnson/go-hclog"
+	"k8s.io/klog/v2"
+)
+
+// Install makes l the output of klog, usually once at startup:
+//
+//	hclogklog.Install(logger.Named("client-go"))
+//
+// The entries of Info, Warning, Error and Fatal and their variants are logged
+// at Info, Warn and Error, with the header of klog stripped, and the
+// structured entries of InfoS and ErrorS with their key/value pairs. Verbose
+// entries, written by V(n) with n > 0, are logged at Debug. Once installed,
+// klog writes nothing to its own output.
+//
+// Calling Install again replaces the logger, so that entries are never
+// written twice. Like klog.SetLogger, it must not be called while other
+// goroutines log through klog.
+func Install(l hclog.Logger) {
+	klog.SetLoggerWithOptions(logr.New(&sink{l: l}), klog.WriteKlogBuffer(func(data []byte) {
+		writeBuffer(l, data)
+	}))
+}
+
+// severityLevels maps the severity characters starting klog headers onto
+// levels.
+var severityLevels = map[byte]hclog.Level{
+	'I': hclog.Info,
+	'W': hclog.Warn,
+	'E': hclog.Error,
+	'F': hclog.Error,
+}
+
+// writeBuffer logs an entry formatted by klog, as in:
+//
+//	W1014 12:00:00.000000   12345 reflector.go:424] watch of *v1.Pod ended
+//
+// Entries without a header, as written when klog is told to skip them, are
+// logged at Info.
+func writeBuffer(l hclog.Logger, data []byte) {
+	level, msg := parseHeader(bytes.TrimRight(data, "\n"))
+	l.Log(level, string(msg))
+}
+
+// headerLen is the length of the header of klog entries up to the file name.
+const headerLen = len("Lmmdd hh:mm:ss.uuuuuu threadid ")
+
+// parseHeader returns the level of the entry data and its message, stripped of
+// the header.
+func parseHeader(data []byte) (hclog.Level, []byte) {
+	if len(data) <= headerLen || data[5] != ' ' || data[14] != '.' {
+		return hclog.Info, data
+	}
+	level, ok := severityLevels[data[0]]
+	if !ok {
+		return hclog.Info, data
+	}
+	end := bytes.Index(data[headerLen:], []byte("] "))
+	if end < 0 {
+		return hclog.Info, data
+	}
+	return level, data[headerLen+end+2:]
+}
+
+// sink is the logr.LogSink through which klog writes structured entries.
+type sink struct {
+	l hclog.Logger
+}
+
+var _ logr.LogSink = (*sink)(nil)
+
+func (s *sink) Init(logr.RuntimeInfo) {}
+
+// Enabled reports whether entries at the verbosity level of logr are let
+// through, level 0 being Info and greater levels Debug.
+func (s *sink) Enabled(level int) bool {
+	if level > 0 {
+		return s.l.IsDebug()
+	}
+	return s.l.IsInfo()
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if level > 0 {
+		s.l.Debug(msg, keysAndValues...)
+	} else {
+		s.l.Info(msg, keysAndValues...)
+	}
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if err != nil {
+		keysAndValues = append(keysAndValues, hclog.ErrorKey, err)
+	}
+	s.l.Error(msg, keysAndValues...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &sink{l: s.l.With(keysAndValues...)}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{l: s.l.Named(name)}
+}
@@ -0,0 +1,64 @@
+package hclogklog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	hclog "github.com/varnson/go-hclog"
+	"k8s.io/klog/v2"
+)
+
+func TestInstall(t *testing.T) {
+	defer klog.ClearLogger()
+
+	var buf bytes.Buffer
+	logger := hclog.New(&hclog.LoggerOptions{
+		Output:      &buf,
+		Level:       hclog.Debug,
+		DisableTime: true,
+	})
+
+	Install(logger)
+	Install(logger)
+
+	klog.Info("starting informers")
+	klog.Warningf("watch of %s ended", "*v1.Pod")
+	klog.Error("connection refused")
+	klog.InfoS("synced", "resource", "pods")
+	klog.ErrorS(errors.New("timeout"), "lease renewal failed", "lease", "leader")
+	logr.New(&sink{l: logger}).WithName("leaderelection").V(1).Info("renewed")
+	klog.Flush()
+
+	expected := "[INFO]  -- starting informers\n" +
+		"[WARN]  -- watch of *v1.Pod ended\n" +
+		"[ERROR] -- connection refused\n" +
+		"[INFO]  -- synced: resource=pods\n" +
+		"[ERROR] -- lease renewal failed: lease=leader error=timeout\n" +
+		"[DEBUG] [module=leaderelection] -- renewed\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	cases := []struct {
+		line  string
+		level hclog.Level
+		msg   string
+	}{
+		{"I1014 12:00:00.000000   12345 main.go:10] started", hclog.Info, "started"},
+		{"W1014 12:00:00.000000   12345 reflector.go:424] watch ended", hclog.Warn, "watch ended"},
+		{"E1014 12:00:00.000000      42 client.go:1] x] y", hclog.Error, "x] y"},
+		{"F1014 12:00:00.000000   12345 main.go:10] fatal", hclog.Error, "fatal"},
+		{"no header", hclog.Info, "no header"},
+		{"X1014 12:00:00.000000   12345 main.go:10] unknown", hclog.Info, "X1014 12:00:00.000000   12345 main.go:10] unknown"},
+	}
+	for _, c := range cases {
+		level, msg := parseHeader([]byte(c.line))
+		if level != c.level || string(msg) != c.msg {
+			t.Errorf("%q: expected %s %q, got %s %q", c.line, c.level, c.msg, level, msg)
+		}
+	}
+}
@@ -0,0 +1,575 @@
+// Package hclogloki pushes the entries of hclog loggers to Grafana Loki,
+// through a SinkAdapter registered on an InterceptLogger.
+//
+// It only depends on the standard library, but is a module of its own like the
+// other sinks, so that it is versioned apart from the loggers.
+package hclogloki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+)
+
+// LineFormat is the format of the lines pushed, holding the message and the
+// key/value pairs not mapped to labels.
+type LineFormat int
+
+const (
+	// LineJSON formats lines as JSON objects, the message under @message as
+	// in the JSON format of the loggers.
+	LineJSON LineFormat = iota
+
+	// LineLogfmt formats lines as logfmt, the message under msg.
+	LineLogfmt
+)
+
+// Defaults of the Options left unset.
+const (
+	DefaultLevelLabel    = "level"
+	DefaultNameLabel     = "logger"
+	DefaultBatchSize     = 100
+	DefaultBufferSize    = 10000
+	DefaultFlushInterval = time.Second
+	DefaultMaxAttempts   = 5
+	DefaultMinBackoff    = 500 * time.Millisecond
+	DefaultMaxBackoff    = 30 * time.Second
+)
+
+// Options configures the Sink returned by NewSink.
+type Options struct {
+	// URL is the push endpoint, as in http://loki:3100/loki/api/v1/push.
+	URL string
+
+	// Labels are added to every stream, as in {"app": "web"}.
+	Labels map[string]string
+
+	// LabelKeys are the keys of the key/value pairs made labels rather than
+	// written to the line. Labels are indexed by Loki, so they should only
+	// take a few values.
+	LabelKeys []string
+
+	// LevelLabel and NameLabel are the labels of the level and the name of
+	// the logger. They default to DefaultLevelLabel and DefaultNameLabel.
+	LevelLabel string
+	NameLabel  string
+
+	// Format is the format of the lines.
+	Format LineFormat
+
+	// Level is the minimum level of the entries pushed. Defaults to pushing
+	// all of them.
+	Level hclog.Level
+
+	// BatchSize is the maximum number of entries pushed at once. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+
+	// FlushInterval is the time after which entries are pushed even if there
+	// are fewer than BatchSize. Defaults to DefaultFlushInterval.
+	FlushInterval time.Duration
+
+	// BufferSize is the number of entries held while they can't be pushed,
+	// beyond which new entries are dropped. Defaults to DefaultBufferSize.
+	BufferSize int
+
+	// MaxAttempts is the number of attempts to push a batch, retrying after
+	// 429 and 5xx responses and network errors, before dropping it. Defaults
+	// to DefaultMaxAttempts.
+	MaxAttempts int
+
+	// MinBackoff and MaxBackoff bound the time waited before retrying when
+	// the response has no Retry-After header, doubling with every attempt.
+	// They default to DefaultMinBackoff and DefaultMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Header is added to the push requests, for example with the
+	// X-Scope-OrgID header of the tenant or an Authorization header.
+	Header http.Header
+
+	// Client sends the push requests. Defaults to a client with a timeout of
+	// 10 seconds.
+	Client *http.Client
+
+	// OnError is called with the errors pushing entries, from the goroutine
+	// of the Sink.
+	OnError func(error)
+}
+
+// now returns the time of entries, replaced in tests.
+var now = time.Now
+
+// Sink is an hclog.SinkAdapter pushing entries to Loki:
+//
+//	sink, err := hclogloki.NewSink(hclogloki.Options{
+//		URL:       "http://loki:3100/loki/api/v1/push",
+//		Labels:    map[string]string{"app": "web"},
+//		LabelKeys: []string{"region"},
+//	})
+//	defer sink.Close(ctx)
+//	logger.RegisterSink(sink)
+//
+// Entries are pushed in gzipped JSON batches by a goroutine of the Sink, so
+// that loggers never wait on Loki. The level, the name of the logger and the
+// LabelKeys make the labels of the stream of an entry, the message and the
+// other key/value pairs its line. As Loki rejects entries older than the last
+// one of their stream, the timestamps of a stream never go back: an entry
+// timestamped before the previous one of its stream gets its time.
+type Sink struct {
+	opts      Options
+	labelKeys map[string]bool
+
+	mu      sync.Mutex
+	pending []entry
+	closed  bool
+
+	wake  chan struct{}
+	stop  chan struct{}
+	abort chan struct{}
+	done  chan struct{}
+
+	dropped uint64
+
+	// last is the timestamp of the last entry pushed to each stream, only
+	// used by the goroutine of the Sink.
+	last map[string]int64
+}
+
+var _ hclog.SinkAdapter = (*Sink)(nil)
+
+// entry is an entry waiting to be pushed.
+type entry struct {
+	labels map[string]string
+	stream string
+	ts     int64
+	line   string
+}
+
+// NewSink returns a Sink pushing to opts.URL.
+func NewSink(opts Options) (*Sink, error) {
+	switch {
+	case opts.URL == "":
+		return nil, errors.New("loki push URL is empty")
+	case opts.Format != LineJSON && opts.Format != LineLogfmt:
+		return nil, fmt.Errorf("unknown loki line format %d", opts.Format)
+	case opts.BatchSize < 0 || opts.BufferSize < 0 || opts.MaxAttempts < 0:
+		return nil, errors.New("negative loki batch size, buffer size or attempts")
+	case opts.FlushInterval < 0 || opts.MinBackoff < 0 || opts.MaxBackoff < 0:
+		return nil, errors.New("negative loki flush interval or backoff")
+	}
+	if opts.LevelLabel == "" {
+		opts.LevelLabel = DefaultLevelLabel
+	}
+	if opts.NameLabel == "" {
+		opts.NameLabel = DefaultNameLabel
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = DefaultMinBackoff
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = opts.MinBackoff
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	s := &Sink{
+		opts:      opts,
+		labelKeys: make(map[string]bool, len(opts.LabelKeys)),
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		abort:     make(chan struct{}),
+		done:      make(chan struct{}),
+		last:      make(map[string]int64),
+	}
+	for _, key := range opts.LabelKeys {
+		s.labelKeys[key] = true
+	}
+	go s.run()
+	return s, nil
+}
+
+// Accept implements hclog.SinkAdapter, formatting the entry and queuing it to
+// be pushed.
+func (s *Sink) Accept(name string, level hclog.Level, msg string, args ...interface{}) {
+	if level < s.opts.Level || level == hclog.Off {
+		return
+	}
+
+	labels := make(map[string]string, len(s.opts.Labels)+2)
+	for k, v := range s.opts.Labels {
+		labels[k] = v
+	}
+	labels[s.opts.LevelLabel] = level.String()
+	if name != "" {
+		labels[s.opts.NameLabel] = name
+	}
+
+	var fields []interface{}
+	for _, kv := range pairs(args) {
+		if s.labelKeys[kv.key] {
+			labels[kv.key] = fmt.Sprint(kv.value)
+			continue
+		}
+		fields = append(fields, kv.key, kv.value)
+	}
+
+	e := entry{
+		labels: labels,
+		stream: streamKey(labels),
+		ts:     now().UnixNano(),
+		line:   s.formatLine(msg, fields),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || len(s.pending) >= s.opts.BufferSize {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	s.pending = append(s.pending, e)
+	if len(s.pending) >= s.opts.BatchSize {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Dropped returns the number of entries dropped because the buffer was full,
+// their push failed for good or the Sink closed.
+func (s *Sink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close pushes the entries still buffered. It gives up once ctx is done,
+// dropping the entries left.
+func (s *Sink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		close(s.abort)
+		<-s.done
+		return ctx.Err()
+	}
+}
+
+// keyValue is a key/value pair given to Accept.
+type keyValue struct {
+	key   string
+	value interface{}
+}
+
+// pairs returns the key/value pairs of args, with string keys.
+func pairs(args []interface{}) []keyValue {
+	kvs := make([]keyValue, 0, len(args)/2+1)
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(hclog.Field); ok {
+			kvs = append(kvs, keyValue{f.Key, f.Value()})
+			i++
+			continue
+		}
+		if i+1 == len(args) {
+			kvs = append(kvs, keyValue{hclog.MissingKey, args[i]})
+			break
+		}
+		kvs = append(kvs, keyValue{fmt.Sprint(args[i]), args[i+1]})
+		i += 2
+	}
+	return kvs
+}
+
+// streamKey returns a key identifying the stream of labels.
+func streamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(strconv.Quote(k))
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// formatLine formats the line of an entry, from its message and the key/value
+// pairs left once the labels are taken out.
+func (s *Sink) formatLine(msg string, fields []interface{}) string {
+	if s.opts.Format == LineLogfmt {
+		var b strings.Builder
+		writeLogfmt(&b, "msg", msg)
+		for i := 0; i < len(fields); i += 2 {
+			b.WriteByte(' ')
+			writeLogfmt(&b, fields[i].(string), lineValue(fields[i+1]))
+		}
+		return b.String()
+	}
+
+	obj := make(map[string]interface{}, len(fields)/2+1)
+	obj["@message"] = msg
+	for i := 0; i < len(fields); i += 2 {
+		obj[fields[i].(string)] = lineValue(fields[i+1])
+	}
+	line, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Sprintf(`{"@message":%q,"@error":%q}`, msg, err.Error())
+	}
+	return string(line)
+}
+
+// lineValue returns value as written in lines, errors and Stringers as
+// strings.
+func lineValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+// writeLogfmt writes key=value, quoting the value if needed.
+func writeLogfmt(b *strings.Builder, key string, value interface{}) {
+	b.WriteString(key)
+	b.WriteByte('=')
+	str, ok := value.(string)
+	if !ok {
+		str = fmt.Sprint(value)
+	}
+	if str == "" || strings.ContainsAny(str, " \"=\t\n\r\\") {
+		b.WriteString(strconv.Quote(str))
+	} else {
+		b.WriteString(str)
+	}
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		stopped := false
+		select {
+		case <-s.wake:
+		case <-ticker.C:
+		case <-s.stop:
+			stopped = true
+		}
+		if !s.flush() || stopped {
+			return
+		}
+	}
+}
+
+// flush pushes the buffered entries. It returns false if the Sink was aborted.
+func (s *Sink) flush() bool {
+	for {
+		batch := s.batch()
+		if len(batch) == 0 {
+			return true
+		}
+		body, err := s.encode(batch)
+		if err == nil {
+			err = s.push(body)
+		}
+		if err == errAborted {
+			return false
+		}
+		if err != nil {
+			atomic.AddUint64(&s.dropped, uint64(len(batch)))
+			s.reportError(err)
+		}
+
+		s.mu.Lock()
+		s.pending = s.pending[len(batch):]
+		s.mu.Unlock()
+	}
+}
+
+// batch returns the oldest buffered entries, up to BatchSize.
+func (s *Sink) batch() []entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.pending)
+	if n > s.opts.BatchSize {
+		n = s.opts.BatchSize
+	}
+	return s.pending[:n:n]
+}
+
+// pushStream and pushRequest are the body of push requests.
+type pushStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type pushRequest struct {
+	Streams []*pushStream `json:"streams"`
+}
+
+// encode returns the gzipped push request of batch, with the timestamps of
+// every stream clamped so that they never go back.
+func (s *Sink) encode(batch []entry) ([]byte, error) {
+	var req pushRequest
+	streams := make(map[string]*pushStream)
+	for _, e := range batch {
+		ps, ok := streams[e.stream]
+		if !ok {
+			ps = &pushStream{Stream: e.labels}
+			streams[e.stream] = ps
+			req.Streams = append(req.Streams, ps)
+		}
+		ts := e.ts
+		if last, ok := s.last[e.stream]; ok && ts < last {
+			ts = last
+		}
+		s.last[e.stream] = ts
+		ps.Values = append(ps.Values, [2]string{strconv.FormatInt(ts, 10), e.line})
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to encode loki push request: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode loki push request: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// errAborted is returned by push when the Sink was aborted while waiting to
+// retry.
+var errAborted = errors.New("loki sink aborted")
+
+// push sends a push request, retrying after 429 and 5xx responses and network
+// errors.
+func (s *Sink) push(body []byte) error {
+	backoff := s.opts.MinBackoff
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := s.post(body)
+		if err == nil {
+			return nil
+		}
+		if retryAfter < 0 || attempt == s.opts.MaxAttempts {
+			return err
+		}
+		s.reportError(err)
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		if backoff *= 2; backoff > s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.abort:
+			timer.Stop()
+			return errAborted
+		}
+	}
+}
+
+// post sends a push request once. On failure, it returns the time to wait
+// according to the Retry-After header, zero for the backoff, or a negative
+// duration if the request must not be retried.
+func (s *Sink) post(body []byte) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return -1, fmt.Errorf("failed to push to loki: %w", err)
+	}
+	for k, v := range s.opts.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to push to loki: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	err = fmt.Errorf("failed to push to loki: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return -1, err
+	}
+	return retryAfter(resp.Header.Get("Retry-After")), err
+}
+
+// retryAfter parses the value of a Retry-After header, in seconds or as a
+// date, returning zero if it is missing or invalid.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func (s *Sink) reportError(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
@@ -0,0 +1,265 @@
+package hclogloki
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+)
+
+// lokiServer is a fake Loki push endpoint, answering with the statuses of
+// responses before accepting requests.
+type lokiServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []int
+	requests  int
+	streams   []pushStream
+	headers   []http.Header
+	received  chan struct{}
+}
+
+func newLokiServer(t *testing.T, responses ...int) *lokiServer {
+	s := &lokiServer{responses: responses, received: make(chan struct{}, 100)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		s.headers = append(s.headers, r.Header.Clone())
+		if len(s.responses) > 0 {
+			status := s.responses[0]
+			s.responses = s.responses[1:]
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			return
+		}
+
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("Expected a gzipped body, got %q", r.Header.Get("Content-Encoding"))
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("Expected a gzipped body, got %s", err)
+			return
+		}
+		var req struct {
+			Streams []pushStream `json:"streams"`
+		}
+		if err := json.NewDecoder(zr).Decode(&req); err != nil {
+			t.Errorf("Expected a push request, got %s", err)
+			return
+		}
+		s.streams = append(s.streams, req.Streams...)
+		w.WriteHeader(http.StatusNoContent)
+		s.received <- struct{}{}
+	}))
+	return s
+}
+
+func (s *lokiServer) pushed() []pushStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pushStream(nil), s.streams...)
+}
+
+func TestSink(t *testing.T) {
+	server := newLokiServer(t)
+	defer server.Close()
+
+	sink, err := NewSink(Options{
+		URL:       server.URL,
+		Labels:    map[string]string{"app": "web"},
+		LabelKeys: []string{"region"},
+		Level:     hclog.Info,
+		Header:    http.Header{"X-Scope-Orgid": []string{"tenant"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink.Accept("http", hclog.Debug, "filtered out")
+	sink.Accept("http", hclog.Info, "request served", "region", "eu", "status", 200)
+	sink.Accept("", hclog.Error, "request failed", "error", errors.New("boom"))
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	streams := server.pushed()
+	if len(streams) != 2 {
+		t.Fatalf("Expected 2 streams, got %d", len(streams))
+	}
+
+	labels := streams[0].Stream
+	if labels["app"] != "web" || labels["level"] != "info" || labels["logger"] != "http" || labels["region"] != "eu" {
+		t.Errorf("Expected the static, level, name and promoted labels, got %v", labels)
+	}
+	if len(streams[0].Values) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(streams[0].Values))
+	}
+	if line := streams[0].Values[0][1]; line != `{"@message":"request served","status":200}` {
+		t.Errorf("Expected %q, got %q", `{"@message":"request served","status":200}`, line)
+	}
+
+	labels = streams[1].Stream
+	if _, ok := labels["logger"]; ok || labels["level"] != "error" {
+		t.Errorf("Expected an error stream without a logger label, got %v", labels)
+	}
+	if line := streams[1].Values[0][1]; line != `{"@message":"request failed","error":"boom"}` {
+		t.Errorf("Expected %q, got %q", `{"@message":"request failed","error":"boom"}`, line)
+	}
+
+	if got := server.headers[0].Get("X-Scope-OrgID"); got != "tenant" {
+		t.Errorf("Expected %q, got %q", "tenant", got)
+	}
+}
+
+func TestSink_Logfmt(t *testing.T) {
+	server := newLokiServer(t)
+	defer server.Close()
+
+	sink, err := NewSink(Options{URL: server.URL, Format: LineLogfmt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Accept("", hclog.Info, "request served", "path", "/a b", "took", time.Second)
+	sink.Close(context.Background())
+
+	streams := server.pushed()
+	if len(streams) != 1 {
+		t.Fatalf("Expected 1 stream, got %d", len(streams))
+	}
+	if line := streams[0].Values[0][1]; line != `msg="request served" path="/a b" took=1s` {
+		t.Errorf("Expected %q, got %q", `msg="request served" path="/a b" took=1s`, line)
+	}
+}
+
+func TestSink_ClampsTimestamps(t *testing.T) {
+	defer func() { now = time.Now }()
+
+	server := newLokiServer(t)
+	defer server.Close()
+
+	sink, err := NewSink(Options{URL: server.URL, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Unix(1000, 0)
+	times := []time.Time{base, base.Add(-time.Second), base.Add(-2 * time.Second), base.Add(time.Second)}
+	for i, ts := range times {
+		now = func() time.Time { return ts }
+		sink.Accept("", hclog.Info, "entry", "i", i)
+		if i == 1 {
+			<-server.received
+		}
+	}
+	sink.Close(context.Background())
+
+	var got []string
+	for _, s := range server.pushed() {
+		for _, v := range s.Values {
+			got = append(got, v[0])
+		}
+	}
+	expected := []string{"1000000000000", "1000000000000", "1000000000000", "1001000000000"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, got)
+			break
+		}
+	}
+}
+
+func TestSink_Retries(t *testing.T) {
+	server := newLokiServer(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	var errs []error
+	sink, err := NewSink(Options{
+		URL:        server.URL,
+		MinBackoff: time.Millisecond,
+		OnError:    func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Accept("", hclog.Info, "retried")
+	sink.Close(context.Background())
+
+	if len(server.pushed()) != 1 {
+		t.Errorf("Expected the entry to be pushed after retrying")
+	}
+	if server.requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", server.requests)
+	}
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %v", errs)
+	}
+	if sink.Dropped() != 0 {
+		t.Errorf("Expected no entry dropped, got %d", sink.Dropped())
+	}
+}
+
+func TestSink_DropsRejectedBatches(t *testing.T) {
+	server := newLokiServer(t, http.StatusBadRequest)
+	defer server.Close()
+
+	sink, err := NewSink(Options{URL: server.URL, MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Accept("", hclog.Info, "rejected")
+	sink.Close(context.Background())
+
+	if server.requests != 1 {
+		t.Errorf("Expected 1 request, got %d", server.requests)
+	}
+	if sink.Dropped() != 1 {
+		t.Errorf("Expected 1 entry dropped, got %d", sink.Dropped())
+	}
+}
+
+func TestSink_BufferBound(t *testing.T) {
+	sink, err := NewSink(Options{
+		URL:           "http://127.0.0.1:1/loki/api/v1/push",
+		BufferSize:    2,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		sink.Accept("", hclog.Info, "entry")
+	}
+	if sink.Dropped() != 3 {
+		t.Errorf("Expected 3 entries dropped, got %d", sink.Dropped())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	sink.Close(ctx)
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d := retryAfter("3"); d != 3*time.Second {
+		t.Errorf("Expected %s, got %s", 3*time.Second, d)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := retryAfter(date); d <= 0 || d > time.Minute {
+		t.Errorf("Expected up to a minute, got %s", d)
+	}
+	if d := retryAfter("soon"); d != 0 {
+		t.Errorf("Expected 0, got %s", d)
+	}
+}
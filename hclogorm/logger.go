@@ -0,0 +1,157 @@
+// Package hclogorm adapts hclog loggers to the logger.Interface of GORM, so
+// that the SQL statements run by GORM are logged with structured fields.
+//
+// It is a module of its own, so that the go-hclog module doesn't depend on
+// GORM.
+package hclogorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Keys of the fields written for the SQL statements traced by GORM.
+const (
+	SQLKey          = "sql"
+	RowsAffectedKey = "rows_affected"
+	ElapsedKey      = "elapsed"
+)
+
+// Options configures the loggers returned by NewLogger.
+type Options struct {
+	// SlowThreshold is the time after which statements are logged at Warn
+	// rather than Debug. Zero disables it.
+	SlowThreshold time.Duration
+
+	// IgnoreRecordNotFoundError logs the statements failing with
+	// gorm.ErrRecordNotFound like successful ones, rather than at Error.
+	IgnoreRecordNotFoundError bool
+}
+
+// Logger is the logger.Interface returned by NewLogger.
+type Logger struct {
+	l    hclog.Logger
+	opts Options
+
+	// level filters entries for loggers given to NewLogger which can't derive
+	// a logger at another level, once LogMode has been called.
+	level hclog.Level
+}
+
+var _ gormlogger.Interface = (*Logger)(nil)
+
+// NewLogger returns a logger.Interface writing the entries of GORM to l:
+//
+//	db, err := gorm.Open(dialector, &gorm.Config{
+//		Logger: hclogorm.NewLogger(logger, hclogorm.Options{SlowThreshold: 200 * time.Millisecond}),
+//	})
+//
+// The statements traced by GORM are logged at Debug with the SQL, the rows
+// affected and the elapsed time as fields, at Warn if they took longer than
+// the slow threshold, and at Error if they failed.
+func NewLogger(l hclog.Logger, opts Options) *Logger {
+	return &Logger{l: l, opts: opts, level: hclog.NoLevel}
+}
+
+// gormLevels maps the levels of GORM onto those of hclog. Info is the level
+// at which GORM logs every statement, which Trace logs at Debug.
+var gormLevels = map[gormlogger.LogLevel]hclog.Level{
+	gormlogger.Silent: hclog.Off,
+	gormlogger.Error:  hclog.Error,
+	gormlogger.Warn:   hclog.Warn,
+	gormlogger.Info:   hclog.Debug,
+}
+
+// LogMode returns a logger writing to a sublogger at level.
+func (g *Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	hlevel, ok := gormLevels[level]
+	if !ok {
+		hlevel = hclog.Debug
+	}
+
+	sub := *g
+	if r, ok := g.l.(hclog.Reconfigurable); ok {
+		sub.l = r.WithOptions(func(opts *hclog.LoggerOptions) {
+			opts.Level = hlevel
+		})
+		sub.level = hclog.NoLevel
+	} else {
+		sub.level = hlevel
+	}
+	return &sub
+}
+
+// Info logs msg, formatted with data, at Info.
+func (g *Logger) Info(_ context.Context, msg string, data ...interface{}) {
+	g.logf(hclog.Info, msg, data)
+}
+
+// Warn logs msg, formatted with data, at Warn.
+func (g *Logger) Warn(_ context.Context, msg string, data ...interface{}) {
+	g.logf(hclog.Warn, msg, data)
+}
+
+// Error logs msg, formatted with data, at Error.
+func (g *Logger) Error(_ context.Context, msg string, data ...interface{}) {
+	g.logf(hclog.Error, msg, data)
+}
+
+// Trace logs the statement returned by fc, which is only called if the entry
+// is let through.
+func (g *Logger) Trace(_ context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+
+	level := hclog.Debug
+	switch {
+	case err != nil && !(g.opts.IgnoreRecordNotFoundError && errors.Is(err, gormlogger.ErrRecordNotFound)):
+		level = hclog.Error
+	case g.opts.SlowThreshold != 0 && elapsed > g.opts.SlowThreshold:
+		level = hclog.Warn
+	}
+	if !g.enabled(level) {
+		return
+	}
+
+	sql, rows := fc()
+	args := []interface{}{SQLKey, sql, ElapsedKey, elapsed}
+	if rows >= 0 {
+		args = append(args, RowsAffectedKey, rows)
+	}
+
+	switch level {
+	case hclog.Error:
+		g.l.Error("query failed", append(args, hclog.ErrorKey, err)...)
+	case hclog.Warn:
+		g.l.Warn("slow query", append(args, "slow_threshold", g.opts.SlowThreshold)...)
+	default:
+		g.l.Debug("query", args...)
+	}
+}
+
+func (g *Logger) logf(level hclog.Level, msg string, data []interface{}) {
+	if g.enabled(level) {
+		g.l.Log(level, fmt.Sprintf(msg, data...))
+	}
+}
+
+// enabled reports whether an entry at level is let through.
+func (g *Logger) enabled(level hclog.Level) bool {
+	if g.level != hclog.NoLevel && level < g.level {
+		return false
+	}
+	switch level {
+	case hclog.Debug:
+		return g.l.IsDebug()
+	case hclog.Info:
+		return g.l.IsInfo()
+	case hclog.Warn:
+		return g.l.IsWarn()
+	default:
+		return g.l.IsError()
+	}
+}
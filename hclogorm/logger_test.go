@@ -0,0 +1,110 @@
+package hclogorm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func newTestLogger(level hclog.Level) (hclog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return hclog.New(&hclog.LoggerOptions{
+		Output:      &buf,
+		Level:       level,
+		DisableTime: true,
+	}), &buf
+}
+
+func query(sql string, rows int64) func() (string, int64) {
+	return func() (string, int64) { return sql, rows }
+}
+
+func TestLogger_Trace(t *testing.T) {
+	t.Run("logs statements at debug", func(t *testing.T) {
+		l, buf := newTestLogger(hclog.Debug)
+
+		NewLogger(l, Options{}).Trace(context.Background(), time.Now(), query("SELECT 1", 1), nil)
+
+		str := buf.String()
+		if !strings.HasPrefix(str, `[DEBUG] -- query: sql="SELECT 1" elapsed=`) || !strings.HasSuffix(str, " rows_affected=1\n") {
+			t.Errorf("Unexpected output %q", str)
+		}
+	})
+
+	t.Run("logs slow statements at warn", func(t *testing.T) {
+		l, buf := newTestLogger(hclog.Info)
+
+		g := NewLogger(l, Options{SlowThreshold: time.Millisecond})
+		g.Trace(context.Background(), time.Now().Add(-time.Second), query("SELECT 1", 1), nil)
+		g.Trace(context.Background(), time.Now(), query("SELECT 2", 1), nil)
+
+		str := buf.String()
+		if !strings.HasPrefix(str, `[WARN]  -- slow query: sql="SELECT 1"`) || !strings.HasSuffix(str, " slow_threshold=1ms\n") {
+			t.Errorf("Unexpected output %q", str)
+		}
+	})
+
+	t.Run("logs failed statements at error", func(t *testing.T) {
+		l, buf := newTestLogger(hclog.Info)
+
+		g := NewLogger(l, Options{IgnoreRecordNotFoundError: true})
+		g.Trace(context.Background(), time.Now(), query("SELECT 1", -1), errors.New("connection reset"))
+		g.Trace(context.Background(), time.Now(), query("SELECT 2", 0), fmt.Errorf("first: %w", gormlogger.ErrRecordNotFound))
+
+		str := buf.String()
+		if !strings.HasPrefix(str, `[ERROR] -- query failed: sql="SELECT 1"`) || !strings.HasSuffix(str, ` error="connection reset"`+"\n") {
+			t.Errorf("Unexpected output %q", str)
+		}
+		if strings.Count(str, "\n") != 1 {
+			t.Errorf("Expected record not found errors to be ignored, got %q", str)
+		}
+	})
+
+	t.Run("only calls fc for entries let through", func(t *testing.T) {
+		l, buf := newTestLogger(hclog.Info)
+
+		called := false
+		NewLogger(l, Options{}).Trace(context.Background(), time.Now(), func() (string, int64) {
+			called = true
+			return "SELECT 1", 1
+		}, nil)
+
+		if called || buf.Len() != 0 {
+			t.Errorf("Expected the statement not to be traced, got %q", buf.String())
+		}
+	})
+}
+
+func TestLogger_LogMode(t *testing.T) {
+	l, buf := newTestLogger(hclog.Info)
+	g := NewLogger(l, Options{})
+
+	g.LogMode(gormlogger.Info).Trace(context.Background(), time.Now(), query("SELECT 1", 1), nil)
+	if !strings.HasPrefix(buf.String(), "[DEBUG] -- query:") {
+		t.Errorf("Expected statements to be logged in info mode, got %q", buf.String())
+	}
+	buf.Reset()
+
+	silent := g.LogMode(gormlogger.Silent)
+	silent.Error(context.Background(), "failed: %s", "boom")
+	silent.Trace(context.Background(), time.Now(), query("SELECT 1", 1), errors.New("boom"))
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be logged in silent mode, got %q", buf.String())
+	}
+
+	g.LogMode(gormlogger.Error).Warn(context.Background(), "dropped")
+	g.Warn(context.Background(), "kept %d", 1)
+	if expected := "[WARN]  -- kept 1\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+	if !l.IsInfo() || l.IsDebug() {
+		t.Errorf("Expected LogMode not to change the level of the logger")
+	}
+}
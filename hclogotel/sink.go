@@ -0,0 +1,361 @@
+// Package hclogotel exports the entries of hclog loggers as OpenTelemetry log
+// records, through a SinkAdapter registered on an InterceptLogger.
+//
+// It is a module of its own, so that the go-hclog module doesn't depend on
+// OpenTelemetry.
+package hclogotel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+)
+
+// DefaultScope is the instrumentation scope of the records of unnamed loggers.
+const DefaultScope = "github.com/varnson/go-hclog"
+
+// DefaultQueueSize is the number of entries queued for export when
+// SinkOptions.QueueSize is not set.
+const DefaultQueueSize = 1024
+
+// DefaultMaxBlock is how long Accept waits for room in the queue for the
+// entries at or above SinkOptions.BlockLevel, when SinkOptions.MaxBlock is not
+// set.
+const DefaultMaxBlock = time.Second
+
+// DefaultDropReportInterval is how often drops are reported to
+// SinkOptions.DropReporter, when SinkOptions.DropReportInterval is not set.
+const DefaultDropReportInterval = time.Minute
+
+// SinkOptions configures the SinkAdapter returned by NewSinkAdapter.
+type SinkOptions struct {
+	// Level is the minimum level of the entries exported. It defaults to
+	// Warn, so that the loggers can write everything else to their own output
+	// only.
+	Level hclog.Level
+
+	// QueueSize is the number of entries queued while the exporter is busy,
+	// beyond which entries are dropped. It defaults to DefaultQueueSize.
+	QueueSize int
+
+	// BlockLevel is the level at and above which entries aren't dropped when
+	// the queue is full: Accept waits for room instead, for up to MaxBlock,
+	// and drops the entry after that, so that an exporter which stopped
+	// doesn't hold up the loggers for good. NoLevel, the default, drops the
+	// entries at every level.
+	BlockLevel hclog.Level
+
+	// MaxBlock is how long Accept waits for the entries at or above
+	// BlockLevel. It defaults to DefaultMaxBlock.
+	MaxBlock time.Duration
+
+	// DropReporter, if set, is written a warning every DropReportInterval
+	// in which entries were dropped, with the number dropped at each level.
+	// It must not be a logger the SinkAdapter is registered on, or the
+	// warnings would be queued for export along with the entries.
+	DropReporter hclog.Logger
+
+	// DropReportInterval is how often drops are reported to DropReporter.
+	// It defaults to DefaultDropReportInterval.
+	DropReportInterval time.Duration
+}
+
+// SinkAdapter is an hclog.SinkAdapter emitting entries as log records to the
+// loggers of an OpenTelemetry LoggerProvider:
+//
+//	sink := hclogotel.NewSinkAdapter(provider, &hclogotel.SinkOptions{Level: hclog.Warn})
+//	defer sink.Close(ctx)
+//	logger.RegisterSink(sink)
+//
+// The level of entries maps onto the severity of records, their message onto
+// the body and their key/value pairs onto attributes. The name of the logger
+// is the instrumentation scope.
+//
+// Entries are emitted by a goroutine of the SinkAdapter, so that a slow
+// exporter doesn't hold up the loggers. When the queue is full, entries are
+// dropped, but for those at or above SinkOptions.BlockLevel, and counted by
+// Dropped and DroppedByLevel.
+type SinkAdapter struct {
+	provider   log.LoggerProvider
+	level      hclog.Level
+	blockLevel hclog.Level
+	maxBlock   time.Duration
+
+	loggers sync.Map // scope name -> log.Logger
+
+	mu      sync.RWMutex
+	closed  bool
+	entries chan entry
+	done    chan struct{}
+
+	// dropped counts the entries dropped at each level.
+	dropped [hclog.Error + 1]atomic.Uint64
+
+	stopReports chan struct{}
+}
+
+var _ hclog.SinkAdapter = (*SinkAdapter)(nil)
+
+// entry is an entry queued for export.
+type entry struct {
+	name   string
+	record log.Record
+}
+
+// NewSinkAdapter returns a SinkAdapter emitting to the loggers of provider.
+// The SinkAdapter must be closed once deregistered, to emit the entries still
+// queued.
+func NewSinkAdapter(provider log.LoggerProvider, opts *SinkOptions) *SinkAdapter {
+	if opts == nil {
+		opts = &SinkOptions{}
+	}
+	level := opts.Level
+	if level == hclog.NoLevel {
+		level = hclog.Warn
+	}
+	size := opts.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+
+	maxBlock := opts.MaxBlock
+	if maxBlock <= 0 {
+		maxBlock = DefaultMaxBlock
+	}
+
+	s := &SinkAdapter{
+		provider:    provider,
+		level:       level,
+		blockLevel:  opts.BlockLevel,
+		maxBlock:    maxBlock,
+		entries:     make(chan entry, size),
+		done:        make(chan struct{}),
+		stopReports: make(chan struct{}),
+	}
+	go s.run()
+	if opts.DropReporter != nil {
+		interval := opts.DropReportInterval
+		if interval <= 0 {
+			interval = DefaultDropReportInterval
+		}
+		go s.reportDrops(opts.DropReporter, interval)
+	}
+	return s
+}
+
+// Accept implements hclog.SinkAdapter, queuing the entry for export if its
+// level is at least the minimum level.
+func (s *SinkAdapter) Accept(name string, level hclog.Level, msg string, args ...interface{}) {
+	if level < s.level || level == hclog.Off {
+		return
+	}
+
+	now := time.Now()
+	var r log.Record
+	r.SetTimestamp(now)
+	r.SetObservedTimestamp(now)
+	r.SetSeverity(severities[level])
+	r.SetSeverityText(strings.ToUpper(level.String()))
+	r.SetBody(attribute.StringValue(msg))
+	addAttributes(&r, args)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		s.dropped[level].Add(1)
+		return
+	}
+	e := entry{name: name, record: r}
+	select {
+	case s.entries <- e:
+		return
+	default:
+	}
+	if s.blockLevel == hclog.NoLevel || level < s.blockLevel {
+		s.dropped[level].Add(1)
+		return
+	}
+
+	timer := time.NewTimer(s.maxBlock)
+	defer timer.Stop()
+	select {
+	case s.entries <- e:
+	case <-timer.C:
+		s.dropped[level].Add(1)
+	}
+}
+
+// Dropped returns the number of entries dropped because the queue was full or
+// the SinkAdapter closed.
+func (s *SinkAdapter) Dropped() uint64 {
+	var n uint64
+	for level := range s.dropped {
+		n += s.dropped[level].Load()
+	}
+	return n
+}
+
+// DroppedByLevel returns the number of entries dropped at each level, as
+// Dropped does.
+func (s *SinkAdapter) DroppedByLevel() map[hclog.Level]uint64 {
+	counts := make(map[hclog.Level]uint64, len(s.dropped)-1)
+	for level := hclog.Trace; level <= hclog.Error; level++ {
+		counts[level] = s.dropped[level].Load()
+	}
+	return counts
+}
+
+// reportDrops warns reporter of the entries dropped every interval in which
+// some were, until Close is called.
+func (s *SinkAdapter) reportDrops(reporter hclog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := make(map[hclog.Level]uint64)
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.stopReports:
+			return
+		}
+
+		counts := s.DroppedByLevel()
+		var args []interface{}
+		var total uint64
+		for level := hclog.Trace; level <= hclog.Error; level++ {
+			if n := counts[level] - last[level]; n > 0 {
+				args = append(args, level.String(), n)
+				total += n
+			}
+		}
+		last = counts
+		if total > 0 {
+			reporter.Warn("dropped log records", append([]interface{}{"total", total, "interval", interval}, args...)...)
+		}
+	}
+}
+
+// Close emits the entries still queued, and returns once they're all emitted
+// or ctx is done. Entries accepted afterwards are dropped.
+func (s *SinkAdapter) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.entries)
+		close(s.stopReports)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *SinkAdapter) run() {
+	defer close(s.done)
+	for e := range s.entries {
+		s.logger(e.name).Emit(context.Background(), e.record)
+	}
+}
+
+// logger returns the logger of the scope of the loggers named name.
+func (s *SinkAdapter) logger(name string) log.Logger {
+	if name == "" {
+		name = DefaultScope
+	}
+	if l, ok := s.loggers.Load(name); ok {
+		return l.(log.Logger)
+	}
+	l, _ := s.loggers.LoadOrStore(name, s.provider.Logger(name))
+	return l.(log.Logger)
+}
+
+// severities maps the levels onto the severities of records.
+var severities = map[hclog.Level]log.Severity{
+	hclog.Trace: log.SeverityTrace,
+	hclog.Debug: log.SeverityDebug,
+	hclog.Info:  log.SeverityInfo,
+	hclog.Warn:  log.SeverityWarn,
+	hclog.Error: log.SeverityError,
+}
+
+// addAttributes adds the key/value pairs of args to r. The error under
+// hclog.ErrorKey is also set as the error of the record.
+func addAttributes(r *log.Record, args []interface{}) {
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(hclog.Field); ok {
+			addAttribute(r, f.Key, f.Value())
+			i++
+			continue
+		}
+		if i+1 == len(args) {
+			addAttribute(r, hclog.MissingKey, args[i])
+			break
+		}
+		addAttribute(r, fmt.Sprint(args[i]), args[i+1])
+		i += 2
+	}
+}
+
+func addAttribute(r *log.Record, key string, value interface{}) {
+	if err, ok := value.(error); ok && key == hclog.ErrorKey {
+		r.SetErr(err)
+	}
+	r.AddAttributes(attribute.KeyValue{Key: attribute.Key(key), Value: attributeValue(value)})
+}
+
+// attributeValue converts value to an attribute value, as a string for the
+// types attributes don't hold.
+func attributeValue(value interface{}) attribute.Value {
+	switch v := value.(type) {
+	case string:
+		return attribute.StringValue(v)
+	case bool:
+		return attribute.BoolValue(v)
+	case int:
+		return attribute.IntValue(v)
+	case int8:
+		return attribute.Int64Value(int64(v))
+	case int16:
+		return attribute.Int64Value(int64(v))
+	case int32:
+		return attribute.Int64Value(int64(v))
+	case int64:
+		return attribute.Int64Value(v)
+	case uint8:
+		return attribute.Int64Value(int64(v))
+	case uint16:
+		return attribute.Int64Value(int64(v))
+	case uint32:
+		return attribute.Int64Value(int64(v))
+	case float32:
+		return attribute.Float64Value(float64(v))
+	case float64:
+		return attribute.Float64Value(v)
+	case []byte:
+		return attribute.ByteSliceValue(v)
+	case []string:
+		return attribute.StringSliceValue(v)
+	case time.Duration:
+		return attribute.StringValue(v.String())
+	case time.Time:
+		return attribute.StringValue(v.Format(time.RFC3339Nano))
+	case error:
+		return attribute.StringValue(v.Error())
+	case fmt.Stringer:
+		return attribute.StringValue(v.String())
+	case nil:
+		return attribute.StringValue("<nil>")
+	}
+	return attribute.StringValue(fmt.Sprintf("%+v", value))
+}
@@ -0,0 +1,251 @@
+package hclogotel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	hclog "github.com/varnson/go-hclog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// memoryExporter keeps the records exported to it.
+type memoryExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+
+	// block, if set, holds up exports until it is closed.
+	block chan struct{}
+}
+
+func (e *memoryExporter) Export(_ context.Context, records []sdklog.Record) error {
+	if e.block != nil {
+		<-e.block
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(context.Context) error   { return nil }
+func (e *memoryExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *memoryExporter) exported() []sdklog.Record {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sdklog.Record(nil), e.records...)
+}
+
+func attributes(r sdklog.Record) map[string]string {
+	attrs := map[string]string{}
+	r.WalkAttributes(func(kv attribute.KeyValue) bool {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+		return true
+	})
+	return attrs
+}
+
+func newProvider(exp *memoryExporter) *sdklog.LoggerProvider {
+	return sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exp)))
+}
+
+func TestSinkAdapter(t *testing.T) {
+	t.Run("exports entries from the minimum level", func(t *testing.T) {
+		exp := &memoryExporter{}
+		sink := NewSinkAdapter(newProvider(exp), nil)
+
+		logger := hclog.NewInterceptLogger(&hclog.LoggerOptions{Output: io.Discard, Level: hclog.Trace})
+		logger.RegisterSink(sink)
+
+		logger.Info("not exported")
+		logger.Named("raft").Warn("heartbeat timeout", "peer", "node-2", "attempt", 3, "elapsed", time.Second)
+		logger.Error("apply failed", hclog.ErrorKey, errors.New("disk full"), "dangling")
+
+		if err := sink.Close(context.Background()); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		records := exp.exported()
+		if len(records) != 2 {
+			t.Fatalf("Expected 2 records, got %d", len(records))
+		}
+
+		warn := records[0]
+		if warn.Severity() != log.SeverityWarn || warn.SeverityText() != "WARN" {
+			t.Errorf("Expected a warn record, got %s %q", warn.Severity(), warn.SeverityText())
+		}
+		if warn.Body().AsString() != "heartbeat timeout" {
+			t.Errorf("Expected %q, got %q", "heartbeat timeout", warn.Body().AsString())
+		}
+		if scope := warn.InstrumentationScope().Name; scope != "raft" {
+			t.Errorf("Expected the raft scope, got %q", scope)
+		}
+		attrs := attributes(warn)
+		expected := map[string]string{"peer": "node-2", "attempt": "3", "elapsed": "1s"}
+		for k, v := range expected {
+			if attrs[k] != v {
+				t.Errorf("Expected %s=%s, got %q", k, v, attrs[k])
+			}
+		}
+
+		failed := records[1]
+		if failed.Severity() != log.SeverityError || failed.InstrumentationScope().Name != DefaultScope {
+			t.Errorf("Unexpected record %s in %q", failed.Severity(), failed.InstrumentationScope().Name)
+		}
+		attrs = attributes(failed)
+		if attrs[hclog.ErrorKey] != "disk full" || attrs[hclog.MissingKey] != "dangling" {
+			t.Errorf("Unexpected attributes %v", attrs)
+		}
+		// The SDK derives the exception attributes from the error of the
+		// record.
+		if attrs["exception.message"] != "disk full" {
+			t.Errorf("Expected the error of the record to be set, got %v", attrs)
+		}
+	})
+
+	t.Run("drops entries when the exporter is slow", func(t *testing.T) {
+		exp := &memoryExporter{block: make(chan struct{})}
+		sink := NewSinkAdapter(newProvider(exp), &SinkOptions{QueueSize: 1})
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 10; i++ {
+				sink.Accept("", hclog.Error, "burst")
+			}
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected Accept not to block")
+		}
+
+		close(exp.block)
+		if err := sink.Close(context.Background()); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		exported := uint64(len(exp.exported()))
+		if exported+sink.Dropped() != 10 || sink.Dropped() == 0 {
+			t.Errorf("Expected dropped entries, got %d exported and %d dropped", exported, sink.Dropped())
+		}
+	})
+
+	t.Run("blocks for the entries at the block level", func(t *testing.T) {
+		exp := &memoryExporter{block: make(chan struct{})}
+		sink := NewSinkAdapter(newProvider(exp), &SinkOptions{
+			Level:      hclog.Debug,
+			QueueSize:  1,
+			BlockLevel: hclog.Warn,
+			MaxBlock:   time.Hour,
+		})
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 5; i++ {
+				sink.Accept("", hclog.Debug, "debug")
+				sink.Accept("", hclog.Error, "error")
+			}
+			close(done)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		close(exp.block)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected Accept to return once the exporter caught up")
+		}
+		if err := sink.Close(context.Background()); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		n := 0
+		for _, r := range exp.exported() {
+			if r.Body().AsString() == "error" {
+				n++
+			}
+		}
+		if n != 5 {
+			t.Errorf("Expected every error entry to be exported, got %d", n)
+		}
+		dropped := sink.DroppedByLevel()
+		if dropped[hclog.Error] != 0 || dropped[hclog.Debug] == 0 {
+			t.Errorf("Expected debug entries only to be dropped, got %v", dropped)
+		}
+	})
+
+	t.Run("drops blocked entries after the maximum wait", func(t *testing.T) {
+		exp := &memoryExporter{block: make(chan struct{})}
+		defer close(exp.block)
+		sink := NewSinkAdapter(newProvider(exp), &SinkOptions{
+			QueueSize:  1,
+			BlockLevel: hclog.Warn,
+			MaxBlock:   10 * time.Millisecond,
+		})
+
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			sink.Accept("", hclog.Error, "error")
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Fatalf("Expected Accept to give up waiting, took %s", elapsed)
+		}
+		if dropped := sink.DroppedByLevel()[hclog.Error]; dropped == 0 || dropped != sink.Dropped() {
+			t.Errorf("Expected error entries to be dropped, got %v", sink.DroppedByLevel())
+		}
+	})
+
+	t.Run("reports drops", func(t *testing.T) {
+		exp := &memoryExporter{block: make(chan struct{})}
+		var reports lockedBuffer
+		sink := NewSinkAdapter(newProvider(exp), &SinkOptions{
+			QueueSize:          1,
+			DropReporter:       hclog.New(&hclog.LoggerOptions{Output: &reports, DisableTime: true}),
+			DropReportInterval: 100 * time.Millisecond,
+		})
+
+		for i := 0; i < 5; i++ {
+			sink.Accept("", hclog.Warn, "warn")
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for reports.String() == "" && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		close(exp.block)
+		if err := sink.Close(context.Background()); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		want := fmt.Sprintf("[WARN]  -- dropped log records: total=%d interval=100ms warn=%d\n", sink.Dropped(), sink.Dropped())
+		if got := reports.String(); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+}
+
+// lockedBuffer is a bytes.Buffer safe to read while a logger writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
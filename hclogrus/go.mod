@@ -0,0 +1,17 @@
+module github.com/varnson/go-hclog/hclogrus
+
+go 1.23
+
+require (
+	github.com/sirupsen/logrus v1.10.2
+	github.com/varnson/go-hclog v0.0.0
+)
+
+require (
+	github.com/fatih/color v1.7.0 // indirect
+	github.com/mattn/go-colorable v0.1.4 // indirect
+	github.com/mattn/go-isatty v0.0.10 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+)
+
+replace github.com/varnson/go-hclog => ../
@@ -0,0 +1,98 @@
+// Package hclogrus bridges logrus and hclog, so that code logging with logrus
+// writes through an hclog logger, the same lines to the same outputs.
+//
+// It is a module of its own, so that the go-hclog module doesn't depend on
+// logrus.
+package hclogrus
+
+import (
+	"io"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	hclog "github.com/varnson/go-hclog"
+)
+
+// Hook is a logrus.Hook forwarding the entries of logrus loggers to an hclog
+// Logger.
+//
+// Logrus levels map onto the hclog levels of the same name, and Fatal and Panic
+// onto Error, hclog having no level above it. The hook only writes the entry:
+// logrus itself still exits after a Fatal entry and panics after a Panic entry,
+// once the hooks have run, and the entry is written before that.
+type Hook struct {
+	l hclog.Logger
+}
+
+// NewHook returns a Hook forwarding entries to l.
+func NewHook(l hclog.Logger) *Hook {
+	return &Hook{l: l}
+}
+
+// Levels implements logrus.Hook, firing for all levels. The level of the
+// hclog logger decides what is written.
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook, writing the entry with its fields, sorted by
+// key, as key/value pairs. The error field of logrus is written under
+// hclog.ErrorKey.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := make([]interface{}, 0, len(keys)*2)
+	for _, k := range keys {
+		key := k
+		if k == logrus.ErrorKey {
+			key = hclog.ErrorKey
+		}
+		args = append(args, key, entry.Data[k])
+	}
+
+	h.l.Log(level(entry.Level), entry.Message, args...)
+	return nil
+}
+
+// level returns the hclog level matching the logrus level.
+func level(l logrus.Level) hclog.Level {
+	switch l {
+	case logrus.TraceLevel:
+		return hclog.Trace
+	case logrus.DebugLevel:
+		return hclog.Debug
+	case logrus.InfoLevel:
+		return hclog.Info
+	case logrus.WarnLevel:
+		return hclog.Warn
+	}
+	return hclog.Error
+}
+
+// discardFormatter is the formatter of the loggers returned by
+// NewLogrusLogger, which have nothing to format since the hook writes the
+// entries.
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+// NewLogrusLogger returns a logrus.Logger writing its entries through l only,
+// with the formatting and output of logrus disabled. It lets every level
+// through, so that l decides what is written even when its level changes.
+//
+// Fatal entries are written at the Error level before logrus calls the
+// ExitFunc of the logger, and Panic entries before logrus panics.
+func NewLogrusLogger(l hclog.Logger) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetFormatter(discardFormatter{})
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(NewHook(l))
+	return logger
+}
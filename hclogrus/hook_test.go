@@ -0,0 +1,126 @@
+package hclogrus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	hclog "github.com/varnson/go-hclog"
+)
+
+func TestNewLogrusLogger(t *testing.T) {
+	t.Run("writes through the logger", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Name:        "app",
+			Output:      &buf,
+			Level:       hclog.Trace,
+			DisableTime: true,
+		})
+
+		lr := NewLogrusLogger(logger)
+		lr.Trace("tracing")
+		lr.WithFields(logrus.Fields{"path": "/v1/kv", "attempt": 2}).Info("request served")
+		lr.WithError(errors.New("timeout")).Warn("retrying")
+
+		expected := "[TRACE] [module=app] -- tracing\n" +
+			"[INFO]  [module=app] -- request served: attempt=2 path=/v1/kv\n" +
+			"[WARN]  [module=app] -- retrying: error=timeout\n"
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("follows the level of the logger", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Output:      &buf,
+			Level:       hclog.Warn,
+			DisableTime: true,
+		})
+
+		lr := NewLogrusLogger(logger)
+		lr.Info("filtered")
+		logger.SetLevel(hclog.Info)
+		lr.Info("written")
+
+		expected := "[INFO]  -- written\n"
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("writes fatal entries before exiting", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		lr := NewLogrusLogger(logger)
+		code := -1
+		lr.ExitFunc = func(c int) {
+			if buf.Len() == 0 {
+				t.Errorf("Expected the entry to be written before exiting")
+			}
+			code = c
+		}
+		lr.Fatal("shutting down")
+
+		if code != 1 {
+			t.Errorf("Expected an exit code of 1, got %d", code)
+		}
+		if expected := "[ERROR] -- shutting down\n"; buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("writes panic entries before panicking", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		lr := NewLogrusLogger(logger)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected logrus to panic")
+				}
+			}()
+			lr.Panic("invariant broken")
+		}()
+
+		if expected := "[ERROR] -- invariant broken\n"; buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+}
+
+func TestHook(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Output:      &buf,
+		DisableTime: true,
+	})
+
+	var out bytes.Buffer
+	lr := logrus.New()
+	lr.SetOutput(&out)
+	lr.AddHook(NewHook(logger))
+	lr.WithField("user", "bob").Error("login failed")
+
+	if expected := "[ERROR] -- login failed: user=bob\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+	if out.Len() == 0 {
+		t.Errorf("Expected logrus to keep writing its own output")
+	}
+}
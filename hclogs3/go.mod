@@ -0,0 +1,3 @@
+module github.com/varnson/go-hclog/hclogs3
+
+go 1.13
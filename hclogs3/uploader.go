@@ -0,0 +1,520 @@
+// Package hclogs3 uploads the files rotated by a logger.LogFile to S3
+// compatible object storage, and keeps them from being pruned until they're
+// uploaded.
+//
+// It is a module of its own, and talks to the storage through the Client
+// interface so that it doesn't depend on an SDK either: an adapter to the AWS
+// SDK or to a MinIO client is a few lines.
+package hclogs3
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Object is an object to store.
+type Object struct {
+	// Key is the key of the object, the prefix of the Uploader followed by
+	// the name of the file.
+	Key string
+
+	// Body is the content of the object, rewound before every attempt.
+	Body io.ReadSeeker
+
+	// Size is the size of Body in bytes.
+	Size int64
+
+	// ContentMD5 is the base64 encoded MD5 digest of Body, as sent in the
+	// Content-MD5 header.
+	ContentMD5 string
+
+	// ChecksumSHA256 is the base64 encoded SHA-256 digest of Body, as sent
+	// in the x-amz-checksum-sha256 header.
+	ChecksumSHA256 string
+}
+
+// Client stores objects. Implementations are expected to send the checksums
+// of the object for the storage to verify them.
+type Client interface {
+	PutObject(ctx context.Context, obj *Object) error
+}
+
+const (
+	// DefaultMaxAttempts is the number of attempts to upload a file when
+	// Options.MaxAttempts is not set.
+	DefaultMaxAttempts = 5
+
+	// DefaultBackoff is the time waited after the first failed attempt when
+	// Options.Backoff is not set. It doubles with every attempt, up to
+	// MaxBackoff.
+	DefaultBackoff = time.Second
+
+	// MaxBackoff is the longest time waited between two attempts.
+	MaxBackoff = time.Minute
+)
+
+// compressedSuffixes are the suffixes added to rotated files by compression
+// and encryption, which don't make them different files as far as uploads go.
+var compressedSuffixes = []string{".gz.enc", ".zst.enc", ".enc", ".gz", ".zst"}
+
+// Options configures the Uploader returned by NewUploader.
+type Options struct {
+	// Client stores the files.
+	Client Client
+
+	// Prefix is prepended to the names of the files to make their keys, as
+	// in "logs/web-1/".
+	Prefix string
+
+	// StateFile is the file recording the files uploaded, so that they are
+	// neither uploaded again nor kept from pruning after a restart. It is
+	// required, and usually kept next to the log files under a name the
+	// LogFile doesn't take for one of its own.
+	//
+	// Files are recorded by their name without index or compression suffix,
+	// along with their modification time, as the index naming of the LogFile
+	// shifts the files to other names. Renames keep the modification time,
+	// and so does compression by the LogFile or by the gzip and zstd tools.
+	StateFile string
+
+	// MaxAttempts is the number of attempts to upload a file before giving
+	// up on it until the next Recover. Defaults to DefaultMaxAttempts.
+	MaxAttempts int
+
+	// Backoff is the time waited after the first failed attempt. Defaults
+	// to DefaultBackoff.
+	Backoff time.Duration
+
+	// OnError is called with the errors of failed attempts.
+	OnError func(error)
+}
+
+// Uploader uploads rotated files one at a time in the background:
+//
+//	up, err := hclogs3.NewUploader(hclogs3.Options{
+//		Client:    client,
+//		Prefix:    "logs/web-1/",
+//		StateFile: filepath.Join(dir, ".uploaded"),
+//	})
+//	lf, err := logger.NewLogFile(logger.LogFileOptions{
+//		Path:     dir,
+//		FileName: "web.log",
+//		MaxFiles: 10,
+//		OnRotate: func(path string, _ int64, _ logger.RotateReason) { up.Enqueue(path) },
+//		CanPrune: up.CanPrune,
+//	})
+//	up.Recover(lf.RotatedFiles)
+//	defer up.Close(ctx)
+//
+// Files are only reported prunable by CanPrune once uploaded, so the retention
+// limits of the LogFile never remove a file before it reached the storage.
+type Uploader struct {
+	opts Options
+
+	mu       sync.Mutex
+	uploaded map[string]bool
+	queued   map[string]bool
+	queue    []queuedFile
+	closed   bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	// ctx is the context of uploads, canceled when Close gives up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewUploader returns an Uploader configured with opts, having read the files
+// already uploaded from the state file.
+func NewUploader(opts Options) (*Uploader, error) {
+	switch {
+	case opts.Client == nil:
+		return nil, errors.New("upload client is nil")
+	case opts.StateFile == "":
+		return nil, errors.New("upload state file is empty")
+	case opts.MaxAttempts < 0:
+		return nil, fmt.Errorf("negative number of upload attempts %d", opts.MaxAttempts)
+	case opts.Backoff < 0:
+		return nil, fmt.Errorf("negative upload backoff %s", opts.Backoff)
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = DefaultBackoff
+	}
+
+	uploaded, err := readState(opts.StateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload state: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u := &Uploader{
+		opts:     opts,
+		uploaded: uploaded,
+		queued:   make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go u.run()
+	return u, nil
+}
+
+// queuedFile is a file queued for upload.
+type queuedFile struct {
+	path string
+	id   string
+}
+
+// fileID returns the id under which the file at path, or the file compressed
+// from it, is recorded.
+func fileID(path string) (string, error) {
+	_, fi, err := statRotated(path)
+	if err != nil {
+		return "", err
+	}
+	return infoID(fi), nil
+}
+
+// infoID returns the id of a file, which stays the same when the file is
+// compressed or shifted to another index: its name without these suffixes,
+// followed by its modification time.
+func infoID(fi os.FileInfo) string {
+	name := fi.Name()
+	for _, suffix := range compressedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			break
+		}
+	}
+	if i := strings.LastIndexByte(name, '.'); i >= 0 && isIndex(name[i+1:]) {
+		name = name[:i]
+	}
+	return name + "@" + fi.ModTime().UTC().Format(time.RFC3339Nano)
+}
+
+// isIndex reports whether s is the index of a rotated file, as in web.log.1.
+func isIndex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// statRotated returns the path and the information of the file at path, or
+// of the file compressed from it.
+func statRotated(path string) (string, os.FileInfo, error) {
+	fi, err := os.Stat(path)
+	if !os.IsNotExist(err) {
+		return path, fi, err
+	}
+	for _, suffix := range compressedSuffixes {
+		if cfi, cerr := os.Stat(path + suffix); cerr == nil {
+			return path + suffix, cfi, nil
+		}
+	}
+	return "", nil, err
+}
+
+// locate returns the current path of the queued file, which may have been
+// compressed, or renamed to another name in its directory, as the index
+// naming of the LogFile does.
+func locate(f queuedFile) (string, error) {
+	path, fi, err := statRotated(f.path)
+	if err == nil && infoID(fi) == f.id {
+		return path, nil
+	}
+	dir := filepath.Dir(f.path)
+	entries, derr := ioutil.ReadDir(dir)
+	if derr != nil {
+		return "", derr
+	}
+	for _, e := range entries {
+		if e.Mode().IsRegular() && infoID(e) == f.id {
+			return filepath.Join(dir, e.Name()), nil
+		}
+	}
+	if err == nil {
+		err = errors.New("file replaced by another one")
+	}
+	return "", err
+}
+
+// Enqueue queues the rotated file at path for upload, unless it is already
+// queued or uploaded. It doesn't block, and is meant to be called from the
+// OnRotate callback of the LogFile.
+func (u *Uploader) Enqueue(path string) {
+	id, err := fileID(path)
+	if err != nil {
+		u.reportError(fmt.Errorf("failed to queue %s: %w", path, err))
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed || u.uploaded[id] || u.queued[id] {
+		return
+	}
+	u.queued[id] = true
+	u.queue = append(u.queue, queuedFile{path: path, id: id})
+
+	select {
+	case u.wake <- struct{}{}:
+	default:
+	}
+}
+
+// CanPrune reports whether the rotated file at path was uploaded, and may be
+// removed. It is meant to be the CanPrune callback of the LogFile.
+func (u *Uploader) CanPrune(path string) bool {
+	id, err := fileID(path)
+	if err != nil {
+		return false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.uploaded[id]
+}
+
+// Recover queues the files returned by list which weren't uploaded, such as
+// the ones rotated before a crash, and forgets the uploaded files that are
+// gone from the state file. It is meant to be called at startup with the
+// RotatedFiles method of the LogFile.
+func (u *Uploader) Recover(list func() ([]string, error)) error {
+	paths, err := list()
+	if err != nil {
+		return fmt.Errorf("failed to list rotated files: %w", err)
+	}
+
+	present := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if id, err := fileID(path); err == nil {
+			present[id] = true
+		}
+		u.Enqueue(path)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for id := range u.uploaded {
+		if !present[id] {
+			delete(u.uploaded, id)
+		}
+	}
+	return writeState(u.opts.StateFile, u.uploaded)
+}
+
+// Close stops uploading once the current upload is done or ctx is done, which
+// cancels it. Files still queued are uploaded by the next Recover.
+func (u *Uploader) Close(ctx context.Context) error {
+	u.mu.Lock()
+	if !u.closed {
+		u.closed = true
+		close(u.stop)
+	}
+	u.mu.Unlock()
+
+	select {
+	case <-u.done:
+		return nil
+	case <-ctx.Done():
+		u.cancel()
+		<-u.done
+		return ctx.Err()
+	}
+}
+
+func (u *Uploader) run() {
+	defer close(u.done)
+	for {
+		select {
+		case <-u.stop:
+			return
+		default:
+		}
+
+		f, ok := u.next()
+		if !ok {
+			select {
+			case <-u.wake:
+				continue
+			case <-u.stop:
+				return
+			}
+		}
+
+		err := u.upload(f)
+
+		u.mu.Lock()
+		delete(u.queued, f.id)
+		if err == nil {
+			u.uploaded[f.id] = true
+			err = appendState(u.opts.StateFile, f.id)
+		}
+		u.mu.Unlock()
+		if err != nil {
+			u.reportError(err)
+		}
+	}
+}
+
+// next pops the next file to upload.
+func (u *Uploader) next() (queuedFile, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.queue) == 0 {
+		return queuedFile{}, false
+	}
+	f := u.queue[0]
+	u.queue = u.queue[1:]
+	return f, true
+}
+
+// upload uploads the queued file, or its compressed version if it was
+// compressed since being queued, retrying failed attempts.
+func (u *Uploader) upload(queued queuedFile) error {
+	path, err := locate(queued)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", queued.path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", path, err)
+	}
+	defer f.Close()
+
+	obj, err := newObject(u.opts.Prefix+filepath.Base(f.Name()), f)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", f.Name(), err)
+	}
+
+	backoff := u.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		if _, err = obj.Body.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", f.Name(), err)
+		}
+		err = u.opts.Client.PutObject(u.ctx, obj)
+		if err == nil {
+			return nil
+		}
+		if attempt == u.opts.MaxAttempts {
+			return fmt.Errorf("failed to upload %s after %d attempts: %w", f.Name(), attempt, err)
+		}
+		u.reportError(fmt.Errorf("failed to upload %s, attempt %d: %w", f.Name(), attempt, err))
+
+		select {
+		case <-time.After(backoff):
+		case <-u.stop:
+			return fmt.Errorf("failed to upload %s: uploader closed", f.Name())
+		}
+		if backoff *= 2; backoff > MaxBackoff {
+			backoff = MaxBackoff
+		}
+	}
+}
+
+// newObject returns the object for f, with its checksums.
+func newObject(key string, f *os.File) (*Object, error) {
+	md5sum, sha := md5.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5sum, sha), f)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		Key:            key,
+		Body:           f,
+		Size:           size,
+		ContentMD5:     base64.StdEncoding.EncodeToString(md5sum.Sum(nil)),
+		ChecksumSHA256: base64.StdEncoding.EncodeToString(sha.Sum(nil)),
+	}, nil
+}
+
+func (u *Uploader) reportError(err error) {
+	if u.opts.OnError != nil {
+		u.opts.OnError(err)
+	}
+}
+
+// readState returns the files recorded in the state file at path, one id per
+// line.
+func readState(path string) (map[string]bool, error) {
+	uploaded := make(map[string]bool)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return uploaded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			uploaded[name] = true
+		}
+	}
+	return uploaded, scanner.Err()
+}
+
+// appendState records the file id in the state file at path, synced so that
+// an upload is never forgotten once the file can be pruned.
+func appendState(path, id string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to record upload of %s: %w", id, err)
+	}
+	if _, err = f.WriteString(id + "\n"); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record upload of %s: %w", id, err)
+	}
+	return nil
+}
+
+// writeState replaces the state file at path with the files uploaded,
+// through a temporary file so that a crash leaves either version whole.
+func writeState(path string, uploaded map[string]bool) error {
+	var b strings.Builder
+	for id := range uploaded {
+		b.WriteString(id)
+		b.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package hclogs3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryClient keeps the objects put, after checking their checksums. The
+// first failures attempts fail.
+type memoryClient struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	attempts int
+	failures int
+}
+
+func (c *memoryClient) PutObject(_ context.Context, obj *Object) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.attempts++
+	if c.attempts <= c.failures {
+		return errors.New("service unavailable")
+	}
+	body, err := ioutil.ReadAll(obj.Body)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(body)
+	if got := base64.StdEncoding.EncodeToString(sum[:]); got != obj.ContentMD5 || int64(len(body)) != obj.Size {
+		return errors.New("bad digest")
+	}
+	if c.objects == nil {
+		c.objects = make(map[string][]byte)
+	}
+	c.objects[obj.Key] = body
+	return nil
+}
+
+func (c *memoryClient) object(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	body, ok := c.objects[key]
+	return body, ok
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// waitPrunable waits for the file at path to be uploaded.
+func waitPrunable(t *testing.T, u *Uploader, path string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !u.CanPrune(path) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s to be uploaded", path)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUploader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hclogs3")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	state := filepath.Join(dir, ".uploaded")
+	first := filepath.Join(dir, "web-20200101000000.log")
+	second := filepath.Join(dir, "web-20200102000000.log")
+	writeFile(t, first, "first file\n")
+	writeFile(t, second, "second file\n")
+
+	client := &memoryClient{failures: 2}
+	var errs []error
+	var errsMu sync.Mutex
+	up, err := NewUploader(Options{
+		Client:    client,
+		Prefix:    "logs/",
+		StateFile: state,
+		Backoff:   time.Millisecond,
+		OnError: func(err error) {
+			errsMu.Lock()
+			errs = append(errs, err)
+			errsMu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if up.CanPrune(first) {
+		t.Errorf("Expected %s not to be prunable before its upload", first)
+	}
+	up.Enqueue(first)
+	waitPrunable(t, up, first)
+	if body, ok := client.object("logs/web-20200101000000.log"); !ok || string(body) != "first file\n" {
+		t.Errorf("Expected the file to be stored, got %q", body)
+	}
+	errsMu.Lock()
+	if len(errs) != 2 {
+		t.Errorf("Expected the 2 failed attempts to be reported, got %v", errs)
+	}
+	errsMu.Unlock()
+	if err := os.Rename(first, first+".gz"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !up.CanPrune(first + ".gz") {
+		t.Errorf("Expected the compressed file to be prunable too")
+	}
+	if err := up.Close(context.Background()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// After a restart, the uploaded file is still prunable, and only the
+	// other one is uploaded, compressed since it was rotated.
+	if err := os.Rename(second, second+".gz"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	up, err = NewUploader(Options{Client: client, Prefix: "logs/", StateFile: state})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer up.Close(context.Background())
+	if !up.CanPrune(first) {
+		t.Errorf("Expected %s to be remembered as uploaded", first)
+	}
+
+	err = up.Recover(func() ([]string, error) {
+		return []string{first, second + ".gz"}, nil
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	waitPrunable(t, up, second)
+	if _, ok := client.object("logs/web-20200102000000.log.gz"); !ok {
+		t.Errorf("Expected the compressed file to be stored")
+	}
+	if client.attempts != 4 {
+		t.Errorf("Expected the uploaded file not to be uploaded again, got %d attempts", client.attempts)
+	}
+
+	// Files gone from the directory are forgotten.
+	if err := up.Recover(func() ([]string, error) { return []string{second + ".gz"}, nil }); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	uploaded, err := readState(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	id, err := fileID(second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(uploaded) != 1 || !uploaded[id] {
+		t.Errorf("Unexpected state %v", uploaded)
+	}
+}
+
+func TestUploader_indexNaming(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hclogs3")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	client := &memoryClient{}
+	up, err := NewUploader(Options{
+		Client:    client,
+		Prefix:    "logs/",
+		StateFile: filepath.Join(dir, ".uploaded"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer up.Close(context.Background())
+
+	first := filepath.Join(dir, "web.log.1")
+	second := filepath.Join(dir, "web.log.2")
+	writeFile(t, first, "first file\n")
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(first, mtime, mtime); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	up.Enqueue(first)
+	waitPrunable(t, up, first)
+
+	// The uploaded file is shifted, and a new one takes its name
+	if err := os.Rename(first, second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	writeFile(t, first, "second file\n")
+	if !up.CanPrune(second) {
+		t.Errorf("Expected the shifted file to stay prunable")
+	}
+	if up.CanPrune(first) {
+		t.Errorf("Expected the new file not to be prunable before its upload")
+	}
+	up.Enqueue(first)
+	waitPrunable(t, up, first)
+	if body, _ := client.object("logs/web.log.1"); string(body) != "second file\n" {
+		t.Errorf("Expected the new file to be stored, got %q", body)
+	}
+}
+
+func TestUploader_maxAttempts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hclogs3")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "web-20200101000000.log")
+	writeFile(t, path, "data")
+
+	failed := make(chan error, 10)
+	up, err := NewUploader(Options{
+		Client:      &memoryClient{failures: 10},
+		StateFile:   filepath.Join(dir, ".uploaded"),
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		OnError:     func(err error) { failed <- err },
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer up.Close(context.Background())
+
+	up.Enqueue(path)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-failed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the failed attempts to be reported")
+		}
+	}
+	if up.CanPrune(path) {
+		t.Errorf("Expected the file not to be prunable")
+	}
+}
+
+func TestNewUploader_invalid(t *testing.T) {
+	cases := []Options{
+		{StateFile: "state"},
+		{Client: &memoryClient{}},
+		{Client: &memoryClient{}, StateFile: "state", MaxAttempts: -1},
+		{Client: &memoryClient{}, StateFile: "state", Backoff: -time.Second},
+	}
+	for _, opts := range cases {
+		if _, err := NewUploader(opts); err == nil {
+			t.Errorf("Expected an error for %+v", opts)
+		}
+	}
+}
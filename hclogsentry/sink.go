@@ -0,0 +1,360 @@
+// Package hclogsentry creates Sentry events from the entries of hclog
+// loggers, through a SinkAdapter registered on an InterceptLogger.
+//
+// It is a module of its own, so that the go-hclog module doesn't depend on
+// sentry-go.
+package hclogsentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sentry "github.com/getsentry/sentry-go"
+	hclog "github.com/varnson/go-hclog"
+)
+
+// FieldsContext is the context of events holding the key/value pairs of the
+// entry not made tags.
+const FieldsContext = "fields"
+
+// Defaults of the Options left unset.
+const (
+	DefaultBufferSize    = 100
+	DefaultMaxErrorDepth = 10
+	DefaultRateInterval  = time.Minute
+)
+
+// Client is the part of a sentry.Hub used by the Sink. *sentry.Hub implements
+// it, so the Sink usually gets sentry.CurrentHub() or a hub of its own.
+type Client interface {
+	CaptureEvent(event *sentry.Event) *sentry.EventID
+	FlushWithContext(ctx context.Context) bool
+}
+
+var _ Client = (*sentry.Hub)(nil)
+
+// Options configures the Sink returned by NewSink.
+type Options struct {
+	// Client captures the events. Defaults to sentry.CurrentHub().
+	Client Client
+
+	// Level is the minimum level of the entries making events. Defaults to
+	// hclog.Error.
+	Level hclog.Level
+
+	// TagKeys are the keys of the key/value pairs made tags of the events,
+	// the others going to the FieldsContext.
+	TagKeys []string
+
+	// SampleRate is the fraction of the entries making events, between 0
+	// and 1. Zero makes events of all the entries.
+	SampleRate float64
+
+	// MaxEvents is the number of events created per RateInterval, beyond
+	// which entries are dropped until the next interval. Zero doesn't limit
+	// the number of events.
+	MaxEvents int
+
+	// RateInterval is the interval of MaxEvents. Defaults to
+	// DefaultRateInterval.
+	RateInterval time.Duration
+
+	// MaxErrorDepth is the number of wrapped errors made exceptions of the
+	// events. Defaults to DefaultMaxErrorDepth.
+	MaxErrorDepth int
+
+	// BufferSize is the number of events held while the Client captures
+	// others, beyond which new events are dropped. Defaults to
+	// DefaultBufferSize.
+	BufferSize int
+}
+
+// Sink is an hclog.SinkAdapter creating Sentry events from entries:
+//
+//	sink, err := hclogsentry.NewSink(hclogsentry.Options{TagKeys: []string{"region"}})
+//	defer sink.Close(ctx)
+//	logger.RegisterSink(sink)
+//
+// The error logged under hclog.ErrorKey, or else the first error value of the
+// entry, makes the exceptions of the event, with the stacktrace of an
+// hclog.CapturedStacktrace given at the end of the entry or else of the
+// caller. Entries without an error make message events.
+//
+// Events are handed to the Client by a goroutine of the Sink, through a bounded
+// buffer, so that logging never waits on Sentry, and entries logged by the
+// Client itself are buffered like any other rather than recursing into it.
+type Sink struct {
+	opts    Options
+	tagKeys map[string]bool
+
+	mu          sync.Mutex
+	closed      bool
+	windowStart time.Time
+	windowCount int
+
+	events  chan *sentry.Event
+	dropped uint64
+	done    chan struct{}
+}
+
+var _ hclog.SinkAdapter = (*Sink)(nil)
+
+// NewSink returns a Sink creating events of the entries at opts.Level or
+// above.
+func NewSink(opts Options) (*Sink, error) {
+	switch {
+	case opts.SampleRate < 0 || opts.SampleRate > 1:
+		return nil, fmt.Errorf("sentry sample rate %v is not between 0 and 1", opts.SampleRate)
+	case opts.MaxEvents < 0 || opts.RateInterval < 0:
+		return nil, errors.New("negative sentry max events or rate interval")
+	case opts.MaxErrorDepth < 0 || opts.BufferSize < 0:
+		return nil, errors.New("negative sentry max error depth or buffer size")
+	}
+	if opts.Client == nil {
+		opts.Client = sentry.CurrentHub()
+	}
+	if opts.Level == hclog.NoLevel {
+		opts.Level = hclog.Error
+	}
+	if opts.RateInterval == 0 {
+		opts.RateInterval = DefaultRateInterval
+	}
+	if opts.MaxErrorDepth == 0 {
+		opts.MaxErrorDepth = DefaultMaxErrorDepth
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+
+	s := &Sink{
+		opts:    opts,
+		tagKeys: make(map[string]bool, len(opts.TagKeys)),
+		events:  make(chan *sentry.Event, opts.BufferSize),
+		done:    make(chan struct{}),
+	}
+	for _, key := range opts.TagKeys {
+		s.tagKeys[key] = true
+	}
+	go s.run()
+	return s, nil
+}
+
+// Accept implements hclog.SinkAdapter, creating an event of the entry and
+// queuing it to be captured.
+func (s *Sink) Accept(name string, level hclog.Level, msg string, args ...interface{}) {
+	if level < s.opts.Level || level == hclog.Off {
+		return
+	}
+	if !s.sample() {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+
+	event := s.event(name, level, msg, args)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Dropped returns the number of entries at the level of the Sink which made
+// no event, because of sampling, of the rate limit or of the buffer being
+// full.
+func (s *Sink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close captures the events still buffered and flushes the Client, giving up
+// once ctx is done.
+func (s *Sink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if !s.opts.Client.FlushWithContext(ctx) {
+		return fmt.Errorf("failed to flush sentry events: %w", ctx.Err())
+	}
+	return nil
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	for event := range s.events {
+		s.opts.Client.CaptureEvent(event)
+	}
+}
+
+// sample reports whether an entry makes an event, according to the sample
+// rate and the rate limit.
+func (s *Sink) sample() bool {
+	if s.opts.SampleRate > 0 && rand.Float64() >= s.opts.SampleRate {
+		return false
+	}
+	if s.opts.MaxEvents == 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.opts.RateInterval {
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	if s.windowCount >= s.opts.MaxEvents {
+		return false
+	}
+	s.windowCount++
+	return true
+}
+
+// event returns the event of an entry. It is called on the goroutine logging
+// the entry, so that the stacktrace of errors without one is of the caller.
+func (s *Sink) event(name string, level hclog.Level, msg string, args []interface{}) *sentry.Event {
+	event := sentry.NewEvent()
+	event.Level = sentryLevel(level)
+	event.Logger = name
+	event.Message = msg
+
+	var stacktrace hclog.CapturedStacktrace
+	if len(args)%2 == 1 {
+		if cs, ok := args[len(args)-1].(hclog.CapturedStacktrace); ok {
+			stacktrace = cs
+			args = args[:len(args)-1]
+		}
+	}
+
+	var err error
+	fields := make(map[string]interface{}, len(args)/2)
+	for _, kv := range pairs(args) {
+		if e, ok := kv.value.(error); ok && e != nil && (err == nil || kv.key == hclog.ErrorKey) {
+			err = e
+		}
+		if s.tagKeys[kv.key] {
+			if event.Tags == nil {
+				event.Tags = make(map[string]string)
+			}
+			event.Tags[kv.key] = fmt.Sprint(kv.value)
+			continue
+		}
+		fields[kv.key] = fieldValue(kv.value)
+	}
+	if len(fields) > 0 {
+		event.Contexts[FieldsContext] = fields
+	}
+
+	if err != nil {
+		event.SetException(err, s.opts.MaxErrorDepth)
+	} else if stacktrace != "" {
+		event.Exception = []sentry.Exception{{Value: msg}}
+	}
+	if stacktrace != "" && len(event.Exception) > 0 {
+		event.Exception[len(event.Exception)-1].Stacktrace = parseStacktrace(string(stacktrace))
+	}
+	return event
+}
+
+// sentryLevel returns the Sentry level of level.
+func sentryLevel(level hclog.Level) sentry.Level {
+	switch {
+	case level >= hclog.Error:
+		return sentry.LevelError
+	case level == hclog.Warn:
+		return sentry.LevelWarning
+	case level == hclog.Info:
+		return sentry.LevelInfo
+	}
+	return sentry.LevelDebug
+}
+
+// keyValue is a key/value pair given to Accept.
+type keyValue struct {
+	key   string
+	value interface{}
+}
+
+// pairs returns the key/value pairs of args, with string keys.
+func pairs(args []interface{}) []keyValue {
+	kvs := make([]keyValue, 0, len(args)/2+1)
+	for i := 0; i < len(args); {
+		if f, ok := args[i].(hclog.Field); ok {
+			kvs = append(kvs, keyValue{f.Key, f.Value()})
+			i++
+			continue
+		}
+		if i+1 == len(args) {
+			kvs = append(kvs, keyValue{hclog.MissingKey, args[i]})
+			break
+		}
+		kvs = append(kvs, keyValue{fmt.Sprint(args[i]), args[i+1]})
+		i += 2
+	}
+	return kvs
+}
+
+// fieldValue returns value as held by the FieldsContext, errors and Stringers
+// as strings.
+func fieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+// parseStacktrace returns the Sentry stacktrace of a CapturedStacktrace, made
+// of a function line and a tab indented file:line line per frame, the
+// innermost frame first.
+func parseStacktrace(stack string) *sentry.Stacktrace {
+	lines := strings.Split(stack, "\n")
+	var frames []sentry.Frame
+	for i := 0; i+1 < len(lines); i += 2 {
+		location := strings.TrimPrefix(lines[i+1], "\t")
+		file, line := location, 0
+		if n := strings.LastIndexByte(location, ':'); n >= 0 {
+			if l, err := strconv.Atoi(location[n+1:]); err == nil {
+				file, line = location[:n], l
+			}
+		}
+		frames = append(frames, sentry.NewFrame(runtime.Frame{
+			Function: lines[i],
+			File:     file,
+			Line:     line,
+		}))
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+
+	// Sentry wants the outermost frame first.
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return &sentry.Stacktrace{Frames: frames}
+}
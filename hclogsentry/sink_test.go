@@ -0,0 +1,183 @@
+package hclogsentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sentry "github.com/getsentry/sentry-go"
+	hclog "github.com/varnson/go-hclog"
+)
+
+// recordingClient is a Client recording the events captured.
+type recordingClient struct {
+	mu      sync.Mutex
+	events  []*sentry.Event
+	flushed bool
+}
+
+func (c *recordingClient) CaptureEvent(event *sentry.Event) *sentry.EventID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+	return &event.EventID
+}
+
+func (c *recordingClient) FlushWithContext(ctx context.Context) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushed = true
+	return true
+}
+
+func TestSink(t *testing.T) {
+	client := &recordingClient{}
+	sink, err := NewSink(Options{Client: client, TagKeys: []string{"region"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink.Accept("http", hclog.Warn, "below the level")
+	sink.Accept("http", hclog.Error, "request failed",
+		"region", "eu", "status", 500, "error", fmt.Errorf("serving: %w", errors.New("boom")))
+	sink.Accept("", hclog.Error, "no error", "attempt", 3)
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !client.flushed {
+		t.Errorf("Expected the client to be flushed")
+	}
+	if len(client.events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(client.events))
+	}
+
+	event := client.events[0]
+	if event.Message != "request failed" || event.Logger != "http" || event.Level != sentry.LevelError {
+		t.Errorf("Expected the message, logger and level of the entry, got %q, %q and %q", event.Message, event.Logger, event.Level)
+	}
+	if event.Tags["region"] != "eu" {
+		t.Errorf("Expected %q, got %q", "eu", event.Tags["region"])
+	}
+	fields := event.Contexts[FieldsContext]
+	if fields["status"] != 500 || fields["error"] != "serving: boom" {
+		t.Errorf("Expected the fields of the entry, got %v", fields)
+	}
+	if len(event.Exception) != 2 {
+		t.Fatalf("Expected 2 exceptions, got %d", len(event.Exception))
+	}
+	if value := event.Exception[1].Value; value != "serving: boom" {
+		t.Errorf("Expected %q, got %q", "serving: boom", value)
+	}
+	if event.Exception[1].Stacktrace == nil {
+		t.Errorf("Expected a stacktrace on the outermost exception")
+	}
+
+	event = client.events[1]
+	if len(event.Exception) != 0 || event.Message != "no error" {
+		t.Errorf("Expected a message event, got %v", event.Exception)
+	}
+}
+
+func TestSink_CapturedStacktrace(t *testing.T) {
+	client := &recordingClient{}
+	sink, err := NewSink(Options{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink.Accept("", hclog.Error, "with stacktrace", "error", errors.New("boom"), hclog.Stacktrace())
+	sink.Accept("", hclog.Error, "stacktrace only", hclog.Stacktrace())
+	sink.Close(context.Background())
+
+	if len(client.events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(client.events))
+	}
+	for _, event := range client.events {
+		if len(event.Exception) != 1 || event.Exception[0].Stacktrace == nil {
+			t.Fatalf("Expected an exception with a stacktrace, got %v", event.Exception)
+		}
+		frames := event.Exception[0].Stacktrace.Frames
+		found := false
+		for _, frame := range frames[len(frames)-2:] {
+			if frame.Function == "TestSink_CapturedStacktrace" && strings.HasSuffix(frame.Module, "hclogsentry") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected the innermost frames to be the test, got %+v", frames)
+		}
+		if _, ok := event.Contexts[FieldsContext][hclog.MissingKey]; ok {
+			t.Errorf("Expected the stacktrace not to be a field")
+		}
+	}
+	if value := client.events[1].Exception[0].Value; value != "stacktrace only" {
+		t.Errorf("Expected %q, got %q", "stacktrace only", value)
+	}
+}
+
+func TestSink_RateLimit(t *testing.T) {
+	client := &recordingClient{}
+	sink, err := NewSink(Options{Client: client, MaxEvents: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		sink.Accept("", hclog.Error, "storm")
+	}
+	sink.Close(context.Background())
+
+	if len(client.events) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(client.events))
+	}
+	if sink.Dropped() != 3 {
+		t.Errorf("Expected 3 entries dropped, got %d", sink.Dropped())
+	}
+}
+
+func TestSink_Hub(t *testing.T) {
+	transport := &recordingTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := NewSink(Options{Client: sentry.NewHub(client, sentry.NewScope())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := hclog.NewInterceptLogger(&hclog.LoggerOptions{Output: hclog.DefaultOutput, Level: hclog.Off})
+	logger.RegisterSink(sink)
+	logger.Error("sent through the hub", "error", errors.New("boom"))
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(transport.events) != 1 || transport.events[0].Message != "sent through the hub" {
+		t.Errorf("Expected the event to be sent, got %v", transport.events)
+	}
+}
+
+// recordingTransport is a sentry.Transport recording the events sent.
+type recordingTransport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *recordingTransport) Configure(options sentry.ClientOptions) {}
+
+func (t *recordingTransport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+func (t *recordingTransport) Flush(timeout time.Duration) bool { return true }
+
+func (t *recordingTransport) FlushWithContext(ctx context.Context) bool { return true }
+
+func (t *recordingTransport) Close() {}
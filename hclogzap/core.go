@@ -0,0 +1,103 @@
+// Package hclogzap adapts hclog loggers to zap, so that libraries logging with
+// zap write through an hclog logger, its levels, formats and outputs.
+//
+// It is a module of its own, so that the go-hclog module doesn't depend on
+// zap.
+package hclogzap
+
+import (
+	hclog "github.com/varnson/go-hclog"
+	"go.uber.org/zap/zapcore"
+)
+
+// core is the zapcore.Core returned by NewZapCore.
+type core struct {
+	l hclog.Logger
+}
+
+// NewZapCore returns a zapcore.Core writing the entries of zap loggers to l:
+//
+//	zapLogger := zap.New(hclogzap.NewZapCore(logger))
+//
+// The zap levels map onto the levels of l, DPanic, Panic and Fatal onto Error,
+// and zap fields onto key/value pairs. The names given to zap loggers name
+// subloggers of l, and With derives a sublogger implying the fields.
+func NewZapCore(l hclog.Logger) zapcore.Core {
+	return &core{l: l}
+}
+
+// level returns the level of l matching the zap level.
+func level(zl zapcore.Level) hclog.Level {
+	switch {
+	case zl <= zapcore.DebugLevel:
+		return hclog.Debug
+	case zl == zapcore.InfoLevel:
+		return hclog.Info
+	case zl == zapcore.WarnLevel:
+		return hclog.Warn
+	}
+	return hclog.Error
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *core) Enabled(zl zapcore.Level) bool {
+	switch level(zl) {
+	case hclog.Debug:
+		return c.l.IsDebug()
+	case hclog.Info:
+		return c.l.IsInfo()
+	case hclog.Warn:
+		return c.l.IsWarn()
+	}
+	return c.l.IsError()
+}
+
+// With implements zapcore.Core, deriving a sublogger implying fields.
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	return &core{l: c.l.With(args(fields)...)}
+}
+
+// Check implements zapcore.Core.
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	l := c.l
+	if ent.LoggerName != "" {
+		l = l.Named(ent.LoggerName)
+	}
+
+	kv := args(fields)
+	if ent.Stack != "" {
+		kv = append(kv, hclog.CapturedStacktrace(ent.Stack))
+	}
+	l.Log(level(ent.Level), ent.Message, kv...)
+	return nil
+}
+
+// Sync implements zapcore.Core. Entries are written when logged, so there is
+// nothing to flush.
+func (c *core) Sync() error {
+	return nil
+}
+
+// args returns fields as key/value pairs, in order.
+func args(fields []zapcore.Field) []interface{} {
+	kv := make([]interface{}, 0, len(fields)*2)
+	for _, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		for k, v := range enc.Fields {
+			kv = append(kv, k, v)
+		}
+	}
+	return kv
+}
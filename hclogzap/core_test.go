@@ -0,0 +1,99 @@
+package hclogzap
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	hclog "github.com/varnson/go-hclog"
+	"go.uber.org/zap"
+)
+
+func TestNewZapCore(t *testing.T) {
+	t.Run("writes through the logger", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Name:        "app",
+			Output:      &buf,
+			Level:       hclog.Debug,
+			DisableTime: true,
+		})
+
+		z := zap.New(NewZapCore(logger))
+		z.Debug("debugging", zap.Int("attempt", 2))
+		z.Info("request served", zap.String("path", "/v1/kv"), zap.Bool("cached", true))
+		z.Error("request failed", zap.Error(errors.New("timeout")))
+
+		expected := "[DEBUG] [module=app] -- debugging: attempt=2\n" +
+			"[INFO]  [module=app] -- request served: path=/v1/kv cached=true\n" +
+			"[ERROR] [module=app] -- request failed: error=timeout\n"
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("honors the level of the logger", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Output:      &buf,
+			Level:       hclog.Warn,
+			DisableTime: true,
+		})
+
+		z := zap.New(NewZapCore(logger))
+		z.Info("filtered")
+		if ce := z.Check(zap.InfoLevel, "filtered"); ce != nil {
+			t.Errorf("Expected info entries to be disabled")
+		}
+		z.Warn("written")
+
+		logger.SetLevel(hclog.Info)
+		z.Info("written too")
+
+		expected := "[WARN]  -- written\n[INFO]  -- written too\n"
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("derives subloggers", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Name:        "app",
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		z := zap.New(NewZapCore(logger)).Named("raft").With(zap.String("peer", "a"))
+		z.Info("elected")
+
+		expected := "[INFO]  [module=app.raft] -- elected: peer=a\n"
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("writes stack traces", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		z := zap.New(NewZapCore(logger), zap.AddStacktrace(zap.ErrorLevel))
+		z.Error("failed")
+
+		lines := strings.Split(buf.String(), "\n")
+		if lines[0] != "[ERROR] -- failed:" {
+			t.Errorf("Expected the entry on the first line, got %q", lines[0])
+		}
+		if !strings.Contains(buf.String(), "hclogzap.TestNewZapCore") {
+			t.Errorf("Expected a stack trace, got %q", buf.String())
+		}
+	})
+}
@@ -0,0 +1,63 @@
+// Package hclogzstd compresses the files rotated by a logger.LogFile with
+// zstd, as its Compression codec:
+//
+//	logger.NewLogFile(logger.LogFileOptions{
+//		FileName:         "app.log",
+//		Compression:      hclogzstd.Codec{},
+//		CompressionLevel: 3,
+//	})
+//
+// It is a module of its own so that the logger package doesn't depend on the
+// zstd implementation. Support tooling reads the files with
+// logger.OpenRotated(path, nil, hclogzstd.Codec{}).
+package hclogzstd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Suffix is added to the name of files compressed with Codec.
+const Suffix = ".zst"
+
+// Codec implements logger.Codec with zstd. Levels are those of the zstd
+// command line, from 1 to 22, mapped to the closest level of the encoder,
+// and zero is its default level, equivalent to 3.
+type Codec struct {
+	// Concurrency is the number of goroutines compressing a file, one by
+	// default so that the compression of rotated files stays in the
+	// background.
+	Concurrency int
+}
+
+// Suffix returns Suffix.
+func (Codec) Suffix() string {
+	return Suffix
+}
+
+// NewWriter returns a zstd encoder writing to w at level.
+func (c Codec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level < 0 || level > 22 {
+		return nil, fmt.Errorf("zstd level %d is not between 1 and 22", level)
+	}
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	opts := []zstd.EOption{zstd.WithEncoderConcurrency(concurrency)}
+	if level != 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+	return zstd.NewWriter(w, opts...)
+}
+
+// NewReader returns a zstd decoder reading from r.
+func (Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
@@ -0,0 +1,62 @@
+package hclogzstd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// codec is the interface of logger.Codec, which this module doesn't import.
+type codec interface {
+	Suffix() string
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var _ codec = Codec{}
+
+func TestCodec(t *testing.T) {
+	entries := strings.Repeat("2020-01-01T12:00:00.000Z [INFO]  -- entry: key=value\n", 1000)
+	for _, level := range []int{0, 1, 3, 19} {
+		var buf bytes.Buffer
+		w, err := Codec{}.NewWriter(&buf, level)
+		if err != nil {
+			t.Fatalf("level %d: %s", level, err)
+		}
+		if _, err := io.WriteString(w, entries); err != nil {
+			t.Fatalf("level %d: %s", level, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("level %d: %s", level, err)
+		}
+		if buf.Len() >= len(entries)/10 {
+			t.Errorf("Expected level %d to compress, got %d bytes", level, buf.Len())
+		}
+
+		r, err := Codec{}.NewReader(&buf)
+		if err != nil {
+			t.Fatalf("level %d: %s", level, err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("level %d: %s", level, err)
+		}
+		r.Close()
+		if string(got) != entries {
+			t.Errorf("Expected the entries back at level %d, got %d bytes", level, len(got))
+		}
+	}
+}
+
+func TestCodec_InvalidLevel(t *testing.T) {
+	for _, level := range []int{-1, 23} {
+		if _, err := (Codec{}).NewWriter(ioutil.Discard, level); err == nil {
+			t.Errorf("Expected an error for level %d", level)
+		}
+	}
+	if got := (Codec{}).Suffix(); got != ".zst" {
+		t.Errorf("Expected %q, got %q", ".zst", got)
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/varnson/go-hclog/hclogzstd
+
+go 1.25
+
+require github.com/klauspost/compress v1.20.1
@@ -0,0 +1,69 @@
+package hclog
+
+import "sort"
+
+// ErrorKey is the key under which errors are logged by Err, WithError,
+// ErrorReturn and RecoverAndLog.
+const ErrorKey = "error"
+
+// ErrorReturn writes msg and args to l at the ERROR level, with err under the
+// "error" key, and returns err, so that an error can be logged and returned
+// in one statement:
+//
+//	if err := conn.Close(); err != nil {
+//		return hclog.ErrorReturn(logger, err, "failed to close connection")
+//	}
+//
+// A nil err is returned without writing anything.
+func ErrorReturn(l Logger, err error, msg string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	// args are copied rather than appended to, so that they don't escape and
+	// calls with a nil err don't allocate.
+	all := make([]interface{}, 0, len(args)+2)
+	all = append(all, args...)
+	withCallerSkip(l, 1).Error(msg, append(all, ErrorKey, err)...)
+	return err
+}
+
+// LogIf writes msg and args to l at level if cond holds, and does nothing
+// otherwise. Calls with a false cond return before anything is formatted or
+// allocated.
+func LogIf(l Logger, cond bool, level Level, msg string, args ...interface{}) {
+	if !cond {
+		return
+	}
+	// args are copied so that they don't escape, see ErrorReturn.
+	withCallerSkip(l, 1).Log(level, msg, append([]interface{}(nil), args...)...)
+}
+
+// WithFields returns l.With called with the entries of m as key/value pairs,
+// sorted by key so that the implied arguments don't depend on the iteration
+// order of the map. Like with With, a key already implied by l takes the
+// value given in m.
+func WithFields(l Logger, m map[string]interface{}) Logger {
+	if len(m) == 0 {
+		return l
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := make([]interface{}, 0, len(m)*2)
+	for _, k := range keys {
+		args = append(args, k, m[k])
+	}
+	return l.With(args...)
+}
+
+// WithError returns l.With called with err under ErrorKey, or l itself if err
+// is nil.
+func WithError(l Logger, err error) Logger {
+	if err == nil {
+		return l
+	}
+	return l.With(ErrorKey, err)
+}
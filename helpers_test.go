@@ -0,0 +1,166 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorReturn(t *testing.T) {
+	t.Run("logs and returns the error", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		err := errors.New("connection reset")
+		assert.Equal(t, err, ErrorReturn(logger, err, "failed to close", "peer", "a"))
+		assert.Equal(t, "[ERROR] -- failed to close: peer=a error=\"connection reset\"\n", buf.String())
+	})
+
+	t.Run("ignores nil errors", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{Output: &buf})
+
+		assert.NoError(t, ErrorReturn(logger, nil, "failed to close"))
+		assert.Equal(t, "", buf.String())
+		assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+			ErrorReturn(logger, nil, "failed to close", "peer", "a")
+		}))
+	})
+
+	t.Run("keeps the caller location", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			IncludeLocation: true,
+		})
+
+		_, _, line, _ := runtime.Caller(0)
+		ErrorReturn(logger, errors.New("failed"), "here")
+
+		want := fmt.Sprintf("[ERROR][go-hclog/helpers_test.go:%d] -- here: error=failed\n", line+1)
+		assert.Equal(t, want, buf.String())
+	})
+}
+
+func TestLogIf(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := New(&LoggerOptions{
+		Output:      &buf,
+		DisableTime: true,
+	})
+
+	LogIf(logger, false, Info, "skipped", "a", 1)
+	LogIf(logger, true, Warn, "written", "a", 1)
+	assert.Equal(t, "[WARN]  -- written: a=1\n", buf.String())
+
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+		LogIf(logger, false, Info, "skipped", "a", 1)
+	}))
+}
+
+func TestWithFields(t *testing.T) {
+	t.Run("implies the fields in key order", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		}).With("method", "GET", "request", 1)
+
+		WithFields(logger, map[string]interface{}{
+			"request": 2,
+			"path":    "/v1/kv",
+			"client":  "10.0.0.1",
+		}).Info("request served")
+
+		assert.Equal(t, "[INFO]  -- request served: client=10.0.0.1 method=GET path=/v1/kv request=2\n", buf.String())
+	})
+
+	t.Run("implies the fields in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		}).With("request", 1)
+
+		WithFields(logger, map[string]interface{}{"request": 2, "path": "/v1/kv"}).Info("request served")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, float64(2), entry["request"])
+		assert.Equal(t, "/v1/kv", entry["path"])
+	})
+
+	t.Run("returns the logger for empty maps", func(t *testing.T) {
+		logger := New(&LoggerOptions{})
+		assert.Equal(t, logger, WithFields(logger, nil))
+	})
+}
+
+func TestWithError(t *testing.T) {
+	t.Run("implies the error", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		WithError(logger, errors.New("timeout")).Warn("retrying")
+		assert.Equal(t, "[WARN]  -- retrying: error=timeout\n", buf.String())
+	})
+
+	t.Run("replaces the implied error", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		})
+
+		logger = WithError(logger, errors.New("timeout"))
+		WithError(logger, errors.New("refused")).Warn("retrying")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "refused", entry[ErrorKey])
+	})
+
+	t.Run("returns the logger for nil errors", func(t *testing.T) {
+		logger := New(&LoggerOptions{})
+		assert.Equal(t, logger, WithError(logger, nil))
+	})
+}
+
+func BenchmarkHelpers(b *testing.B) {
+	logger := New(&LoggerOptions{Output: &bytes.Buffer{}})
+
+	b.Run("ErrorReturn with a nil error", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ErrorReturn(logger, nil, "failed to close", "peer", "a")
+		}
+	})
+
+	b.Run("LogIf with a false condition", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			LogIf(logger, false, Info, "skipped", "peer", "a")
+		}
+	})
+}
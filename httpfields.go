@@ -0,0 +1,111 @@
+package hclog
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultExcludedHeaders are the headers RequestFields never writes, unless
+// ExcludeHeaders is set in the RequestFieldOptions, as they hold credentials.
+var DefaultExcludedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// RequestFieldOptions configures the headers written by
+// RequestFieldOptions.RequestFields.
+type RequestFieldOptions struct {
+	// Headers are the request headers to write, under "header_" and the
+	// name of the header in lower case with its dashes as underscores, as in
+	// header_x_request_id. The values of a header given more than once are
+	// joined with commas.
+	Headers []string
+
+	// AllHeaders writes every header of the request, in the order of their
+	// names, as Headers does.
+	AllHeaders bool
+
+	// ExcludeHeaders are the headers never written, even if given in
+	// Headers. DefaultExcludedHeaders is used if nil: set an empty slice to
+	// write every header.
+	ExcludeHeaders []string
+}
+
+// RequestFields returns the key/value pairs describing r, for the entries
+// logged about it:
+//
+//	logger.Info("request", hclog.RequestFields(r)...)
+//
+// The keys are method, path, host, remote_addr, user_agent and proto, all of
+// them written for every request so that the entries can be indexed alike.
+// The path leaves out the query, which may hold secrets. No header is written:
+// use RequestFieldOptions for those.
+func RequestFields(r *http.Request) []interface{} {
+	return (&RequestFieldOptions{}).RequestFields(r)
+}
+
+// RequestFields returns the key/value pairs of the package function
+// RequestFields, followed by the headers selected by o.
+func (o *RequestFieldOptions) RequestFields(r *http.Request) []interface{} {
+	path := ""
+	if r.URL != nil {
+		path = r.URL.Path
+	}
+	fields := []interface{}{
+		"method", r.Method,
+		"path", path,
+		"host", r.Host,
+		"remote_addr", r.RemoteAddr,
+		"user_agent", r.UserAgent(),
+		"proto", r.Proto,
+	}
+
+	names := o.Headers
+	if o.AllHeaders {
+		names = make([]string, 0, len(r.Header))
+		for name := range r.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		return fields
+	}
+
+	exclude := o.ExcludeHeaders
+	if exclude == nil {
+		exclude = DefaultExcludedHeaders
+	}
+	excluded := make(map[string]struct{}, len(exclude))
+	for _, name := range exclude {
+		excluded[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := excluded[name]; ok {
+			continue
+		}
+		values := r.Header[name]
+		if len(values) == 0 {
+			continue
+		}
+		fields = append(fields, headerKey(name), strings.Join(values, ", "))
+	}
+	return fields
+}
+
+// headerKey returns the key of the header name.
+func headerKey(name string) string {
+	return "header_" + strings.ReplaceAll(strings.ToLower(name), "-", "_")
+}
+
+// ResponseFields returns the key/value pairs describing the response to a
+// request, for the entries logged about it along with RequestFields: status,
+// bytes, the size of the body written, and duration_ms, the time taken to
+// serve the request in milliseconds as a float.
+func ResponseFields(status int, bytes int64, d time.Duration) []interface{} {
+	return []interface{}{
+		"status", status,
+		"bytes", bytes,
+		"duration_ms", float64(d) / float64(time.Millisecond),
+	}
+}
@@ -0,0 +1,77 @@
+package hclog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestFields(t *testing.T) {
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest("POST", "http://example.com/v1/kv/foo?token=secret", nil)
+		r.RemoteAddr = "10.0.0.2:51234"
+		r.Header.Set("User-Agent", "curl/8.0")
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("Proxy-Authorization", "Basic secret")
+		r.Header.Set("Cookie", "session=secret")
+		r.Header.Set("Set-Cookie", "session=secret")
+		r.Header.Set("X-Request-Id", "abc")
+		r.Header.Add("Accept", "text/plain")
+		r.Header.Add("Accept", "application/json")
+		return r
+	}
+
+	t.Run("writes the canonical keys", func(t *testing.T) {
+		assert.Equal(t, []interface{}{
+			"method", "POST",
+			"path", "/v1/kv/foo",
+			"host", "example.com",
+			"remote_addr", "10.0.0.2:51234",
+			"user_agent", "curl/8.0",
+			"proto", "HTTP/1.1",
+		}, RequestFields(newRequest()))
+	})
+
+	t.Run("writes the headers given", func(t *testing.T) {
+		opts := &RequestFieldOptions{Headers: []string{"x-request-id", "Accept", "Authorization", "X-Missing"}}
+		fields := opts.RequestFields(newRequest())
+		assert.Equal(t, []interface{}{
+			"header_x_request_id", "abc",
+			"header_accept", "text/plain, application/json",
+		}, fields[12:])
+	})
+
+	t.Run("writes every header but the excluded ones", func(t *testing.T) {
+		opts := &RequestFieldOptions{AllHeaders: true}
+		fields := opts.RequestFields(newRequest())
+		assert.Equal(t, []interface{}{
+			"header_accept", "text/plain, application/json",
+			"header_user_agent", "curl/8.0",
+			"header_x_request_id", "abc",
+		}, fields[12:])
+
+		opts = &RequestFieldOptions{AllHeaders: true, ExcludeHeaders: []string{"accept", "user-agent", "x-request-id"}}
+		fields = opts.RequestFields(newRequest())
+		assert.Equal(t, []interface{}{
+			"header_authorization", "Bearer secret",
+			"header_cookie", "session=secret",
+			"header_proxy_authorization", "Basic secret",
+			"header_set_cookie", "session=secret",
+		}, fields[12:])
+	})
+
+	t.Run("pairs with the response fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		r := newRequest()
+		logger.Info("request", append(RequestFields(r), ResponseFields(http.StatusCreated, 512, 1500*time.Microsecond)...)...)
+		assert.Equal(t,
+			"[INFO]  -- request: method=POST path=/v1/kv/foo host=example.com remote_addr=10.0.0.2:51234 user_agent=curl/8.0 proto=HTTP/1.1 status=201 bytes=512 duration_ms=1.5\n",
+			buf.String())
+	})
+}
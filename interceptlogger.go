@@ -1,6 +1,8 @@
 package hclog
 
 import (
+	"context"
+	"errors"
 	"io"
 	"log"
 	"sync"
@@ -32,6 +34,10 @@ func NewInterceptLogger(opts *LoggerOptions) InterceptLogger {
 
 	atomic.StoreInt32(intercept.sinkCount, 0)
 
+	if opts != nil {
+		opts.envWarning.report(l)
+	}
+
 	return intercept
 }
 
@@ -48,12 +54,66 @@ func (i *interceptLogger) log(level Level, msg string, args ...interface{}) {
 	if atomic.LoadInt32(i.sinkCount) == 0 {
 		return
 	}
+	if l, ok := i.Logger.(*intLogger); ok && (l.names.denies(i.Name()) || l.shutdown.isStopped()) {
+		// Sinks don't receive the entries dropped by name either, nor the
+		// entries logged after Shutdown, if sinks were registered since.
+		return
+	}
 
 	i.mu.Lock()
 	defer i.mu.Unlock()
-	for s := range i.Sinks {
-		s.Accept(i.Name(), level, msg, i.retrieveImplied(args...)...)
+	args, _, _ = splitLocation(args)
+	i.acceptEntry(level, msg, args)
+}
+
+// logFields is like log, for the Field values given to the methods of
+// FieldLogger. Sinks receive the fields as key/value pairs.
+func (i *interceptLogger) logFields(level Level, msg string, fields []Field) {
+	if fl, ok := i.Logger.(FieldLogger); ok {
+		fl.LogF(level, msg, fields...)
+	} else {
+		i.Logger.Log(level, msg, fieldArgs(fields)...)
+	}
+	if atomic.LoadInt32(i.sinkCount) == 0 {
+		return
 	}
+	if l, ok := i.Logger.(*intLogger); ok && (l.names.denies(i.Name()) || l.shutdown.isStopped()) {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.acceptEntry(level, msg, fieldArgs(fields))
+}
+
+// LogF emits the message and fields at the provided level to log and sinks
+func (i *interceptLogger) LogF(level Level, msg string, fields ...Field) {
+	i.logFields(level, msg, fields)
+}
+
+// TraceF emits the message and fields at TRACE level to log and sinks
+func (i *interceptLogger) TraceF(msg string, fields ...Field) {
+	i.logFields(Trace, msg, fields)
+}
+
+// DebugF emits the message and fields at DEBUG level to log and sinks
+func (i *interceptLogger) DebugF(msg string, fields ...Field) {
+	i.logFields(Debug, msg, fields)
+}
+
+// InfoF emits the message and fields at INFO level to log and sinks
+func (i *interceptLogger) InfoF(msg string, fields ...Field) {
+	i.logFields(Info, msg, fields)
+}
+
+// WarnF emits the message and fields at WARN level to log and sinks
+func (i *interceptLogger) WarnF(msg string, fields ...Field) {
+	i.logFields(Warn, msg, fields)
+}
+
+// ErrorF emits the message and fields at ERROR level to log and sinks
+func (i *interceptLogger) ErrorF(msg string, fields ...Field) {
+	i.logFields(Error, msg, fields)
 }
 
 // Emit the message and args at TRACE level to log and sinks
@@ -108,12 +168,20 @@ func (i *interceptLogger) ResetNamed(name string) Logger {
 
 // Create a new sub-Logger that a name decending from the current name.
 // This is used to create a subsystem specific Logger.
-// Registered sinks will subscribe to these messages as well.
+// Registered sinks will subscribe to these messages as well, and the logger
+// recorded by RegisterNamed is the InterceptLogger, so that they also receive
+// the messages of the loggers looked up.
 func (i *interceptLogger) NamedIntercept(name string) InterceptLogger {
 	var sub interceptLogger
 
 	sub = *i
-	sub.Logger = i.Logger.Named(name)
+	if l, ok := i.Logger.(*intLogger); ok {
+		sl := l.named(name)
+		sub.Logger = sl
+		l.registry.add(sl.name, &sub)
+	} else {
+		sub.Logger = i.Logger.Named(name)
+	}
 	return &sub
 }
 
@@ -125,7 +193,13 @@ func (i *interceptLogger) ResetNamedIntercept(name string) InterceptLogger {
 	var sub interceptLogger
 
 	sub = *i
-	sub.Logger = i.Logger.ResetNamed(name)
+	if l, ok := i.Logger.(*intLogger); ok {
+		sl := l.resetNamed(name)
+		sub.Logger = sl
+		l.registry.add(sl.name, &sub)
+	} else {
+		sub.Logger = i.Logger.ResetNamed(name)
+	}
 	return &sub
 }
 
@@ -157,6 +231,11 @@ func (i *interceptLogger) DeregisterSink(sink SinkAdapter) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
+	// The sink may be gone already, removed by Shutdown or deregistered
+	// twice.
+	if _, ok := i.Sinks[sink]; !ok {
+		return
+	}
 	delete(i.Sinks, sink)
 
 	atomic.AddInt32(i.sinkCount, -1)
@@ -201,3 +280,127 @@ func (i *interceptLogger) ResetOutputWithFlush(opts *LoggerOptions, flushable Fl
 		return nil
 	}
 }
+
+func (i *interceptLogger) SetLevelOverride(name string, level Level) {
+	if lo, ok := i.Logger.(LevelOverridable); ok {
+		lo.SetLevelOverride(name, level)
+	}
+}
+
+// WithOptions returns a new logger with the options of the logger wrapped by
+// i, as changed by modify, sharing the sinks of i.
+func (i *interceptLogger) WithOptions(modify func(opts *LoggerOptions)) Logger {
+	sub := *i
+	switch l := i.Logger.(type) {
+	case *intLogger:
+		frames := 2
+		if l.callerOffset > 0 {
+			frames = l.callerOffset - offsetIntLogger
+		}
+		sub.Logger = l.withOptions(modify, frames)
+	case Reconfigurable:
+		sub.Logger = l.WithOptions(modify)
+	}
+	return &sub
+}
+
+func (i *interceptLogger) LevelOverrides() map[string]Level {
+	if lo, ok := i.Logger.(LevelOverridable); ok {
+		return lo.LevelOverrides()
+	}
+	return nil
+}
+
+func (i *interceptLogger) Loggers() []LoggerInfo {
+	if lr, ok := i.Logger.(LoggerRegistry); ok {
+		return lr.Loggers()
+	}
+	return nil
+}
+
+func (i *interceptLogger) GetLogger(name string) (Logger, bool) {
+	if lr, ok := i.Logger.(LoggerRegistry); ok {
+		return lr.GetLogger(name)
+	}
+	return nil, false
+}
+
+func (i *interceptLogger) Release(name string) {
+	if lr, ok := i.Logger.(LoggerRegistry); ok {
+		lr.Release(name)
+	}
+}
+
+func (i *interceptLogger) GetLevel() Level {
+	if lg, ok := i.Logger.(LevelGetter); ok {
+		return lg.GetLevel()
+	}
+	return NoLevel
+}
+
+// Audit writes an audit entry to the logger, see Auditor. Audit entries are
+// not sent to the sinks.
+func (i *interceptLogger) Audit(msg string, args ...interface{}) error {
+	return i.audit(msg, args...)
+}
+
+// audit keeps the stack frame depth of Audit the same as that of the other
+// logging methods.
+func (i *interceptLogger) audit(msg string, args ...interface{}) error {
+	if a, ok := i.Logger.(Auditor); ok {
+		return a.Audit(msg, args...)
+	}
+	return errors.New("logger does not support audit entries")
+}
+
+// Shutdown shuts down the logger wrapped by i, as described for the loggers
+// returned by New, then flushes and closes the sinks, which are deregistered:
+// sinks are shut down if they implement Shutdowner, or closed if they
+// implement a Close method taking a context, given the context of the first
+// call, or io.Closer, or flushed if they implement Flushable.
+func (i *interceptLogger) Shutdown(ctx context.Context) error {
+	l, ok := i.Logger.(*intLogger)
+	if !ok {
+		if s, ok := i.Logger.(Shutdowner); ok {
+			return s.Shutdown(ctx)
+		}
+		return nil
+	}
+	return l.shutdown.run(ctx, func(ctx context.Context) error {
+		err := l.closeOutput()
+
+		i.mu.Lock()
+		sinks := make([]SinkAdapter, 0, len(i.Sinks))
+		for sink := range i.Sinks {
+			sinks = append(sinks, sink)
+			delete(i.Sinks, sink)
+		}
+		atomic.StoreInt32(i.sinkCount, 0)
+		i.mu.Unlock()
+
+		for _, sink := range sinks {
+			if serr := closeSink(ctx, sink); err == nil {
+				err = serr
+			}
+		}
+		return err
+	})
+}
+
+func (i *interceptLogger) exitOptions() exitOptions {
+	return exitOptionsOf(i.Logger)
+}
+
+func (i *interceptLogger) keyTable() *keyTable {
+	if kt, ok := i.Logger.(interface{ keyTable() *keyTable }); ok {
+		return kt.keyTable()
+	}
+	return defaultKeyTable
+}
+
+func (i *interceptLogger) jsonFormat() bool {
+	if jf, ok := i.Logger.(interface{ jsonFormat() bool }); ok {
+		return jf.jsonFormat()
+	}
+	return false
+}
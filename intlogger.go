@@ -37,6 +37,16 @@ var (
 		Error: "[ERROR]",
 	}
 
+	// _levelToLogutils are the level tokens of BracketLevelFirst, those
+	// known to logutils.LevelFilter.
+	_levelToLogutils = map[Level]string{
+		Trace: "[TRACE]",
+		Debug: "[DEBUG]",
+		Info:  "[INFO]",
+		Warn:  "[WARN]",
+		Error: "[ERR]",
+	}
+
 	_levelToColor = map[Level]*color.Color{
 		Debug: color.New(color.FgHiWhite),
 		Trace: color.New(color.FgHiGreen),
@@ -48,14 +58,25 @@ var (
 
 // Make sure that intLogger is a Logger
 var _ Logger = &intLogger{}
+var _ LevelOverridable = &intLogger{}
+var _ LevelGetter = &intLogger{}
+var _ Auditor = &intLogger{}
+var _ FieldLogger = &intLogger{}
+var _ Reconfigurable = &intLogger{}
 
 // intLogger is an internal logger implementation. Internal in that it is
 // defined entirely by this package.
+//
+// The fields of an intLogger are set when it is created and never changed, so
+// that With, Named and the like can copy it without locking while it logs.
+// What does change is behind pointers read atomically: the output in the
+// writer, swapped by ResetOutput, and the level, set by SetLevel.
 type intLogger struct {
 	json         bool
 	callerOffset int
 	name         string
 	timeFormat   string
+	timeEncoding TimeEncoding
 
 	// This is an interface so that it's shared by any derived loggers, since
 	// those derived loggers share the bufio.Writer as well.
@@ -63,17 +84,97 @@ type intLogger struct {
 	writer *writer
 	level  *int32
 
+	// overrides are the levels of named loggers, shared with the root logger.
+	// levelSet is set once SetLevel is called on a logger with an independent
+	// level, which then takes precedence over the overrides.
+	overrides *levelOverrides
+	levelSet  *int32
+
+	// implied are the arguments given to With. They are shared with the
+	// subloggers and never appended to in place: see withImplied.
 	implied []interface{}
 
 	exclude func(level Level, msg string, args ...interface{}) bool
 
+	// names drops entries by logger name, and is shared with the root logger.
+	names *nameFilter
+
+	// keys are the keys of Once and Every, shared with the root logger.
+	keys *keyTable
+
 	// create subloggers with their own level setting
 	independentLevels bool
+
+	// keyNormalization and collisionPrefix rewrite the keys of key/value
+	// pairs, see fieldKey.
+	keyNormalization KeyNormalization
+	collisionPrefix  string
+
+	// limits truncate messages and values, and drop excess key/value pairs.
+	limits limits
+
+	// rawNewlines leaves the newlines of messages and values unescaped in
+	// the text format.
+	rawNewlines bool
+
+	// formatters are the ValueFormatters, nil if there are none.
+	formatters *valueFormatters
+
+	// keyFilter drops pairs by key, nil if there are no OmitKeys or OnlyKeys.
+	keyFilter *keyFilter
+
+	// middleware are the Middleware, nil if there are none.
+	middleware []func(e *Entry) bool
+
+	// exit are the options of Fatal.
+	exit exitOptions
+
+	// suppress demotes or drops the entries of SuppressErrors, nil if there
+	// are none.
+	suppress *errorSuppressor
+
+	// seq numbers the entries, shared with the root logger, nil unless
+	// Sequence or EntryIDs are set.
+	seq *sequence
+
+	// registry records the named loggers, shared with the root logger, nil
+	// unless RegisterNamed is set.
+	registry *loggerRegistry
+
+	// shutdown is the state of Shutdown, shared with the root logger.
+	shutdown *shutdownState
+
+	// strictArgs panics on malformed key/value pairs, see StrictArgs.
+	strictArgs bool
+
+	// namePadding pads the names of text entries, see NamePadding.
+	namePadding namePadding
+
+	// bracketLevelFirst writes text entries in the layout of the logutils
+	// package, see BracketLevelFirst.
+	bracketLevelFirst bool
+
+	// locationKey is the key of the location of JSON entries written as an
+	// object, empty to write it as a string under "@caller".
+	locationKey string
+
+	// orderJSON writes the keys of JSON entries in the order logged, see
+	// OrderJSONKeys.
+	orderJSON bool
+
+	// scope is the scope of a logger returned by Scope, marking its entries
+	// once released. noScopeChecks leaves it nil.
+	scope         *scope
+	noScopeChecks bool
 }
 
 // New returns a configured logger.
 func New(opts *LoggerOptions) Logger {
-	return newLogger(opts)
+	l := newLogger(opts)
+	if opts != nil {
+		opts.envWarning.report(l)
+	}
+	return l
 }
 
 // NewSinkAdapter returns a SinkAdapter with configured settings
@@ -92,9 +193,9 @@ func newLogger(opts *LoggerOptions) *intLogger {
 		opts = &LoggerOptions{}
 	}
 
-	output := opts.Output
-	if output == nil {
-		output = DefaultOutput
+	w := opts.Output
+	if w == nil {
+		w = DefaultOutput
 	}
 
 	level := opts.Level
@@ -111,17 +212,52 @@ func newLogger(opts *LoggerOptions) *intLogger {
 		json:              opts.JSONFormat,
 		name:              opts.Name,
 		timeFormat:        TimeFormat,
+		timeEncoding:      opts.TimeEncoding,
 		mutex:             mutex,
-		writer:            newWriter(output, opts.Color),
 		level:             new(int32),
+		overrides:         newLevelOverrides(opts.LevelOverrides),
+		levelSet:          new(int32),
 		exclude:           opts.Exclude,
+		names:             newNameFilter(opts.ExcludeNames, opts.IncludeOnlyNames),
+		keys:              newKeyTable(keyTableSize),
 		independentLevels: opts.IndependentLevels,
+		keyNormalization:  opts.NormalizeKeys,
+		collisionPrefix:   opts.KeyCollisionPrefix,
+		limits: limits{
+			messageBytes: opts.MaxMessageBytes,
+			fieldBytes:   opts.MaxFieldBytes,
+			fields:       opts.MaxFields,
+		},
+		rawNewlines:       opts.AllowRawNewlines,
+		formatters:        newValueFormatters(opts.ValueFormatters),
+		keyFilter:         newKeyFilter(opts.OmitKeys, opts.OnlyKeys),
+		middleware:        newMiddleware(opts.Middleware),
+		exit:              newExitOptions(opts),
+		suppress:          newErrorSuppressor(opts.SuppressErrors),
+		seq:               newSequence(opts),
+		registry:          newLoggerRegistry(opts),
+		shutdown:          newShutdownState(opts),
+		strictArgs:        opts.StrictArgs,
+		namePadding:       newNamePadding(opts.NamePadding),
+		bracketLevelFirst: opts.BracketLevelFirst,
+		orderJSON:         opts.OrderJSONKeys,
+		noScopeChecks:     opts.DisableScopeChecks,
+	}
+	if l.collisionPrefix == "" {
+		l.collisionPrefix = DefaultKeyCollisionPrefix
+	}
+	if opts.StructuredLocation {
+		l.locationKey = opts.LocationKey
+		if l.locationKey == "" {
+			l.locationKey = DefaultLocationKey
+		}
 	}
 	if opts.IncludeLocation {
 		l.callerOffset = offsetIntLogger
 	}
 
-	l.setColorization(opts)
+	out := &output{w: w, color: opts.Color}
+	out.setColorization(opts)
 
 	if opts.DisableTime {
 		l.timeFormat = ""
@@ -129,6 +265,20 @@ func newLogger(opts *LoggerOptions) *intLogger {
 		l.timeFormat = opts.TimeFormat
 	}
 
+	if opts.AdaptToJournald {
+		if f, ok := w.(*os.File); ok && isJournalStream(f) {
+			l.timeFormat = ""
+			out.journald = true
+		}
+	}
+	l.writer = newWriter(out)
+
+	if opts.LevelVar != nil {
+		l.level = &opts.LevelVar.level
+		if opts.LevelVar.Level() != NoLevel {
+			level = opts.LevelVar.Level()
+		}
+	}
 	atomic.StoreInt32(l.level, int32(level))
 
 	return l
@@ -141,28 +291,164 @@ const offsetIntLogger = 3
 // Log a message and a set of key/value pairs if the given level is at
 // or more severe that the threshold configured in the Logger.
 func (l *intLogger) log(name string, level Level, msg string, args ...interface{}) {
-	if level < Level(atomic.LoadInt32(l.level)) {
+	var (
+		loc      locationOverride
+		override bool
+	)
+	if args, loc, override = splitLocation(args); override {
+		l = l.withLocation(bool(loc))
+	}
+	if l.strictArgs {
+		checkArgs(l.implied, args)
+	}
+	if l.suppress != nil {
+		var keep bool
+		if level, keep = l.suppress.level(level, args, nil); !keep {
+			return
+		}
+	}
+	if level < l.levelFor(name) {
+		return
+	}
+	if l.names.denies(name) {
+		l.names.drop()
+		countDropped()
+		return
+	}
+
+	t := time.Now()
+
+	if l.middleware != nil {
+		l.logEntry(&Entry{Time: t, Level: level, Name: name, Message: msg, Args: l.entryArgs(args, nil)})
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.shutdown.isStopped() || l.exclude != nil && l.exclude(level, msg, args...) {
+		countDropped()
+		return
+	}
+
+	if l.json {
+		l.logJSON(t, name, level, msg, nil, args...)
+	} else {
+		l.logPlain(t, name, level, msg, nil, args...)
+	}
+
+	l.writer.Flush(level)
+}
+
+// logFields is like log, for the Field values given to the methods of
+// FieldLogger.
+func (l *intLogger) logFields(name string, level Level, msg string, fields []Field) {
+	if l.strictArgs {
+		checkFields(l.implied, fields)
+	}
+	if l.suppress != nil {
+		var keep bool
+		if level, keep = l.suppress.level(level, nil, fields); !keep {
+			return
+		}
+	}
+	if level < l.levelFor(name) {
+		return
+	}
+	if l.names.denies(name) {
+		l.names.drop()
+		countDropped()
 		return
 	}
 
 	t := time.Now()
 
+	if l.middleware != nil {
+		l.logEntry(&Entry{Time: t, Level: level, Name: name, Message: msg, Args: l.entryArgs(nil, fields)})
+		return
+	}
+
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	if l.exclude != nil && l.exclude(level, msg, args...) {
+	if l.shutdown.isStopped() || l.exclude != nil && l.exclude(level, msg, fieldArgs(fields)...) {
+		countDropped()
 		return
 	}
 
 	if l.json {
-		l.logJSON(t, name, level, msg, args...)
+		l.logJSON(t, name, level, msg, fields)
 	} else {
-		l.logPlain(t, name, level, msg, args...)
+		l.logPlain(t, name, level, msg, fields)
 	}
 
 	l.writer.Flush(level)
 }
 
+// AuditKey is the key of the field tagging the entries written with Audit.
+const AuditKey = "audit"
+
+// Audit writes an entry at the INFO level tagged with AuditKey, regardless of
+// the level of the logger, level overrides, name filters and Exclude. The
+// entry is written synchronously, flushing the output if it implements
+// Flushable, and an error is returned if it couldn't be written. Outputs
+// filtering by level write it through AuditWriter, and an entry dropped by the
+// output anyway is reported as io.ErrShortWrite. The Middleware may rewrite
+// the entry, but it is written even if one of them returns false.
+func (l *intLogger) Audit(msg string, args ...interface{}) error {
+	return l.audit(msg, args...)
+}
+
+// audit keeps the stack frame depth of Audit the same as that of the other
+// logging methods.
+func (l *intLogger) audit(msg string, args ...interface{}) error {
+	var (
+		loc      locationOverride
+		override bool
+	)
+	if args, loc, override = splitLocation(args); override {
+		l = l.withLocation(bool(loc))
+	}
+	t, name, level := time.Now(), l.name, Info
+	args = append([]interface{}{AuditKey, true}, args...)
+
+	sl := l
+	if l.middleware != nil {
+		// The middleware may rewrite the entry but not drop it
+		e := &Entry{Time: t, Level: level, Name: name, Message: msg, Args: l.entryArgs(args, nil)}
+		for _, m := range l.middleware {
+			callMiddleware(m, e)
+		}
+		t, name, level, msg, args = e.Time, e.Name, e.Level, e.Message, e.Args
+
+		// The implied arguments are already in the args of e
+		el := *l
+		el.implied = nil
+		sl = &el
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.shutdown.isStopped() {
+		return ErrShutdown
+	}
+
+	if l.json {
+		sl.logJSON(t, name, level, msg, nil, args...)
+	} else {
+		sl.logPlain(t, name, level, msg, nil, args...)
+	}
+
+	if err := l.writer.FlushAudit(); err != nil {
+		return err
+	}
+	if f, ok := l.writer.output().w.(Flushable); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
 // Cleanup a path by returning the last 2 segments of the path only.
 func trimCallerPath(path string) string {
 	// lovely borrowed from zap
@@ -193,44 +479,74 @@ func trimCallerPath(path string) string {
 }
 
 // Non-JSON logging format function
-func (l *intLogger) logPlain(t time.Time, name string, level Level, msg string, args ...interface{}) {
+func (l *intLogger) logPlain(t time.Time, name string, level Level, msg string, fields []Field, args ...interface{}) {
 	if len(l.timeFormat) > 0 {
 		l.writer.WriteString(t.Format(l.timeFormat))
 		l.writer.WriteByte(' ')
 	}
 
-	s, ok := _levelToBracket[level]
-	if ok {
+	if l.bracketLevelFirst {
+		s, ok := _levelToLogutils[level]
+		if !ok {
+			s = "[?????]"
+		}
 		l.writer.WriteString(s)
+		l.writer.WriteByte(' ')
+
+		if l.callerOffset > 0 {
+			if _, file, line, ok := runtime.Caller(l.callerOffset); ok {
+				l.writer.WriteString(trimCallerPath(file))
+				l.writer.WriteByte(':')
+				l.writer.WriteString(strconv.Itoa(line))
+				l.writer.WriteString(": ")
+			}
+		}
+
+		if name != "" {
+			l.writer.WriteString(name)
+			l.writer.WriteString(": ")
+		}
 	} else {
-		l.writer.WriteString("[?????]")
-	}
+		s, ok := _levelToBracket[level]
+		if ok {
+			l.writer.WriteString(s)
+		} else {
+			l.writer.WriteString("[?????]")
+		}
 
-	if l.callerOffset > 0 {
-		if _, file, line, ok := runtime.Caller(l.callerOffset); ok {
-			l.writer.WriteByte('[')
-			l.writer.WriteString(trimCallerPath(file))
-			l.writer.WriteByte(':')
-			l.writer.WriteString(strconv.Itoa(line))
-			l.writer.WriteByte(']')
+		if l.callerOffset > 0 {
+			if _, file, line, ok := runtime.Caller(l.callerOffset); ok {
+				l.writer.WriteByte('[')
+				l.writer.WriteString(trimCallerPath(file))
+				l.writer.WriteByte(':')
+				l.writer.WriteString(strconv.Itoa(line))
+				l.writer.WriteByte(']')
+			}
 		}
-	}
 
-	l.writer.WriteByte(' ')
+		l.writer.WriteByte(' ')
 
-	if name != "" {
-		l.writer.WriteByte('[')
-		l.writer.WriteString("module")
-		l.writer.WriteByte('=')
-		l.writer.WriteString(name)
-		l.writer.WriteString("] ")
-	}
+		if name != "" {
+			l.writer.WriteByte('[')
+			l.writer.WriteString("module")
+			l.writer.WriteByte('=')
+			l.writer.WriteString(name)
+			l.writer.WriteString("] ")
+		}
+		if l.namePadding.width != 0 {
+			for n := l.namePadding.padding(name); n > 0; n-- {
+				l.writer.WriteByte(' ')
+			}
+		}
 
-	l.writer.WriteString("-- ")
+		l.writer.WriteString("-- ")
+	}
 
-	l.writer.WriteString(msg)
+	l.writer.WriteString(escape(l.limits.message(msg), l.rawNewlines))
 
-	args = append(l.implied, args...)
+	args, fields = l.keyFilter.apply(expandFields(l.withImplied(args)), fields)
+	args, fields = l.limits.apply(l.seq.stamp(args), fields)
+	hasArgs := len(args) > 0
 
 	var stacktrace CapturedStacktrace
 
@@ -246,76 +562,35 @@ func (l *intLogger) logPlain(t time.Time, name string, level Level, msg string,
 			}
 		}
 
-		l.writer.WriteByte(':')
+		if !l.bracketLevelFirst {
+			l.writer.WriteByte(':')
+		}
 
-	FOR:
 		for i := 0; i < len(args); i = i + 2 {
-			var (
-				val string
-				raw bool
-			)
-
-			switch st := args[i+1].(type) {
-			case string:
-				val = st
-			case int:
-				val = strconv.FormatInt(int64(st), 10)
-			case int64:
-				val = strconv.FormatInt(int64(st), 10)
-			case int32:
-				val = strconv.FormatInt(int64(st), 10)
-			case int16:
-				val = strconv.FormatInt(int64(st), 10)
-			case int8:
-				val = strconv.FormatInt(int64(st), 10)
-			case uint:
-				val = strconv.FormatUint(uint64(st), 10)
-			case uint64:
-				val = strconv.FormatUint(uint64(st), 10)
-			case uint32:
-				val = strconv.FormatUint(uint64(st), 10)
-			case uint16:
-				val = strconv.FormatUint(uint64(st), 10)
-			case uint8:
-				val = strconv.FormatUint(uint64(st), 10)
-			case Hex:
-				val = "0x" + strconv.FormatUint(uint64(st), 16)
-			case Octal:
-				val = "0" + strconv.FormatUint(uint64(st), 8)
-			case Binary:
-				val = "0b" + strconv.FormatUint(uint64(st), 2)
-			case CapturedStacktrace:
+			val := l.formatters.format(args[i+1])
+			if st, ok := val.(CapturedStacktrace); ok {
 				stacktrace = st
-				continue FOR
-			case Format:
-				val = fmt.Sprintf(st[0].(string), st[1:]...)
-			default:
-				v := reflect.ValueOf(st)
-				if v.Kind() == reflect.Slice {
-					val = l.renderSlice(v)
-					raw = true
-				} else {
-					val = fmt.Sprintf("%v", st)
-				}
+				continue
 			}
 
 			l.writer.WriteByte(' ')
 			switch st := args[i].(type) {
 			case string:
-				l.writer.WriteString(st)
+				l.writer.WriteString(escape(l.fieldKey(st), false))
 			default:
-				l.writer.WriteString(fmt.Sprintf("%s", st))
+				l.writer.WriteString(escape(l.fieldKey(fmt.Sprintf("%s", st)), false))
 			}
 			l.writer.WriteByte('=')
 
-			if !raw && strings.ContainsAny(val, " \t\n\r") {
-				l.writer.WriteByte('"')
-				l.writer.WriteString(val)
-				l.writer.WriteByte('"')
-			} else {
-				l.writer.WriteString(val)
-			}
+			l.writeTextValue(val)
+		}
+	}
+
+	if len(fields) > 0 {
+		if !hasArgs && !l.bracketLevelFirst {
+			l.writer.WriteByte(':')
 		}
+		l.writeFields(fields)
 	}
 
 	l.writer.WriteString("\n")
@@ -351,6 +626,7 @@ func (l *intLogger) renderSlice(v reflect.Value) string {
 			val = fmt.Sprintf("%v", sv.Interface())
 		}
 
+		val = escape(val, l.rawNewlines)
 		if strings.ContainsAny(val, " \t\n\r") {
 			buf.WriteByte('"')
 			buf.WriteString(val)
@@ -366,9 +642,17 @@ func (l *intLogger) renderSlice(v reflect.Value) string {
 }
 
 // JSON logging function
-func (l *intLogger) logJSON(t time.Time, name string, level Level, msg string, args ...interface{}) {
+func (l *intLogger) logJSON(t time.Time, name string, level Level, msg string, fields []Field, args ...interface{}) {
+	msg = l.limits.message(msg)
 	vals := l.jsonMapEntry(t, name, level, msg)
-	args = append(l.implied, args...)
+	args, fields = l.keyFilter.apply(expandFields(l.withImplied(args)), fields)
+	args, fields = l.limits.apply(l.seq.stamp(args), fields)
+
+	// order is the order of the keys of the entry, for OrderJSONKeys.
+	var order []string
+	if l.orderJSON {
+		order = make([]string, 0, len(args)/2+len(fields))
+	}
 
 	if args != nil && len(args) > 0 {
 		if len(args)%2 != 0 {
@@ -383,12 +667,16 @@ func (l *intLogger) logJSON(t time.Time, name string, level Level, msg string, a
 		}
 
 		for i := 0; i < len(args); i = i + 2 {
-			val := args[i+1]
+			val := l.formatters.format(args[i+1])
 			switch sv := val.(type) {
 			case error:
 				// Check if val is of type error. If error type doesn't
 				// implement json.Marshaler or encoding.TextMarshaler
 				// then set val to err.Error() so that it gets marshaled
+				if errs := joinedErrors(sv); errs != nil {
+					val = l.limitedValues(errs)
+					break
+				}
 				switch sv.(type) {
 				case json.Marshaler, encoding.TextMarshaler:
 				default:
@@ -396,6 +684,13 @@ func (l *intLogger) logJSON(t time.Time, name string, level Level, msg string, a
 				}
 			case Format:
 				val = fmt.Sprintf(sv[0].(string), sv[1:]...)
+			case json.RawMessage:
+				val = l.rawJSON(sv)
+			case time.Time:
+				val = l.timeEncoding.value(sv)
+			}
+			if s, ok := val.(string); ok {
+				val = l.limits.value(s)
 			}
 
 			var key string
@@ -406,17 +701,43 @@ func (l *intLogger) logJSON(t time.Time, name string, level Level, msg string, a
 			default:
 				key = fmt.Sprintf("%s", st)
 			}
+			key = l.fieldKey(key)
 			vals[key] = val
+			order = append(order, key)
+		}
+	}
+
+	for _, f := range fields {
+		key := l.fieldKey(f.Key)
+		order = append(order, key)
+		val := f.Value()
+		if err, ok := val.(error); ok {
+			if errs := joinedErrors(err); errs != nil {
+				vals[key] = l.limitedValues(errs)
+				continue
+			}
+			switch err.(type) {
+			case json.Marshaler, encoding.TextMarshaler:
+			default:
+				val = err.Error()
+			}
+		}
+		if t, ok := val.(time.Time); ok {
+			val = l.timeEncoding.value(t)
+		}
+		if s, ok := val.(string); ok {
+			val = l.limits.value(s)
 		}
+		vals[key] = val
 	}
 
-	err := json.NewEncoder(l.writer).Encode(vals)
+	err := l.encodeJSON(vals, order)
 	if err != nil {
 		if _, ok := err.(*json.UnsupportedTypeError); ok {
 			plainVal := l.jsonMapEntry(t, name, level, msg)
 			plainVal["@warn"] = errJsonUnsupportedTypeMsg
 
-			json.NewEncoder(l.writer).Encode(plainVal)
+			l.encodeJSON(plainVal, nil)
 		}
 	}
 }
@@ -424,7 +745,7 @@ func (l *intLogger) logJSON(t time.Time, name string, level Level, msg string, a
 func (l intLogger) jsonMapEntry(t time.Time, name string, level Level, msg string) map[string]interface{} {
 	vals := map[string]interface{}{
 		"@message":   msg,
-		"@timestamp": t.Format("2006-01-02T15:04:05.000000Z07:00"),
+		"@timestamp": l.timeEncoding.timestamp(t),
 	}
 
 	var levelStr string
@@ -450,8 +771,12 @@ func (l intLogger) jsonMapEntry(t time.Time, name string, level Level, msg strin
 	}
 
 	if l.callerOffset > 0 {
-		if _, file, line, ok := runtime.Caller(l.callerOffset + 1); ok {
-			vals["@caller"] = fmt.Sprintf("%s:%d", file, line)
+		if pc, file, line, ok := runtime.Caller(l.callerOffset + 1); ok {
+			if l.locationKey != "" {
+				vals[l.locationKey] = newJSONLocation(pc, file, line)
+			} else {
+				vals["@caller"] = fmt.Sprintf("%s:%d", file, line)
+			}
 		}
 	}
 	return vals
@@ -462,6 +787,36 @@ func (l *intLogger) Log(level Level, msg string, args ...interface{}) {
 	l.log(l.Name(), level, msg, args...)
 }
 
+// LogF emits the message and fields at the provided level
+func (l *intLogger) LogF(level Level, msg string, fields ...Field) {
+	l.logFields(l.Name(), level, msg, fields)
+}
+
+// TraceF emits the message and fields at TRACE level
+func (l *intLogger) TraceF(msg string, fields ...Field) {
+	l.logFields(l.Name(), Trace, msg, fields)
+}
+
+// DebugF emits the message and fields at DEBUG level
+func (l *intLogger) DebugF(msg string, fields ...Field) {
+	l.logFields(l.Name(), Debug, msg, fields)
+}
+
+// InfoF emits the message and fields at INFO level
+func (l *intLogger) InfoF(msg string, fields ...Field) {
+	l.logFields(l.Name(), Info, msg, fields)
+}
+
+// WarnF emits the message and fields at WARN level
+func (l *intLogger) WarnF(msg string, fields ...Field) {
+	l.logFields(l.Name(), Warn, msg, fields)
+}
+
+// ErrorF emits the message and fields at ERROR level
+func (l *intLogger) ErrorF(msg string, fields ...Field) {
+	l.logFields(l.Name(), Error, msg, fields)
+}
+
 // Emit the message and args at DEBUG level
 func (l *intLogger) Debug(msg string, args ...interface{}) {
 	l.log(l.Name(), Debug, msg, args...)
@@ -489,27 +844,27 @@ func (l *intLogger) Error(msg string, args ...interface{}) {
 
 // Indicate that the logger would emit TRACE level logs
 func (l *intLogger) IsTrace() bool {
-	return Level(atomic.LoadInt32(l.level)) == Trace
+	return l.levelFor(l.name) == Trace
 }
 
 // Indicate that the logger would emit DEBUG level logs
 func (l *intLogger) IsDebug() bool {
-	return Level(atomic.LoadInt32(l.level)) <= Debug
+	return l.levelFor(l.name) <= Debug
 }
 
 // Indicate that the logger would emit INFO level logs
 func (l *intLogger) IsInfo() bool {
-	return Level(atomic.LoadInt32(l.level)) <= Info
+	return l.levelFor(l.name) <= Info
 }
 
 // Indicate that the logger would emit WARN level logs
 func (l *intLogger) IsWarn() bool {
-	return Level(atomic.LoadInt32(l.level)) <= Warn
+	return l.levelFor(l.name) <= Warn
 }
 
 // Indicate that the logger would emit ERROR level logs
 func (l *intLogger) IsError() bool {
-	return Level(atomic.LoadInt32(l.level)) <= Error
+	return l.levelFor(l.name) <= Error
 }
 
 const MissingKey = "EXTRA_VALUE_AT_END"
@@ -518,8 +873,16 @@ const MissingKey = "EXTRA_VALUE_AT_END"
 // the given key/value pairs. This is used to create a context specific
 // Logger.
 func (l *intLogger) With(args ...interface{}) Logger {
+	// WithLocation and NoLocation only apply to the entries they are given
+	// with.
+	args, _, _ = splitLocation(args)
+	if l.strictArgs {
+		checkArgs(nil, args)
+	}
+
 	var extra interface{}
 
+	args = expandFields(args)
 	if len(args)%2 != 0 {
 		extra = args[len(args)-1]
 		args = args[:len(args)-1]
@@ -565,6 +928,14 @@ func (l *intLogger) With(args ...interface{}) Logger {
 // Create a new sub-Logger that a name decending from the current name.
 // This is used to create a subsystem specific Logger.
 func (l *intLogger) Named(name string) Logger {
+	sl := l.named(name)
+	l.registry.add(sl.name, sl)
+	return sl
+}
+
+// named returns Named(name) without recording it in the registry, for an
+// InterceptLogger to record itself instead.
+func (l *intLogger) named(name string) *intLogger {
 	sl := l.copy()
 
 	if sl.name != "" {
@@ -580,10 +951,15 @@ func (l *intLogger) Named(name string) Logger {
 // name. This is used to create a standalone logger that doesn't fall
 // within the normal hierarchy.
 func (l *intLogger) ResetNamed(name string) Logger {
-	sl := l.copy()
+	sl := l.resetNamed(name)
+	l.registry.add(sl.name, sl)
+	return sl
+}
 
+// resetNamed returns ResetNamed(name) without recording it in the registry.
+func (l *intLogger) resetNamed(name string) *intLogger {
+	sl := l.copy()
 	sl.name = name
-
 	return sl
 }
 
@@ -617,15 +993,84 @@ func (l *intLogger) ResetOutputWithFlush(opts *LoggerOptions, flushable Flushabl
 }
 
 func (l *intLogger) resetOutput(opts *LoggerOptions) error {
-	l.writer = newWriter(opts.Output, opts.Color)
-	l.setColorization(opts)
+	out := &output{w: opts.Output, color: opts.Color}
+	out.setColorization(opts)
+	l.writer.setOutput(out)
 	return nil
 }
 
 // Update the logging level on-the-fly. This will affect all subloggers as
-// well.
+// well. Level overrides still apply to the named loggers they match, unless
+// the logger was created with IndependentLevels, in which case the level set
+// here takes precedence.
 func (l *intLogger) SetLevel(level Level) {
 	atomic.StoreInt32(l.level, int32(level))
+	if l.independentLevels {
+		atomic.StoreInt32(l.levelSet, 1)
+	}
+}
+
+// SetLevelOverride sets the level of the loggers named name and their
+// subloggers, overriding the level of the root logger. Passing NoLevel removes
+// the override. This affects every logger derived from the same root logger.
+func (l *intLogger) SetLevelOverride(name string, level Level) {
+	l.overrides.set(name, level)
+}
+
+// LevelOverrides returns a copy of the level overrides shared with the root
+// logger.
+func (l *intLogger) LevelOverrides() map[string]Level {
+	return l.overrides.snapshot()
+}
+
+// Loggers returns the named loggers recorded with RegisterNamed, see
+// LoggerRegistry.
+func (l *intLogger) Loggers() []LoggerInfo {
+	return l.registry.list()
+}
+
+// GetLogger returns the logger recorded with RegisterNamed under name.
+func (l *intLogger) GetLogger(name string) (Logger, bool) {
+	return l.registry.get(name)
+}
+
+// Release forgets the logger recorded with RegisterNamed under name.
+func (l *intLogger) Release(name string) {
+	l.registry.release(name)
+}
+
+// GetLevel returns the level of the logger, or the level overriding it.
+func (l *intLogger) GetLevel() Level {
+	return l.levelFor(l.name)
+}
+
+// DroppedByName returns the number of entries dropped because of the name of
+// their logger, by this logger and all the loggers sharing its root.
+func (l *intLogger) DroppedByName() uint64 {
+	if l.names == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&l.names.dropped)
+}
+
+func (l *intLogger) keyTable() *keyTable {
+	return l.keys
+}
+
+// jsonFormat reports whether l writes JSON.
+func (l *intLogger) jsonFormat() bool {
+	return l.json
+}
+
+// levelFor returns the level of the entries logged under name: the level
+// overriding it if there is one, or the level of the logger.
+func (l *intLogger) levelFor(name string) Level {
+	if atomic.LoadInt32(l.levelSet) == 0 {
+		if level, ok := l.overrides.lookup(name); ok {
+			return level
+		}
+	}
+	return Level(atomic.LoadInt32(l.level))
 }
 
 // Create a *log.Logger that will send it's data through this Logger. This
@@ -640,17 +1085,40 @@ func (l *intLogger) StandardLogger(opts *StandardLoggerOptions) *log.Logger {
 }
 
 func (l *intLogger) StandardWriter(opts *StandardLoggerOptions) io.Writer {
-
 	newLog := &intLogger{
 		json:              l.json,
 		name:              l.name,
 		timeFormat:        l.timeFormat,
+		timeEncoding:      l.timeEncoding,
 		mutex:             l.mutex,
-		writer:            l.writer,
+		writer:            l.writer.clone(),
 		level:             l.level,
+		overrides:         l.overrides,
+		levelSet:          l.levelSet,
 		exclude:           l.exclude,
+		names:             l.names,
+		keys:              l.keys,
 		independentLevels: l.independentLevels,
 		implied:           l.implied,
+		keyNormalization:  l.keyNormalization,
+		collisionPrefix:   l.collisionPrefix,
+		limits:            l.limits,
+		rawNewlines:       l.rawNewlines,
+		formatters:        l.formatters,
+		keyFilter:         l.keyFilter,
+		middleware:        l.middleware,
+		exit:              l.exit,
+		suppress:          l.suppress,
+		seq:               l.seq,
+		registry:          l.registry,
+		shutdown:          l.shutdown,
+		strictArgs:        l.strictArgs,
+		namePadding:       l.namePadding,
+		bracketLevelFirst: l.bracketLevelFirst,
+		locationKey:       l.locationKey,
+		orderJSON:         l.orderJSON,
+		scope:             l.scope,
+		noScopeChecks:     l.noScopeChecks,
 	}
 	if l.callerOffset > 0 {
 		newLog.callerOffset = l.callerOffset + 4
@@ -665,22 +1133,28 @@ func (l *intLogger) StandardWriter(opts *StandardLoggerOptions) io.Writer {
 
 // checks if the underlying io.Writer is a file, and
 // panics if not. For use by colorization.
-func (l *intLogger) checkWriterIsFile() *os.File {
-	fi, ok := l.writer.w.(*os.File)
+func (o *output) checkWriterIsFile() *os.File {
+	fi, ok := o.w.(*os.File)
 	if !ok {
 		panic("Cannot enable coloring of non-file Writers")
 	}
 	return fi
 }
 
+func (l *intLogger) exitOptions() exitOptions {
+	return l.exit
+}
+
 // Accept implements the SinkAdapter interface
 func (i *intLogger) Accept(name string, level Level, msg string, args ...interface{}) {
 	i.log(name, level, msg, args...)
 }
 
-// ImpliedArgs returns the loggers implied args
+// ImpliedArgs returns the loggers implied args. The slice is shared with the
+// logger and must not be changed, though appending to it makes a copy.
 func (i *intLogger) ImpliedArgs() []interface{} {
-	return i.implied
+	implied := i.impliedArgs()
+	return implied[:len(implied):len(implied)]
 }
 
 // Name returns the loggers name
@@ -688,15 +1162,31 @@ func (i *intLogger) Name() string {
 	return i.name
 }
 
-// copy returns a shallow copy of the intLogger, replacing the level pointer
-// when necessary
+// copy returns a shallow copy of the intLogger, with a writer of its own so
+// that ResetOutput swaps the output of one logger only, and replacing the
+// level pointer when necessary
 func (l *intLogger) copy() *intLogger {
 	sl := *l
+	sl.writer = l.writer.clone()
 
 	if l.independentLevels {
 		sl.level = new(int32)
-		*sl.level = *l.level
+		*sl.level = atomic.LoadInt32(l.level)
+		sl.levelSet = new(int32)
+		*sl.levelSet = atomic.LoadInt32(l.levelSet)
 	}
 
 	return &sl
 }
+
+// withImplied returns the implied arguments followed by args, in a slice of
+// its own when there are implied arguments, so that the entries of loggers
+// sharing them don't write into the same array.
+func (l *intLogger) withImplied(args []interface{}) []interface{} {
+	implied := l.impliedArgs()
+	if len(implied) == 0 {
+		return args
+	}
+	all := make([]interface{}, 0, len(implied)+len(args))
+	return append(append(all, implied...), args...)
+}
@@ -0,0 +1,33 @@
+package hclog
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// isJournalStream reports whether f is the stream journald connected to the
+// process, as identified by the device and inode numbers in JOURNAL_STREAM.
+// Anything unexpected reports false, so that the output is formatted as usual.
+func isJournalStream(f *os.File) bool {
+	stream := os.Getenv("JOURNAL_STREAM")
+	idx := strings.IndexByte(stream, ':')
+	if idx < 0 {
+		return false
+	}
+	dev, err := strconv.ParseUint(stream[:idx], 10, 64)
+	if err != nil {
+		return false
+	}
+	ino, err := strconv.ParseUint(stream[idx+1:], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(f.Fd()), &st); err != nil {
+		return false
+	}
+	return uint64(st.Dev) == dev && uint64(st.Ino) == ino
+}
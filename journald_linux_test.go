@@ -0,0 +1,80 @@
+package hclog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_AdaptToJournald(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hclog-journald")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	stream, err := os.Create(filepath.Join(dir, "stream"))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var st syscall.Stat_t
+	require.NoError(t, syscall.Fstat(int(stream.Fd()), &st))
+	journalStream := fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+
+	t.Run("detects the journal stream", func(t *testing.T) {
+		defer setenv(map[string]string{"JOURNAL_STREAM": journalStream})()
+
+		assert.True(t, isJournalStream(stream))
+		assert.False(t, isJournalStream(os.Stdin))
+	})
+
+	t.Run("fails safe", func(t *testing.T) {
+		for _, v := range []string{"", "garbage", "1:", ":2", "1:2"} {
+			func() {
+				defer setenv(map[string]string{"JOURNAL_STREAM": v})()
+				assert.False(t, isJournalStream(stream), "JOURNAL_STREAM=%q", v)
+			}()
+		}
+	})
+
+	t.Run("prefixes lines with priorities", func(t *testing.T) {
+		defer setenv(map[string]string{"JOURNAL_STREAM": journalStream})()
+
+		logger := New(&LoggerOptions{
+			Output:          stream,
+			AdaptToJournald: true,
+		})
+
+		logger.Info("started")
+		logger.Error("failed", CapturedStacktrace("main.main\n\tmain.go:10"))
+
+		data, err := ioutil.ReadFile(stream.Name())
+		require.NoError(t, err)
+		expected := "<6>[INFO]  -- started\n" +
+			"<3>[ERROR] -- failed:\n<3>main.main\n<3>\tmain.go:10\n"
+		assert.Equal(t, expected, string(data))
+	})
+
+	t.Run("formats other outputs as usual", func(t *testing.T) {
+		defer setenv(map[string]string{"JOURNAL_STREAM": journalStream})()
+
+		other, err := os.Create(filepath.Join(dir, "other"))
+		require.NoError(t, err)
+		defer other.Close()
+
+		logger := New(&LoggerOptions{
+			Output:          other,
+			AdaptToJournald: true,
+			TimeFormat:      "2006",
+		})
+		logger.Info("started")
+
+		data, err := ioutil.ReadFile(other.Name())
+		require.NoError(t, err)
+		assert.Regexp(t, `^\d{4} \[INFO\]  -- started\n$`, string(data))
+	})
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package hclog
+
+import "os"
+
+// isJournalStream reports false, journald only running on Linux.
+func isJournalStream(f *os.File) bool {
+	return false
+}
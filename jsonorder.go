@@ -0,0 +1,72 @@
+package hclog
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// encodeJSON writes the JSON entry vals, with the keys in order first if the
+// logger orders them, see OrderJSONKeys, and sorted otherwise.
+func (l *intLogger) encodeJSON(vals map[string]interface{}, order []string) error {
+	if !l.orderJSON {
+		return json.NewEncoder(l.writer).Encode(vals)
+	}
+
+	first := []string{"@timestamp", "@level", "@module", "@message", "@caller"}
+	if l.locationKey != "" {
+		first[len(first)-1] = l.locationKey
+	}
+
+	buf := make([]byte, 0, 256)
+	buf = append(buf, '{')
+	written := make(map[string]struct{}, len(vals))
+	write := func(key string) error {
+		val, ok := vals[key]
+		if !ok {
+			return nil
+		}
+		if _, ok := written[key]; ok {
+			return nil
+		}
+		written[key] = struct{}{}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		if len(written) > 1 {
+			buf = append(buf, ',')
+		}
+		buf = append(append(append(buf, k...), ':'), v...)
+		return nil
+	}
+
+	for _, keys := range [][]string{first, order} {
+		for _, key := range keys {
+			if err := write(key); err != nil {
+				return err
+			}
+		}
+	}
+	if len(written) < len(vals) {
+		rest := make([]string, 0, len(vals)-len(written))
+		for key := range vals {
+			if _, ok := written[key]; !ok {
+				rest = append(rest, key)
+			}
+		}
+		sort.Strings(rest)
+		for _, key := range rest {
+			if err := write(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := l.writer.Write(append(buf, '}', '\n'))
+	return err
+}
@@ -0,0 +1,101 @@
+package hclog
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderJSONKeys(t *testing.T) {
+	// The timestamp and the location vary, and are replaced to compare the
+	// entries.
+	timestamp := regexp.MustCompile(`"@timestamp":"[^"]*"`)
+	caller := regexp.MustCompile(`"@caller":"[^"]*"`)
+	location := regexp.MustCompile(`"caller":\{[^}]*\}`)
+	stacktrace := regexp.MustCompile(`"stacktrace":"[^"]*"`)
+
+	cases := []struct {
+		name string
+		opts LoggerOptions
+		log  func(Logger)
+		want string
+	}{
+		{
+			"logged order",
+			LoggerOptions{Name: "app", OrderJSONKeys: true},
+			func(l Logger) { l.With("z", 1, "a", 2).Info("entry", "m", 3, "b", "four") },
+			`{"@timestamp":"T","@level":"info","@module":"app","@message":"entry","a":2,"z":1,"m":3,"b":"four"}`,
+		},
+		{
+			"fields",
+			LoggerOptions{OrderJSONKeys: true},
+			func(l Logger) { l.With("z", 1).(FieldLogger).WarnF("entry", Str("y", "two"), Int("x", 3)) },
+			`{"@timestamp":"T","@level":"warn","@message":"entry","z":1,"y":"two","x":3}`,
+		},
+		{
+			"repeated key",
+			LoggerOptions{OrderJSONKeys: true},
+			func(l Logger) { l.Info("entry", "a", 1, "b", 2, "a", 3) },
+			`{"@timestamp":"T","@level":"info","@message":"entry","a":3,"b":2}`,
+		},
+		{
+			"key of the logger",
+			LoggerOptions{OrderJSONKeys: true},
+			func(l Logger) { l.Info("entry", "@level", "mine", "b", 2) },
+			`{"@timestamp":"T","@level":"info","@message":"entry","field_@level":"mine","b":2}`,
+		},
+		{
+			"location",
+			LoggerOptions{IncludeLocation: true, OrderJSONKeys: true},
+			func(l Logger) { l.Info("entry", "b", 2) },
+			`{"@timestamp":"T","@level":"info","@message":"entry","@caller":"L","b":2}`,
+		},
+		{
+			"structured location",
+			LoggerOptions{IncludeLocation: true, StructuredLocation: true, OrderJSONKeys: true},
+			func(l Logger) { l.Info("entry", "b", 2) },
+			`{"@timestamp":"T","@level":"info","@message":"entry","caller":{L},"b":2}`,
+		},
+		{
+			"stacktrace and missing value",
+			LoggerOptions{OrderJSONKeys: true},
+			func(l Logger) {
+				l.Info("entry", "b", 2, Stacktrace())
+				l.Info("entry", "b", 2, "c")
+			},
+			`{"@timestamp":"T","@level":"info","@message":"entry","b":2,"stacktrace":"S"}` + "\n" +
+				`{"@timestamp":"T","@level":"info","@message":"entry","b":2,"EXTRA_VALUE_AT_END":"c"}`,
+		},
+		{
+			"unsupported value",
+			LoggerOptions{OrderJSONKeys: true},
+			func(l Logger) { l.Info("entry", "b", func() {}) },
+			`{"@timestamp":"T","@level":"info","@message":"entry","@warn":"` + errJsonUnsupportedTypeMsg + `"}`,
+		},
+		{
+			"sorted",
+			LoggerOptions{Name: "app"},
+			func(l Logger) { l.With("z", 1, "a", 2).Info("entry", "m", 3, "b", "four") },
+			`{"@level":"info","@message":"entry","@module":"app","@timestamp":"T","a":2,"b":"four","m":3,"z":1}`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := c.opts
+			opts.Output = &buf
+			opts.JSONFormat = true
+			c.log(New(&opts))
+
+			got := strings.TrimSpace(buf.String())
+			got = timestamp.ReplaceAllString(got, `"@timestamp":"T"`)
+			got = caller.ReplaceAllString(got, `"@caller":"L"`)
+			got = location.ReplaceAllString(got, `"caller":{L}`)
+			got = stacktrace.ReplaceAllString(got, `"stacktrace":"S"`)
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
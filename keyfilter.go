@@ -0,0 +1,85 @@
+package hclog
+
+// keyFilter drops key/value pairs by key, as set by OmitKeys and OnlyKeys.
+type keyFilter struct {
+	omit map[string]struct{}
+	only map[string]struct{}
+}
+
+// newKeyFilter returns a filter for the given keys, or nil if there are none.
+func newKeyFilter(omit, only []string) *keyFilter {
+	if len(omit) == 0 && len(only) == 0 {
+		return nil
+	}
+	f := &keyFilter{omit: keySet(omit)}
+	if len(only) > 0 {
+		f.only = keySet(only)
+	}
+	return f
+}
+
+func keySet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	return set
+}
+
+// drops reports whether the pairs of key are dropped.
+func (f *keyFilter) drops(key string) bool {
+	if _, ok := f.omit[key]; ok {
+		return true
+	}
+	if f.only == nil {
+		return false
+	}
+	_, ok := f.only[key]
+	return !ok
+}
+
+// apply returns args and fields without the pairs the filter drops, before
+// anything is formatted. A trailing value without a key, such as a
+// CapturedStacktrace, is kept. args and fields are only copied when pairs are
+// dropped.
+func (f *keyFilter) apply(args []interface{}, fields []Field) ([]interface{}, []Field) {
+	if f == nil {
+		return args, fields
+	}
+
+	for i := 0; i+1 < len(args); i += 2 {
+		if key, ok := args[i].(string); ok && f.drops(key) {
+			args = f.filterArgs(args)
+			break
+		}
+	}
+	for _, field := range fields {
+		if f.drops(field.Key) {
+			fields = f.filterFields(fields)
+			break
+		}
+	}
+	return args, fields
+}
+
+func (f *keyFilter) filterArgs(args []interface{}) []interface{} {
+	kept := make([]interface{}, 0, len(args))
+	i := 0
+	for ; i+1 < len(args); i += 2 {
+		if key, ok := args[i].(string); ok && f.drops(key) {
+			continue
+		}
+		kept = append(kept, args[i], args[i+1])
+	}
+	return append(kept, args[i:]...)
+}
+
+func (f *keyFilter) filterFields(fields []Field) []Field {
+	kept := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		if !f.drops(field.Key) {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
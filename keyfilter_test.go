@@ -0,0 +1,94 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStringer counts how many times it is formatted.
+type countingStringer struct {
+	calls *int
+}
+
+func (s countingStringer) String() string {
+	*s.calls++
+	return "formatted"
+}
+
+func TestLogger_OmitKeys(t *testing.T) {
+	t.Run("drops pairs in text", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			OmitKeys:    []string{"request_headers"},
+		}).With("request_headers", "verbose")
+
+		calls := 0
+		logger.Info("request", "path", "/", "request_headers", countingStringer{&calls})
+		logger.(FieldLogger).InfoF("request", Str("request_headers", "verbose"), Int("status", 200))
+		logger.Error("failed", "request_headers", "verbose", CapturedStacktrace("stack"))
+
+		expected := "[INFO]  -- request: path=/\n" +
+			"[INFO]  -- request: status=200\n" +
+			"[ERROR] -- failed:\nstack\n"
+		assert.Equal(t, expected, buf.String())
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("drops pairs in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+			OmitKeys:   []string{"request_headers", "@level"},
+		})
+
+		logger.Info("request", "path", "/", "request_headers", "verbose")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "/", entry["path"])
+		assert.Equal(t, "info", entry["@level"])
+		assert.NotContains(t, entry, "request_headers")
+	})
+
+	t.Run("keeps only some keys", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			OnlyKeys:    []string{"path", "status", "request_headers"},
+			OmitKeys:    []string{"request_headers"},
+		})
+
+		logger.Info("request", "path", "/", "user", "bob", "request_headers", "verbose", "status", 200)
+		assert.Equal(t, "[INFO]  -- request: path=/ status=200\n", buf.String())
+	})
+
+	t.Run("changes with WithOptions", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			OmitKeys:    []string{"request_headers"},
+		})
+
+		verbose := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			assert.Equal(t, []string{"request_headers"}, opts.OmitKeys)
+			opts.OmitKeys = nil
+		})
+
+		logger.Info("request", "request_headers", "verbose")
+		verbose.Info("request", "request_headers", "verbose")
+		assert.Equal(t, "[INFO]  -- request\n[INFO]  -- request: request_headers=verbose\n", buf.String())
+	})
+}
@@ -0,0 +1,65 @@
+package hclog
+
+import "strings"
+
+// KeyNormalization selects how the keys of key/value pairs are rewritten
+// before they are written.
+type KeyNormalization uint8
+
+const (
+	// KeysUnchanged writes keys as they are given. It is the default.
+	KeysUnchanged KeyNormalization = iota
+
+	// KeysUnderscored replaces the whitespace and dots in keys with
+	// underscores, so that "request id" and "request.id" are written as
+	// "request_id".
+	KeysUnderscored
+)
+
+// DefaultKeyCollisionPrefix is prepended to the keys of key/value pairs which
+// collide with the keys the logger writes itself, unless KeyCollisionPrefix is
+// set in the options.
+const DefaultKeyCollisionPrefix = "field_"
+
+// builtinKeys are the keys written by the logger itself in the JSON format.
+// Key/value pairs using one of them are written with the collision prefix, in
+// both formats, rather than replacing or duplicating the entry's own.
+var builtinKeys = map[string]struct{}{
+	"@caller":    {},
+	"@level":     {},
+	"@message":   {},
+	"@module":    {},
+	"@timestamp": {},
+	"@warn":      {},
+}
+
+// fieldKey returns key as written by l: normalized, then prefixed if it
+// collides with a built-in key.
+func (l *intLogger) fieldKey(key string) string {
+	if l.keyNormalization == KeysUnderscored {
+		key = underscoreKey(key)
+	}
+	if len(key) > 0 && key[0] == '@' {
+		if _, ok := builtinKeys[key]; ok {
+			return l.collisionPrefix + key
+		}
+	}
+	if l.locationKey != "" && key == l.locationKey {
+		return l.collisionPrefix + key
+	}
+	return key
+}
+
+// underscoreKey replaces the whitespace and dots in key with underscores.
+func underscoreKey(key string) string {
+	if !strings.ContainsAny(key, " \t\n\r.") {
+		return key
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r', '.':
+			return '_'
+		}
+		return r
+	}, key)
+}
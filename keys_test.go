@@ -0,0 +1,95 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_keys(t *testing.T) {
+	t.Run("leaves keys unchanged by default", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.Info("request", "request id", 1, "http.method", "GET")
+		assert.Equal(t, "[INFO]  -- request: request id=1 http.method=GET\n", buf.String())
+	})
+
+	t.Run("underscores keys in text", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:        &buf,
+			DisableTime:   true,
+			NormalizeKeys: KeysUnderscored,
+		}).With("client addr", "10.0.0.1")
+
+		logger.(FieldLogger).InfoF("request", Str("http.method", "GET"))
+		logger.Info("request", "request id", 1)
+		assert.Equal(t, "[INFO]  -- request: client_addr=10.0.0.1 http_method=GET\n[INFO]  -- request: client_addr=10.0.0.1 request_id=1\n", buf.String())
+	})
+
+	t.Run("underscores keys in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:        &buf,
+			JSONFormat:    true,
+			NormalizeKeys: KeysUnderscored,
+		})
+
+		logger.Info("request", "request id", 1, Str("http.method", "GET"))
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, float64(1), entry["request_id"])
+		assert.Equal(t, "GET", entry["http_method"])
+		assert.NotContains(t, entry, "request id")
+	})
+
+	t.Run("prefixes keys colliding with built-in keys", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		})
+
+		logger.(FieldLogger).LogF(Info, "request", Str("@level", "debug"))
+		logger.Info("request", "@message", "spoofed", "@timestamp", 0)
+
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(lines[0], &entry))
+		assert.Equal(t, "info", entry["@level"])
+		assert.Equal(t, "debug", entry["field_@level"])
+
+		entry = nil
+		require.NoError(t, json.Unmarshal(lines[1], &entry))
+		assert.Equal(t, "request", entry["@message"])
+		assert.Equal(t, "spoofed", entry["field_@message"])
+		assert.Equal(t, float64(0), entry["field_@timestamp"])
+	})
+
+	t.Run("prefixes colliding keys in text too", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:             &buf,
+			DisableTime:        true,
+			KeyCollisionPrefix: "user.",
+		})
+
+		logger.Info("request", "@module", "spoofed", "module", "kept")
+		assert.Equal(t, "[INFO]  -- request: user.@module=spoofed module=kept\n", buf.String())
+	})
+}
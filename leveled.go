@@ -0,0 +1,40 @@
+package hclog
+
+// LeveledLogger adapts a Logger to the structural LeveledLogger interfaces of
+// libraries such as hashicorp/go-retryablehttp, which take loggers with
+// exactly these four methods:
+//
+//	Error(msg string, keysAndValues ...interface{})
+//	Info(msg string, keysAndValues ...interface{})
+//	Debug(msg string, keysAndValues ...interface{})
+//	Warn(msg string, keysAndValues ...interface{})
+//
+// Its methods forward the key/value pairs to the Logger untouched. It is made
+// with Leveled, so that the location of the entries is that of the callers of
+// LeveledLogger rather than its own.
+type LeveledLogger struct {
+	Logger
+}
+
+// Leveled returns a LeveledLogger writing to l, as in:
+//
+//	retryClient.Logger = hclog.Leveled(logger)
+func Leveled(l Logger) LeveledLogger {
+	return LeveledLogger{Logger: withCallerSkip(l, 1)}
+}
+
+func (l LeveledLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.Logger.Error(msg, keysAndValues...)
+}
+
+func (l LeveledLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.Logger.Info(msg, keysAndValues...)
+}
+
+func (l LeveledLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.Logger.Debug(msg, keysAndValues...)
+}
+
+func (l LeveledLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.Logger.Warn(msg, keysAndValues...)
+}
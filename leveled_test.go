@@ -0,0 +1,61 @@
+package hclog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// retryableLeveledLogger is the LeveledLogger interface of
+// hashicorp/go-retryablehttp.
+type retryableLeveledLogger interface {
+	Error(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Debug(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+}
+
+var _ retryableLeveledLogger = LeveledLogger{}
+
+func TestLeveled(t *testing.T) {
+	t.Run("matches the structural interface", func(t *testing.T) {
+		iface := reflect.TypeOf((*retryableLeveledLogger)(nil)).Elem()
+		typ := reflect.TypeOf(LeveledLogger{})
+		for i := 0; i < iface.NumMethod(); i++ {
+			want := iface.Method(i)
+			got, ok := typ.MethodByName(want.Name)
+			if !assert.True(t, ok, want.Name) {
+				continue
+			}
+			// The method of the type takes the receiver first.
+			in := make([]reflect.Type, 0, got.Type.NumIn()-1)
+			for j := 1; j < got.Type.NumIn(); j++ {
+				in = append(in, got.Type.In(j))
+			}
+			sig := reflect.FuncOf(in, nil, got.Type.IsVariadic())
+			assert.Equal(t, want.Type, sig, want.Name)
+		}
+	})
+
+	t.Run("forwards key/value pairs", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			Level:           Debug,
+			IncludeLocation: true,
+		})
+
+		var leveled retryableLeveledLogger = Leveled(logger)
+		leveled.Debug("performing request", "method", "GET", "url", "http://localhost")
+		leveled.Warn("retrying request", "attempt", 2)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "[DEBUG][go-hclog/leveled_test.go:53] -- performing request: method=GET url=http://localhost")
+		assert.Contains(t, lines[1], "[WARN] [go-hclog/leveled_test.go:54] -- retrying request: attempt=2")
+	})
+}
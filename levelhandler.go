@@ -0,0 +1,114 @@
+package hclog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// levelState is the JSON body served and accepted by the handler returned by
+// NewLevelHandler.
+type levelState struct {
+	Name      string            `json:"name,omitempty"`
+	Level     string            `json:"level,omitempty"`
+	Overrides map[string]string `json:"overrides,omitempty"`
+	Loggers   []loggerState     `json:"loggers,omitempty"`
+}
+
+// loggerState is a named logger listed by the handler returned by
+// NewLevelHandler.
+type loggerState struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+// levelHandler is the http.Handler returned by NewLevelHandler.
+type levelHandler struct {
+	root Logger
+}
+
+// NewLevelHandler returns an http.Handler to inspect and change the levels of
+// root at runtime. GET returns the level of root, and the level overrides if
+// root supports them, as in:
+//
+//	{"level":"info","overrides":{"raft":"debug"}}
+//
+// If root was created with RegisterNamed, GET also lists the named loggers
+// derived from it, with the level each is at:
+//
+//	{"level":"info","loggers":[{"name":"raft","level":"debug"}]}
+//
+// PUT and POST change the level of root with a body such as {"level":"debug"},
+// or the level override of a named logger with {"name":"raft","level":"trace"},
+// where the level "none" removes the override. They respond like GET, with the
+// levels in effect after the change.
+func NewLevelHandler(root Logger) http.Handler {
+	return &levelHandler{root: root}
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req levelState
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.apply(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.state())
+}
+
+// apply changes the level of root, or the override of req.Name.
+func (h *levelHandler) apply(req levelState) error {
+	level := LevelFromString(req.Level)
+	removal := req.Name != "" && strings.EqualFold(strings.TrimSpace(req.Level), "none")
+	if level == NoLevel && !removal {
+		return fmt.Errorf("invalid log level %q, valid levels are trace, debug, info, warn, error and off", req.Level)
+	}
+
+	if req.Name == "" {
+		h.root.SetLevel(level)
+		return nil
+	}
+	lo, ok := h.root.(LevelOverridable)
+	if !ok {
+		return fmt.Errorf("logger does not support level overrides")
+	}
+	lo.SetLevelOverride(req.Name, level)
+	return nil
+}
+
+// state returns the levels of root.
+func (h *levelHandler) state() levelState {
+	var state levelState
+	if lg, ok := h.root.(LevelGetter); ok {
+		state.Level = lg.GetLevel().String()
+	}
+	if lo, ok := h.root.(LevelOverridable); ok {
+		overrides := lo.LevelOverrides()
+		if len(overrides) > 0 {
+			state.Overrides = make(map[string]string, len(overrides))
+			for name, level := range overrides {
+				state.Overrides[name] = level.String()
+			}
+		}
+	}
+	if lr, ok := h.root.(LoggerRegistry); ok {
+		for _, info := range lr.Loggers() {
+			state.Loggers = append(state.Loggers, loggerState{Name: info.Name, Level: info.Level.String()})
+		}
+	}
+	return state
+}
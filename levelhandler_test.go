@@ -0,0 +1,101 @@
+package hclog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelHandler(t *testing.T) {
+	serve := func(h http.Handler, method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/log-level", strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("returns the levels", func(t *testing.T) {
+		logger := New(&LoggerOptions{
+			Output:         &bytes.Buffer{},
+			Level:          Info,
+			LevelOverrides: map[string]Level{"raft": Debug},
+		})
+
+		rec := serve(NewLevelHandler(logger), http.MethodGet, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"level":"info","overrides":{"raft":"debug"}}`, rec.Body.String())
+	})
+
+	t.Run("lists the named loggers", func(t *testing.T) {
+		logger := New(&LoggerOptions{
+			Output:         &bytes.Buffer{},
+			Level:          Info,
+			LevelOverrides: map[string]Level{"raft": Debug},
+			RegisterNamed:  true,
+		})
+		logger.Named("raft").Named("fsm")
+		logger.Named("http")
+
+		rec := serve(NewLevelHandler(logger), http.MethodGet, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"level":"info","overrides":{"raft":"debug"},"loggers":[`+
+			`{"name":"http","level":"info"},{"name":"raft","level":"debug"},{"name":"raft.fsm","level":"debug"}]}`, rec.Body.String())
+	})
+
+	t.Run("changes the root level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, Level: Info, DisableTime: true})
+		h := NewLevelHandler(logger)
+
+		rec := serve(h, http.MethodPut, `{"level":"debug"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+
+		logger.Debug("lowered")
+		assert.Equal(t, "[DEBUG] -- lowered\n", buf.String())
+	})
+
+	t.Run("changes level overrides", func(t *testing.T) {
+		logger := NewInterceptLogger(&LoggerOptions{Output: &bytes.Buffer{}, Level: Info})
+		h := NewLevelHandler(logger)
+
+		rec := serve(h, http.MethodPost, `{"name":"raft","level":"trace"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"level":"info","overrides":{"raft":"trace"}}`, rec.Body.String())
+		assert.True(t, logger.Named("raft").IsTrace())
+
+		rec = serve(h, http.MethodPost, `{"name":"raft","level":"none"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"level":"info"}`, rec.Body.String())
+		assert.False(t, logger.Named("raft").IsTrace())
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		logger := New(&LoggerOptions{Output: &bytes.Buffer{}, Level: Info})
+		h := NewLevelHandler(logger)
+
+		rec := serve(h, http.MethodPut, `{"level":"verbose"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `invalid log level "verbose"`)
+
+		rec = serve(h, http.MethodPut, `{"level":"none"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = serve(h, http.MethodPut, `not json`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = serve(NewLevelHandler(NewNullLogger()), http.MethodPut, `{"name":"raft","level":"debug"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "does not support level overrides")
+
+		rec = serve(h, http.MethodDelete, "")
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "GET, PUT, POST", rec.Header().Get("Allow"))
+
+		assert.Equal(t, Info, logger.(LevelGetter).GetLevel())
+	})
+}
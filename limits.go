@@ -0,0 +1,75 @@
+package hclog
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// TruncatedFieldsKey is the key under which the number of key/value pairs
+// dropped because of MaxFields is written.
+const TruncatedFieldsKey = "truncated_fields"
+
+// limits are the sizes beyond which messages and values are truncated, and
+// key/value pairs dropped. Zero means unlimited.
+type limits struct {
+	messageBytes int
+	fieldBytes   int
+	fields       int
+}
+
+// message returns msg, truncated to the MaxMessageBytes limit.
+func (l limits) message(msg string) string {
+	return truncateString(msg, l.messageBytes)
+}
+
+// value returns val, truncated to the MaxFieldBytes limit.
+func (l limits) value(val string) string {
+	return truncateString(val, l.fieldBytes)
+}
+
+// apply drops the key/value pairs of args, then the fields, beyond the
+// MaxFields limit, and appends a TruncatedFieldsKey pair with the number
+// dropped. A trailing value without a key, such as a CapturedStacktrace, is
+// kept. args is only copied when pairs are dropped.
+func (l limits) apply(args []interface{}, fields []Field) ([]interface{}, []Field) {
+	if l.fields <= 0 {
+		return args, fields
+	}
+
+	pairs := len(args) / 2
+	if pairs+len(fields) <= l.fields {
+		return args, fields
+	}
+
+	var trailing []interface{}
+	if len(args)%2 != 0 {
+		trailing = args[len(args)-1:]
+	}
+
+	dropped := pairs + len(fields) - l.fields
+	keep := pairs
+	if keep > l.fields {
+		keep = l.fields
+	}
+	keepFields := l.fields - keep
+
+	limited := make([]interface{}, 0, keep*2+2+len(trailing))
+	limited = append(limited, args[:keep*2]...)
+	limited = append(limited, TruncatedFieldsKey, dropped)
+	limited = append(limited, trailing...)
+	return limited, fields[:keepFields]
+}
+
+// truncateString truncates s to at most max bytes, without splitting a UTF-8
+// sequence, and appends the number of bytes cut. A max of zero or less leaves
+// s as is.
+func truncateString(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + " (truncated " + strconv.Itoa(len(s)-cut) + " bytes)"
+}
@@ -0,0 +1,110 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "short", truncateString("short", 10))
+	assert.Equal(t, "unlimited", truncateString("unlimited", 0))
+	assert.Equal(t, "abc (truncated 3 bytes)", truncateString("abcdef", 3))
+
+	// "é" is two bytes, which a limit of 2 would split.
+	got := truncateString("aébc", 2)
+	assert.Equal(t, "a (truncated 4 bytes)", got)
+	assert.True(t, utf8.ValidString(got))
+
+	// "世" is three bytes, all of which are continuation bytes but the first.
+	for max := 1; max < 6; max++ {
+		assert.True(t, utf8.ValidString(truncateString("世界", max)), "max %d", max)
+	}
+}
+
+func TestLogger_limits(t *testing.T) {
+	t.Run("truncates messages and values in text", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			MaxMessageBytes: 8,
+			MaxFieldBytes:   4,
+		})
+
+		logger.Info("response received", "body", "0123456789", "status", 200)
+		assert.Equal(t, "[INFO]  -- response (truncated 9 bytes): body=\"0123 (truncated 6 bytes)\" status=200\n", buf.String())
+	})
+
+	t.Run("truncates typed fields", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:        &buf,
+			DisableTime:   true,
+			MaxFieldBytes: 3,
+		})
+
+		logger.(FieldLogger).InfoF("response received", Str("body", "abcdef"), Int("status", 200123))
+		assert.Equal(t, "[INFO]  -- response received: body=\"abc (truncated 3 bytes)\" status=200123\n", buf.String())
+	})
+
+	t.Run("drops excess fields", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			MaxFields:   2,
+		}).With("request", 1)
+
+		logger.Info("request", "a", 1, "b", 2, "c", 3)
+		logger.(FieldLogger).InfoF("request", Int("a", 1), Int("b", 2))
+		logger.Info("request", "a", 1)
+
+		expected := "[INFO]  -- request: request=1 a=1 truncated_fields=2\n" +
+			"[INFO]  -- request: request=1 truncated_fields=1 a=1\n" +
+			"[INFO]  -- request: request=1 a=1\n"
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("keeps the stacktrace", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			MaxFields:   1,
+		})
+
+		logger.Error("failed", "a", 1, "b", 2, CapturedStacktrace("stack"))
+		assert.Equal(t, "[ERROR] -- failed: a=1 truncated_fields=1\nstack\n", buf.String())
+	})
+
+	t.Run("writes valid JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			JSONFormat:      true,
+			MaxMessageBytes: 2,
+			MaxFieldBytes:   2,
+			MaxFields:       1,
+		})
+
+		logger.Info("héllo wörld", "body", "wörld\"\\"+strings.Repeat("x", 100), "extra", 1)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "h (truncated 12 bytes)", entry["@message"])
+		assert.Equal(t, "w (truncated 107 bytes)", entry["body"])
+		assert.Equal(t, float64(1), entry[TruncatedFieldsKey])
+		assert.NotContains(t, entry, "extra")
+	})
+}
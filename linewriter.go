@@ -0,0 +1,109 @@
+package hclog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// StreamKey is the key of the field naming the stream, stdout or stderr, of
+// the entries written through StdoutWriter and StderrWriter.
+const StreamKey = "stream"
+
+// maxLineBytes bounds the size of the lines buffered by the writers of
+// NewLineWriter. Longer lines are written as several entries of this size.
+const maxLineBytes = 1 << 20
+
+// errLineWriterClosed is returned by the writers of NewLineWriter once closed.
+var errLineWriterClosed = errors.New("write to closed line writer")
+
+// StdoutWriter returns a writer for the standard output of a child process,
+// writing every line as a Debug entry with stream=stdout:
+//
+//	cmd.Stdout = hclog.StdoutWriter(logger)
+//	cmd.Stderr = hclog.StderrWriter(logger)
+//
+// Close it once the process exited, to write the line it left unterminated.
+func StdoutWriter(l Logger) io.WriteCloser {
+	return NewLineWriter(l.With(StreamKey, "stdout"), Debug)
+}
+
+// StderrWriter returns a writer for the standard error of a child process,
+// writing every line as a Warn entry with stream=stderr. Like StdoutWriter,
+// it must be closed once the process exited.
+func StderrWriter(l Logger) io.WriteCloser {
+	return NewLineWriter(l.With(StreamKey, "stderr"), Warn)
+}
+
+// NewLineWriter returns a writer splitting what is written to it into lines,
+// each written as an entry at level through the StandardWriter of l. Unlike
+// the StandardWriter itself, it doesn't expect every write to be a line, so it
+// suits output written in arbitrary chunks, such as that of a child process.
+// Lines longer than 1 MiB are written as several entries. Close writes the line
+// left unterminated, if any.
+func NewLineWriter(l Logger, level Level) io.WriteCloser {
+	return &lineWriter{
+		w: l.StandardWriter(&StandardLoggerOptions{ForceLevel: level}),
+	}
+}
+
+// lineWriter is the io.WriteCloser returned by NewLineWriter.
+type lineWriter struct {
+	w io.Writer
+
+	mu     sync.Mutex
+	buf    []byte
+	closed bool
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, errLineWriterClosed
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			break
+		}
+		w.buf = append(w.buf, p[:i]...)
+		w.flush()
+		p = p[i+1:]
+	}
+	for len(w.buf) >= maxLineBytes {
+		w.w.Write(w.buf[:maxLineBytes])
+		w.buf = append(w.buf[:0], w.buf[maxLineBytes:]...)
+	}
+	return n, nil
+}
+
+// flush writes the buffered line.
+func (w *lineWriter) flush() {
+	line := bytes.TrimSuffix(w.buf, []byte{'\r'})
+	for len(line) > maxLineBytes {
+		w.w.Write(line[:maxLineBytes])
+		line = line[maxLineBytes:]
+	}
+	w.w.Write(line)
+	w.buf = w.buf[:0]
+}
+
+// Close writes the line left unterminated, if any.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if len(w.buf) > 0 {
+		w.flush()
+	}
+	w.buf = nil
+	return nil
+}
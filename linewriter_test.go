@@ -0,0 +1,84 @@
+package hclog
+
+import (
+	"bytes"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineWriter(t *testing.T) {
+	t.Run("writes an entry per line across writes", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, Level: Trace, DisableTime: true})
+
+		w := StdoutWriter(logger)
+		w.Write([]byte("first li"))
+		w.Write([]byte("ne\r\nsecond line\nthi"))
+		assert.Equal(t,
+			"[DEBUG] -- first line: stream=stdout\n[DEBUG] -- second line: stream=stdout\n",
+			buf.String())
+
+		require.NoError(t, w.Close())
+		assert.Equal(t, "[DEBUG] -- thi: stream=stdout\n", strings.SplitAfterN(buf.String(), "\n", 3)[2])
+
+		_, err := w.Write([]byte("late\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("writes stderr at warn", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		w := StderrWriter(logger)
+		w.Write([]byte("disk almost full\n"))
+		w.Close()
+		assert.Equal(t, "[WARN]  -- disk almost full: stream=stderr\n", buf.String())
+	})
+
+	t.Run("splits very long lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		w := NewLineWriter(logger, Info)
+		long := strings.Repeat("x", maxLineBytes+10)
+		for i := 0; i < len(long); i += 4096 {
+			end := i + 4096
+			if end > len(long) {
+				end = len(long)
+			}
+			w.Write([]byte(long[i:end]))
+		}
+		w.Write([]byte("\n"))
+		w.Close()
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+		assert.Equal(t, "[INFO]  -- "+strings.Repeat("x", maxLineBytes), lines[0])
+		assert.Equal(t, "[INFO]  -- xxxxxxxxxx", lines[1])
+	})
+
+	t.Run("plumbs the output of a child process", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("needs sh")
+		}
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, Level: Debug, DisableTime: true})
+
+		stdout, stderr := StdoutWriter(logger), StderrWriter(logger)
+		cmd := exec.Command("sh", "-c", "echo out; echo err >&2; printf partial")
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		require.NoError(t, cmd.Run())
+		stdout.Close()
+		stderr.Close()
+
+		out := buf.String()
+		assert.Contains(t, out, "[DEBUG] -- out: stream=stdout\n")
+		assert.Contains(t, out, "[WARN]  -- err: stream=stderr\n")
+		assert.Contains(t, out, "[DEBUG] -- partial: stream=stdout\n")
+	})
+}
@@ -0,0 +1,108 @@
+package hclog
+
+import (
+	"runtime"
+	"strings"
+)
+
+// DefaultLocationKey is the key of the location of JSON entries written with
+// StructuredLocation, unless LocationKey is set in the options.
+const DefaultLocationKey = "caller"
+
+// jsonLocation is the location of JSON entries written with
+// StructuredLocation.
+type jsonLocation struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"function,omitempty"`
+}
+
+// newJSONLocation returns the location of a call, as returned by
+// runtime.Caller.
+func newJSONLocation(pc uintptr, file string, line int) jsonLocation {
+	loc := jsonLocation{File: trimCallerPath(file), Line: line}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		loc.Function = fn.Name()
+	}
+	return loc
+}
+
+// locationOverride is the type of WithLocation and NoLocation.
+type locationOverride bool
+
+const (
+	// WithLocation, given among the arguments of a logging method, writes the
+	// location of the call, as IncludeLocation does, whether or not the logger
+	// includes locations. It isn't written itself, and takes no value.
+	WithLocation = locationOverride(true)
+
+	// NoLocation, given among the arguments of a logging method, leaves out
+	// the location of the call, sparing the cost of finding it, whether or
+	// not the logger includes locations. It isn't written itself, and takes
+	// no value.
+	NoLocation = locationOverride(false)
+)
+
+// splitLocation returns args without WithLocation and NoLocation, and the
+// last of them given, if any. args is returned as is if it holds neither.
+func splitLocation(args []interface{}) ([]interface{}, locationOverride, bool) {
+	var (
+		loc   locationOverride
+		found bool
+		rest  []interface{}
+	)
+	for i, arg := range args {
+		l, ok := arg.(locationOverride)
+		if !ok {
+			if found {
+				rest = append(rest, arg)
+			}
+			continue
+		}
+		if !found {
+			rest = append(make([]interface{}, 0, len(args)-1), args[:i]...)
+		}
+		loc, found = l, true
+	}
+	if !found {
+		return args, loc, false
+	}
+	return rest, loc, true
+}
+
+// withLocation returns a copy of l including the location of the entries it
+// writes or not, for an entry given WithLocation or NoLocation. It must be
+// called from log or audit, to find the location of the call.
+func (l *intLogger) withLocation(include bool) *intLogger {
+	if include == (l.callerOffset > 0) {
+		return l
+	}
+	sl := *l
+	if include {
+		sl.callerOffset = locationOffset()
+	} else {
+		sl.callerOffset = 0
+	}
+	return &sl
+}
+
+// locationOffset returns the callerOffset of the call logging an entry, for a
+// logger not including locations, which doesn't track the stack frames added
+// by the loggers wrapping it. The call is the first outside of this package,
+// or in a test of this package.
+func locationOffset() int {
+	pcs := make([]uintptr, 32)
+	// Skip runtime.Callers, locationOffset, withLocation and log, to start
+	// from the logging method, which is one frame up from the caller of
+	// logPlain.
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(4, pcs)])
+	for offset := offsetIntLogger - 1; ; offset++ {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, hclogPackage+".") || strings.HasSuffix(frame.File, "_test.go") {
+			return offset
+		}
+		if !more {
+			return 0
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// argsSink records the args of the entries it accepts.
+type argsSink struct {
+	args [][]interface{}
+}
+
+func (s *argsSink) Accept(name string, level Level, msg string, args ...interface{}) {
+	s.args = append(s.args, args)
+}
+
+func TestLocationOverride(t *testing.T) {
+	t.Run("forces the location", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		logger.Named("raft").Error("failed", "a", 1, WithLocation)
+		_, _, line, _ := runtime.Caller(0)
+		logger.Info("plain", "a", 1)
+		assert.Equal(t,
+			"[ERROR][go-hclog/location_test.go:"+strconv.Itoa(line-1)+"] [module=raft] -- failed: a=1\n"+
+				"[INFO]  -- plain: a=1\n",
+			buf.String())
+	})
+
+	t.Run("suppresses the location", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, IncludeLocation: true})
+
+		logger.Info("hot path", NoLocation, "a", 1)
+		assert.Equal(t, "[INFO]  -- hot path: a=1\n", buf.String())
+	})
+
+	t.Run("forces the location in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, JSONFormat: true})
+
+		logger.Warn("failed", WithLocation)
+		_, file, line, _ := runtime.Caller(0)
+
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		assert.Equal(t, fmt.Sprintf("%s:%d", file, line-1), raw["@caller"])
+		assert.Len(t, raw, 4)
+	})
+
+	t.Run("locates the calls of wrapping loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+		base := New(&LoggerOptions{Output: &buf, DisableTime: true})
+		logger := NewInterceptLogger(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		cases := []struct {
+			name   string
+			log    func()
+			suffix string
+		}{
+			{"derived", func() { base.With("a", 1).Named("raft").Info("entry", WithLocation) }, "-- entry: a=1\n"},
+			{"intercepting", func() { logger.Info("entry", WithLocation) }, "-- entry\n"},
+			{"with middleware", func() {
+				base.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+					opts.Middleware = []func(*Entry) bool{func(*Entry) bool { return true }}
+				}).Info("entry", WithLocation)
+			}, "-- entry\n"},
+			{"audit", func() { base.(Auditor).Audit("entry", WithLocation) }, "-- entry: audit=true\n"},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				buf.Reset()
+				c.log()
+				assert.Contains(t, buf.String(), "[go-hclog/location_test.go:")
+				assert.True(t, strings.HasSuffix(buf.String(), c.suffix), buf.String())
+			})
+		}
+	})
+
+	t.Run("is never written as a field", func(t *testing.T) {
+		var buf bytes.Buffer
+		sink := &argsSink{}
+		logger := NewInterceptLogger(&LoggerOptions{Output: &buf, DisableTime: true, StrictArgs: true})
+		logger.RegisterSink(sink)
+
+		logger.With(NoLocation, "a", 1).Info("entry", NoLocation, "b", 2, WithLocation)
+		assert.NotContains(t, buf.String(), "true")
+		assert.Contains(t, buf.String(), "-- entry: a=1 b=2\n")
+		require.Len(t, sink.args, 1)
+		assert.Equal(t, []interface{}{"a", 1, "b", 2}, sink.args[0])
+	})
+}
+
+func TestSplitLocation(t *testing.T) {
+	args := []interface{}{"a", 1}
+	rest, _, found := splitLocation(args)
+	assert.False(t, found)
+	assert.Equal(t, args, rest)
+
+	rest, loc, found := splitLocation([]interface{}{WithLocation, "a", 1, NoLocation})
+	assert.True(t, found)
+	assert.Equal(t, NoLocation, loc)
+	assert.Equal(t, []interface{}{"a", 1}, rest)
+}
+
+func BenchmarkLocation(b *testing.B) {
+	args := []interface{}{"a", 1, "b", "two"}
+	cases := []struct {
+		name     string
+		location bool
+		arg      interface{}
+	}{
+		{"without location", false, nil},
+		{"with location", true, nil},
+		{"suppressed", true, NoLocation},
+		{"forced", false, WithLocation},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			logger := New(&LoggerOptions{Output: ioutil.Discard, IncludeLocation: c.location})
+			callArgs := args
+			if c.arg != nil {
+				callArgs = append(args[:len(args):len(args)], c.arg)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				logger.Info("entry", callArgs...)
+			}
+		})
+	}
+}
+
+func TestStructuredLocation(t *testing.T) {
+	t.Run("writes the location as an object", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:             &buf,
+			JSONFormat:         true,
+			IncludeLocation:    true,
+			StructuredLocation: true,
+		})
+
+		logger.Info("entry", "caller", "user")
+		_, _, line, _ := runtime.Caller(0)
+
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		assert.Equal(t, map[string]interface{}{
+			"file":     "go-hclog/location_test.go",
+			"line":     float64(line - 1),
+			"function": "github.com/varnson/go-hclog.TestStructuredLocation.func1",
+		}, raw["caller"])
+		assert.Equal(t, "user", raw["field_caller"])
+		assert.NotContains(t, raw, "@caller")
+	})
+
+	t.Run("writes the location under its key", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:             &buf,
+			JSONFormat:         true,
+			StructuredLocation: true,
+			LocationKey:        "logging.googleapis.com/sourceLocation",
+		})
+
+		logger.Info("entry")
+		logger.Warn("entry", WithLocation)
+
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(lines[0], &raw))
+		assert.NotContains(t, raw, "logging.googleapis.com/sourceLocation")
+		require.NoError(t, json.Unmarshal(lines[1], &raw))
+		assert.Contains(t, raw["logging.googleapis.com/sourceLocation"], "function")
+	})
+
+	t.Run("keeps text entries unchanged", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:             &buf,
+			DisableTime:        true,
+			IncludeLocation:    true,
+			StructuredLocation: true,
+		})
+
+		logger.Info("entry", "caller", "user")
+		_, _, line, _ := runtime.Caller(0)
+		assert.Equal(t, "[INFO] [go-hclog/location_test.go:"+strconv.Itoa(line-1)+"] -- entry: field_caller=user\n", buf.String())
+	})
+
+	t.Run("is kept by derived loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:             &buf,
+			JSONFormat:         true,
+			IncludeLocation:    true,
+			StructuredLocation: true,
+			LocationKey:        "src",
+		})
+
+		logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.Name = "raft"
+		}).Info("entry")
+
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		assert.Contains(t, raw["src"], "line")
+	})
+}
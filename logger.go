@@ -1,10 +1,13 @@
 package hclog
 
 import (
+	"context"
 	"io"
 	"log"
 	"os"
+	"reflect"
 	"strings"
+	"time"
 )
 
 var (
@@ -221,6 +224,12 @@ type LoggerOptions struct {
 	// The threshold for the logger. Anything less severe is supressed
 	Level Level
 
+	// LevelVar holds the level of the logger, so that setting the variable
+	// changes the level of the logger and SetLevel changes the variable. Its
+	// level takes precedence over Level, unless it is NoLevel, in which case
+	// it is set to Level.
+	LevelVar *LevelVar
+
 	// Where to write the logs to. Defaults to os.Stderr if nil
 	Output io.Writer
 
@@ -232,9 +241,36 @@ type LoggerOptions struct {
 	// Control if the output should be in JSON.
 	JSONFormat bool
 
-	// Include file and line information in each log line
+	// Include file and line information in each log line. WithLocation and
+	// NoLocation, given to a logging method, override it for that entry.
 	IncludeLocation bool
 
+	// StructuredLocation writes the location of JSON entries as an object
+	// under LocationKey, rather than as a file:line string under "@caller":
+	//
+	//	"caller":{"file":"pkg/foo.go","line":42,"function":"example.com/pkg.Foo"}
+	//
+	// Key/value pairs using LocationKey are written with the collision
+	// prefix, in both formats, like those using the keys of the logger. Text
+	// entries keep the file:line of the location.
+	StructuredLocation bool
+
+	// LocationKey is the key of the location written with StructuredLocation,
+	// DefaultLocationKey if empty.
+	LocationKey string
+
+	// OrderJSONKeys writes the keys of JSON entries in the order they are
+	// logged in, rather than sorted as encoding/json sorts the keys of maps:
+	// first "@timestamp", "@level", "@module", "@message" and the location,
+	// then the implied arguments, the arguments and the fields of the entry,
+	// a key given more than once keeping its first place and its last value,
+	// and last the other keys written by the logger, such as "stacktrace".
+	OrderJSONKeys bool
+
+	// DisableScopeChecks makes Scope the same as With, sparing the check of
+	// the scope of each entry, for production builds.
+	DisableScopeChecks bool
+
 	// The time format to use instead of the default
 	TimeFormat string
 
@@ -242,6 +278,13 @@ type LoggerOptions struct {
 	// because setting TimeFormat to empty assumes the default format.
 	DisableTime bool
 
+	// TimeEncoding is how the JSON format writes the time of the entries,
+	// and the time.Time values logged, for consistency. Defaults to
+	// TimeRFC3339 strings; the others write numbers since the Unix epoch,
+	// which are cheaper to ingest at volume. TimeFormat and DisableTime
+	// only apply to the text format.
+	TimeEncoding TimeEncoding
+
 	// Color the output. On Windows, colored logs are only avaiable for io.Writers that
 	// are concretely instances of *os.File.
 	Color ColorOption
@@ -257,6 +300,177 @@ type LoggerOptions struct {
 	// logger will not effect any subloggers, and SetLevel on any subloggers
 	// will not effect the parent or sibling loggers.
 	IndependentLevels bool
+
+	// LevelOverrides sets the level of named loggers, by their full name as
+	// returned by Name, in place of Level. An override also applies to the
+	// subloggers of the logger it names, the longest matching name winning, so
+	// that "raft" covers "raft.fsm" unless "raft.fsm" has an override of its own.
+	LevelOverrides map[string]Level
+
+	// ExcludeNames drops the entries of the loggers of which the full name
+	// matches one of the patterns, before they are formatted. A pattern is
+	// either a glob, as in "noisy-*", or a name, which also matches the
+	// subloggers of the logger of that name.
+	ExcludeNames []string
+
+	// IncludeOnlyNames drops the entries of the loggers of which the full name
+	// matches none of the patterns, given like with ExcludeNames. Leaving it
+	// empty includes every logger. ExcludeNames applies first, so a logger
+	// matching both is dropped.
+	IncludeOnlyNames []string
+
+	// NamePadding pads the "[module=name]" of text entries so that names of
+	// this many bytes or fewer line up, and the messages of the entries with
+	// them, those without a name included. AutoNamePadding pads to the
+	// longest name written so far by the logger and the loggers derived from
+	// it, so alignment only settles once the longest name has been written:
+	// the entries written before are left as they are. Zero, the default,
+	// doesn't pad.
+	NamePadding int
+
+	// BracketLevelFirst writes text entries in the layout of the loggers
+	// filtered with logutils.LevelFilter, the level bracketed first after the
+	// time, as in:
+	//
+	//	2024-06-10T06:13:20.000Z [WARN] raft: heartbeat failed peer=10.0.0.2
+	//
+	// The levels are written as TRACE, DEBUG, INFO, WARN and ERR, the location
+	// of IncludeLocation follows the level as file:line:, and the name is
+	// followed by a colon. NamePadding doesn't apply. The filters of
+	// the logger package, LogFile's included, and the inferred levels of
+	// StandardLogger read the levels of this layout.
+	BracketLevelFirst bool
+
+	// NormalizeKeys rewrites the keys of key/value pairs before they are
+	// written, in both formats. The default leaves them unchanged.
+	NormalizeKeys KeyNormalization
+
+	// KeyCollisionPrefix is prepended to the keys of key/value pairs which
+	// collide with the keys written by the logger itself, such as "@message"
+	// and "@level", so that they neither replace nor duplicate them. It
+	// defaults to DefaultKeyCollisionPrefix.
+	KeyCollisionPrefix string
+
+	// MaxMessageBytes truncates messages longer than this many bytes, and
+	// appends how many were cut, as in "(truncated 120 bytes)". Zero means no
+	// limit.
+	MaxMessageBytes int
+
+	// MaxFieldBytes truncates the values of key/value pairs like
+	// MaxMessageBytes does messages. In JSON, it applies to the values written
+	// as strings. Zero means no limit.
+	MaxFieldBytes int
+
+	// MaxFields drops the key/value pairs of an entry beyond this many,
+	// implied arguments first, and writes how many were dropped under
+	// TruncatedFieldsKey. Zero means no limit.
+	MaxFields int
+
+	// AllowRawNewlines writes the newlines of messages and values as they are
+	// in the text format, rather than escaped as \n and \r, for output relying
+	// on multi-line entries. Escaping keeps values from forging log lines, and
+	// other control characters are escaped regardless. Stack traces are always
+	// written on lines of their own.
+	AllowRawNewlines bool
+
+	// ValueFormatters format the values of key/value pairs of the given types
+	// before they are written, the value returned being written as usual. A
+	// formatter registered for an interface type applies to the values
+	// implementing it, unless their own type has a formatter. Values returned
+	// by a formatter are formatted again, up to a few times, if their type has
+	// a formatter too.
+	//
+	//	ValueFormatters: map[reflect.Type]func(interface{}) interface{}{
+	//		reflect.TypeOf(Money{}): func(v interface{}) interface{} {
+	//			return v.(Money).Compact()
+	//		},
+	//	}
+	ValueFormatters map[reflect.Type]func(interface{}) interface{}
+
+	// AdaptToJournald adapts the output to journald when Output is the stream
+	// journald connected to the process, as told by JOURNAL_STREAM: the time,
+	// which journald records, is left out, and each line is prefixed with the
+	// syslog priority of its level, such as <3> for errors, so that journalctl
+	// can filter by priority. Other outputs are formatted as usual.
+	AdaptToJournald bool
+
+	// OmitKeys drops the key/value pairs of these keys, implied or given with
+	// the entry, before they are formatted. The keys written by the logger
+	// itself, such as the time and level, are never dropped.
+	OmitKeys []string
+
+	// OnlyKeys drops the key/value pairs of any other keys, like OmitKeys. A
+	// key in both is dropped.
+	OnlyKeys []string
+
+	// Middleware are called in order with every entry let through by the
+	// level and the name filters, before Exclude and before it is encoded,
+	// to enrich, rewrite or drop it: an entry is dropped once one of them
+	// returns false, unless it is an Audit entry. They are inherited by the
+	// loggers derived from this one, see Entry.
+	Middleware []func(e *Entry) (keep bool)
+
+	// SuppressErrors demotes to Debug the entries of which an error value
+	// matches one of these errors with errors.Is, whatever its key, such as
+	// the context.Canceled errors logged on shutdown. The entries matching an
+	// error wrapped with DropError are dropped instead. Only the values given
+	// with the entry are checked, not the implied arguments of the logger.
+	SuppressErrors []error
+
+	// ExitHooks are run in order by Fatal before it exits the process, to
+	// flush sinks or write a crash marker. Fatal waits for them up to
+	// ExitHookTimeout, which defaults to DefaultExitHookTimeout.
+	ExitHooks       []func()
+	ExitHookTimeout time.Duration
+
+	// ExitCode is the status Fatal exits the process with. Defaults to 1.
+	ExitCode int
+
+	// Sequence writes a sequence number under SeqKey first in every entry,
+	// counting from 1 the entries written by the logger and the loggers
+	// derived from it, whatever their output, so that gaps and reordering
+	// can be detected once entries are collected. Entries are numbered as
+	// they are written, after the level, the filters and Middleware, so
+	// dropped entries don't leave gaps. Loggers created with WithOptions
+	// keep counting. OmitKeys and OnlyKeys don't apply to the sequence
+	// number. The sinks of an InterceptLogger are loggers of their own,
+	// which number the entries they accept if created with Sequence.
+	Sequence bool
+
+	// EntryIDs writes a random 128-bit ID under EntryIDKey in every entry,
+	// after the sequence number, to deduplicate entries collected through
+	// several paths. OmitKeys and OnlyKeys don't apply to it either.
+	EntryIDs bool
+
+	// RegisterNamed records the loggers created with Named and ResetNamed
+	// from the logger and the loggers derived from it, so that they can be
+	// listed with their levels, and looked up by name, through the
+	// LoggerRegistry interface. They are recorded by name, the last one
+	// created under a name replacing the one before, so that the registry
+	// only grows with the names used: loggers given a name of their own,
+	// such as one per connection, should be released once done with.
+	RegisterNamed bool
+
+	// StrictArgs panics with an *ArgsError on key/value pairs which are
+	// otherwise written as best they can: an odd number of arguments, written
+	// under MissingKey, a key other than a string, or a key given twice,
+	// including the keys implied by With. The error holds the file:line of
+	// the call. This is meant for development and tests, to fail loudly on
+	// mistakes, and costs nothing when off.
+	StrictArgs bool
+
+	// seqCounter is the counter of Sequence kept by WithOptions.
+	seqCounter *uint64
+
+	// registry is the registry of RegisterNamed kept by WithOptions.
+	registry *loggerRegistry
+
+	// shutdown is the state of Shutdown kept by WithOptions.
+	shutdown *shutdownState
+
+	// envWarning reports the malformed environment variables found by
+	// DefaultOptionsFromEnv.
+	envWarning *envWarning
 }
 
 // InterceptLogger describes the interface for using a logger
@@ -298,6 +512,22 @@ type SinkAdapter interface {
 	Accept(name string, level Level, msg string, args ...interface{})
 }
 
+// EntrySink is implemented by sinks which take the entries of an
+// InterceptLogger as an Entry, built once for all of them, rather than through
+// Accept. The sinks returned by NewSinkAdapter and NewEncoderSink implement it,
+// so that each of them filters the entries by its own level and writes them in
+// its own format. The Entry is shared by the sinks: it must not be changed,
+// nor kept beyond the call.
+type EntrySink interface {
+	AcceptEntry(e *Entry)
+}
+
+// Encoder writes entries in a format of its own, for the sinks returned by
+// NewEncoderSink.
+type Encoder interface {
+	Encode(w io.Writer, e *Entry) error
+}
+
 // Flushable represents a method for flushing an output buffer. It can be used
 // if Resetting the log to use a new output, in order to flush the writes to
 // the existing output beforehand.
@@ -317,6 +547,68 @@ type OutputResettable interface {
 	ResetOutputWithFlush(opts *LoggerOptions, flushable Flushable) error
 }
 
+// Reconfigurable is implemented by loggers which can derive a logger with
+// different options, such as a JSON logger for a subsystem feeding a machine
+// pipeline while the rest logs text.
+type Reconfigurable interface {
+	// WithOptions returns a new logger with the name and implied arguments of
+	// this logger, and its options as changed by modify.
+	WithOptions(modify func(opts *LoggerOptions)) Logger
+}
+
+// LevelOverridable is implemented by loggers of which the level of named
+// subloggers can be overridden at runtime.
+type LevelOverridable interface {
+	// SetLevelOverride sets the level of the loggers named name and their
+	// subloggers, or removes the override if level is NoLevel.
+	SetLevelOverride(name string, level Level)
+
+	// LevelOverrides returns a copy of the level overrides in effect.
+	LevelOverrides() map[string]Level
+}
+
+// Auditor is implemented by loggers which can write audit entries, for
+// security relevant events which must be written whatever the configuration.
+type Auditor interface {
+	// Audit writes an entry tagged with the AuditKey field, bypassing the
+	// level, level overrides, name filters and Exclude, and returns an error
+	// if the entry couldn't be written.
+	Audit(msg string, args ...interface{}) error
+}
+
+// Shutdowner is implemented by loggers which can be shut down, flushing and
+// closing their output and sinks, before the process exits.
+type Shutdowner interface {
+	// Shutdown stops the logger and the loggers derived from it from writing
+	// entries, then flushes and closes the output and sinks. It returns the
+	// first error, or the error of ctx if it is done first, and is safe to
+	// call more than once.
+	Shutdown(ctx context.Context) error
+}
+
+// Capturer is implemented by loggers which can copy their entries to another
+// writer for a while, as InterceptLogger does.
+type Capturer interface {
+	// CaptureWindow writes the entries at level or above to w as well, for d
+	// or until stop is called, whichever comes first.
+	CaptureWindow(level Level, w io.Writer, d time.Duration) (stop func())
+}
+
+// Scoper is implemented by loggers which can derive a logger for a scope, such
+// as a request, flagging its entries once the scope is released.
+type Scoper interface {
+	// Scope returns a logger implying args, as With does, and the function
+	// releasing its scope.
+	Scope(args ...interface{}) (Logger, func())
+}
+
+// LevelGetter is implemented by loggers which can report their level.
+type LevelGetter interface {
+	// GetLevel returns the level of the logger, taking level overrides into
+	// account.
+	GetLevel() Level
+}
+
 // Locker is used for locking output. If not set when creating a logger, a
 // sync.Mutex will be used internally.
 type Locker interface {
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sync"
+	"sync/atomic"
+
+	hclog "github.com/varnson/go-hclog"
+)
+
+var (
+	_ io.Writer         = (*FilterWriter)(nil)
+	_ hclog.LevelWriter = (*FilterWriter)(nil)
+	_ hclog.Flushable   = (*FilterWriter)(nil)
+)
+
+// maxLineBytes bounds the size of the lines held back by FilterWriter. Longer
+// lines are filtered as several lines of this size.
+const maxLineBytes = 1 << 20
+
+// FilterWriter is an io.Writer dropping the lines matching patterns, for
+// output of which only the bytes can be filtered, such as that of a child
+// process written to a LogFile:
+//
+//	noise := []*regexp.Regexp{regexp.MustCompile(`health check`)}
+//	w := logger.NewFilterWriter(logFile, noise, nil)
+//
+// Lines written over several calls to Write are held back until they are
+// complete, and then passed or dropped as a whole, up to maxLineBytes. Flush
+// writes out a line left incomplete.
+type FilterWriter struct {
+	writer io.Writer
+	drop   []*regexp.Regexp
+	keep   []*regexp.Regexp
+
+	// lock guards pending, the start of a line not complete yet.
+	lock    sync.Mutex
+	pending []byte
+
+	dropped uint64
+}
+
+// NewFilterWriter returns a FilterWriter writing to w the lines matching none
+// of the drop patterns. If keep patterns are given, the lines must also match
+// one of them, a line matching both a drop and a keep pattern being dropped.
+func NewFilterWriter(w io.Writer, drop, keep []*regexp.Regexp) *FilterWriter {
+	return &FilterWriter{writer: w, drop: drop, keep: keep}
+}
+
+// Dropped returns the number of lines dropped.
+func (f *FilterWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+// Write writes the complete lines of p passing the filter, holding back the
+// final line of p if it isn't complete. This implements io.Writer.
+func (f *FilterWriter) Write(p []byte) (n int, err error) {
+	return f.LevelWrite(hclog.NoLevel, p)
+}
+
+// LevelWrite is like Write, passing the level on to the writer if it
+// implements hclog.LevelWriter. This implements hclog.LevelWriter.
+func (f *FilterWriter) LevelWrite(level hclog.Level, p []byte) (n int, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	data := p
+	if len(f.pending) > 0 {
+		data = append(f.pending, p...)
+		f.pending = nil
+	}
+
+	var out []byte
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			for len(data) >= maxLineBytes {
+				if f.passes(data[:maxLineBytes]) {
+					out = append(out, data[:maxLineBytes]...)
+				}
+				data = data[maxLineBytes:]
+			}
+			f.pending = append([]byte(nil), data...)
+			break
+		}
+		line := data[:i+1]
+		data = data[i+1:]
+		if f.passes(line[:i]) {
+			out = append(out, line...)
+		}
+	}
+
+	if len(out) > 0 {
+		if _, err := levelWrite(f.writer, level, out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes out the line held back by Write if it passes the filter, and
+// flushes the writer if it implements hclog.Flushable. This implements
+// hclog.Flushable.
+func (f *FilterWriter) Flush() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if len(f.pending) > 0 {
+		line := f.pending
+		f.pending = nil
+		if f.passes(line) {
+			if _, err := f.writer.Write(line); err != nil {
+				return err
+			}
+		}
+	}
+	if fl, ok := f.writer.(hclog.Flushable); ok {
+		return fl.Flush()
+	}
+	return nil
+}
+
+// passes reports whether line passes the filter, counting it as dropped if
+// not.
+func (f *FilterWriter) passes(line []byte) bool {
+	for _, re := range f.drop {
+		if re.Match(line) {
+			atomic.AddUint64(&f.dropped, 1)
+			return false
+		}
+	}
+	if len(f.keep) == 0 {
+		return true
+	}
+	for _, re := range f.keep {
+		if re.Match(line) {
+			return true
+		}
+	}
+	atomic.AddUint64(&f.dropped, 1)
+	return false
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+	hclog "github.com/varnson/go-hclog"
+)
+
+func TestFilterWriter(t *testing.T) {
+	drop := []*regexp.Regexp{regexp.MustCompile(`health check`)}
+	keep := []*regexp.Regexp{regexp.MustCompile(`^\[(WARN|ERROR)\]`)}
+
+	cases := []struct {
+		name   string
+		drop   []*regexp.Regexp
+		keep   []*regexp.Regexp
+		writes []string
+		expect string
+	}{
+		{
+			name:   "drop",
+			drop:   drop,
+			writes: []string{"[INFO] started\n[INFO] health check\n", "[WARN] slow\n"},
+			expect: "[INFO] started\n[WARN] slow\n",
+		},
+		{
+			name:   "keep",
+			keep:   keep,
+			writes: []string{"[INFO] started\n[WARN] slow\n[ERROR] failed\n"},
+			expect: "[WARN] slow\n[ERROR] failed\n",
+		},
+		{
+			name:   "drop wins over keep",
+			drop:   drop,
+			keep:   keep,
+			writes: []string{"[WARN] health check failed\n[WARN] slow\n"},
+			expect: "[WARN] slow\n",
+		},
+		{
+			name:   "lines split across writes",
+			drop:   drop,
+			writes: []string{"[INFO] hea", "lth check\n[INFO] st", "arted\n"},
+			expect: "[INFO] started\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f := NewFilterWriter(&buf, tc.drop, tc.keep)
+			for _, w := range tc.writes {
+				n, err := f.Write([]byte(w))
+				if err != nil {
+					t.Fatalf("err: %v", err)
+				}
+				if n != len(w) {
+					t.Fatalf("expected %d bytes written, got %d", len(w), n)
+				}
+			}
+			if got := buf.String(); got != tc.expect {
+				t.Fatalf("expected %q, got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestFilterWriter_Dropped(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewFilterWriter(&buf, []*regexp.Regexp{regexp.MustCompile(`noise`)}, nil)
+	f.Write([]byte("noise\nsignal\nnoise\n"))
+	if got := f.Dropped(); got != 2 {
+		t.Fatalf("expected 2 lines dropped, got %d", got)
+	}
+}
+
+func TestFilterWriter_Flush(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewFilterWriter(&buf, []*regexp.Regexp{regexp.MustCompile(`noise`)}, nil)
+
+	f.Write([]byte("incomplete"))
+	if buf.Len() != 0 {
+		t.Fatalf("expected the incomplete line to be held back, got %q", buf.String())
+	}
+	if err := f.Flush(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got := buf.String(); got != "incomplete" {
+		t.Fatalf("expected %q, got %q", "incomplete", got)
+	}
+
+	f.Write([]byte("more noise"))
+	f.Flush()
+	if got := buf.String(); got != "incomplete" {
+		t.Fatalf("expected the incomplete noise to be dropped, got %q", got)
+	}
+}
+
+func TestFilterWriter_longLine(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewFilterWriter(&buf, nil, nil)
+
+	long := bytes.Repeat([]byte("a"), maxLineBytes+3)
+	f.Write(long[:10])
+	f.Write(long[10:])
+	if buf.Len() != maxLineBytes || len(f.pending) != 3 {
+		t.Fatalf("expected %d bytes written and 3 held back, got %d and %d", maxLineBytes, buf.Len(), len(f.pending))
+	}
+	f.Write([]byte("\n"))
+	if got := buf.String(); got != string(long)+"\n" {
+		t.Fatalf("expected the long line, got %d bytes", len(got))
+	}
+}
+
+func TestFilterWriter_LogFile(t *testing.T) {
+	tempDir := testutil.TempDir(t, "FilterWriter")
+	defer os.RemoveAll(tempDir)
+	logFile, err := NewLogFile(LogFileOptions{Path: tempDir, FileName: testFileName})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f := NewFilterWriter(logFile, []*regexp.Regexp{regexp.MustCompile(`health check`)}, nil)
+
+	logger := hclog.New(&hclog.LoggerOptions{Output: f, DisableTime: true})
+	logger.Info("health check")
+	logger.Info("started")
+	logFile.Close()
+
+	content, err := ioutil.ReadFile(filepath.Join(tempDir, testFileName))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got := string(content); got != "[INFO]  -- started\n" {
+		t.Fatalf("expected %q, got %q", "[INFO]  -- started\n", got)
+	}
+}
@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	hclog "github.com/varnson/go-hclog"
+)
+
+// defaultLevels are the levels we use, from the least to the most severe.
+var defaultLevels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERR"}
+
+// levelChecker is implemented by the filters a LogFile can be configured
+// with: levelFilter, and logutils.LevelFilter for the filters shared with
+// Setup and the callers of LevelFilter.
+type levelChecker interface {
+	Check(line []byte) bool
+}
+
+var (
+	_ levelChecker    = (*levelFilter)(nil)
+	_ io.Writer       = (*levelFilter)(nil)
+	_ hclog.Flushable = (*levelFilter)(nil)
+)
+
+// levelFilter filters log lines by their level, read from the first
+// bracketed token of the line as in "[WARN] message". Lines at or above the
+// minimum level pass, as do lines without a bracketed token or with one that
+// isn't a level, exactly like with logutils.LevelFilter.
+//
+// Unlike logutils, the minimum level can be changed while the filter is in
+// use, and a line written over several calls to Write is passed or dropped as
+// a whole: its start is held back until the level can be read, and the rest
+// follows the decision made for it.
+type levelFilter struct {
+	// levels are the known levels, from the least to the most severe.
+	levels []string
+
+	// min is the index in levels of the minimum level, accessed atomically.
+	min int32
+
+	// writer receives the lines which pass the filter.
+	writer io.Writer
+
+	// lock guards the state of the line being written.
+	lock sync.Mutex
+
+	// pending holds the start of a line of which the level can't be read yet.
+	pending []byte
+
+	// midLine is set when the last write didn't end its line, and drop when
+	// that line is being dropped.
+	midLine bool
+	drop    bool
+}
+
+// newLevelFilter returns a filter over levels, which must be ordered from the
+// least to the most severe, passing minLevel and above to w.
+func newLevelFilter(levels []string, minLevel string, w io.Writer) (*levelFilter, error) {
+	f := &levelFilter{levels: levels, writer: w}
+	if err := f.SetMinLevel(minLevel); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// index returns the position of level in the levels of f, and -1 for
+// unknown levels.
+func (f *levelFilter) index(level string) int {
+	for i, l := range f.levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetMinLevel changes the minimum level to the level named level. It is safe
+// to call while the filter is in use.
+func (f *levelFilter) SetMinLevel(level string) error {
+	i := f.index(level)
+	if i < 0 {
+		return fmt.Errorf("invalid log level %q, valid log levels are %v", level, f.levels)
+	}
+	atomic.StoreInt32(&f.min, int32(i))
+	return nil
+}
+
+// SetLevel changes the minimum level to an hclog level.
+func (f *levelFilter) SetLevel(level hclog.Level) error {
+	name, ok := levelName(level)
+	if !ok {
+		return fmt.Errorf("invalid log level %q, valid log levels are %v", level, f.levels)
+	}
+	return f.SetMinLevel(name)
+}
+
+// MinLevel returns the name of the minimum level.
+func (f *levelFilter) MinLevel() string {
+	return f.levels[atomic.LoadInt32(&f.min)]
+}
+
+// Check reports whether line passes the filter.
+func (f *levelFilter) Check(line []byte) bool {
+	level, _ := bracketLevel(line)
+	return f.allows(level)
+}
+
+// CheckLevel reports whether entries at an hclog level pass the filter.
+// Levels without a name, such as hclog.NoLevel, pass like unknown levels.
+func (f *levelFilter) CheckLevel(level hclog.Level) bool {
+	name, _ := levelName(level)
+	return f.allows(name)
+}
+
+func (f *levelFilter) allows(level string) bool {
+	i := f.index(level)
+	return i < 0 || i >= int(atomic.LoadInt32(&f.min))
+}
+
+// Write writes p to the writer of f unless the line it belongs to is below
+// the minimum level. This implements io.Writer.
+func (f *levelFilter) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.midLine {
+		f.midLine = p[len(p)-1] != '\n'
+		if f.drop {
+			return len(p), nil
+		}
+		return f.writer.Write(p)
+	}
+
+	line := p
+	if len(f.pending) > 0 {
+		f.pending = append(f.pending, p...)
+		line = f.pending
+	}
+	if _, ok := bracketLevel(line); !ok && bytes.IndexByte(line, '\n') < 0 {
+		// The level may still be in the rest of the line.
+		if len(f.pending) == 0 {
+			f.pending = append(f.pending, p...)
+		}
+		return len(p), nil
+	}
+	f.pending = nil
+
+	f.drop = !f.Check(line)
+	f.midLine = line[len(line)-1] != '\n'
+	if f.drop {
+		return len(p), nil
+	}
+	if _, err := f.writer.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes out the start of a line held back by Write, which passes the
+// filter since it has no level. This implements hclog.Flushable.
+func (f *levelFilter) Flush() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if len(f.pending) == 0 {
+		return nil
+	}
+	line := f.pending
+	f.pending = nil
+	f.midLine, f.drop = true, false
+	_, err := f.writer.Write(line)
+	return err
+}
+
+// bracketLevel returns the first bracketed token of line, as checked by
+// logutils, and false if line has none.
+func bracketLevel(line []byte) (string, bool) {
+	x := bytes.IndexByte(line, '[')
+	if x < 0 {
+		return "", false
+	}
+	y := bytes.IndexByte(line[x:], ']')
+	if y < 0 {
+		return "", false
+	}
+	return string(line[x+1 : x+y]), true
+}
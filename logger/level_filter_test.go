@@ -0,0 +1,244 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/logutils"
+	hclog "github.com/varnson/go-hclog"
+)
+
+func TestLevelFilter_impl(t *testing.T) {
+	var _ io.Writer = new(levelFilter)
+}
+
+func TestLevelFilter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	filter, err := newLevelFilter([]string{"DEBUG", "WARN", "ERROR"}, "WARN", buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	logger := log.New(filter, "", 0)
+	logger.Print("[WARN] foo")
+	logger.Println("[ERROR] bar")
+	logger.Println("[DEBUG] baz")
+	logger.Println("[WARN] buzz")
+
+	result := buf.String()
+	expected := "[WARN] foo\n[ERROR] bar\n[WARN] buzz\n"
+	if result != expected {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestLevelFilterCheck(t *testing.T) {
+	filter, err := newLevelFilter([]string{"DEBUG", "WARN", "ERROR"}, "WARN", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	testCases := []struct {
+		line  string
+		check bool
+	}{
+		{"[WARN] foo\n", true},
+		{"[ERROR] bar\n", true},
+		{"[DEBUG] baz\n", false},
+		{"[WARN] buzz\n", true},
+	}
+
+	for _, testCase := range testCases {
+		result := filter.Check([]byte(testCase.line))
+		if result != testCase.check {
+			t.Errorf("Fail: %s", testCase.line)
+		}
+	}
+}
+
+func TestLevelFilter_SetMinLevel(t *testing.T) {
+	filter, err := newLevelFilter([]string{"DEBUG", "WARN", "ERROR"}, "ERROR", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	testCases := []struct {
+		line        string
+		checkBefore bool
+		checkAfter  bool
+	}{
+		{"[WARN] foo\n", false, true},
+		{"[ERROR] bar\n", true, true},
+		{"[DEBUG] baz\n", false, false},
+		{"[WARN] buzz\n", false, true},
+	}
+
+	for _, testCase := range testCases {
+		result := filter.Check([]byte(testCase.line))
+		if result != testCase.checkBefore {
+			t.Errorf("Fail: %s", testCase.line)
+		}
+	}
+
+	// Update the minimum level to WARN
+	if err := filter.SetMinLevel("WARN"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, testCase := range testCases {
+		result := filter.Check([]byte(testCase.line))
+		if result != testCase.checkAfter {
+			t.Errorf("Fail: %s", testCase.line)
+		}
+	}
+
+	if err := filter.SetMinLevel("INFO"); err == nil {
+		t.Errorf("Expected an error for an unknown level")
+	}
+	if filter.MinLevel() != "WARN" {
+		t.Errorf("Expected the minimum level to stay WARN, got %s", filter.MinLevel())
+	}
+}
+
+func TestLevelFilter_logutilsParity(t *testing.T) {
+	lines := []string{
+		"[TRACE] trace\n",
+		"[DEBUG] debug\n",
+		"[INFO] info\n",
+		"[WARN] warn\n",
+		"[ERR] err\n",
+		"[ERROR] error\n",
+		"2020/01/01 00:00:00 [DEBUG] with a timestamp\n",
+		"no level\n",
+		"[] empty\n",
+		"[DEBUG without a closing bracket\n",
+		"] [DEBUG] closing bracket first\n",
+		"[OTHER] [DEBUG] unknown level first\n",
+		"first\n[DEBUG] second line\n",
+		"[debug] lower case\n",
+		"",
+	}
+	for _, min := range defaultLevels {
+		compat := LevelFilter()
+		compat.MinLevel = logutils.LogLevel(min)
+		filter, err := newLevelFilter(defaultLevels, min, nil)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		for _, line := range lines {
+			if got, want := filter.Check([]byte(line)), compat.Check([]byte(line)); got != want {
+				t.Errorf("%s: expected %v for %q, got %v", min, want, line, got)
+			}
+		}
+	}
+}
+
+func TestLevelFilter_partialLines(t *testing.T) {
+	buf := new(bytes.Buffer)
+	filter, err := newLevelFilter(defaultLevels, "INFO", buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	writes := []string{
+		"[DEBUG] dropped ", "across writes\n",
+		"[IN", "FO] kept ", "across writes\n",
+		"[DE", "BUG] split prefix\n",
+		"no level, ", "still kept\n",
+		"[WARN] kept\n[DEBUG] in the same write\n",
+	}
+	for _, w := range writes {
+		if n, err := filter.Write([]byte(w)); err != nil || n != len(w) {
+			t.Fatalf("Expected %d bytes written, got %d (%v)", len(w), n, err)
+		}
+	}
+
+	want := "[INFO] kept across writes\nno level, still kept\n[WARN] kept\n[DEBUG] in the same write\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestLevelFilter_Flush(t *testing.T) {
+	buf := new(bytes.Buffer)
+	filter, err := newLevelFilter(defaultLevels, "INFO", buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	filter.Write([]byte("no level yet"))
+	if buf.Len() != 0 {
+		t.Errorf("Expected the start of the line to be held back, got %q", buf.String())
+	}
+	if err := filter.Flush(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	filter.Write([]byte(" [DEBUG] rest\n"))
+
+	want := "no level yet [DEBUG] rest\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestLevelFilter_hclogLevels(t *testing.T) {
+	filter, err := newLevelFilter(defaultLevels, "INFO", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if filter.CheckLevel(hclog.Debug) {
+		t.Errorf("Expected debug entries to be filtered")
+	}
+	if !filter.CheckLevel(hclog.Error) {
+		t.Errorf("Expected error entries to pass")
+	}
+	if !filter.CheckLevel(hclog.NoLevel) {
+		t.Errorf("Expected entries without a level to pass")
+	}
+
+	if err := filter.SetLevel(hclog.Error); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if filter.MinLevel() != "ERR" {
+		t.Errorf("Expected ERR, got %s", filter.MinLevel())
+	}
+	if filter.Check([]byte("[WARN] warn\n")) {
+		t.Errorf("Expected warn entries to be filtered")
+	}
+	if err := filter.SetLevel(hclog.Off); err == nil {
+		t.Errorf("Expected an error for a level without a name")
+	}
+}
+
+func TestLevelFilter_bracketLevelFirst(t *testing.T) {
+	buf := new(bytes.Buffer)
+	filter, err := newLevelFilter(defaultLevels, "WARN", buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Output:            filter,
+		Level:             hclog.Trace,
+		BracketLevelFirst: true,
+	})
+	logger.Named("raft").Info("info")
+	logger.Named("raft").Warn("warn")
+	logger.Debug("debug")
+	logger.Error("error", "a", 1)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	if !strings.HasSuffix(lines[0], " [WARN] raft: warn") {
+		t.Errorf("bad: %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " [ERR] error a=1") {
+		t.Errorf("bad: %q", lines[1])
+	}
+}
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	hclog "github.com/varnson/go-hclog"
+)
+
+// LevelSplitWriter is an io.Writer that routes entries at or above a level to
+// a second writer, typically a LogFile of its own with separate rotation and
+// retention, so that errors can be read without going through every entry.
+// Entries are written to both writers, or only to Split if Exclusive is set.
+type LevelSplitWriter struct {
+	// Main receives the entries below Level, and all entries unless
+	// Exclusive is set.
+	Main io.Writer
+
+	// Split receives the entries at or above Level.
+	Split io.Writer
+
+	// Exclusive writes the entries at or above Level only to Split.
+	Exclusive bool
+
+	filter *levelFilter
+}
+
+// NewLevelSplitWriter returns a LevelSplitWriter sending entries at or above
+// level to split.
+func NewLevelSplitWriter(main, split io.Writer, level string, exclusive bool) (*LevelSplitWriter, error) {
+	filter, err := newLevelFilter(defaultLevels, filterLevel(level), ioutil.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %q, valid log levels are %v", level, defaultLevels)
+	}
+	return &LevelSplitWriter{
+		Main:      main,
+		Split:     split,
+		Exclusive: exclusive,
+		filter:    filter,
+	}, nil
+}
+
+// SplitFileName returns the name of the file holding the entries split off
+// from fileName, as in name-error.log for name.log.
+func SplitFileName(fileName string) string {
+	ext := filepath.Ext(fileName)
+	if ext == "" {
+		ext = ".log"
+	}
+	return strings.TrimSuffix(fileName, ext) + "-error" + ext
+}
+
+// isSplitFile reports whether name is the file holding the entries split off
+// from this LogFile, or one of its rotated files, which match the file name
+// pattern of this LogFile but aren't part of its retention.
+func (l *LogFile) isSplitFile(name string) bool {
+	split := SplitFileName(l.fileName)
+	ext := filepath.Ext(split)
+	return name == split || strings.HasPrefix(name, strings.TrimSuffix(split, ext)+"-")
+}
+
+func (w *LevelSplitWriter) Write(p []byte) (n int, err error) {
+	// Entries without a level stay in the main writer, unlike with the level
+	// filter which lets them through.
+	name, ok := entryLevel(p)
+	return w.write(hclog.NoLevel, name, ok, p)
+}
+
+// LevelWrite routes an entry of which the logger already knows the level,
+// without parsing p, and passes the level on to the writers implementing
+// hclog.LevelWriter. This implements hclog.LevelWriter.
+func (w *LevelSplitWriter) LevelWrite(level hclog.Level, p []byte) (n int, err error) {
+	name, ok := levelName(level)
+	if !ok {
+		name, ok = entryLevel(p)
+	}
+	return w.write(level, name, ok, p)
+}
+
+func (w *LevelSplitWriter) write(level hclog.Level, name string, ok bool, p []byte) (n int, err error) {
+	if ok && w.filter.allows(name) {
+		if _, err := levelWrite(w.Split, level, p); err != nil {
+			return 0, err
+		}
+		if w.Exclusive {
+			return len(p), nil
+		}
+	}
+	return levelWrite(w.Main, level, p)
+}
+
+// levelWrite writes p to w with LevelWrite if w implements hclog.LevelWriter
+// and level is known, and with Write otherwise.
+func levelWrite(w io.Writer, level hclog.Level, p []byte) (int, error) {
+	if lw, ok := w.(hclog.LevelWriter); ok && level != hclog.NoLevel {
+		return lw.LevelWrite(level, p)
+	}
+	return w.Write(p)
+}
+
+// multiWriter is like io.MultiWriter, but keeps the level of entries written
+// with LevelWrite for the writers implementing hclog.LevelWriter.
+type multiWriter []io.Writer
+
+func (m multiWriter) Write(p []byte) (n int, err error) {
+	return m.LevelWrite(hclog.NoLevel, p)
+}
+
+// LevelWrite implements hclog.LevelWriter.
+func (m multiWriter) LevelWrite(level hclog.Level, p []byte) (n int, err error) {
+	for _, w := range m {
+		n, err = levelWrite(w, level, p)
+		if err != nil {
+			return n, err
+		}
+		if n != len(p) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+	hclog "github.com/varnson/go-hclog"
+)
+
+func TestLevelSplitWriter_impl(t *testing.T) {
+	var _ io.Writer = new(LevelSplitWriter)
+}
+
+func TestLevelSplitWriter(t *testing.T) {
+	entries := []string{
+		"[INFO] text info\n",
+		"[WARN] text warn\n",
+		"[ERR] text error\n",
+		`{"@level":"debug","@message":"json debug"}` + "\n",
+		`{"@level":"error","@message":"json error"}` + "\n",
+		"no level\n",
+	}
+	cases := []struct {
+		exclusive bool
+		main      string
+		split     string
+	}{
+		{
+			exclusive: false,
+			main:      entries[0] + entries[1] + entries[2] + entries[3] + entries[4] + entries[5],
+			split:     entries[1] + entries[2] + entries[4],
+		},
+		{
+			exclusive: true,
+			main:      entries[0] + entries[3] + entries[5],
+			split:     entries[1] + entries[2] + entries[4],
+		},
+	}
+	for _, c := range cases {
+		main, split := new(bytes.Buffer), new(bytes.Buffer)
+		w, err := NewLevelSplitWriter(main, split, "warn", c.exclusive)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		for _, e := range entries {
+			if n, err := w.Write([]byte(e)); err != nil || n != len(e) {
+				t.Fatalf("bad: %d %v", n, err)
+			}
+		}
+		if main.String() != c.main {
+			t.Errorf("exclusive=%v: bad main: %q", c.exclusive, main.String())
+		}
+		if split.String() != c.split {
+			t.Errorf("exclusive=%v: bad split: %q", c.exclusive, split.String())
+		}
+	}
+}
+
+func TestLevelSplitWriter_invalidLevel(t *testing.T) {
+	if _, err := NewLevelSplitWriter(ioutil.Discard, ioutil.Discard, "verbose", false); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestLevelSplitWriter_logFiles(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LevelSplitWriter")
+	defer os.RemoveAll(tempDir)
+	var reported []error
+	main := &LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+		MaxFiles:  1,
+		OnError:   func(err error) { reported = append(reported, err) },
+	}
+	defer main.Close()
+	split := &LogFile{
+		logFilter: LevelFilter(),
+		fileName:  SplitFileName(testFileName),
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+	}
+	defer split.Close()
+	w, err := NewLevelSplitWriter(main, split, "ERR", false)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Rotating and pruning the main file leaves the split files alone
+	w.Write([]byte("[ERR] first\n"))
+	w.Write([]byte("[ERR] second\n"))
+	w.Write([]byte("[INFO] third\n"))
+	w.Write([]byte("[INFO] fourth\n"))
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, "Consul-error.log")); string(bytes) != "[ERR] second\n" {
+		t.Errorf("bad split file: %q", bytes)
+	}
+	want := 4
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+	if len(reported) != 0 {
+		t.Errorf("Expected no errors for the split files, got %v", reported)
+	}
+}
+
+func TestLevelSplitWriter_LevelWrite(t *testing.T) {
+	main, split := new(bytes.Buffer), new(bytes.Buffer)
+	w, err := NewLevelSplitWriter(main, split, "warn", true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	// The level given by the logger wins over the content
+	w.LevelWrite(hclog.Error, []byte("no level\n"))
+	w.LevelWrite(hclog.Info, []byte("[ERR] looks like an error\n"))
+	if split.String() != "no level\n" {
+		t.Errorf("bad split: %q", split.String())
+	}
+	if main.String() != "[ERR] looks like an error\n" {
+		t.Errorf("bad main: %q", main.String())
+	}
+}
+
+func TestMultiWriter_LevelWrite(t *testing.T) {
+	buf := new(bytes.Buffer)
+	split := new(bytes.Buffer)
+	w, err := NewLevelSplitWriter(ioutil.Discard, split, "error", true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	m := multiWriter{buf, w}
+	m.LevelWrite(hclog.Error, []byte("an error\n"))
+	if buf.String() != "an error\n" || split.String() != "an error\n" {
+		t.Errorf("bad: %q %q", buf.String(), split.String())
+	}
+}
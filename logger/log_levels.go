@@ -4,13 +4,20 @@ import (
 	"io/ioutil"
 
 	"github.com/hashicorp/logutils"
+	hclog "github.com/varnson/go-hclog"
 )
 
-// LevelFilter returns a LevelFilter that is configured with the log
-// levels that we use.
+// LevelFilter returns a logutils LevelFilter that is configured with the log
+// levels that we use. LogFile and LevelSplitWriter filter with a native filter
+// of the same behavior; this is kept for the callers of Setup and for filters
+// shared with a LogFile.
 func LevelFilter() *logutils.LevelFilter {
+	levels := make([]logutils.LogLevel, len(defaultLevels))
+	for i, level := range defaultLevels {
+		levels[i] = logutils.LogLevel(level)
+	}
 	return &logutils.LevelFilter{
-		Levels:   []logutils.LogLevel{"TRACE", "DEBUG", "INFO", "WARN", "ERR"},
+		Levels:   levels,
 		MinLevel: "INFO",
 		Writer:   ioutil.Discard,
 	}
@@ -26,3 +33,52 @@ func ValidateLevelFilter(minLevel logutils.LogLevel, filter *logutils.LevelFilte
 	}
 	return false
 }
+
+// validLevel reports whether level is one of the levels we use.
+func validLevel(level string) bool {
+	for _, l := range defaultLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// entryLevel returns the level of a log entry, read from the "@level" field of
+// JSON entries or the first bracketed token of text entries, and false if the
+// entry has no known level.
+func entryLevel(b []byte) (string, bool) {
+	if level, ok := jsonLevel(b); ok {
+		return level, true
+	}
+	level, ok := bracketLevel(b)
+	if !ok {
+		return "", false
+	}
+	if level == "ERROR" {
+		level = "ERR"
+	}
+	if !validLevel(level) {
+		return "", false
+	}
+	return level, true
+}
+
+// levelName returns the name the level filter uses for an hclog level, and
+// false for levels it has no name for.
+func levelName(level hclog.Level) (string, bool) {
+	switch level {
+	case hclog.Trace:
+		return "TRACE", true
+	case hclog.Debug:
+		return "DEBUG", true
+	case hclog.Info:
+		return "INFO", true
+	case hclog.Warn:
+		return "WARN", true
+	case hclog.Error:
+		return "ERR", true
+	default:
+		return "", false
+	}
+}
@@ -1,24 +1,133 @@
 package logger
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/hashicorp/logutils"
+	hclog "github.com/varnson/go-hclog"
+)
+
+// ErrLogFileClosed is returned when writing to a LogFile after Close.
+var ErrLogFileClosed = errors.New("log file is closed")
+
+// ErrLogFileLocked is returned when opening a LogFile with ExclusiveLock while
+// another process holds the lock.
+var ErrLogFileLocked = errors.New("log file is locked by another process")
+
+var (
+	_ io.Closer         = (*LogFile)(nil)
+	_ hclog.Flushable   = (*LogFile)(nil)
+	_ hclog.LevelWriter = (*LogFile)(nil)
+	_ hclog.AuditWriter = (*LogFile)(nil)
 )
 
 var (
 	now = time.Now
+
+	// compressedSuffixes are the suffixes of rotated files compressed or
+	// encrypted by LogFile or externally, which still count as rotated files.
+	// Longer suffixes come first, so that they are trimmed whole.
+	compressedSuffixes = []string{".gz" + encryptedSuffix, ".zst" + encryptedSuffix, encryptedSuffix, ".gz", ".zst"}
+
+	// fileCheckInterval is how often Write checks that the file at the
+	// configured path is still the one being written to. Checking on every
+	// write would cost a stat call per entry.
+	fileCheckInterval = time.Second
+
+	// rename moves rotated files away, it is replaced in tests to simulate
+	// failures.
+	rename = renameFile
+)
+
+// SyncPolicy controls how often LogFile flushes written data to stable
+// storage with File.Sync. Syncing gives durability beyond the operating
+// system's page cache at a significant cost in write throughput, see
+// BenchmarkLogFile_WriteSyncEveryWrite.
+type SyncPolicy int
+
+const (
+	// SyncNever leaves writing data to disk to the operating system. This is
+	// the default.
+	SyncNever SyncPolicy = iota
+
+	// SyncEveryWrite syncs the file after every write.
+	SyncEveryWrite
+
+	// SyncPeriodic syncs the file once SyncBytes have been written since the
+	// last sync, and every SyncInterval.
+	SyncPeriodic
+)
+
+// RotateReason tells why a log file was rotated.
+type RotateReason int
+
+const (
+	// RotateSize is a rotation because the file reached MaxBytes.
+	RotateSize RotateReason = iota
+
+	// RotateDuration is a rotation because the rotation duration elapsed or
+	// the daily rotation time was reached.
+	RotateDuration
+
+	// RotateManual is a rotation requested with Rotate.
+	RotateManual
+
+	// RotateSignal is a rotation triggered by a signal registered with
+	// RotateOnSignal.
+	RotateSignal
 )
 
+func (r RotateReason) String() string {
+	switch r {
+	case RotateSize:
+		return "size"
+	case RotateDuration:
+		return "duration"
+	case RotateManual:
+		return "manual"
+	case RotateSignal:
+		return "signal"
+	default:
+		return "unknown"
+	}
+}
+
+// LogFileStats holds counters about a LogFile, as returned by Stats.
+type LogFileStats struct {
+	// Rotations is the number of times the file was rotated.
+	Rotations int64
+
+	// FileSize is the number of bytes written to the current file.
+	FileSize int64
+
+	// TotalBytes is the number of bytes of entries written since the
+	// LogFile was created, across all files.
+	TotalBytes int64
+}
+
 //LogFile is used to setup a file based logger that also performs log rotation
 type LogFile struct {
 	// Log level Filter to filter out logs that do not matcch LogLevel criteria
-	logFilter *logutils.LevelFilter
+	logFilter levelChecker
+
+	// filterLock guards logFilter, which SetMinLevel swaps at runtime
+	filterLock sync.RWMutex
 
 	//Name of the log file
 	fileName string
@@ -50,10 +159,596 @@ type LogFile struct {
 	// Max rotated files to keep before removing them.
 	MaxFiles int
 
+	//MaxTotalBytes is the maximum total size of the rotated files, compressed
+	//or not, the oldest being removed first. The active file isn't counted.
+	//Zero doesn't limit the total size.
+	MaxTotalBytes int64
+
+	//RotateDaily rotates the file on the first write after RotateHour local
+	//time, or UTC with RotateTimeUTC, instead of after duration has elapsed.
+	//Rotated files are named after the day of the data they contain.
+	RotateDaily bool
+
+	//RotateHour is the hour of the day (0-23) at which daily rotation happens
+	RotateHour int
+
+	//RotateTimeFormat is the time layout used in the name of rotated files.
+	//Defaults to "20060102150405", or "20060102" with RotateDaily.
+	RotateTimeFormat string
+
+	//RotateTimeUTC formats the time in the name of rotated files in UTC
+	//instead of local time, and aligns daily rotation to UTC days
+	RotateTimeUTC bool
+
+	//FileMode is the permission used to create log files, 0644 by default.
+	//As with any file creation the process umask still applies, and existing
+	//files keep their permissions. Rotated files keep the mode of the file
+	//they were rotated from.
+	FileMode os.FileMode
+
+	//DirMode is the permission used to create the log directory if it
+	//doesn't exist, 0755 by default. The process umask still applies.
+	DirMode os.FileMode
+
+	//SyncPolicy controls when written data is synced to disk
+	SyncPolicy SyncPolicy
+
+	//SyncBytes is the number of bytes after which the file is synced with
+	//SyncPeriodic. Zero disables syncing based on size.
+	SyncBytes int64
+
+	//SyncInterval is the interval at which the file is synced in the
+	//background with SyncPeriodic. Zero disables syncing based on time.
+	SyncInterval time.Duration
+
+	//BufferSize is the size in bytes of the buffer holding entries before
+	//they are written to the file. Zero disables buffering, writing every
+	//entry to the file immediately. Buffered entries that have not been
+	//flushed are lost if the process crashes, but a flush always writes whole
+	//entries, so the file never ends with a partial entry.
+	BufferSize int
+
+	//FlushInterval is the interval at which buffered entries are flushed to
+	//the file in the background. Zero only flushes the buffer when it is full
+	//or when Flush, Sync or Rotate are called.
+	FlushInterval time.Duration
+
+	//SymlinkName is the name of a symbolic link kept in the log directory
+	//pointing at the active log file, so that it can be followed with
+	//"tail -F" whatever the rotation naming. Empty disables the link. Where
+	//symbolic links can't be created, such as on Windows without the
+	//privilege, the link is skipped and the failure reported once to OnError.
+	SymlinkName string
+
+	//Header returns a header written at the top of every new log file, for
+	//example to identify the binary, version and PID. It counts toward
+	//MaxBytes but isn't checked against the level filter. It is only written
+	//to empty files, so reopening a file that already has entries doesn't
+	//repeat it.
+	Header func() []byte
+
+	//Compress compresses rotated files with gzip, adding a .gz suffix. Files
+	//are compressed one at a time by a background worker, so that rotation
+	//doesn't wait for it, and files left uncompressed when the process
+	//stopped are compressed once the file is opened again. Close waits for
+	//pending compressions.
+	Compress bool
+
+	//Compression selects the Codec compressing rotated files, in place of
+	//the gzip of Compress, as with hclogzstd for zstd. Nil leaves them
+	//uncompressed unless Compress is set.
+	Compression Codec
+
+	//CompressionLevel is the level passed to the Codec, zero being its
+	//default level.
+	CompressionLevel int
+
+	//EncryptionKey returns the AES key, 16, 24 or 32 bytes long, and its ID
+	//to encrypt rotated files with AES-GCM, adding a .enc suffix after the
+	//.gz one if Compress is set. It is called for every file, so the key can
+	//come from a KMS and be rotated. Files are encrypted by the compression
+	//worker, and the plaintext file is only removed once the encrypted one
+	//is complete, so a failure leaves it in place and reports it to OnError.
+	//The key ID is written in the header of the file for DecryptFile.
+	EncryptionKey func() (keyID string, key []byte, err error)
+
+	//ChainKey makes the file tamper-evident by chaining every entry to the
+	//previous one with an HMAC-SHA256 keyed with it. The digest is added at
+	//the end of the entry, as a chain= field for text or an "@chain" field
+	//for JSON, unless ChainSidecar is set. Rotation seals the chain of the
+	//rotated file with a final record, and the next file starts a new chain.
+	//A file that is reopened continues the chain of its last entry. Use
+	//VerifyChain to check a file.
+	ChainKey []byte
+
+	//ChainSidecar writes the digests to a sidecar file named after the log
+	//file with a .chain suffix rather than inline, leaving the entries as
+	//they are. The sidecar file follows the log file through rotation and
+	//is removed with it, but isn't moved by an external tool moving the file
+	//before Reopen.
+	ChainSidecar bool
+
+	//ExclusiveLock takes an advisory lock on a sidecar file named after the
+	//active file with a .lock suffix when the file is first opened, so that
+	//two processes never write to the same log file. Opening fails with
+	//ErrLogFileLocked if another process holds it. The lock is held across
+	//rotations and released by Close, the sidecar file itself is left in
+	//place.
+	ExclusiveLock bool
+
+	//RotateNaming selects between naming rotated files after the rotation
+	//time, the default, or with an index as in name.log.1.
+	RotateNaming RotateNaming
+
+	//CopyTruncate rotates by copying the file to its rotated name and then
+	//truncating it in place, instead of renaming it, for tailers that must
+	//keep reading the same file. Entries written by LogFile are never lost,
+	//but a tailer that is behind may miss what was written just before the
+	//truncation, and copying takes longer than renaming while writes are
+	//blocked.
+	CopyTruncate bool
+
+	//IdleCheckInterval is the interval at which a background goroutine checks
+	//whether the rotation duration elapsed, so that an idle file is rotated
+	//on time rather than on the next write. Zero only checks when writing.
+	IdleCheckInterval time.Duration
+
+	//OnRotate is called after the file was moved to rotatedPath, with its
+	//size and the reason for the rotation, for example to upload it. It is
+	//called without the LogFile locked, once the new file is open, so it may
+	//take its time but can be called concurrently.
+	OnRotate func(rotatedPath string, size int64, reason RotateReason)
+
+	//CanPrune is called before a rotated file is removed because of MaxFiles,
+	//MaxTotalBytes or its age, and the file is kept if it returns false, for
+	//example until it was uploaded. Kept files still count toward the limits,
+	//and their removal is tried again with the next rotation. It is called
+	//with the LogFile locked, so it must not call methods of the LogFile.
+	CanPrune func(path string) bool
+
+	//FallbackAfter is the number of consecutive failed writes after which
+	//entries are written to Fallback instead, so that they aren't lost while
+	//the file is unwritable, for example because the disk is full. The file
+	//is tried again, reopening it if needed, after FallbackRetryInterval, and
+	//entries go back to it as soon as a write succeeds. Zero disables the
+	//fallback.
+	FallbackAfter int
+
+	//FallbackRetryInterval is the interval before writing to the file is
+	//tried again once entries go to Fallback, doubled after every failed
+	//attempt up to a minute. Defaults to a second.
+	FallbackRetryInterval time.Duration
+
+	//Fallback is the writer used while the file is unwritable. Defaults to
+	//os.Stderr.
+	Fallback io.Writer
+
+	//FallbackNoticeInterval is the minimum interval between the notices
+	//written to Fallback about the file being unwritable. Defaults to a
+	//minute.
+	FallbackNoticeInterval time.Duration
+
+	//OnError is called with errors that don't prevent writing, such as a
+	//failure to rotate the file or to remove old rotated files. It is called
+	//with the LogFile locked, so it must not call methods of the LogFile.
+	OnError func(error)
+
+	//buf buffers writes to FileInfo when BufferSize is set
+	buf *bufio.Writer
+
+	//unsynced is the number of bytes written since the last sync
+	unsynced int64
+
+	//backgroundStarted tells if the background flush and sync have been
+	//started
+	backgroundStarted bool
+
+	//done stops the background goroutines when closed
+	done chan struct{}
+
+	//closed tells if Close was called
+	closed bool
+
+	//rotated is the rotation to pass to OnRotate once the mutex is released
+	rotated *rotateEvent
+
+	//rotations and totalBytes are the counters returned by Stats
+	rotations  int64
+	totalBytes int64
+
+	//strays are the files that look like rotated files but can't be parsed,
+	//already reported to OnError
+	strays map[string]bool
+
+	//compressQueue holds the rotated files waiting for the compression
+	//worker, which closes compressDone once the queue is closed and drained
+	compressQueue chan string
+	compressDone  chan struct{}
+
+	//compressMissed is set when a file couldn't be queued for compression
+	compressMissed int32
+
+	//lockFile is the sidecar file holding the ExclusiveLock
+	lockFile *os.File
+
+	//chainPrev is the digest of the last entry chained with ChainKey, nil
+	//at the start of a chain, and chainJSON tells if that entry was JSON
+	chainPrev []byte
+	chainJSON bool
+
+	//chainMAC computes the digests of the chain
+	chainMAC hash.Hash
+
+	//chainFile is the sidecar file of digests with ChainSidecar
+	chainFile *os.File
+
+	//failures is the number of consecutive failed writes
+	failures int
+
+	//retryDelay is the current interval between attempts to write to the
+	//file while falling back, and nextRetry the time of the next one
+	retryDelay time.Duration
+	nextRetry  time.Time
+
+	//lastNotice is the last time a fallback notice was written
+	lastNotice time.Time
+
+	//headerSize is the size of the header written to the current file
+	headerSize int64
+
+	//symlinkFailed tells if creating the symlink failed and was reported
+	symlinkFailed bool
+
+	//lastChecked is the last time the file was checked for having been
+	//moved or deleted
+	lastChecked time.Time
+
 	//acquire is the mutex utilized to ensure we have no concurrency issues
 	acquire sync.Mutex
 }
 
+// LogFileOptions is used to configure a LogFile created with NewLogFile.
+type LogFileOptions struct {
+	// Path is the directory the log files are written to. It is created if
+	// it doesn't exist.
+	Path string
+
+	// FileName is the name of the active log file. Rotated files are named
+	// after it, with the rotation time added before the extension.
+	FileName string
+
+	// LogLevel is the minimum level of entries written to the file. Defaults
+	// to INFO.
+	LogLevel string
+
+	// Duration is the time after which the file is rotated. Defaults to 24
+	// hours.
+	Duration time.Duration
+
+	// MaxBytes is the size in bytes after which the file is rotated. Zero
+	// disables rotation by size.
+	MaxBytes int
+
+	// MaxFiles is the number of rotated files to keep. Zero keeps all of
+	// them.
+	MaxFiles int
+
+	// MaxTotalBytes is the maximum total size of the rotated files. Zero
+	// doesn't limit it.
+	MaxTotalBytes int64
+
+	// RotateDaily rotates the file once a day at RotateHour instead of after
+	// Duration.
+	RotateDaily bool
+
+	// RotateHour is the hour of the day (0-23) for daily rotation.
+	RotateHour int
+
+	// RotateTimeFormat is the time layout used in rotated file names.
+	RotateTimeFormat string
+
+	// RotateTimeUTC uses UTC for the time in rotated file names.
+	RotateTimeUTC bool
+
+	// FileMode is the permission of created log files, 0644 by default.
+	FileMode os.FileMode
+
+	// DirMode is the permission of the created log directory, 0755 by
+	// default.
+	DirMode os.FileMode
+
+	// SyncPolicy controls when written data is synced to disk.
+	SyncPolicy SyncPolicy
+
+	// SyncBytes is the number of bytes after which the file is synced with
+	// SyncPeriodic.
+	SyncBytes int64
+
+	// SyncInterval is the interval at which the file is synced with
+	// SyncPeriodic.
+	SyncInterval time.Duration
+
+	// BufferSize is the size of the write buffer. Zero disables buffering.
+	BufferSize int
+
+	// FlushInterval is the interval at which buffered entries are flushed.
+	FlushInterval time.Duration
+
+	// SymlinkName is the name of a symbolic link pointing at the active log
+	// file. Empty disables the link.
+	SymlinkName string
+
+	// Header returns a header written at the top of every new log file.
+	Header func() []byte
+
+	// RotateNaming selects how rotated files are named.
+	RotateNaming RotateNaming
+
+	// CopyTruncate rotates by copying and truncating the file instead of
+	// renaming it.
+	CopyTruncate bool
+
+	// Compress compresses rotated files with gzip in the background.
+	Compress bool
+
+	// Compression selects another Codec than the gzip of Compress, such as
+	// zstd from hclogzstd.
+	Compression Codec
+
+	// CompressionLevel is the level of the Codec, zero being its default.
+	CompressionLevel int
+
+	// EncryptionKey encrypts rotated files with AES-GCM in the background,
+	// with the key and key ID it returns.
+	EncryptionKey func() (keyID string, key []byte, err error)
+
+	// ChainKey chains every entry to the previous one with an HMAC keyed
+	// with it, so that VerifyChain detects modified or truncated files.
+	ChainKey []byte
+
+	// ChainSidecar writes the digests of ChainKey to a .chain sidecar file
+	// instead of adding them to the entries.
+	ChainSidecar bool
+
+	// ExclusiveLock fails NewLogFile with ErrLogFileLocked if another
+	// process has the log file open with ExclusiveLock.
+	ExclusiveLock bool
+
+	// IdleCheckInterval is the interval at which an idle file is checked
+	// for rotation. Zero only checks when writing.
+	IdleCheckInterval time.Duration
+
+	// OnRotate is called after the file was rotated.
+	OnRotate func(rotatedPath string, size int64, reason RotateReason)
+
+	// CanPrune is called before rotated files are removed, and keeps them if
+	// it returns false.
+	CanPrune func(path string) bool
+
+	// FallbackAfter is the number of consecutive failed writes after which
+	// entries are written to Fallback. Zero disables the fallback.
+	FallbackAfter int
+
+	// Fallback is the writer used while the file is unwritable. Defaults to
+	// os.Stderr.
+	Fallback io.Writer
+
+	// FallbackRetryInterval is the initial interval before writing to the
+	// file is tried again while falling back. Defaults to a second.
+	FallbackRetryInterval time.Duration
+
+	// FallbackNoticeInterval is the minimum interval between notices about
+	// the file being unwritable. Defaults to a minute.
+	FallbackNoticeInterval time.Duration
+
+	// OnError is called with errors that don't prevent writing.
+	OnError func(error)
+}
+
+// validate checks the options for values that can't work.
+func (o *LogFileOptions) validate() error {
+	switch {
+	case o.FileName == "":
+		return errors.New("log file name is empty")
+	case strings.ContainsAny(o.FileName, `/`+string(os.PathSeparator)):
+		return fmt.Errorf("log file name %q contains a path separator", o.FileName)
+	case strings.ContainsAny(o.SymlinkName, `/`+string(os.PathSeparator)):
+		return fmt.Errorf("log symlink name %q contains a path separator", o.SymlinkName)
+	case o.SymlinkName != "" && o.SymlinkName == o.FileName:
+		return fmt.Errorf("log symlink name %q is the log file name", o.SymlinkName)
+	case o.Duration < 0:
+		return fmt.Errorf("negative log rotation duration %s", o.Duration)
+	case o.MaxBytes < 0:
+		return fmt.Errorf("negative log rotation size %d", o.MaxBytes)
+	case o.MaxTotalBytes < 0:
+		return fmt.Errorf("negative total size of log files to keep %d", o.MaxTotalBytes)
+	case o.MaxFiles < 0:
+		return fmt.Errorf("negative number of log files to keep %d", o.MaxFiles)
+	case o.RotateHour < 0 || o.RotateHour > 23:
+		return fmt.Errorf("log rotation hour %d is not between 0 and 23", o.RotateHour)
+	case o.SyncBytes < 0:
+		return fmt.Errorf("negative log sync size %d", o.SyncBytes)
+	case o.SyncInterval < 0:
+		return fmt.Errorf("negative log sync interval %s", o.SyncInterval)
+	case o.BufferSize < 0:
+		return fmt.Errorf("negative log buffer size %d", o.BufferSize)
+	case o.IdleCheckInterval < 0:
+		return fmt.Errorf("negative log idle check interval %s", o.IdleCheckInterval)
+	case o.FallbackAfter < 0:
+		return fmt.Errorf("negative number of log write failures before fallback %d", o.FallbackAfter)
+	case o.FallbackRetryInterval < 0:
+		return fmt.Errorf("negative log fallback retry interval %s", o.FallbackRetryInterval)
+	case o.FallbackNoticeInterval < 0:
+		return fmt.Errorf("negative log fallback notice interval %s", o.FallbackNoticeInterval)
+	case o.FlushInterval < 0:
+		return fmt.Errorf("negative log flush interval %s", o.FlushInterval)
+	case o.ChainSidecar && len(o.ChainKey) == 0:
+		return errors.New("log chain sidecar set without a chain key")
+	}
+	if o.RotateTimeFormat != "" {
+		if err := validateRotateTimeFormat(o.RotateTimeFormat); err != nil {
+			return err
+		}
+	}
+	if codec := o.Compression; codec != nil || o.Compress {
+		if codec == nil {
+			codec = Gzip
+		}
+		if err := validateCodec(codec, o.CompressionLevel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewLogFile returns a LogFile configured with opts. The options are
+// validated and the log file is opened right away, so that a misconfiguration
+// such as an unwritable directory is reported here rather than on the first
+// write.
+func NewLogFile(opts LogFileOptions) (*LogFile, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	minLevel := "INFO"
+	if opts.LogLevel != "" {
+		minLevel = filterLevel(opts.LogLevel)
+	}
+	logFilter, err := newLevelFilter(defaultLevels, minLevel, ioutil.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %q, valid log levels are %v", opts.LogLevel, defaultLevels)
+	}
+
+	duration := opts.Duration
+	if duration == 0 {
+		duration = defaultRotateDuration
+	}
+
+	l := &LogFile{
+		logFilter:         logFilter,
+		fileName:          opts.FileName,
+		logPath:           opts.Path,
+		duration:          duration,
+		MaxBytes:          opts.MaxBytes,
+		MaxFiles:          opts.MaxFiles,
+		MaxTotalBytes:     opts.MaxTotalBytes,
+		RotateDaily:       opts.RotateDaily,
+		RotateHour:        opts.RotateHour,
+		RotateTimeFormat:  opts.RotateTimeFormat,
+		RotateTimeUTC:     opts.RotateTimeUTC,
+		FileMode:          opts.FileMode,
+		DirMode:           opts.DirMode,
+		SyncPolicy:        opts.SyncPolicy,
+		SyncBytes:         opts.SyncBytes,
+		SyncInterval:      opts.SyncInterval,
+		BufferSize:        opts.BufferSize,
+		FlushInterval:     opts.FlushInterval,
+		SymlinkName:       opts.SymlinkName,
+		Header:            opts.Header,
+		OnRotate:          opts.OnRotate,
+		CanPrune:          opts.CanPrune,
+		OnError:           opts.OnError,
+		IdleCheckInterval: opts.IdleCheckInterval,
+		CopyTruncate:      opts.CopyTruncate,
+		ExclusiveLock:     opts.ExclusiveLock,
+		Compress:          opts.Compress,
+		Compression:       opts.Compression,
+		CompressionLevel:  opts.CompressionLevel,
+		EncryptionKey:     opts.EncryptionKey,
+		ChainKey:          opts.ChainKey,
+		ChainSidecar:      opts.ChainSidecar,
+		RotateNaming:      opts.RotateNaming,
+
+		FallbackAfter:          opts.FallbackAfter,
+		Fallback:               opts.Fallback,
+		FallbackRetryInterval:  opts.FallbackRetryInterval,
+		FallbackNoticeInterval: opts.FallbackNoticeInterval,
+	}
+
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+	if err := l.openNew(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+const (
+	// defaultFileMode is the permission of created log files
+	defaultFileMode os.FileMode = 0644
+
+	// defaultDirMode is the permission of created log directories
+	defaultDirMode os.FileMode = 0755
+
+	// defaultRotateTimeFormat is the time layout of rotated file names
+	defaultRotateTimeFormat = "20060102150405"
+
+	// defaultDailyRotateTimeFormat is the time layout of rotated file names
+	// when rotating daily
+	defaultDailyRotateTimeFormat = "20060102"
+
+	// defaultFallbackNoticeInterval is the default minimum interval between
+	// fallback notices
+	defaultFallbackNoticeInterval = time.Minute
+
+	// defaultFallbackRetryInterval is the default initial interval between
+	// attempts to write to the file while falling back, and
+	// maxFallbackRetryInterval the interval it is doubled up to
+	defaultFallbackRetryInterval = time.Second
+	maxFallbackRetryInterval     = time.Minute
+
+	// maxRotatedFileAge is the age after which rotated files are removed
+	maxRotatedFileAge = 30 * 24 * time.Hour
+)
+
+// rotateTimeFormat returns the time layout used in rotated file names.
+func (l *LogFile) rotateTimeFormat() string {
+	if l.RotateTimeFormat != "" {
+		return l.RotateTimeFormat
+	}
+	if l.RotateDaily {
+		return defaultDailyRotateTimeFormat
+	}
+	return defaultRotateTimeFormat
+}
+
+// validateRotateTimeFormat checks that layout can be used as part of a
+// rotated file name.
+func validateRotateTimeFormat(layout string) error {
+	formatted := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(layout)
+	if formatted == "" {
+		return fmt.Errorf("rotate time format %q produces an empty string", layout)
+	}
+	if strings.ContainsAny(formatted, `/`+string(os.PathSeparator)) {
+		return fmt.Errorf("rotate time format %q produces a path separator", layout)
+	}
+	return nil
+}
+
+// rotateTimestamp formats t for use in the name of a rotated file.
+func (l *LogFile) rotateTimestamp(t time.Time) string {
+	if l.RotateTimeUTC {
+		t = t.UTC()
+	}
+	return t.Format(l.rotateTimeFormat())
+}
+
+// fileMode returns the permission used to create log files.
+func (l *LogFile) fileMode() os.FileMode {
+	if l.FileMode != 0 {
+		return l.FileMode
+	}
+	return defaultFileMode
+}
+
+// dirMode returns the permission used to create the log directory.
+func (l *LogFile) dirMode() os.FileMode {
+	if l.DirMode != 0 {
+		return l.DirMode
+	}
+	return defaultDirMode
+}
+
 func (l *LogFile) fileNamePattern() string {
 	// Extract the file extension
 	fileExt := filepath.Ext(l.fileName)
@@ -66,102 +761,1146 @@ func (l *LogFile) fileNamePattern() string {
 }
 
 func (l *LogFile) openNew() error {
-	fileNamePattern := l.fileNamePattern()
-	// New file name has the format : filename-timestamp.extension
-	createTime := now()
-	//newfileName := fmt.Sprintf(fileNamePattern, strconv.FormatInt(createTime.UnixNano(), 10))
-	l.rotateName = fmt.Sprintf(fileNamePattern, "-"+time.Unix(createTime.Unix(), 0).Format("20060102150405"))
-	newfileName := fmt.Sprintf(fileNamePattern, "")
-	newfilePath := filepath.Join(l.logPath, newfileName)
-	l.fullName = newfilePath
-	l.rotateName = filepath.Join(l.logPath, l.rotateName)
-	// Try creating a file. We truncate the file because we are the only authority to write the logs
-	filePointer, err := os.OpenFile(newfilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err := validateRotateTimeFormat(l.rotateTimeFormat()); err != nil {
+		return err
+	}
+	newfileName := fmt.Sprintf(l.fileNamePattern(), "")
+	l.fullName = filepath.Join(l.logPath, newfileName)
+	if err := l.lock(); err != nil {
+		return err
+	}
+	if err := l.openFile(); err != nil {
+		return err
+	}
+	fi, err := l.FileInfo.Stat()
 	if err != nil {
+		l.FileInfo.Close()
+		l.FileInfo = nil
+		l.buf = nil
 		return err
 	}
 
-	l.FileInfo = filePointer
 	// New file, new bytes tracker, new creation time :)
-	l.LastCreated = createTime
+	// A file left by a previous run is appended to, so its size and last
+	// modification time are accounted for. Otherwise a process restarting
+	// more often than its rotation limits would never rotate.
+	createTime := now()
 	l.BytesWritten = 0
+	if fi.Size() > 0 {
+		l.BytesWritten = fi.Size()
+		if fi.ModTime().Before(createTime) {
+			createTime = fi.ModTime()
+		}
+	}
+	l.resetFile(createTime)
+	l.resumeChain(fi.Size())
+	if err := l.writeHeader(); err != nil {
+		return err
+	}
+	l.updateSymlink()
+	l.startBackground()
+	l.startCompressor()
 	return nil
 }
 
-func (l *LogFile) rotate() error {
-	// Get the time from the last point of contact
-	timeElapsed := time.Since(l.LastCreated)
-	// Rotate if we hit the byte file limit or the time limit
-	if (l.BytesWritten >= int64(l.MaxBytes) && (l.MaxBytes > 0)) || timeElapsed >= l.duration {
-		l.FileInfo.Close()
-		os.Rename(l.fullName, l.rotateName)
-		//if err := l.pruneFiles(); err != nil {
-		//	return err
-		//}
-
-		//delete old files(>30 days)
-		filepath.Walk(filepath.Dir(l.fullName), func(path string, f os.FileInfo, err error) error {
-			if f == nil {
-				return err
-			}
-			if f.IsDir() {
-				return nil
-			}
-			if !strings.HasSuffix(f.Name(), ".log") {
-				return nil
-			}
-			if time.Since(f.ModTime()) > 30*24*time.Hour {
-				os.Remove(path)
-			} else { //if file is not old enough ,skip this process
-				return filepath.SkipDir
-			}
-			return nil
-		})
-		return l.openNew()
+// resetFile sets the creation time of the current file and the rotated name
+// derived from it.
+func (l *LogFile) resetFile(createTime time.Time) {
+	l.LastCreated = createTime
+	l.unsynced = 0
+
+	fileNamePattern := l.fileNamePattern()
+	// New file name has the format : filename-timestamp.extension
+	//newfileName := fmt.Sprintf(fileNamePattern, strconv.FormatInt(createTime.UnixNano(), 10))
+	if l.RotateDaily {
+		// Daily files carry the date of the day they hold data for, which
+		// differs from the creation date before RotateHour.
+		l.rotateName = fmt.Sprintf(fileNamePattern, "-"+l.rotateTimestamp(l.dailyBoundary(createTime)))
+	} else {
+		l.rotateName = fmt.Sprintf(fileNamePattern, "-"+l.rotateTimestamp(time.Unix(createTime.Unix(), 0)))
 	}
-	return nil
+	l.rotateName = filepath.Join(l.logPath, l.rotateName)
 }
 
-func (l *LogFile) pruneFiles() error {
-	if l.MaxFiles == 0 {
+// writeHeader writes the Header to the file if it is empty.
+func (l *LogFile) writeHeader() error {
+	l.headerSize = 0
+	if l.Header == nil {
 		return nil
 	}
-	pattern := l.fileNamePattern()
-	//get all the files that match the log file pattern
-	globExpression := filepath.Join(l.logPath, fmt.Sprintf(pattern, "*"))
-	matches, err := filepath.Glob(globExpression)
+	fi, err := l.FileInfo.Stat()
 	if err != nil {
 		return err
 	}
-	// Prune if there are more files stored than the configured max
-	stale := len(matches) - l.MaxFiles
-	for i := 0; i < stale; i++ {
-		if err := os.Remove(matches[i]); err != nil {
+	if fi.Size() > 0 {
+		return nil
+	}
+	// The buffer is empty in a new file, so the header can bypass it
+	header := l.Header()
+	var digest []byte
+	if l.chained() {
+		header, digest = l.chainRecord(header)
+	}
+	n, err := l.FileInfo.Write(header)
+	l.BytesWritten += int64(n)
+	l.headerSize = int64(n)
+	if err == nil && digest != nil {
+		l.commitChain(header, digest)
+	}
+	return err
+}
+
+// updateSymlink points the SymlinkName link at the active file. The link is
+// created under a temporary name and renamed over the existing one, so that
+// readers never find it missing or dangling.
+func (l *LogFile) updateSymlink() {
+	if l.SymlinkName == "" || l.symlinkFailed {
+		return
+	}
+	link := filepath.Join(l.logPath, l.SymlinkName)
+	tmp := filepath.Join(l.logPath, "."+l.SymlinkName+".tmp")
+	os.Remove(tmp)
+	err := os.Symlink(filepath.Base(l.fullName), tmp)
+	if err == nil {
+		if err = os.Rename(tmp, link); err != nil {
+			os.Remove(tmp)
+		}
+	}
+	if err != nil {
+		l.symlinkFailed = true
+		l.reportError(fmt.Errorf("failed to create log symlink: %w", err))
+	}
+}
+
+// openFile opens fullName for appending, creating the log directory and the
+// file if needed.
+func (l *LogFile) openFile() error {
+	if l.logPath != "" {
+		if err := os.MkdirAll(l.logPath, l.dirMode()); err != nil {
+			return err
+		}
+	}
+	// Try creating a file. We truncate the file because we are the only authority to write the logs
+	filePointer, err := os.OpenFile(l.fullName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, l.fileMode())
+	if err != nil {
+		return err
+	}
+
+	l.FileInfo = filePointer
+	l.buf = nil
+	if l.BufferSize > 0 {
+		l.buf = bufio.NewWriterSize(filePointer, l.BufferSize)
+	}
+	return nil
+}
+
+// lock takes the ExclusiveLock if configured and not held yet.
+func (l *LogFile) lock() error {
+	if !l.ExclusiveLock || l.lockFile != nil {
+		return nil
+	}
+	if l.logPath != "" {
+		if err := os.MkdirAll(l.logPath, l.dirMode()); err != nil {
 			return err
 		}
 	}
+	f, err := os.OpenFile(l.fullName+".lock", os.O_CREATE|os.O_RDWR, l.fileMode())
+	if err != nil {
+		return err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return err
+	}
+	l.lockFile = f
 	return nil
 }
 
-// Write is used to implement io.Writer
-func (l *LogFile) Write(b []byte) (n int, err error) {
-	// Filter out log entries that do not match log level criteria
-	if !l.logFilter.Check(b) {
-		return 0, nil
+// reportError passes err to OnError, if both are set.
+func (l *LogFile) reportError(err error) {
+	if err != nil && l.OnError != nil {
+		l.OnError(err)
 	}
+}
 
-	l.acquire.Lock()
-	defer l.acquire.Unlock()
-	//Create a new file if we have no file to write to
-	if l.FileInfo == nil {
-		if err := l.openNew(); err != nil {
-			return 0, err
+// closeFile flushes any buffered entries, syncs the file if a sync policy is
+// set and closes it. The caller must hold the acquire mutex.
+func (l *LogFile) closeFile() error {
+	err := l.flush()
+	if l.SyncPolicy != SyncNever {
+		if serr := l.FileInfo.Sync(); err == nil {
+			err = serr
+		}
+	}
+	if cerr := l.FileInfo.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := l.closeChain(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// startBackground starts flushing and syncing the file in the background if
+// configured to. The caller must hold the acquire mutex.
+func (l *LogFile) startBackground() {
+	if l.backgroundStarted {
+		return
+	}
+	var flushInterval, syncInterval time.Duration
+	if l.BufferSize > 0 {
+		flushInterval = l.FlushInterval
+	}
+	if l.SyncPolicy == SyncPeriodic {
+		syncInterval = l.SyncInterval
+	}
+	if flushInterval <= 0 && syncInterval <= 0 && l.IdleCheckInterval <= 0 {
+		return
+	}
+	l.backgroundStarted = true
+	if l.done == nil {
+		l.done = make(chan struct{})
+	}
+	go l.background(flushInterval, syncInterval, l.IdleCheckInterval, l.done)
+}
+
+// background periodically flushes, syncs and checks the file for rotation
+// until done is closed.
+func (l *LogFile) background(flushInterval, syncInterval, idleInterval time.Duration, done <-chan struct{}) {
+	var flushC, syncC, idleC <-chan time.Time
+	if flushInterval > 0 {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		flushC = ticker.C
+	}
+	if syncInterval > 0 {
+		ticker := time.NewTicker(syncInterval)
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+	if idleInterval > 0 {
+		ticker := time.NewTicker(idleInterval)
+		defer ticker.Stop()
+		idleC = ticker.C
+	}
+	for {
+		select {
+		case <-flushC:
+			l.Flush()
+		case <-syncC:
+			l.Sync()
+		case <-idleC:
+			l.rotateIdle()
+		case <-done:
+			return
+		}
+	}
+}
+
+// rotateIdle rotates the file if the rotation duration elapsed since it was
+// created. A file without entries is left alone, so that an idle process
+// doesn't leave a trail of empty files behind.
+func (l *LogFile) rotateIdle() {
+	l.acquire.Lock()
+	if !l.closed && l.FileInfo != nil && l.BytesWritten > l.headerSize && l.rotationDue() {
+		l.reportError(l.rotateFile(RotateDuration))
+	}
+	ev := l.takeRotated()
+	l.acquire.Unlock()
+	l.notifyRotate(ev)
+}
+
+// Close flushes any buffered entries, syncs the file if a sync policy is set,
+// closes it and stops the background goroutines. Writing to a closed LogFile
+// returns ErrLogFileClosed rather than reopening the file. Calling Close more
+// than once is a no-op.
+func (l *LogFile) Close() error {
+	l.acquire.Lock()
+	if l.closed {
+		l.acquire.Unlock()
+		return nil
+	}
+	l.closed = true
+	if l.done != nil {
+		close(l.done)
+	}
+	var err error
+	if l.FileInfo != nil {
+		err = l.closeFile()
+		l.FileInfo = nil
+		l.buf = nil
+	}
+	var compressDone chan struct{}
+	if l.compressQueue != nil {
+		close(l.compressQueue)
+		compressDone = l.compressDone
+	}
+	l.acquire.Unlock()
+
+	// The compression worker takes the mutex to report errors, so it is
+	// waited for without holding it
+	if compressDone != nil {
+		if werr := l.waitCompressor(compressDone); err == nil {
+			err = werr
+		}
+	}
+	// The lock is released last, once nothing more is written
+	if l.lockFile != nil {
+		if cerr := l.lockFile.Close(); err == nil {
+			err = cerr
+		}
+		l.lockFile = nil
+	}
+	return err
+}
+
+// Flush writes any buffered entries to the file. This implements
+// hclog.Flushable.
+func (l *LogFile) Flush() error {
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+	return l.flush()
+}
+
+func (l *LogFile) flush() error {
+	if l.buf == nil {
+		return nil
+	}
+	return l.buf.Flush()
+}
+
+// Sync writes any buffered entries to the file and commits the contents of
+// the file to stable storage.
+func (l *LogFile) Sync() error {
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+	return l.sync()
+}
+
+func (l *LogFile) sync() error {
+	if l.FileInfo == nil {
+		return nil
+	}
+	if err := l.flush(); err != nil {
+		return err
+	}
+	l.unsynced = 0
+	return l.FileInfo.Sync()
+}
+
+// syncWritten syncs the file after n bytes were written if the sync policy
+// calls for it.
+func (l *LogFile) syncWritten(n int) error {
+	switch l.SyncPolicy {
+	case SyncEveryWrite:
+		return l.sync()
+	case SyncPeriodic:
+		l.unsynced += int64(n)
+		if l.SyncBytes > 0 && l.unsynced >= l.SyncBytes {
+			return l.sync()
+		}
+	}
+	return nil
+}
+
+// dailyBoundary returns the latest daily rotation point at or before t, in
+// UTC with RotateTimeUTC so that the day in the name matches the boundary.
+func (l *LogFile) dailyBoundary(t time.Time) time.Time {
+	if l.RotateTimeUTC {
+		t = t.UTC()
+	}
+	boundary := time.Date(t.Year(), t.Month(), t.Day(), l.RotateHour, 0, 0, 0, t.Location())
+	if boundary.After(t) {
+		boundary = time.Date(t.Year(), t.Month(), t.Day()-1, l.RotateHour, 0, 0, 0, t.Location())
+	}
+	return boundary
+}
+
+// rotationDue reports whether the time limit for the current file is reached.
+func (l *LogFile) rotationDue() bool {
+	if l.RotateDaily {
+		// Using time.Date rather than adding 24h keeps the boundary on the
+		// right hour across DST changes. A file created several days ago is
+		// still only rotated once.
+		last := l.dailyBoundary(l.LastCreated)
+		next := time.Date(last.Year(), last.Month(), last.Day()+1, l.RotateHour, 0, 0, 0, last.Location())
+		return !now().Before(next)
+	}
+	// Get the time from the last point of contact
+	return now().Sub(l.LastCreated) >= l.duration
+}
+
+// rotate rotates the file before writing an entry of the given size if that
+// entry would exceed the byte file limit or if the time limit is reached.
+func (l *LogFile) rotate(size int) error {
+	// Entries are never split over two files, so an entry is written to a new
+	// file rather than exceeding MaxBytes. An entry larger than MaxBytes on its
+	// own is still written whole, to a file of its own, after the header.
+	exceeds := l.MaxBytes > 0 && l.BytesWritten > l.headerSize && l.BytesWritten+int64(size) > int64(l.MaxBytes)
+	if exceeds {
+		return l.rotateFile(RotateSize)
+	}
+	if l.rotationDue() {
+		return l.rotateFile(RotateDuration)
+	}
+	return nil
+}
+
+// rotateEvent is a rotation to pass to OnRotate.
+type rotateEvent struct {
+	path   string
+	size   int64
+	reason RotateReason
+}
+
+// takeRotated returns the pending rotation and clears it. The caller must
+// hold the acquire mutex, and pass the result to notifyRotate once released.
+func (l *LogFile) takeRotated() *rotateEvent {
+	ev := l.rotated
+	l.rotated = nil
+	return ev
+}
+
+// notifyRotate calls OnRotate for ev, if both are set.
+func (l *LogFile) notifyRotate(ev *rotateEvent) {
+	if ev != nil && l.OnRotate != nil {
+		l.OnRotate(ev.path, ev.size, ev.reason)
+	}
+}
+
+// rotateFile closes the current file, moves it to its rotated name, removes
+// old log files, opens a fresh file to write to and prunes the rotated files
+// past MaxFiles. The caller must hold the acquire mutex.
+//
+// If the file can't be moved, it is opened again to keep appending to it,
+// without resetting BytesWritten and LastCreated so that rotation is retried
+// on the next write, and the error is returned. FileInfo is nil after an
+// error if no file could be opened at all. Failures to remove old files
+// don't prevent writing and are only passed to OnError.
+func (l *LogFile) rotateFile(reason RotateReason) error {
+	if l.CopyTruncate {
+		return l.copyTruncate(reason)
+	}
+	l.reportError(l.closeFile())
+	size := l.BytesWritten
+	rotatedPath, err := l.nextRotateName()
+	if err == nil {
+		err = rename(l.fullName, rotatedPath)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to rotate log file: %w", err)
+		if oerr := l.openFile(); oerr != nil {
+			l.FileInfo = nil
+			l.buf = nil
+			return fmt.Errorf("%v, and failed to reopen it: %w", err, oerr)
+		}
+		return err
+	}
+	l.sealChain(rotatedPath, false)
+	l.rotations++
+	l.rotated = &rotateEvent{path: rotatedPath, size: size, reason: reason}
+	l.queueCompress(rotatedPath)
+
+	if err := l.deleteOldFiles(); err != nil {
+		l.reportError(fmt.Errorf("failed to remove old log files: %w", err))
+	}
+	if err := l.openNew(); err != nil {
+		l.FileInfo = nil
+		l.buf = nil
+		return err
+	}
+	if err := l.pruneFiles(); err != nil {
+		l.reportError(fmt.Errorf("failed to prune log files: %w", err))
+	}
+	return nil
+}
+
+// copyTruncate rotates the file by copying it to its rotated name and
+// truncating it, keeping it open. If the copy fails, the file is left as is
+// and the rotation retried on the next write. The caller must hold the
+// acquire mutex.
+func (l *LogFile) copyTruncate(reason RotateReason) error {
+	if err := l.flush(); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	size := l.BytesWritten
+	rotatedPath, err := l.nextRotateName()
+	if err == nil {
+		err = copyFile(l.fullName, rotatedPath, l.fileMode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	// O_APPEND makes the next write start at the truncated end
+	if err := l.FileInfo.Truncate(0); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	l.sealChain(rotatedPath, true)
+	l.rotations++
+	l.rotated = &rotateEvent{path: rotatedPath, size: size, reason: reason}
+	l.queueCompress(rotatedPath)
+
+	l.BytesWritten = 0
+	l.resetFile(now())
+	if err := l.writeHeader(); err != nil {
+		return err
+	}
+	if err := l.deleteOldFiles(); err != nil {
+		l.reportError(fmt.Errorf("failed to remove old log files: %w", err))
+	}
+	if err := l.pruneFiles(); err != nil {
+		l.reportError(fmt.Errorf("failed to prune log files: %w", err))
+	}
+	return nil
+}
+
+// copyFile copies the file at src to a new file at dst.
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// deleteOldFiles removes the rotated files of this log file that are older
+// than maxRotatedFileAge. All candidates are collected before any is removed,
+// so the result doesn't depend on the order the directory is listed in.
+func (l *LogFile) deleteOldFiles() error {
+	dir := filepath.Dir(l.fullName)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var old []string
+	for _, f := range entries {
+		if f.IsDir() || !l.isRotatedFile(f.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		if now().Sub(f.ModTime()) > maxRotatedFileAge && l.canPrune(path) {
+			old = append(old, path)
+		}
+	}
+	for _, path := range old {
+		if rerr := l.removeRotated(path); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// uniqueRotateName returns rotateName, or if a file with that name exists
+// because the file was already rotated within the same second, rotateName
+// with the first free sequence number added, as in name-20060102150405.1.log.
+func (l *LogFile) uniqueRotateName() string {
+	if _, err := os.Lstat(l.rotateName); os.IsNotExist(err) {
+		return l.rotateName
+	}
+	ext := filepath.Ext(l.rotateName)
+	base := strings.TrimSuffix(l.rotateName, ext)
+	for seq := 1; ; seq++ {
+		name := fmt.Sprintf("%s.%d%s", base, seq, ext)
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			return name
+		}
+	}
+}
+
+// rotatedFile is a file previously rotated by this LogFile.
+type rotatedFile struct {
+	path string
+
+	//created is the time embedded in the file name
+	created time.Time
+
+	//seq is the sequence number added to the name on collisions
+	seq int
+
+	//size is the size of the file, compressed or not
+	size int64
+}
+
+// reportStray reports a file that looks like a rotated file but whose name
+// can't be parsed, once per file.
+func (l *LogFile) reportStray(path string) {
+	if l.strays[path] {
+		return
+	}
+	if l.strays == nil {
+		l.strays = make(map[string]bool)
+	}
+	l.strays[path] = true
+	l.reportError(fmt.Errorf("ignoring %s, its name doesn't have a valid rotation time", path))
+}
+
+// parseRotateStamp parses the part of a rotated file name between the file
+// name and the extension, as produced by uniqueRotateName.
+func (l *LogFile) parseRotateStamp(stamp string) (time.Time, int, bool) {
+	loc := time.Local
+	if l.RotateTimeUTC {
+		loc = time.UTC
+	}
+	layout := l.rotateTimeFormat()
+	// time.Parse accepts fractional seconds the layout doesn't contain, so the
+	// timestamp has to format back to the same string to be one of ours.
+	parse := func(value string) (time.Time, bool) {
+		t, err := time.ParseInLocation(layout, value, loc)
+		return t, err == nil && t.Format(layout) == value
+	}
+	if t, ok := parse(stamp); ok {
+		return t, 0, true
+	}
+	idx := strings.LastIndexByte(stamp, '.')
+	if idx == -1 {
+		return time.Time{}, 0, false
+	}
+	seq, err := strconv.Atoi(stamp[idx+1:])
+	if err != nil || seq < 1 {
+		return time.Time{}, 0, false
+	}
+	t, ok := parse(stamp[:idx])
+	return t, seq, ok
+}
+
+// parseRotatedName returns the rotation time and sequence number of the
+// rotated file name, and false if it isn't a rotated file of this LogFile.
+// The name may have one of compressedSuffixes.
+//
+// With the index naming, the index is returned as the sequence number.
+func (l *LogFile) parseRotatedName(name string) (time.Time, int, bool) {
+	if l.RotateNaming == RotateNamingIndex {
+		index, ok := l.parseIndexName(name)
+		return time.Time{}, index, ok
+	}
+	name = trimCompressedSuffix(name)
+	prefix := fmt.Sprintf(l.fileNamePattern(), "-")
+	ext := filepath.Ext(prefix)
+	prefix = strings.TrimSuffix(prefix, ext)
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+		return time.Time{}, 0, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+	return l.parseRotateStamp(stamp)
+}
+
+// trimCompressedSuffix removes any of compressedSuffixes from name.
+func trimCompressedSuffix(name string) string {
+	for _, suffix := range compressedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
+
+// isRotatedFile reports whether name is a rotated file of this LogFile,
+// optionally compressed.
+func (l *LogFile) isRotatedFile(name string) bool {
+	_, _, ok := l.parseRotatedName(name)
+	return ok
+}
+
+// rotatedFiles returns the files rotated by this LogFile, compressed or not,
+// oldest first according to the time or the index in their name, as
+// compressing a file changes its modification time. Files matching the file
+// name pattern but without a valid rotation timestamp or index, such as ones
+// belonging to another LogFile, are ignored and reported once to OnError.
+func (l *LogFile) rotatedFiles() ([]rotatedFile, error) {
+	//get all the rotated files that match the log file pattern, whatever the
+	//time layout used in their name
+	globExpression := l.rotatedGlob()
+	matches, err := filepath.Glob(globExpression)
+	if err != nil {
+		return nil, err
+	}
+	if l.RotateNaming != RotateNamingIndex {
+		for _, suffix := range compressedSuffixes {
+			compressed, err := filepath.Glob(globExpression + suffix)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, compressed...)
+		}
+	}
+	var files []rotatedFile
+	for _, match := range matches {
+		if l.isSplitFile(filepath.Base(match)) {
+			continue
+		}
+		// Partial compressed or encrypted files, chain sidecar files and the
+		// lock file aren't rotated files
+		if isPartial(match) || strings.HasSuffix(match, chainSuffix) || match == l.activePath()+".lock" {
+			continue
+		}
+		created, seq, ok := l.parseRotatedName(filepath.Base(match))
+		if !ok {
+			l.reportStray(match)
+			continue
+		}
+		fi, err := os.Lstat(match)
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{path: match, created: created, seq: seq, size: fi.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		// The highest index is the oldest file
+		if l.RotateNaming == RotateNamingIndex {
+			return files[i].seq > files[j].seq
+		}
+		if !files[i].created.Equal(files[j].created) {
+			return files[i].created.Before(files[j].created)
+		}
+		return files[i].seq < files[j].seq
+	})
+	return files, nil
+}
+
+// Rotate forces a rotation of the current log file, regardless of the size
+// and time limits. The current file is closed and moved to its rotated name,
+// old files are cleaned up and a new file is opened. If no file has been
+// opened yet, Rotate only opens one. It is safe to call concurrently with
+// Write.
+func (l *LogFile) Rotate() error {
+	return l.rotateFor(RotateManual)
+}
+
+func (l *LogFile) rotateFor(reason RotateReason) error {
+	l.acquire.Lock()
+	err := l.rotateLocked(reason)
+	ev := l.takeRotated()
+	l.acquire.Unlock()
+	l.notifyRotate(ev)
+	return err
+}
+
+func (l *LogFile) rotateLocked(reason RotateReason) error {
+	if l.closed {
+		return ErrLogFileClosed
+	}
+	if l.FileInfo == nil {
+		return l.openNew()
+	}
+	return l.rotateFile(reason)
+}
+
+// Stats returns the rotation count, the size of the current file and the
+// number of bytes written since the LogFile was created.
+func (l *LogFile) Stats() LogFileStats {
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+	return LogFileStats{
+		Rotations:  l.rotations,
+		FileSize:   l.BytesWritten,
+		TotalBytes: l.totalBytes,
+	}
+}
+
+// PublishExpvar publishes the statistics returned by Stats as the expvar
+// variables <prefix>.rotations, <prefix>.file_size and <prefix>.total_bytes,
+// next to those of hclog.PublishExpvar. Calling it again with the same prefix
+// does nothing.
+func (l *LogFile) PublishExpvar(prefix string) {
+	hclog.PublishExpvarFunc(prefix+".rotations", func() interface{} {
+		return l.Stats().Rotations
+	})
+	hclog.PublishExpvarFunc(prefix+".file_size", func() interface{} {
+		return l.Stats().FileSize
+	})
+	hclog.PublishExpvarFunc(prefix+".total_bytes", func() interface{} {
+		return l.Stats().TotalBytes
+	})
+}
+
+func (l *LogFile) pruneFiles() error {
+	if l.MaxFiles == 0 && l.MaxTotalBytes == 0 {
+		return nil
+	}
+	matches, err := l.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	// Prune if there are more files stored than the configured max
+	var stale int
+	if l.MaxFiles > 0 && len(matches) > l.MaxFiles {
+		stale = len(matches) - l.MaxFiles
+	}
+	// and then the oldest files until the rest fits in MaxTotalBytes
+	if l.MaxTotalBytes > 0 {
+		var total int64
+		for _, f := range matches[stale:] {
+			total += f.size
+		}
+		for stale < len(matches) && total > l.MaxTotalBytes {
+			total -= matches[stale].size
+			stale++
+		}
+	}
+	for i := 0; i < stale; i++ {
+		if !l.canPrune(matches[i].path) {
+			continue
+		}
+		if err := l.removeRotated(matches[i].path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canPrune reports whether the rotated file at path may be removed.
+func (l *LogFile) canPrune(path string) bool {
+	return l.CanPrune == nil || l.CanPrune(path)
+}
+
+// RotatedFiles returns the paths of the files rotated by this LogFile,
+// compressed or not, oldest first, for example to find the files a previous
+// run left to process.
+func (l *LogFile) RotatedFiles() ([]string, error) {
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+
+	files, err := l.rotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.path)
+	}
+	return paths, nil
+}
+
+// Reopen closes the current file and opens the configured path again. This is
+// meant to be used after an external tool such as logrotate has moved the
+// file away, so that new entries are written to a file at the configured path
+// instead of the renamed one. BytesWritten is reset from the size of the newly
+// opened file.
+func (l *LogFile) Reopen() error {
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+	if l.closed {
+		return ErrLogFileClosed
+	}
+	return l.reopen()
+}
+
+func (l *LogFile) reopen() error {
+	if l.FileInfo != nil {
+		l.closeFile()
+	}
+	return l.openNew()
+}
+
+// NotifyOnSignal reopens the log file every time one of the given signals is
+// received, which defaults to SIGHUP if none are given. This makes LogFile
+// work with an external logrotate configured with a postrotate "kill -HUP".
+// The returned function stops listening for the signals.
+func (l *LogFile) NotifyOnSignal(sigs ...os.Signal) (stop func()) {
+	// Reopen takes the same mutex as Write, so an entry is never split
+	// between the old and the new file.
+	return onSignal(func() { l.Reopen() }, sigs)
+}
+
+// RotateOnSignal rotates the log file every time one of the given signals is
+// received, which defaults to SIGHUP if none are given, for when LogFile does
+// the rotation itself rather than an external logrotate. The returned
+// function stops listening for the signals.
+func (l *LogFile) RotateOnSignal(sigs ...os.Signal) (stop func()) {
+	return onSignal(func() { l.rotateFor(RotateSignal) }, sigs)
+}
+
+// onSignal calls fn every time one of sigs, or SIGHUP if none are given, is
+// received, until the returned function is called.
+func onSignal(fn func(), sigs []os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				fn()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// filterLevel translates a level name, in any case, to the name used by the
+// level filter. The hclog name "error" is accepted for "ERR".
+func filterLevel(level string) string {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if level == "ERROR" {
+		level = "ERR"
+	}
+	return level
+}
+
+// checkLevel reports whether filter lets the entry b through. JSON entries
+// written by hclog carry their level in the "@level" field rather than as a
+// bracketed prefix, so it is extracted and checked in its place.
+func checkLevel(filter levelChecker, b []byte) bool {
+	if level, ok := jsonLevel(b); ok {
+		return filter.Check([]byte("[" + level + "]"))
+	}
+	return filter.Check(b)
+}
+
+// jsonLevel returns the filter level of a JSON log entry, and false if b isn't
+// a JSON object with a known "@level".
+func jsonLevel(b []byte) (string, bool) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || b[0] != '{' {
+		return "", false
+	}
+	var entry struct {
+		Level string `json:"@level"`
+	}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return "", false
+	}
+	switch level := filterLevel(entry.Level); level {
+	case "TRACE", "DEBUG", "INFO", "WARN", "ERR":
+		return level, true
+	}
+	return "", false
+}
+
+// SetMinLevel changes the minimum level of entries written to the file. The
+// filter is replaced rather than modified, so a filter shared with other
+// writers isn't affected and entries already being written keep the level
+// they were checked against.
+func (l *LogFile) SetMinLevel(level string) error {
+	l.filterLock.Lock()
+	defer l.filterLock.Unlock()
+
+	levels := defaultLevels
+	switch f := l.logFilter.(type) {
+	case *levelFilter:
+		levels = f.levels
+	case *logutils.LevelFilter:
+		levels = make([]string, len(f.Levels))
+		for i, level := range f.Levels {
+			levels[i] = string(level)
+		}
+	}
+	filter, err := newLevelFilter(levels, filterLevel(level), ioutil.Discard)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q, valid log levels are %v", level, levels)
+	}
+	l.logFilter = filter
+	return nil
+}
+
+// Write is used to implement io.Writer
+func (l *LogFile) Write(b []byte) (n int, err error) {
+	l.filterLock.RLock()
+	filter := l.logFilter
+	l.filterLock.RUnlock()
+
+	// Filter out log entries that do not match log level criteria
+	if filter != nil && !checkLevel(filter, b) {
+		return 0, nil
+	}
+	return l.writeEntry(b)
+}
+
+// LevelWrite writes an entry of which the logger already knows the level,
+// so that it is filtered without parsing b. This implements
+// hclog.LevelWriter. Entries without a level are filtered like with Write.
+func (l *LogFile) LevelWrite(level hclog.Level, b []byte) (n int, err error) {
+	l.filterLock.RLock()
+	filter := l.logFilter
+	l.filterLock.RUnlock()
+
+	if filter != nil {
+		name, ok := levelName(level)
+		if ok && !filter.Check([]byte("["+name+"]")) || !ok && !checkLevel(filter, b) {
+			return 0, nil
+		}
+	}
+	return l.writeEntry(b)
+}
+
+// AuditWrite writes an entry of hclog's Audit, whatever the minimum level of
+// the file. This implements hclog.AuditWriter.
+func (l *LogFile) AuditWrite(b []byte) (n int, err error) {
+	return l.writeEntry(b)
+}
+
+// writeEntry writes an entry that passed the level filter.
+func (l *LogFile) writeEntry(b []byte) (n int, err error) {
+	l.acquire.Lock()
+	if l.fallingBack() {
+		n, err = l.fallbackWriter().Write(b)
+	} else {
+		n, err = l.write(b)
+		if l.FallbackAfter > 0 && err != ErrLogFileClosed {
+			n, err = l.fallback(b, n, err)
+		}
+	}
+	ev := l.takeRotated()
+	l.acquire.Unlock()
+	l.notifyRotate(ev)
+	return n, err
+}
+
+// fallingBack reports whether entries go to the Fallback writer without
+// trying the file, until the next retry. The caller must hold the acquire
+// mutex.
+func (l *LogFile) fallingBack() bool {
+	return l.FallbackAfter > 0 && !l.closed && l.failures >= l.FallbackAfter && now().Before(l.nextRetry)
+}
+
+// fallbackWriter returns Fallback, or os.Stderr if it isn't set.
+func (l *LogFile) fallbackWriter() io.Writer {
+	if l.Fallback == nil {
+		return os.Stderr
+	}
+	return l.Fallback
+}
+
+// fallback writes b to the Fallback writer once FallbackAfter consecutive
+// writes to the file have failed, err being the result of the latest one.
+// After a failure the file is closed, so that the next attempt reopens it,
+// and the attempt is put off by a doubling interval. The caller must hold the
+// acquire mutex.
+func (l *LogFile) fallback(b []byte, n int, err error) (int, error) {
+	w := l.fallbackWriter()
+	if err == nil {
+		if l.failures >= l.FallbackAfter {
+			fmt.Fprintf(w, "[INFO] logger: log file %s is writable again\n", l.fullName)
+		}
+		l.failures = 0
+		l.retryDelay = 0
+		return n, nil
+	}
+
+	l.failures++
+	if l.FileInfo != nil {
+		l.closeFile()
+		l.FileInfo = nil
+		l.buf = nil
+	}
+	if l.failures < l.FallbackAfter {
+		return n, err
+	}
+	switch {
+	case l.retryDelay == 0:
+		l.retryDelay = l.FallbackRetryInterval
+		if l.retryDelay == 0 {
+			l.retryDelay = defaultFallbackRetryInterval
+		}
+	case l.retryDelay < maxFallbackRetryInterval:
+		l.retryDelay *= 2
+		if l.retryDelay > maxFallbackRetryInterval {
+			l.retryDelay = maxFallbackRetryInterval
+		}
+	}
+	l.nextRetry = now().Add(l.retryDelay)
+	interval := l.FallbackNoticeInterval
+	if interval == 0 {
+		interval = defaultFallbackNoticeInterval
+	}
+	if t := now(); t.Sub(l.lastNotice) >= interval {
+		l.lastNotice = t
+		fmt.Fprintf(w, "[ERR] logger: failed to write to log file %s, writing to the fallback: %v\n", l.fullName, err)
+	}
+	return w.Write(b)
+}
+
+// write writes an entry that passed the level filter. The caller must hold
+// the acquire mutex.
+func (l *LogFile) write(b []byte) (n int, err error) {
+	if l.closed {
+		return 0, ErrLogFileClosed
+	}
+	//Create a new file if we have no file to write to
+	if l.FileInfo == nil {
+		if err := l.openNew(); err != nil {
+			return 0, err
+		}
+	}
+	// Reopen the file if someone else deleted or moved it, as we would
+	// otherwise keep writing to a file nobody can see
+	if t := now(); t.Sub(l.lastChecked) >= fileCheckInterval {
+		l.lastChecked = t
+		if l.fileMoved() {
+			if err := l.reopen(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	// Check for the last contact and rotate if necessary. When the file
+	// can't be rotated but is still open the entry is appended to it, and
+	// the error only goes to OnError.
+	size := len(b)
+	if l.chained() && !l.ChainSidecar {
+		size += maxChainFieldLen
+	}
+	if err := l.rotate(size); err != nil {
+		if l.FileInfo == nil {
+			return 0, err
+		}
+		l.reportError(err)
+	}
+	// The entry is chained once rotated, as rotation starts a new chain
+	rec, digest := b, []byte(nil)
+	if l.chained() {
+		rec, digest = l.chainRecord(b)
+	}
+	if l.buf != nil {
+		// Flush ahead of an entry that doesn't fit so that flushes never
+		// split an entry
+		if len(rec) > l.buf.Available() && l.buf.Buffered() > 0 {
+			if err := l.buf.Flush(); err != nil {
+				return 0, err
+			}
+		}
+		n, err = l.buf.Write(rec)
+	} else {
+		n, err = l.FileInfo.Write(rec)
+	}
+	if err != nil {
+		if n > len(b) {
+			n = len(b)
 		}
+		return n, err
 	}
-	// Check for the last contact and rotate if necessary
-	if err := l.rotate(); err != nil {
-		return 0, err
+	// BytesWritten counts the bytes accepted, whether or not they were
+	// flushed to the file yet
+	l.BytesWritten += int64(n)
+	l.totalBytes += int64(n)
+	if digest != nil {
+		l.commitChain(rec, digest)
 	}
-	l.BytesWritten += int64(len(b))
-	return l.FileInfo.Write(b)
+	return len(b), l.syncWritten(n)
 }
@@ -0,0 +1,507 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// chainSuffix is added to the name of a log file to name its sidecar
+	// file of digests with ChainSidecar.
+	chainSuffix = ".chain"
+
+	// chainDigestLen is the length of a hex encoded digest.
+	chainDigestLen = 2 * sha256.Size
+
+	// chainTextField and chainJSONField introduce the digest added inline
+	// at the end of text and JSON entries.
+	chainTextField = " chain="
+	chainJSONField = `,"@chain":"`
+
+	// maxChainFieldLen is the most bytes added to an entry by the inline
+	// digest, counting the newline added to entries without one.
+	maxChainFieldLen = len(chainJSONField) + chainDigestLen + 2
+
+	// chainSealSidecar starts the line of the seal record in a sidecar file.
+	chainSealSidecar = "sealed"
+)
+
+var (
+	// chainSealText and chainSealJSON are the content of the record sealing
+	// the chain of a rotated file, in text and JSON files.
+	chainSealText = []byte("log chain sealed\n")
+	chainSealJSON = []byte(`{"@message":"log chain sealed"}` + "\n")
+
+	// chainStart is the digest preceding the first entry of a file.
+	chainStart = make([]byte, sha256.Size)
+)
+
+// ChainStatus is the result of a successful VerifyChain.
+type ChainStatus struct {
+	// Entries is the number of entries in the file, not counting the seal.
+	Entries int
+
+	// Sealed tells if the chain ends with the seal written on rotation. A
+	// rotated file that isn't sealed was truncated, only the active file
+	// is legitimately unsealed.
+	Sealed bool
+}
+
+// ChainError is returned by VerifyChain for a file whose hash chain is
+// broken, because an entry was modified, removed, inserted or truncated.
+type ChainError struct {
+	// Path is the file verified.
+	Path string
+
+	// Entry is the number of the first broken entry, starting from 1.
+	Entry int
+
+	// Offset is the offset in the file of the start of that entry.
+	Offset int64
+
+	// Reason tells how the entry is broken.
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("hash chain of %s broken at entry %d, offset %d: %s", e.Path, e.Entry, e.Offset, e.Reason)
+}
+
+// chainSum returns the digest of content chained to prev. Seal records are
+// marked so that no entry can pass for one.
+func chainSum(mac hash.Hash, prev, content []byte, seal bool) []byte {
+	mac.Reset()
+	mac.Write(prev)
+	if seal {
+		mac.Write([]byte{1})
+	} else {
+		mac.Write([]byte{0})
+	}
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+// chainContent returns b ending with a newline, as every chained entry does.
+func chainContent(b []byte) []byte {
+	if len(b) > 0 && b[len(b)-1] == '\n' {
+		return b
+	}
+	c := make([]byte, len(b)+1)
+	copy(c, b)
+	c[len(b)] = '\n'
+	return c
+}
+
+// isJSONEntry reports whether content, ending with a newline, is a JSON
+// object with fields, to which the digest can be added as another one.
+func isJSONEntry(content []byte) bool {
+	body := content[:len(content)-1]
+	return len(body) > 2 && body[0] == '{' && body[len(body)-1] == '}'
+}
+
+// withChainField returns content with the digest added at its end, as the
+// last field of a JSON object or a chain= field for text.
+func withChainField(content, digest []byte) []byte {
+	body := content[:len(content)-1]
+	rec := make([]byte, 0, len(content)+maxChainFieldLen)
+	if isJSONEntry(content) {
+		rec = append(append(rec, body[:len(body)-1]...), chainJSONField...)
+		rec = append(rec, hex.EncodeToString(digest)...)
+		return append(rec, '"', '}', '\n')
+	}
+	rec = append(append(rec, body...), chainTextField...)
+	rec = append(rec, hex.EncodeToString(digest)...)
+	return append(rec, '\n')
+}
+
+// parseChainField returns the content and digest of a line ending with the
+// inline digest, and false if it doesn't end with one.
+func parseChainField(line []byte) ([]byte, []byte, bool) {
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		return nil, nil, false
+	}
+	body := line[:len(line)-1]
+	if bytes.HasSuffix(body, []byte(`"}`)) && len(body) >= chainDigestLen+len(chainJSONField)+2 {
+		hexStart := len(body) - 2 - chainDigestLen
+		prefix := body[:hexStart-len(chainJSONField)]
+		if string(body[hexStart-len(chainJSONField):hexStart]) == chainJSONField {
+			digest, err := hex.DecodeString(string(body[hexStart : hexStart+chainDigestLen]))
+			if err == nil {
+				return append(append([]byte(nil), prefix...), '}', '\n'), digest, true
+			}
+		}
+	}
+	if len(body) >= chainDigestLen+len(chainTextField) {
+		hexStart := len(body) - chainDigestLen
+		if string(body[hexStart-len(chainTextField):hexStart]) == chainTextField {
+			digest, err := hex.DecodeString(string(body[hexStart:]))
+			if err == nil {
+				return append(append([]byte(nil), body[:hexStart-len(chainTextField)]...), '\n'), digest, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// chained reports whether entries are hash chained.
+func (l *LogFile) chained() bool {
+	return len(l.ChainKey) > 0
+}
+
+// chainPath returns the path of the sidecar file of the log file at path,
+// compressed or not.
+func chainPath(path string) string {
+	return trimCompressedSuffix(path) + chainSuffix
+}
+
+// chainDigest returns the digest of content chained to the previous entry.
+func (l *LogFile) chainDigest(content []byte, seal bool) []byte {
+	if l.chainMAC == nil {
+		l.chainMAC = hmac.New(sha256.New, l.ChainKey)
+	}
+	prev := l.chainPrev
+	if prev == nil {
+		prev = chainStart
+	}
+	return chainSum(l.chainMAC, prev, content, seal)
+}
+
+// chainRecord returns what to write to the file for the entry b, and its
+// digest, to pass to commitChain once written.
+func (l *LogFile) chainRecord(b []byte) ([]byte, []byte) {
+	content := chainContent(b)
+	digest := l.chainDigest(content, false)
+	if l.ChainSidecar {
+		return content, digest
+	}
+	return withChainField(content, digest), digest
+}
+
+// commitChain makes the digest of the record written the previous one, and
+// writes it to the sidecar file with ChainSidecar. A failure to write the
+// sidecar file is only reported, the entry being in the log file.
+func (l *LogFile) commitChain(rec, digest []byte) {
+	l.chainPrev = digest
+	l.chainJSON = isJSONEntry(rec)
+	if !l.ChainSidecar {
+		return
+	}
+	if l.chainFile == nil {
+		f, err := os.OpenFile(l.fullName+chainSuffix, os.O_CREATE|os.O_APPEND|os.O_WRONLY, l.fileMode())
+		if err != nil {
+			l.reportError(fmt.Errorf("failed to write the hash chain of %s: %w", l.fullName, err))
+			return
+		}
+		l.chainFile = f
+	}
+	line := strconv.Itoa(len(rec)) + " " + hex.EncodeToString(digest) + "\n"
+	if _, err := l.chainFile.WriteString(line); err != nil {
+		l.reportError(fmt.Errorf("failed to write the hash chain of %s: %w", l.fullName, err))
+	}
+}
+
+// closeChain closes the sidecar file.
+func (l *LogFile) closeChain() error {
+	if l.chainFile == nil {
+		return nil
+	}
+	err := l.chainFile.Close()
+	l.chainFile = nil
+	return err
+}
+
+// resumeChain sets the previous digest of the file just opened with size
+// bytes, from the last record of a file left by a previous run. A file that
+// can't be resumed starts a new chain, which VerifyChain reports as broken at
+// that point, and the failure is reported to OnError.
+func (l *LogFile) resumeChain(size int64) {
+	if !l.chained() {
+		return
+	}
+	l.chainPrev, l.chainJSON = nil, false
+	sidecar := l.fullName + chainSuffix
+	if size == 0 {
+		if l.ChainSidecar {
+			// A stale sidecar file can't belong to an empty log file
+			l.closeChain()
+			os.Remove(sidecar)
+		}
+		return
+	}
+	path := l.fullName
+	if l.ChainSidecar {
+		path = sidecar
+	}
+	digest, json, err := lastChainDigest(path, l.ChainSidecar)
+	if err != nil {
+		l.reportError(fmt.Errorf("failed to resume the hash chain of %s, starting a new one: %w", l.fullName, err))
+		return
+	}
+	l.chainPrev, l.chainJSON = digest, json
+}
+
+// lastChainDigest returns the last digest of a log file chained inline, or
+// of a sidecar file, and whether the inline entry is JSON.
+func lastChainDigest(path string, sidecar bool) ([]byte, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	// The digest is at the end of the last line, whatever its length
+	tail := make([]byte, maxChainFieldLen+8)
+	offset := fi.Size() - int64(len(tail))
+	if offset < 0 {
+		offset, tail = 0, tail[:fi.Size()]
+	}
+	if _, err := f.ReadAt(tail, offset); err != nil {
+		return nil, false, err
+	}
+	if sidecar {
+		if len(tail) < chainDigestLen+1 || tail[len(tail)-1] != '\n' {
+			return nil, false, errors.New("no digest at the end of the file")
+		}
+		digest, err := hex.DecodeString(string(tail[len(tail)-1-chainDigestLen : len(tail)-1]))
+		return digest, false, err
+	}
+	_, digest, ok := parseChainField(tail)
+	if !ok {
+		return nil, false, errors.New("no digest at the end of the file")
+	}
+	return digest, bytes.HasSuffix(tail, []byte("\"}\n")), nil
+}
+
+// sealChain writes the record sealing the chain of the file rotated to
+// rotatedPath, and moves the sidecar file next to it, by copying it if the
+// file was copied. The next entry starts a new chain. Failures are only
+// reported, the rotation being done.
+func (l *LogFile) sealChain(rotatedPath string, copied bool) {
+	if !l.chained() {
+		return
+	}
+	if err := l.writeSeal(rotatedPath, copied); err != nil {
+		l.reportError(fmt.Errorf("failed to seal the hash chain of %s: %w", rotatedPath, err))
+	}
+	l.chainPrev, l.chainJSON = nil, false
+}
+
+func (l *LogFile) writeSeal(rotatedPath string, copied bool) error {
+	content := chainSealText
+	if l.chainJSON && !l.ChainSidecar {
+		content = chainSealJSON
+	}
+	digest := l.chainDigest(content, true)
+	rec := withChainField(content, digest)
+	path := rotatedPath
+	if l.ChainSidecar {
+		path = chainPath(rotatedPath)
+		rec = []byte(chainSealSidecar + " " + hex.EncodeToString(digest) + "\n")
+		active := l.fullName + chainSuffix
+		err := l.closeChain()
+		if copied {
+			if err == nil {
+				err = copyFile(active, path, l.fileMode())
+			}
+			if err == nil {
+				err = os.Truncate(active, 0)
+			}
+		} else if err == nil {
+			err = rename(active, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(rec)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// moveChain renames the sidecar file of the rotated file at from along with
+// it, with ChainSidecar.
+func (l *LogFile) moveChain(from, to string) error {
+	if !l.ChainSidecar {
+		return nil
+	}
+	if err := rename(chainPath(from), chainPath(to)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeRotated removes the rotated file at path, and its sidecar file with
+// ChainSidecar.
+func (l *LogFile) removeRotated(path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if l.ChainSidecar {
+		if err := os.Remove(chainPath(path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyChain checks the hash chain of the log file at path, written by a
+// LogFile with ChainKey set to key, and returns the first broken entry as a
+// *ChainError. The digests are read from the sidecar file next to path if
+// there is one, and inline otherwise. Files compressed with gzip are read
+// through it, encrypted files must be decrypted first.
+//
+// A valid chain proves that no entry was modified, removed or inserted.
+// Entries removed from the end of the file only show as a missing seal, so
+// a rotated file must also be Sealed.
+func VerifyChain(path string, key []byte) (ChainStatus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ChainStatus{}, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return ChainStatus{}, err
+		}
+		r = gz
+	}
+
+	sidecar, err := os.Open(chainPath(path))
+	if os.IsNotExist(err) {
+		return verifyInline(path, bufio.NewReader(r), key)
+	}
+	if err != nil {
+		return ChainStatus{}, err
+	}
+	defer sidecar.Close()
+	return verifySidecar(path, bufio.NewReader(r), bufio.NewReader(sidecar), key)
+}
+
+// chainVerifier follows the chain of a file being verified.
+type chainVerifier struct {
+	path   string
+	mac    hash.Hash
+	prev   []byte
+	status ChainStatus
+	offset int64
+}
+
+// check checks the record of size bytes with content and digest, and
+// returns the broken entry if it doesn't match.
+func (v *chainVerifier) check(content, digest []byte, size int64) error {
+	if v.status.Sealed {
+		return v.broken("entry after the seal")
+	}
+	if hmac.Equal(digest, chainSum(v.mac, v.prev, content, false)) {
+		v.status.Entries++
+	} else if (bytes.Equal(content, chainSealText) || bytes.Equal(content, chainSealJSON)) &&
+		hmac.Equal(digest, chainSum(v.mac, v.prev, content, true)) {
+		v.status.Sealed = true
+	} else {
+		return v.broken("digest mismatch")
+	}
+	v.prev = digest
+	v.offset += size
+	return nil
+}
+
+func (v *chainVerifier) broken(reason string) error {
+	return &ChainError{Path: v.path, Entry: v.status.Entries + 1, Offset: v.offset, Reason: reason}
+}
+
+// verifyInline verifies a file with the digest at the end of every entry.
+// Lines without a digest belong to the next entry that has one.
+func verifyInline(path string, r *bufio.Reader, key []byte) (ChainStatus, error) {
+	v := &chainVerifier{path: path, mac: hmac.New(sha256.New, key), prev: chainStart}
+	var pending []byte
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if content, digest, ok := parseChainField(line); ok {
+				size := int64(len(pending) + len(line))
+				if cerr := v.check(append(pending, content...), digest, size); cerr != nil {
+					return v.status, cerr
+				}
+				pending = pending[:0]
+			} else {
+				pending = append(pending, line...)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return v.status, err
+		}
+	}
+	if len(pending) > 0 {
+		return v.status, v.broken("entry without a digest")
+	}
+	return v.status, nil
+}
+
+// verifySidecar verifies a file with the size and digest of every entry in
+// the sidecar file.
+func verifySidecar(path string, r *bufio.Reader, sidecar *bufio.Reader, key []byte) (ChainStatus, error) {
+	v := &chainVerifier{path: path, mac: hmac.New(sha256.New, key), prev: chainStart}
+	for {
+		line, err := sidecar.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return v.status, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasSuffix(line, "\n") {
+			return v.status, v.broken("invalid sidecar record")
+		}
+		digest, herr := hex.DecodeString(fields[1])
+		if herr != nil {
+			return v.status, v.broken("invalid sidecar record")
+		}
+		if fields[0] == chainSealSidecar {
+			if cerr := v.check(chainSealText, digest, 0); cerr != nil {
+				return v.status, cerr
+			}
+			continue
+		}
+		size, serr := strconv.Atoi(fields[0])
+		if serr != nil || size < 0 {
+			return v.status, v.broken("invalid sidecar record")
+		}
+		content := make([]byte, size)
+		if _, rerr := io.ReadFull(r, content); rerr != nil {
+			return v.status, v.broken("entry truncated")
+		}
+		if cerr := v.check(content, digest, int64(size)); cerr != nil {
+			return v.status, cerr
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return v.status, v.broken("data not covered by the chain")
+	}
+	return v.status, nil
+}
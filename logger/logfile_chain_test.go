@@ -0,0 +1,335 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+)
+
+var testChainKey = []byte("chain key")
+
+// writeChained writes entries to a LogFile chaining them in tempDir, rotates
+// it once and writes entries again, then returns the rotated file.
+func writeChained(t *testing.T, tempDir string, sidecar bool, entries ...string) (*LogFile, string) {
+	logFile := &LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     24 * time.Hour,
+		ChainKey:     testChainKey,
+		ChainSidecar: sidecar,
+		Header:       func() []byte { return []byte("# header\n") },
+	}
+	for _, entry := range entries {
+		if _, err := logFile.Write([]byte(entry)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, entry := range entries {
+		if _, err := logFile.Write([]byte(entry)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	rotated, err := logFile.RotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("Expected a rotated file, got %v", rotated)
+	}
+	return logFile, rotated[0]
+}
+
+// verifyChain checks that the chain of path is valid with entries entries.
+func verifyChain(t *testing.T, path string, entries int, sealed bool) {
+	status, err := VerifyChain(path, testChainKey)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if status.Entries != entries || status.Sealed != sealed {
+		t.Errorf("Expected %d entries, sealed %v, got %+v", entries, sealed, status)
+	}
+}
+
+// chainBroken checks that the chain of path is broken at entry.
+func chainBroken(t *testing.T, path string, entry int) {
+	_, err := VerifyChain(path, testChainKey)
+	cerr, ok := err.(*ChainError)
+	if !ok {
+		t.Fatalf("Expected a *ChainError, got %v", err)
+	}
+	if cerr.Entry != entry {
+		t.Errorf("Expected entry %d to be broken, got %v", entry, cerr)
+	}
+}
+
+func TestLogFile_Chain(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterChain")
+	defer os.RemoveAll(tempDir)
+	logFile, rotated := writeChained(t, tempDir, false,
+		"[INFO] first\n", "[WARN] second: key=value\n", "[ERROR] multi\n  | line\n", "[INFO] no newline")
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The header and four entries, and the seal
+	verifyChain(t, rotated, 5, true)
+	verifyChain(t, filepath.Join(tempDir, testFileName), 5, false)
+
+	b, err := ioutil.ReadFile(rotated)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if len(lines) != 8 || !strings.HasPrefix(lines[1], "[INFO] first chain=") {
+		t.Fatalf("Expected a digest at the end of the entries, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[3], "[ERROR] multi\n") || !strings.HasPrefix(lines[6], "log chain sealed chain=") {
+		t.Errorf("Expected the multi-line entry and the seal, got %q", lines)
+	}
+
+	write := func(lines []string) {
+		if err := ioutil.WriteFile(rotated, []byte(strings.Join(lines, "")), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	t.Run("modified entry", func(t *testing.T) {
+		modified := append([]string(nil), lines...)
+		modified[2] = strings.Replace(modified[2], "value", "forged", 1)
+		write(modified)
+		chainBroken(t, rotated, 3)
+	})
+
+	t.Run("removed entry", func(t *testing.T) {
+		removed := append(append([]string(nil), lines[:1]...), lines[2:]...)
+		write(removed)
+		chainBroken(t, rotated, 2)
+	})
+
+	t.Run("swapped entries", func(t *testing.T) {
+		swapped := append([]string(nil), lines...)
+		swapped[1], swapped[2] = swapped[2], swapped[1]
+		write(swapped)
+		chainBroken(t, rotated, 2)
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		write(lines[:5])
+		verifyChain(t, rotated, 4, false)
+		write(append(lines[:3:3], "[ERROR] multi\n"))
+		chainBroken(t, rotated, 4)
+	})
+
+	t.Run("appended entry", func(t *testing.T) {
+		write(append(append([]string(nil), lines...), lines[1]))
+		chainBroken(t, rotated, 6)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		write(lines)
+		if _, err := VerifyChain(rotated, []byte("other key")); err == nil {
+			t.Errorf("Expected an error verifying with another key")
+		}
+	})
+}
+
+func TestLogFile_ChainJSON(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterChainJSON")
+	defer os.RemoveAll(tempDir)
+	logFile, rotated := writeChained(t, tempDir, false,
+		`{"@level":"info","@message":"first"}`+"\n", `{"@level":"info","@message":"second"}`+"\n")
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	verifyChain(t, rotated, 3, true)
+
+	b, err := ioutil.ReadFile(rotated)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	for _, line := range lines[1:] {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Expected JSON entries, got %q: %s", line, err)
+		}
+		if len(entry["@chain"].(string)) != chainDigestLen || entry["@message"] == nil {
+			t.Errorf("Expected the digest as another field, got %v", entry)
+		}
+	}
+	if !strings.HasPrefix(lines[3], `{"@message":"log chain sealed","@chain":`) {
+		t.Errorf("Expected a JSON seal, got %q", lines[3])
+	}
+}
+
+func TestLogFile_ChainSidecar(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterChainSidecar")
+	defer os.RemoveAll(tempDir)
+	logFile, rotated := writeChained(t, tempDir, true, "[INFO] first\n", "[INFO] second\n")
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b, err := ioutil.ReadFile(rotated)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(b) != "# header\n[INFO] first\n[INFO] second\n" {
+		t.Errorf("Expected the entries to be left as they are, got %q", b)
+	}
+	if _, err := os.Stat(rotated + chainSuffix); err != nil {
+		t.Fatalf("Expected the sidecar file to follow the rotated file: %s", err)
+	}
+	verifyChain(t, rotated, 3, true)
+	verifyChain(t, filepath.Join(tempDir, testFileName), 3, false)
+
+	modified := bytes.Replace(b, []byte("second"), []byte("forged"), 1)
+	if err := ioutil.WriteFile(rotated, modified, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	chainBroken(t, rotated, 3)
+	if err := ioutil.WriteFile(rotated, append(b, "[INFO] third\n"...), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	chainBroken(t, rotated, 4)
+
+	// Pruning the rotated file removes its sidecar file
+	if err := logFile.removeRotated(rotated); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(rotated + chainSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the sidecar file to be removed")
+	}
+}
+
+func TestLogFile_ChainIndexNaming(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterChainIndex")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     24 * time.Hour,
+		RotateNaming: RotateNamingIndex,
+		MaxFiles:     2,
+		ChainKey:     testChainKey,
+		ChainSidecar: true,
+	}
+	for i := 0; i < 3; i++ {
+		logFile.Write([]byte("[INFO] entry\n"))
+		if err := logFile.Rotate(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, index := range []string{".1", ".2"} {
+		verifyChain(t, filepath.Join(tempDir, testFileName+index), 1, true)
+	}
+	files, _ := ioutil.ReadDir(tempDir)
+	if len(files) != 5 {
+		t.Errorf("Expected the files kept and their sidecar files, got %d files", len(files))
+	}
+}
+
+func TestLogFile_ChainResume(t *testing.T) {
+	t.Parallel()
+	for _, sidecar := range []bool{false, true} {
+		tempDir := testutil.TempDir(t, "LogWriterChainResume")
+		defer os.RemoveAll(tempDir)
+		for i := 0; i < 2; i++ {
+			logFile, err := NewLogFile(LogFileOptions{
+				FileName:     testFileName,
+				Path:         tempDir,
+				ChainKey:     testChainKey,
+				ChainSidecar: sidecar,
+			})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			logFile.Write([]byte("[INFO] entry\n"))
+			if err := logFile.Close(); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+		}
+		verifyChain(t, filepath.Join(tempDir, testFileName), 2, false)
+	}
+}
+
+func TestLogFile_ChainCopyTruncate(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterChainCopyTruncate")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     24 * time.Hour,
+		CopyTruncate: true,
+		ChainKey:     testChainKey,
+		ChainSidecar: true,
+	}
+	logFile.Write([]byte("[INFO] first\n"))
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	logFile.Write([]byte("[INFO] second\n"))
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	rotated, err := logFile.RotatedFiles()
+	if err != nil || len(rotated) != 1 {
+		t.Fatalf("Expected a rotated file, got %v: %v", rotated, err)
+	}
+	verifyChain(t, rotated[0], 1, true)
+	verifyChain(t, filepath.Join(tempDir, testFileName), 1, false)
+}
+
+func benchmarkLogFileWriteChain(b *testing.B, sidecar bool) {
+	tempDir := testutil.TempDir(b, "LogWriterBenchChain")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     24 * time.Hour,
+		ChainKey:     testChainKey,
+		ChainSidecar: sidecar,
+	}
+	defer logFile.Close()
+	entry := []byte("2020-01-01T12:00:00.000Z [INFO] -- benchmark entry: key=value\n")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logFile.Write(entry)
+	}
+}
+
+// BenchmarkLogFile_WriteChain measures the overhead of ChainKey, compared to
+// BenchmarkLogFile_Write.
+func BenchmarkLogFile_WriteChain(b *testing.B) {
+	benchmarkLogFileWriteChain(b, false)
+}
+
+// BenchmarkLogFile_WriteChainSidecar measures the overhead of ChainSidecar,
+// which writes to the sidecar file without buffering.
+func BenchmarkLogFile_WriteChainSidecar(b *testing.B) {
+	benchmarkLogFileWriteChain(b, true)
+}
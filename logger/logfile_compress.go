@@ -0,0 +1,372 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// compressQueueSize is the number of rotated files that can be waiting
+	// for compression. Files rotated while the queue is full are picked up
+	// by a scan of the directory once the queue drains.
+	compressQueueSize = 16
+
+	// partialSuffix is added to the compressed or encrypted file while it is
+	// being written, after its own suffix, so that a compression interrupted
+	// by a crash is never taken for a complete one.
+	partialSuffix = ".tmp"
+)
+
+// Codec compresses rotated files, as selected by the Compression option of
+// LogFile. Gzip is built in, github.com/varnson/go-hclog/hclogzstd provides
+// zstd without adding its dependency to this module.
+type Codec interface {
+	// Suffix is added to the name of compressed files. It must be one of
+	// the suffixes LogFile recognizes for rotated files, .gz or .zst.
+	Suffix() string
+
+	// NewWriter returns a writer compressing to w at level, zero being the
+	// default level of the codec. Closing it flushes it, but doesn't close w.
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error)
+
+	// NewReader returns a reader decompressing r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Gzip is the gzip Codec, used by Compress. Its levels are those of
+// compress/gzip.
+var Gzip Codec = gzipCodec{}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Suffix() string { return ".gz" }
+
+func (gzipCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// validateCodec checks that codec produces files LogFile recognizes, at a
+// level it supports.
+func validateCodec(codec Codec, level int) error {
+	suffix := codec.Suffix()
+	if trimCompressedSuffix("x"+suffix) != "x" || strings.HasSuffix(suffix, encryptedSuffix) {
+		return fmt.Errorf("unsupported log compression suffix %q", suffix)
+	}
+	w, err := codec.NewWriter(ioutil.Discard, level)
+	if err != nil {
+		return fmt.Errorf("invalid log compression level %d: %w", level, err)
+	}
+	return w.Close()
+}
+
+// codec returns the Codec compressing rotated files, nil if they aren't
+// compressed.
+func (l *LogFile) codec() Codec {
+	if l.Compression != nil {
+		return l.Compression
+	}
+	if l.Compress {
+		return Gzip
+	}
+	return nil
+}
+
+// isPartial reports whether path is a compressed or encrypted file being
+// written.
+func isPartial(path string) bool {
+	if !strings.HasSuffix(path, partialSuffix) {
+		return false
+	}
+	path = strings.TrimSuffix(path, partialSuffix)
+	return trimCompressedSuffix(path) != path
+}
+
+// compressCloseTimeout is how long Close waits for pending compressions.
+var compressCloseTimeout = 30 * time.Second
+
+// startCompressor starts the compression worker if Compress, Compression or
+// EncryptionKey is set, and queues the rotated files left uncompressed or
+// unencrypted by a previous run. Partial files left by a crash are removed,
+// their source is still there and gets compressed again. The caller must hold
+// the acquire mutex.
+func (l *LogFile) startCompressor() {
+	if (l.codec() == nil && l.EncryptionKey == nil) || l.compressQueue != nil {
+		return
+	}
+	l.compressQueue = make(chan string, compressQueueSize)
+	l.compressDone = make(chan struct{})
+	go l.compressor(l.compressQueue, l.compressDone)
+
+	for _, suffix := range compressedSuffixes {
+		partial, _ := filepath.Glob(l.rotatedGlob() + suffix + partialSuffix)
+		for _, path := range partial {
+			os.Remove(path)
+		}
+	}
+	l.queuePending()
+}
+
+// queuePending queues the rotated files that aren't compressed or encrypted
+// yet. The caller must hold the acquire mutex.
+func (l *LogFile) queuePending() {
+	files, err := l.rotatedFiles()
+	if err != nil {
+		l.reportError(fmt.Errorf("failed to list log files to compress: %w", err))
+		return
+	}
+	for _, f := range files {
+		if compress, encrypt := l.pendingWork(f.path); compress || encrypt {
+			l.queueCompress(f.path)
+		}
+	}
+}
+
+// pendingWork returns whether the rotated file at path still has to be
+// compressed, and encrypted. A file that is already encrypted isn't
+// compressed, as encrypted data doesn't compress.
+func (l *LogFile) pendingWork(path string) (compress, encrypt bool) {
+	encrypted := strings.HasSuffix(path, encryptedSuffix)
+	compress = l.codec() != nil && !encrypted && trimCompressedSuffix(path) == path
+	encrypt = l.EncryptionKey != nil && !encrypted
+	return compress, encrypt
+}
+
+// queueCompress queues a rotated file for compression without blocking. If
+// the queue is full, the worker scans the directory for it later. The caller
+// must hold the acquire mutex.
+func (l *LogFile) queueCompress(path string) {
+	if l.compressQueue == nil {
+		return
+	}
+	select {
+	case l.compressQueue <- path:
+	default:
+		atomic.StoreInt32(&l.compressMissed, 1)
+	}
+}
+
+// compressor compresses the queued files one at a time until queue is
+// closed, then closes done.
+func (l *LogFile) compressor(queue <-chan string, done chan<- struct{}) {
+	defer close(done)
+	for path := range queue {
+		l.compressReport(l.compressFile(path))
+		if len(queue) == 0 && atomic.CompareAndSwapInt32(&l.compressMissed, 1, 0) {
+			l.acquire.Lock()
+			if !l.closed {
+				l.queuePending()
+			}
+			l.acquire.Unlock()
+		}
+	}
+}
+
+// compressReport reports a compression error to OnError.
+func (l *LogFile) compressReport(err error) {
+	if err == nil {
+		return
+	}
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+	l.reportError(fmt.Errorf("failed to compress or encrypt log file: %w", err))
+}
+
+// waitCompressor waits for the queued compressions to finish, up to
+// compressCloseTimeout. The queue must be closed.
+func (l *LogFile) waitCompressor(done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-time.After(compressCloseTimeout):
+		return fmt.Errorf("timed out after %s waiting for log files to be compressed", compressCloseTimeout)
+	}
+}
+
+// compressFile compresses the file at path to path.gz or path.zst with the
+// Codec, encrypts it to path.enc, path.gz.enc or path.zst.enc with
+// EncryptionKey, as set, and removes it. A file that no longer exists, because
+// it was compressed or pruned in the meantime, is skipped. The new file is
+// only put in place if path still is the file that was compressed, as the
+// index naming renames rotated files on every rotation, and with the acquire
+// mutex held so that no rotation happens meanwhile. The new file keeps the
+// modification time of path, which identifies the file across renames. On any
+// error the file at path is left in place.
+func (l *LogFile) compressFile(path string) error {
+	compress, encrypt := l.pendingWork(path)
+	if !compress && !encrypt {
+		return nil
+	}
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	var codec Codec
+	var suffix string
+	if compress {
+		codec = l.codec()
+		suffix = codec.Suffix()
+	}
+	var keyID string
+	var key []byte
+	if encrypt {
+		suffix += encryptedSuffix
+		if keyID, key, err = l.EncryptionKey(); err != nil {
+			return fmt.Errorf("failed to get the key to encrypt %s: %w", path, err)
+		}
+		if len(key) == 0 {
+			return fmt.Errorf("failed to get the key to encrypt %s: empty key", path)
+		}
+	}
+	tmp := path + suffix + partialSuffix
+
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+	err = writeCompressed(out, in, codec, l.CompressionLevel, keyID, key)
+	if serr := out.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	in.Close()
+	if err == nil {
+		err = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	l.acquire.Lock()
+	defer l.acquire.Unlock()
+	if current, err := os.Stat(path); err != nil || !os.SameFile(fi, current) {
+		// The file moved to another name, which gets compressed later
+		os.Remove(tmp)
+		atomic.StoreInt32(&l.compressMissed, 1)
+		return nil
+	}
+	if err := os.Rename(tmp, path+suffix); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeCompressed copies in to out, compressed with codec at level unless it
+// is nil, and encrypted with key unless it is nil.
+func writeCompressed(out io.Writer, in io.Reader, codec Codec, level int, keyID string, key []byte) error {
+	w := io.WriteCloser(nopWriteCloser{out})
+	if key != nil {
+		enc, err := newEncryptWriter(out, keyID, key)
+		if err != nil {
+			return err
+		}
+		w = enc
+	}
+	inner := w
+	if codec != nil {
+		cw, err := codec.NewWriter(inner, level)
+		if err != nil {
+			return err
+		}
+		w = cw
+	}
+	_, err := io.Copy(w, in)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if codec != nil {
+		if cerr := inner.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// nopWriteCloser is a writer whose Close does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// OpenRotated opens a rotated file for reading its entries, decrypting it
+// with keyFn as DecryptFile does if its name ends with .enc, and
+// decompressing it with the codec matching its suffix, among Gzip and
+// codecs. This is meant for support tooling reading the files left by
+// LogFile.
+func OpenRotated(path string, keyFn func(keyID string) ([]byte, error), codecs ...Codec) (io.ReadCloser, error) {
+	name := path
+	var rc io.ReadCloser
+	var err error
+	if strings.HasSuffix(name, encryptedSuffix) {
+		if keyFn == nil {
+			return nil, fmt.Errorf("%s is encrypted and no key was given", path)
+		}
+		rc, err = DecryptFile(path, keyFn)
+		name = strings.TrimSuffix(name, encryptedSuffix)
+	} else {
+		rc, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if trimCompressedSuffix(name) == name {
+		return rc, nil
+	}
+	for _, codec := range append([]Codec{Gzip}, codecs...) {
+		if strings.HasSuffix(name, codec.Suffix()) {
+			r, err := codec.NewReader(rc)
+			if err != nil {
+				rc.Close()
+				return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+			}
+			return &decompressReader{ReadCloser: r, src: rc}, nil
+		}
+	}
+	rc.Close()
+	return nil, fmt.Errorf("no codec given to decompress %s", path)
+}
+
+// decompressReader closes the file it decompresses along with the codec
+// reader.
+type decompressReader struct {
+	io.ReadCloser
+	src io.Closer
+}
+
+func (r *decompressReader) Close() error {
+	err := r.ReadCloser.Close()
+	if cerr := r.src.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
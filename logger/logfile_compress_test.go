@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+)
+
+// readGzip returns the decompressed content of the file at path.
+func readGzip(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return string(b)
+}
+
+func TestLogFile_Compress(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterCompress")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+		Compress:  true,
+	}
+	logFile.Write([]byte("Hello World"))
+	logFile.Write([]byte("Second File"))
+	// Close waits for the compression
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	files, err := logFile.rotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != 1 || filepath.Ext(files[0].path) != ".gz" {
+		t.Fatalf("Expected a compressed rotated file, got %v", files)
+	}
+	if got := readGzip(t, files[0].path); got != "Hello World" {
+		t.Errorf("Expected the rotated entry, got %q", got)
+	}
+	if _, err := os.Stat(trimCompressedSuffix(files[0].path)); !os.IsNotExist(err) {
+		t.Errorf("Expected the uncompressed file to be removed")
+	}
+}
+
+func TestLogFile_CompressModTime(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterCompressModTime")
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "Consul-20200101000000.log")
+	if err := ioutil.WriteFile(path, []byte("Hello World"), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	logFile := LogFile{fileName: testFileName, logPath: tempDir, Compress: true}
+	if err := logFile.compressFile(path); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	fi, err := os.Stat(path + ".gz")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Errorf("Expected the compressed file to keep the modification time %s, got %s", mtime, fi.ModTime())
+	}
+}
+
+func TestLogFile_CompressRecovery(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterCompressRecovery")
+	defer os.RemoveAll(tempDir)
+	// A previous run stopped before compressing one file and while
+	// compressing another
+	left := filepath.Join(tempDir, "Consul-20200101000000.log")
+	partial := filepath.Join(tempDir, "Consul-20200102000000.log")
+	for _, path := range []string{left, partial} {
+		if err := ioutil.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := ioutil.WriteFile(partial+".gz"+partialSuffix, []byte("partial"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		Compress:  true,
+	}
+	logFile.Write([]byte("Hello World"))
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, path := range []string{left, partial} {
+		if got := readGzip(t, path+".gz"); got != filepath.Base(path) {
+			t.Errorf("Expected %s to be compressed, got %q", path, got)
+		}
+	}
+	if _, err := os.Stat(partial + ".gz" + partialSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial file to be removed")
+	}
+	want := 3
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+}
+
+// prefixCodec is a Codec "compressing" by adding a prefix, standing for zstd
+// which this module doesn't depend on.
+type prefixCodec struct{}
+
+func (prefixCodec) Suffix() string { return ".zst" }
+
+func (prefixCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level != 0 {
+		return nil, errors.New("invalid level")
+	}
+	if _, err := io.WriteString(w, "zst:"); err != nil {
+		return nil, err
+	}
+	return nopWriteCloser{w}, nil
+}
+
+func (prefixCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	prefix := make([]byte, 4)
+	if _, err := io.ReadFull(r, prefix); err != nil || string(prefix) != "zst:" {
+		return nil, errors.New("invalid prefix")
+	}
+	return ioutil.NopCloser(r), nil
+}
+
+func TestLogFile_Compression(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name   string
+		codec  Codec
+		level  int
+		suffix string
+	}{
+		{"gzip best speed", Gzip, gzip.BestSpeed, ".gz"},
+		{"zst", prefixCodec{}, 0, ".zst"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tempDir := testutil.TempDir(t, "LogWriterCompression")
+			defer os.RemoveAll(tempDir)
+			logFile, err := NewLogFile(LogFileOptions{
+				FileName:         testFileName,
+				Path:             tempDir,
+				MaxBytes:         testBytes,
+				MaxFiles:         1,
+				Compression:      c.codec,
+				CompressionLevel: c.level,
+			})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			logFile.Write([]byte("Hello World"))
+			logFile.Write([]byte("Second File"))
+			logFile.Write([]byte("Third File"))
+			if err := logFile.Close(); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			// The first file was compressed, then pruned
+			files, err := logFile.RotatedFiles()
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if len(files) != 1 || filepath.Ext(files[0]) != c.suffix {
+				t.Fatalf("Expected a rotated file compressed to %s, got %v", c.suffix, files)
+			}
+			r, err := OpenRotated(files[0], nil, prefixCodec{})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			defer r.Close()
+			if b, _ := ioutil.ReadAll(r); string(b) != "Second File" {
+				t.Errorf("Expected the rotated entry, got %q", b)
+			}
+		})
+	}
+}
+
+func TestOpenRotated(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "OpenRotated")
+	defer os.RemoveAll(tempDir)
+	var buf bytes.Buffer
+	if err := writeCompressed(&buf, strings.NewReader("Hello World"), Gzip, 0, "test", testKey); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(tempDir, "Consul-20200101000000.log.gz.enc")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	r, err := OpenRotated(path, testKeyFn)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(b) != "Hello World" {
+		t.Errorf("Expected the decrypted and decompressed entry, got %q: %v", b, err)
+	}
+
+	if _, err := OpenRotated(path, nil); err == nil {
+		t.Errorf("Expected an error opening an encrypted file without a key")
+	}
+	zst := filepath.Join(tempDir, "Consul-20200101000000.log.zst")
+	if err := ioutil.WriteFile(zst, []byte("zst:Hello World"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := OpenRotated(zst, nil); err == nil {
+		t.Errorf("Expected an error opening a zstd file without its codec")
+	}
+}
+
+func TestLogFileOptions_Compression(t *testing.T) {
+	t.Parallel()
+	cases := map[string]LogFileOptions{
+		"invalid gzip level":  {FileName: testFileName, Compress: true, CompressionLevel: 42},
+		"invalid codec level": {FileName: testFileName, Compression: prefixCodec{}, CompressionLevel: 1},
+		"unknown suffix":      {FileName: testFileName, Compression: lz4Codec{}},
+	}
+	for name, opts := range cases {
+		if err := opts.validate(); err == nil {
+			t.Errorf("%s: Expected an error", name)
+		}
+	}
+}
+
+// lz4Codec has a suffix LogFile doesn't recognize.
+type lz4Codec struct{ prefixCodec }
+
+func (lz4Codec) Suffix() string { return ".lz4" }
@@ -0,0 +1,275 @@
+package logger
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// encryptedSuffix is added to rotated files encrypted with EncryptionKey,
+	// after the suffix of their compression if any.
+	encryptedSuffix = ".enc"
+
+	// encryptMagic starts the header of encrypted files, followed by the
+	// version, the length and bytes of the key ID, and the nonce.
+	encryptMagic   = "HCLOGENC"
+	encryptVersion = 1
+
+	// encryptChunkSize is the size of the chunks encrypted one at a time, so
+	// that files are encrypted and decrypted without holding them in memory.
+	// Every chunk but the last is this size, the last one being shorter,
+	// possibly empty, so that a file cut at a chunk boundary isn't taken for
+	// a complete one.
+	encryptChunkSize = 64 * 1024
+)
+
+// errEncryptedTruncated is returned when reading an encrypted file which ends
+// before its last chunk.
+var errEncryptedTruncated = errors.New("encrypted log file is truncated")
+
+// newAEAD returns the AES-GCM cipher of key, which must be 16, 24 or 32 bytes
+// long.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of the chunk of a file, the nonce of its
+// header combined with the index of the chunk.
+func chunkNonce(dst, nonce []byte, chunk uint64) []byte {
+	dst = append(dst[:0], nonce...)
+	var index [8]byte
+	binary.BigEndian.PutUint64(index[:], chunk)
+	for i := range index {
+		dst[len(dst)-8+i] ^= index[i]
+	}
+	return dst
+}
+
+// chunkAD returns the additional data authenticated with a chunk: the header
+// of the file, so that its key ID can't be swapped, and whether the chunk is
+// the last one.
+func chunkAD(dst, header []byte, last bool) []byte {
+	dst = append(dst[:0], header...)
+	if last {
+		return append(dst, 1)
+	}
+	return append(dst, 0)
+}
+
+// encryptWriter encrypts what is written to it in chunks, after writing the
+// header of the file.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	nonce  []byte
+	chunk  uint64
+
+	buf     []byte
+	scratch []byte
+	ad      []byte
+	cnonce  []byte
+}
+
+// newEncryptWriter writes the header of an encrypted file to w, and returns
+// a writer encrypting the content of the file with key.
+func newEncryptWriter(w io.Writer, keyID string, key []byte) (*encryptWriter, error) {
+	if len(keyID) > 255 {
+		return nil, fmt.Errorf("log encryption key ID is longer than 255 bytes")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(encryptMagic), encryptVersion, byte(len(keyID)))
+	header = append(append(header, keyID...), nonce...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		header: header,
+		nonce:  nonce,
+		buf:    make([]byte, 0, encryptChunkSize),
+	}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data follows it, the last
+		// chunk having to be shorter.
+		if len(e.buf) == encryptChunkSize {
+			if err := e.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		c := copy(e.buf[len(e.buf):encryptChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+c]
+		p = p[c:]
+	}
+	return n, nil
+}
+
+// Close writes the last chunk. It doesn't close the underlying writer.
+func (e *encryptWriter) Close() error {
+	if len(e.buf) == encryptChunkSize {
+		if err := e.seal(false); err != nil {
+			return err
+		}
+	}
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(last bool) error {
+	e.cnonce = chunkNonce(e.cnonce, e.nonce, e.chunk)
+	e.ad = chunkAD(e.ad, e.header, last)
+	e.scratch = e.aead.Seal(e.scratch[:0], e.cnonce, e.buf, e.ad)
+	e.chunk++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.scratch)
+	return err
+}
+
+// decryptReader decrypts the chunks of an encrypted file.
+type decryptReader struct {
+	f      *os.File
+	aead   cipher.AEAD
+	header []byte
+	nonce  []byte
+	chunk  uint64
+
+	in     []byte
+	plain  []byte
+	ad     []byte
+	cnonce []byte
+	last   bool
+	err    error
+}
+
+// DecryptFile opens a rotated file encrypted with EncryptionKey, and returns
+// a reader of its content, which is still compressed if the file name ends
+// with .gz.enc or .zst.enc, see OpenRotated. keyFn returns the key of the ID
+// written in the header of the file.
+// Every chunk of the file is authenticated before it is returned, so a file
+// that was tampered with or truncated fails with an error rather than
+// returning forged or partial content as complete.
+func DecryptFile(path string, keyFn func(keyID string) ([]byte, error)) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header, keyID, err := readEncryptHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	key, err := keyFn(keyID)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to get the key %q of %s: %w", keyID, path, err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if len(header) != len(encryptMagic)+2+len(keyID)+aead.NonceSize() {
+		f.Close()
+		return nil, fmt.Errorf("failed to read %s: invalid nonce size", path)
+	}
+
+	return &decryptReader{
+		f:      f,
+		aead:   aead,
+		header: header,
+		nonce:  header[len(header)-aead.NonceSize():],
+		in:     make([]byte, encryptChunkSize+aead.Overhead()),
+	}, nil
+}
+
+// readEncryptHeader reads the header of an encrypted file, and returns it
+// with the key ID it holds. The nonce is assumed to be the standard size of
+// AES-GCM.
+func readEncryptHeader(r io.Reader) ([]byte, string, error) {
+	header := make([]byte, len(encryptMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, "", fmt.Errorf("invalid encrypted log file header: %w", err)
+	}
+	if string(header[:len(encryptMagic)]) != encryptMagic {
+		return nil, "", errors.New("not an encrypted log file")
+	}
+	if version := header[len(encryptMagic)]; version != encryptVersion {
+		return nil, "", fmt.Errorf("unknown encrypted log file version %d", version)
+	}
+	rest := make([]byte, int(header[len(encryptMagic)+1])+12)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, "", fmt.Errorf("invalid encrypted log file header: %w", err)
+	}
+	keyID := string(rest[:len(rest)-12])
+	return append(header, rest...), keyID, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.last {
+			return 0, io.EOF
+		}
+		d.err = d.open()
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk.
+func (d *decryptReader) open() error {
+	n, err := io.ReadFull(d.f, d.in)
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		d.last = true
+	default:
+		return err
+	}
+	if d.last && n < d.aead.Overhead() {
+		return errEncryptedTruncated
+	}
+
+	d.cnonce = chunkNonce(d.cnonce, d.nonce, d.chunk)
+	d.ad = chunkAD(d.ad, d.header, d.last)
+	plain, err := d.aead.Open(d.in[:0], d.cnonce, d.in[:n], d.ad)
+	if err != nil {
+		if d.last {
+			return errEncryptedTruncated
+		}
+		return fmt.Errorf("failed to decrypt log file: %w", err)
+	}
+	d.chunk++
+	d.plain = plain
+	return nil
+}
+
+func (d *decryptReader) Close() error {
+	return d.f.Close()
+}
@@ -0,0 +1,252 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// testKeyFn returns testKey for the key ID "test".
+func testKeyFn(keyID string) ([]byte, error) {
+	if keyID != "test" {
+		return nil, errors.New("unknown key")
+	}
+	return testKey, nil
+}
+
+// readEncrypted returns the decrypted content of the file at path, or the
+// error reading it.
+func readEncrypted(path string, keyFn func(string) ([]byte, error)) (string, error) {
+	r, err := DecryptFile(path, keyFn)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	var in io.Reader = r
+	if strings.HasSuffix(path, ".gz"+encryptedSuffix) {
+		if in, err = gzip.NewReader(r); err != nil {
+			return "", err
+		}
+	}
+	b, err := ioutil.ReadAll(in)
+	return string(b), err
+}
+
+// writeEncrypted writes data encrypted with testKey to path.
+func writeEncrypted(t *testing.T, path string, data []byte) {
+	var buf bytes.Buffer
+	if err := writeCompressed(&buf, bytes.NewReader(data), nil, 0, "test", testKey); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestLogFile_Encrypt(t *testing.T) {
+	t.Parallel()
+	for _, compress := range []bool{false, true} {
+		tempDir := testutil.TempDir(t, "LogWriterEncrypt")
+		defer os.RemoveAll(tempDir)
+		logFile := LogFile{
+			logFilter: LevelFilter(),
+			fileName:  testFileName,
+			logPath:   tempDir,
+			duration:  testDuration,
+			MaxBytes:  testBytes,
+			Compress:  compress,
+			EncryptionKey: func() (string, []byte, error) {
+				return "test", testKey, nil
+			},
+		}
+		logFile.Write([]byte("Hello World"))
+		logFile.Write([]byte("Second File"))
+		if err := logFile.Close(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		files, err := logFile.rotatedFiles()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		suffix := encryptedSuffix
+		if compress {
+			suffix = ".gz" + encryptedSuffix
+		}
+		if len(files) != 1 || !strings.HasSuffix(files[0].path, ".log"+suffix) {
+			t.Fatalf("Expected an encrypted rotated file, got %v", files)
+		}
+		got, err := readEncrypted(files[0].path, testKeyFn)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if got != "Hello World" {
+			t.Errorf("Expected the rotated entry, got %q", got)
+		}
+		if _, err := os.Stat(trimCompressedSuffix(files[0].path)); !os.IsNotExist(err) {
+			t.Errorf("Expected the plaintext file to be removed")
+		}
+		if _, err := readEncrypted(files[0].path, func(string) ([]byte, error) {
+			return []byte("fedcba9876543210fedcba9876543210"), nil
+		}); err == nil {
+			t.Errorf("Expected an error decrypting with the wrong key")
+		}
+	}
+}
+
+func TestLogFile_EncryptFailure(t *testing.T) {
+	t.Parallel()
+	keys := map[string]func() (string, []byte, error){
+		"key error": func() (string, []byte, error) {
+			return "", nil, errors.New("kms unavailable")
+		},
+		"invalid key": func() (string, []byte, error) {
+			return "test", []byte("short"), nil
+		},
+	}
+	for name, key := range keys {
+		t.Run(name, func(t *testing.T) {
+			tempDir := testutil.TempDir(t, "LogWriterEncryptFailure")
+			defer os.RemoveAll(tempDir)
+			var reported []error
+			logFile := LogFile{
+				logFilter:     LevelFilter(),
+				fileName:      testFileName,
+				logPath:       tempDir,
+				duration:      testDuration,
+				MaxBytes:      testBytes,
+				EncryptionKey: key,
+				OnError:       func(err error) { reported = append(reported, err) },
+			}
+			logFile.Write([]byte("Hello World"))
+			logFile.Write([]byte("Second File"))
+			if err := logFile.Close(); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if len(reported) != 1 {
+				t.Errorf("Expected the failure to be reported, got %v", reported)
+			}
+			files, err := logFile.rotatedFiles()
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if len(files) != 1 || filepath.Ext(files[0].path) != ".log" {
+				t.Fatalf("Expected the plaintext rotated file, got %v", files)
+			}
+			if b, _ := ioutil.ReadFile(files[0].path); string(b) != "Hello World" {
+				t.Errorf("Expected the plaintext to be kept, got %q", b)
+			}
+			want := 2
+			if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+				t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+			}
+		})
+	}
+}
+
+func TestLogFile_EncryptRetention(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterEncryptRetention")
+	defer os.RemoveAll(tempDir)
+	old := []string{"Consul-20200101000000.log.enc", "Consul-20200102000000.log.gz.enc"}
+	for _, name := range old {
+		writeEncrypted(t, filepath.Join(tempDir, name), []byte(name))
+	}
+
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+		MaxFiles:  2,
+		EncryptionKey: func() (string, []byte, error) {
+			return "test", testKey, nil
+		},
+	}
+	logFile.Write([]byte("Hello World"))
+	logFile.Write([]byte("Second File"))
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, old[0])); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest encrypted file to be pruned")
+	}
+	files, err := logFile.rotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != 2 || filepath.Base(files[0].path) != old[1] || filepath.Ext(files[1].path) != encryptedSuffix {
+		t.Errorf("Expected the encrypted files to be kept, got %v", files)
+	}
+}
+
+func TestDecryptFile(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "DecryptFile")
+	defer os.RemoveAll(tempDir)
+
+	large := bytes.Repeat([]byte("0123456789abcdef"), encryptChunkSize/8)
+	cases := map[string][]byte{
+		"empty":      nil,
+		"short":      []byte("Hello World"),
+		"full chunk": large[:encryptChunkSize],
+		"chunks":     large,
+		"partial":    large[:len(large)-3],
+	}
+	for name, data := range cases {
+		path := filepath.Join(tempDir, strings.Replace(name, " ", "-", -1)+".log.enc")
+		writeEncrypted(t, path, data)
+		got, err := readEncrypted(path, testKeyFn)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if got != string(data) {
+			t.Errorf("%s: Expected %d bytes, got %d", name, len(data), len(got))
+		}
+	}
+
+	path := filepath.Join(tempDir, "tampered.log.enc")
+	writeEncrypted(t, path, large)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tampered := append([]byte(nil), b...)
+	tampered[len(tampered)/2] ^= 1
+	if err := ioutil.WriteFile(path, tampered, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := readEncrypted(path, testKeyFn); err == nil {
+		t.Errorf("Expected an error reading a tampered file")
+	}
+
+	// Cut at the end of the first chunk, which is a valid chunk on its own
+	header := len(encryptMagic) + 2 + len("test") + 12
+	if err := ioutil.WriteFile(path, b[:header+encryptChunkSize+16], 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := readEncrypted(path, testKeyFn); err != errEncryptedTruncated {
+		t.Errorf("Expected %v, got %v", errEncryptedTruncated, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("Hello World"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := DecryptFile(path, testKeyFn); err == nil {
+		t.Errorf("Expected an error reading a plaintext file")
+	}
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RotateNaming selects how rotated files are named.
+type RotateNaming int
+
+const (
+	// RotateNamingTimestamp adds the rotation time to the name of rotated
+	// files, as in name-20060102150405.log. This is the default.
+	RotateNamingTimestamp RotateNaming = iota
+
+	// RotateNamingIndex adds an index to the name of the active file, as in
+	// name.log.1, like logrotate does. The most recent rotated file has index
+	// 1, and every rotation shifts the existing indices up by one.
+	RotateNamingIndex
+)
+
+// activePath returns the path of the active log file.
+func (l *LogFile) activePath() string {
+	return filepath.Join(l.logPath, fmt.Sprintf(l.fileNamePattern(), ""))
+}
+
+// rotatedGlob returns the glob matching the rotated files of this LogFile,
+// without compressedSuffixes for the timestamp naming.
+func (l *LogFile) rotatedGlob() string {
+	if l.RotateNaming == RotateNamingIndex {
+		return l.activePath() + ".*"
+	}
+	return filepath.Join(l.logPath, fmt.Sprintf(l.fileNamePattern(), "-*"))
+}
+
+// parseIndexName returns the index of a rotated file name with the index
+// naming, and false if it isn't one. The name may have one of
+// compressedSuffixes.
+func (l *LogFile) parseIndexName(name string) (int, bool) {
+	prefix := filepath.Base(l.activePath()) + "."
+	name = trimCompressedSuffix(name)
+	if !strings.HasPrefix(name, prefix) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(name, prefix))
+	if err != nil || index < 1 || strconv.Itoa(index) != strings.TrimPrefix(name, prefix) {
+		return 0, false
+	}
+	return index, true
+}
+
+// nextRotateName returns the name to move the active file to. With the index
+// naming, the existing rotated files are shifted up by one index first,
+// starting from the highest so that no file is overwritten, and files that
+// would go past MaxFiles are removed instead, unless CanPrune refuses it.
+// Compressed files keep their suffix. The caller must hold the acquire mutex.
+func (l *LogFile) nextRotateName() (string, error) {
+	if l.RotateNaming != RotateNamingIndex {
+		return l.uniqueRotateName(), nil
+	}
+	files, err := l.rotatedFiles()
+	if err != nil {
+		return "", err
+	}
+	// rotatedFiles returns the highest index first
+	active := l.activePath()
+	for _, f := range files {
+		if l.MaxFiles > 0 && f.seq >= l.MaxFiles && l.canPrune(f.path) {
+			if err := l.removeRotated(f.path); err != nil {
+				return "", err
+			}
+			continue
+		}
+		suffix := strings.TrimPrefix(filepath.Base(f.path), filepath.Base(trimCompressedSuffix(f.path)))
+		next := fmt.Sprintf("%s.%d%s", active, f.seq+1, suffix)
+		if err := rename(f.path, next); err != nil {
+			return "", err
+		}
+		if err := l.moveChain(f.path, next); err != nil {
+			return "", err
+		}
+	}
+	return active + ".1", nil
+}
@@ -1,14 +1,22 @@
 package logger
 
 import (
+	"errors"
+	"expvar"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/consul/sdk/testutil"
 	"github.com/hashicorp/logutils"
+	hclog "github.com/varnson/go-hclog"
 )
 
 const (
@@ -158,3 +166,1917 @@ func TestLogFile_deleteArchivesDisabled(t *testing.T) {
 		return
 	}
 }
+
+func TestLogFile_Rotate(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterRotate")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  24 * time.Hour,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("Expected rotation to succeed, got an error (%s)", err)
+	}
+	if logFile.BytesWritten != 0 {
+		t.Errorf("Expected BytesWritten to be reset, got %d", logFile.BytesWritten)
+	}
+	logFile.Write([]byte("[INFO] Second File"))
+	want := 2
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+	if got := logFile.BytesWritten; got != int64(len("[INFO] Second File")) {
+		t.Errorf("Expected BytesWritten to track the new file, got %d", got)
+	}
+}
+
+func TestLogFile_RotateBeforeOpen(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterRotateBeforeOpen")
+	defer os.Remove(tempDir)
+	logFile := LogFile{fileName: testFileName, logPath: tempDir, duration: testDuration}
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("Expected rotation to succeed, got an error (%s)", err)
+	}
+	want := 1
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+}
+
+func TestLogFile_RotateConcurrentWrites(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterRotateConcurrent")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  24 * time.Hour,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logFile.Write([]byte("[INFO] concurrent write\n"))
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 5; j++ {
+			if err := logFile.Rotate(); err != nil {
+				t.Errorf("Expected rotation to succeed, got an error (%s)", err)
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestLogFile_Reopen(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterReopen")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  24 * time.Hour,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+
+	// Simulate an external logrotate moving the file away.
+	moved := filepath.Join(tempDir, "moved.log")
+	if err := os.Rename(filepath.Join(tempDir, testFileName), moved); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := logFile.Reopen(); err != nil {
+		t.Fatalf("Expected reopen to succeed, got an error (%s)", err)
+	}
+	if logFile.BytesWritten != 0 {
+		t.Errorf("Expected BytesWritten to be reset, got %d", logFile.BytesWritten)
+	}
+	logFile.Write([]byte("[INFO] Second File"))
+
+	if bytes, _ := ioutil.ReadFile(moved); string(bytes) != "[INFO] Hello World" {
+		t.Errorf("Expected the moved file to keep the old entry, got %q", bytes)
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "[INFO] Second File" {
+		t.Errorf("Expected the reopened file to contain the new entry, got %q", bytes)
+	}
+}
+
+func TestLogFile_NotifyOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP not supported on Windows")
+	}
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterSignal")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  24 * time.Hour,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+	stop := logFile.NotifyOnSignal()
+	defer stop()
+
+	path := filepath.Join(tempDir, testFileName)
+	if err := os.Rename(path, filepath.Join(tempDir, "moved.log")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s to be reopened after SIGHUP", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// setNow replaces the clock used by LogFile and returns a function restoring
+// it. Tests using it must not run in parallel.
+func setNow(fn func() time.Time) (restore func()) {
+	old := now
+	now = fn
+	return func() { now = old }
+}
+
+func TestLogFile_dailyRotation(t *testing.T) {
+	tempDir := testutil.TempDir(t, "LogWriterDaily")
+	defer os.Remove(tempDir)
+	current := time.Date(2020, 1, 1, 23, 30, 0, 0, time.Local)
+	defer setNow(func() time.Time { return current })()
+
+	logFile := LogFile{
+		logFilter:   LevelFilter(),
+		fileName:    testFileName,
+		logPath:     tempDir,
+		RotateDaily: true,
+	}
+	logFile.Write([]byte("[INFO] January 1st"))
+	current = time.Date(2020, 1, 1, 23, 59, 0, 0, time.Local)
+	logFile.Write([]byte("[INFO] Still January 1st"))
+	current = time.Date(2020, 1, 2, 0, 1, 0, 0, time.Local)
+	logFile.Write([]byte("[INFO] January 2nd"))
+
+	// The process being quiet across several midnights causes one rotation.
+	current = time.Date(2020, 1, 5, 10, 0, 0, 0, time.Local)
+	logFile.Write([]byte("[INFO] January 5th"))
+
+	want := map[string]string{
+		"Consul-20200101.log": "[INFO] January 1st[INFO] Still January 1st",
+		"Consul-20200102.log": "[INFO] January 2nd",
+		"Consul.log":          "[INFO] January 5th",
+	}
+	tempFiles, _ := ioutil.ReadDir(tempDir)
+	if len(tempFiles) != len(want) {
+		t.Fatalf("Expected %d files, got %v file(s)", len(want), len(tempFiles))
+	}
+	for name, contents := range want {
+		bytes, err := ioutil.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Expected file %s, got an error (%s)", name, err)
+		}
+		if string(bytes) != contents {
+			t.Errorf("Expected %s to contain %q, got %q", name, contents, bytes)
+		}
+	}
+}
+
+func TestLogFile_dailyRotationHour(t *testing.T) {
+	tempDir := testutil.TempDir(t, "LogWriterDailyHour")
+	defer os.Remove(tempDir)
+	// Before the rotation hour, data still belongs to the previous day.
+	current := time.Date(2020, 1, 2, 1, 0, 0, 0, time.Local)
+	defer setNow(func() time.Time { return current })()
+
+	logFile := LogFile{
+		logFilter:   LevelFilter(),
+		fileName:    testFileName,
+		logPath:     tempDir,
+		RotateDaily: true,
+		RotateHour:  2,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+	current = time.Date(2020, 1, 2, 2, 0, 0, 0, time.Local)
+	logFile.Write([]byte("[INFO] Second File"))
+
+	if _, err := os.Stat(filepath.Join(tempDir, "Consul-20200101.log")); err != nil {
+		t.Errorf("Expected the rotated file to be named after January 1st, got an error (%s)", err)
+	}
+}
+
+func TestLogFile_dailyRotationDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %s", err)
+	}
+	tempDir := testutil.TempDir(t, "LogWriterDailyDST")
+	defer os.Remove(tempDir)
+	// Clocks move forward on 2020-03-08, so that day is only 23 hours long.
+	current := time.Date(2020, 3, 8, 0, 30, 0, 0, loc)
+	defer setNow(func() time.Time { return current })()
+
+	logFile := LogFile{
+		logFilter:   LevelFilter(),
+		fileName:    testFileName,
+		logPath:     tempDir,
+		RotateDaily: true,
+	}
+	logFile.Write([]byte("[INFO] March 8th"))
+	current = time.Date(2020, 3, 9, 0, 30, 0, 0, loc)
+	logFile.Write([]byte("[INFO] March 9th"))
+
+	if _, err := os.Stat(filepath.Join(tempDir, "Consul-20200308.log")); err != nil {
+		t.Errorf("Expected rotation at midnight after the DST change, got an error (%s)", err)
+	}
+}
+
+func TestLogFile_rotateTimeFormat(t *testing.T) {
+	tempDir := testutil.TempDir(t, "LogWriterRotateTimeFormat")
+	defer os.Remove(tempDir)
+	current := time.Date(2020, 1, 1, 12, 30, 15, 0, time.FixedZone("CET", 3600))
+	defer setNow(func() time.Time { return current })()
+
+	logFile := LogFile{
+		logFilter:        LevelFilter(),
+		fileName:         testFileName,
+		logPath:          tempDir,
+		duration:         24 * time.Hour,
+		MaxFiles:         1,
+		RotateTimeFormat: "2006-01-02T150405Z",
+		RotateTimeUTC:    true,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+	current = current.Add(time.Minute)
+	logFile.Rotate()
+	if _, err := os.Stat(filepath.Join(tempDir, "Consul-2020-01-01T113015Z.log")); err != nil {
+		t.Fatalf("Expected the rotated file, got an error (%s)", err)
+	}
+	logFile.Write([]byte("[INFO] Second File"))
+	logFile.Rotate()
+
+	// Pruning on rotation matches the rotated files whatever their time
+	// layout, and leaves the active file alone.
+	tempFiles, _ := ioutil.ReadDir(tempDir)
+	var names []string
+	for _, f := range tempFiles {
+		names = append(names, f.Name())
+	}
+	want := []string{"Consul-2020-01-01T113115Z.log", "Consul.log"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected files %v, got %v", want, names)
+	}
+}
+
+func TestLogFile_dailyRotationUTC(t *testing.T) {
+	tempDir := testutil.TempDir(t, "LogWriterDailyUTC")
+	defer os.Remove(tempDir)
+	// 00:30 on January 2nd in UTC+1 is still January 1st in UTC.
+	cet := time.FixedZone("CET", 3600)
+	current := time.Date(2020, 1, 2, 0, 30, 0, 0, cet)
+	defer setNow(func() time.Time { return current })()
+
+	logFile := LogFile{
+		logFilter:     LevelFilter(),
+		fileName:      testFileName,
+		logPath:       tempDir,
+		RotateDaily:   true,
+		RotateTimeUTC: true,
+	}
+	logFile.Write([]byte("[INFO] January 1st UTC"))
+	current = time.Date(2020, 1, 2, 0, 59, 0, 0, cet)
+	logFile.Write([]byte("[INFO] Still January 1st UTC"))
+	current = time.Date(2020, 1, 2, 1, 0, 0, 0, cet)
+	logFile.Write([]byte("[INFO] January 2nd UTC"))
+
+	bytes, err := ioutil.ReadFile(filepath.Join(tempDir, "Consul-20200101.log"))
+	if err != nil {
+		t.Fatalf("Expected the rotated file to be named after January 1st, got an error (%s)", err)
+	}
+	if want := "[INFO] January 1st UTC[INFO] Still January 1st UTC"; string(bytes) != want {
+		t.Errorf("Expected the rotated file to contain %q, got %q", want, bytes)
+	}
+}
+
+func TestLogFile_invalidRotateTimeFormat(t *testing.T) {
+	t.Parallel()
+	for _, layout := range []string{"", "2006/01/02", "01/02 15:04"} {
+		if err := validateRotateTimeFormat(layout); err == nil {
+			t.Errorf("Expected an error for rotate time format %q", layout)
+		}
+	}
+
+	tempDir := testutil.TempDir(t, "LogWriterInvalidRotateTimeFormat")
+	defer os.Remove(tempDir)
+	logFile := LogFile{fileName: testFileName, logPath: tempDir, RotateTimeFormat: "2006/01/02"}
+	if err := logFile.openNew(); err == nil {
+		t.Errorf("Expected opening a file with an invalid rotate time format to fail")
+	}
+}
+
+func TestLogFile_rotateNameCollision(t *testing.T) {
+	tempDir := testutil.TempDir(t, "LogWriterRotateCollision")
+	defer os.Remove(tempDir)
+	current := time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)
+	defer setNow(func() time.Time { return current })()
+
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		MaxBytes:  testBytes,
+		duration:  24 * time.Hour,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+	logFile.Write([]byte("[INFO] Second File"))
+	logFile.Write([]byte("[INFO] Third File"))
+
+	want := map[string]string{
+		"Consul-20200101120000.log":   "[INFO] Hello World",
+		"Consul-20200101120000.1.log": "[INFO] Second File",
+		"Consul.log":                  "[INFO] Third File",
+	}
+	tempFiles, _ := ioutil.ReadDir(tempDir)
+	if len(tempFiles) != len(want) {
+		t.Fatalf("Expected %d files, got %v file(s)", len(want), len(tempFiles))
+	}
+	for name, contents := range want {
+		bytes, err := ioutil.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("Expected file %s, got an error (%s)", name, err)
+		}
+		if string(bytes) != contents {
+			t.Errorf("Expected %s to contain %q, got %q", name, contents, bytes)
+		}
+	}
+
+	// The sequence number orders files rotated within the same second.
+	files, err := logFile.rotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != 2 || files[0].seq != 0 || files[1].seq != 1 {
+		t.Errorf("Expected rotated files ordered by sequence, got %v", files)
+	}
+}
+
+func TestLogFile_byteRotationBeforeWrite(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterBytesBeforeWrite")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		MaxBytes:  20,
+		duration:  24 * time.Hour,
+	}
+	entries := []string{
+		"[INFO] 1",
+		"[INFO] 2",
+		"[INFO] 3",
+		"[INFO] larger than MaxBytes",
+		"[INFO] 5",
+	}
+	for _, entry := range entries {
+		if _, err := logFile.Write([]byte(entry)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	files, err := logFile.rotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var got []string
+	for _, f := range files {
+		bytes, _ := ioutil.ReadFile(f.path)
+		got = append(got, string(bytes))
+	}
+	bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName))
+	got = append(got, string(bytes))
+
+	want := []string{
+		"[INFO] 1[INFO] 2",
+		"[INFO] 3",
+		"[INFO] larger than MaxBytes",
+		"[INFO] 5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected files %q, got %q", want, got)
+	}
+	for _, contents := range got {
+		if len(contents) > 20 && strings.Count(contents, "[INFO]") > 1 {
+			t.Errorf("Expected file of %d bytes to hold a single entry: %q", len(contents), contents)
+		}
+	}
+}
+
+func TestLogFile_fileMoved(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("open files can't be removed on Windows")
+	}
+	tempDir := testutil.TempDir(t, "LogWriterFileMoved")
+	defer os.Remove(tempDir)
+	current := time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)
+	defer setNow(func() time.Time { return current })()
+
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  24 * time.Hour,
+	}
+	path := filepath.Join(tempDir, testFileName)
+	logFile.Write([]byte("[INFO] Hello World"))
+
+	// Removing the file is only noticed after fileCheckInterval.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	logFile.Write([]byte("[INFO] Lost"))
+	current = current.Add(fileCheckInterval)
+	logFile.Write([]byte("[INFO] Second File"))
+	if bytes, _ := ioutil.ReadFile(path); string(bytes) != "[INFO] Second File" {
+		t.Errorf("Expected the file to be recreated, got %q", bytes)
+	}
+	if logFile.BytesWritten != int64(len("[INFO] Second File")) {
+		t.Errorf("Expected BytesWritten to be reset, got %d", logFile.BytesWritten)
+	}
+
+	// Renaming the file is detected as well.
+	moved := filepath.Join(tempDir, "moved.log")
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	current = current.Add(fileCheckInterval)
+	logFile.Write([]byte("[INFO] Third File"))
+	if bytes, _ := ioutil.ReadFile(path); string(bytes) != "[INFO] Third File" {
+		t.Errorf("Expected the file to be recreated, got %q", bytes)
+	}
+	if bytes, _ := ioutil.ReadFile(moved); string(bytes) != "[INFO] Second File" {
+		t.Errorf("Expected the moved file to be left alone, got %q", bytes)
+	}
+}
+
+func benchmarkLogFileWrite(b *testing.B, checkInterval time.Duration) {
+	old := fileCheckInterval
+	fileCheckInterval = checkInterval
+	defer func() { fileCheckInterval = old }()
+
+	tempDir := testutil.TempDir(b, "LogWriterBench")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  24 * time.Hour,
+	}
+	entry := []byte("2020-01-01T12:00:00.000Z [INFO] -- benchmark entry: key=value\n")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logFile.Write(entry)
+	}
+}
+
+func BenchmarkLogFile_Write(b *testing.B) {
+	benchmarkLogFileWrite(b, fileCheckInterval)
+}
+
+func BenchmarkLogFile_WriteCheckEveryEntry(b *testing.B) {
+	benchmarkLogFileWrite(b, 0)
+}
+
+func TestLogFile_syncPolicy(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterSyncPolicy")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter:  LevelFilter(),
+		fileName:   testFileName,
+		logPath:    tempDir,
+		duration:   24 * time.Hour,
+		SyncPolicy: SyncPeriodic,
+		SyncBytes:  30,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+	if logFile.unsynced != 18 {
+		t.Errorf("Expected 18 unsynced bytes, got %d", logFile.unsynced)
+	}
+	logFile.Write([]byte("[INFO] Second Entry"))
+	if logFile.unsynced != 0 {
+		t.Errorf("Expected the file to be synced after SyncBytes, got %d unsynced bytes", logFile.unsynced)
+	}
+	logFile.Write([]byte("[INFO] Third Entry"))
+	if err := logFile.Sync(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if logFile.unsynced != 0 {
+		t.Errorf("Expected no unsynced bytes after Sync, got %d", logFile.unsynced)
+	}
+}
+
+func TestLogFile_syncInterval(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterSyncInterval")
+	defer os.Remove(tempDir)
+	logFile := &LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     24 * time.Hour,
+		SyncPolicy:   SyncPeriodic,
+		SyncInterval: 10 * time.Millisecond,
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("[INFO] Hello World"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		logFile.acquire.Lock()
+		unsynced := logFile.unsynced
+		logFile.acquire.Unlock()
+		if unsynced == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the file to be synced in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func BenchmarkLogFile_WriteSyncEveryWrite(b *testing.B) {
+	tempDir := testutil.TempDir(b, "LogWriterBenchSync")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter:  LevelFilter(),
+		fileName:   testFileName,
+		logPath:    tempDir,
+		duration:   24 * time.Hour,
+		SyncPolicy: SyncEveryWrite,
+	}
+	entry := []byte("2020-01-01T12:00:00.000Z [INFO] -- benchmark entry: key=value\n")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logFile.Write(entry)
+	}
+}
+
+func TestLogFile_buffered(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterBuffered")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter:  LevelFilter(),
+		fileName:   testFileName,
+		logPath:    tempDir,
+		duration:   24 * time.Hour,
+		BufferSize: 32,
+	}
+	path := filepath.Join(tempDir, testFileName)
+	logFile.Write([]byte("[INFO] Hello World"))
+	if bytes, _ := ioutil.ReadFile(path); len(bytes) != 0 {
+		t.Errorf("Expected the entry to be buffered, got %q", bytes)
+	}
+	if logFile.BytesWritten != 18 {
+		t.Errorf("Expected BytesWritten to count buffered bytes, got %d", logFile.BytesWritten)
+	}
+
+	// An entry that doesn't fit flushes the buffer without being split.
+	logFile.Write([]byte("[INFO] Second Entry"))
+	if bytes, _ := ioutil.ReadFile(path); string(bytes) != "[INFO] Hello World" {
+		t.Errorf("Expected only whole entries to be flushed, got %q", bytes)
+	}
+
+	if err := logFile.Flush(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes, _ := ioutil.ReadFile(path); string(bytes) != "[INFO] Hello World[INFO] Second Entry" {
+		t.Errorf("Expected Flush to write buffered entries, got %q", bytes)
+	}
+}
+
+func TestLogFile_bufferedFlushOnRotate(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterBufferedRotate")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter:  LevelFilter(),
+		fileName:   testFileName,
+		logPath:    tempDir,
+		MaxBytes:   testBytes,
+		duration:   24 * time.Hour,
+		BufferSize: 1024,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+	logFile.Write([]byte("[INFO] Second File"))
+
+	files, err := logFile.rotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 rotated file, got %d", len(files))
+	}
+	if bytes, _ := ioutil.ReadFile(files[0].path); string(bytes) != "[INFO] Hello World" {
+		t.Errorf("Expected the buffer to be flushed before rotating, got %q", bytes)
+	}
+}
+
+func TestLogFile_bufferedFlushInterval(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterBufferedInterval")
+	defer os.Remove(tempDir)
+	logFile := &LogFile{
+		logFilter:     LevelFilter(),
+		fileName:      testFileName,
+		logPath:       tempDir,
+		duration:      24 * time.Hour,
+		BufferSize:    1024,
+		FlushInterval: 10 * time.Millisecond,
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("[INFO] Hello World"))
+
+	path := filepath.Join(tempDir, testFileName)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if bytes, _ := ioutil.ReadFile(path); string(bytes) == "[INFO] Hello World" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the buffer to be flushed in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLogFile_Close(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterClose")
+	defer os.Remove(tempDir)
+	logFile := LogFile{
+		logFilter:     LevelFilter(),
+		fileName:      testFileName,
+		logPath:       tempDir,
+		duration:      24 * time.Hour,
+		BufferSize:    1024,
+		FlushInterval: time.Hour,
+		SyncPolicy:    SyncPeriodic,
+		SyncInterval:  time.Hour,
+	}
+	logFile.Write([]byte("[INFO] Hello World"))
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "[INFO] Hello World" {
+		t.Errorf("Expected Close to flush buffered entries, got %q", bytes)
+	}
+
+	if _, err := logFile.Write([]byte("[INFO] After Close")); err != ErrLogFileClosed {
+		t.Errorf("Expected ErrLogFileClosed writing after Close, got %v", err)
+	}
+	if err := logFile.Rotate(); err != ErrLogFileClosed {
+		t.Errorf("Expected ErrLogFileClosed rotating after Close, got %v", err)
+	}
+	if err := logFile.Close(); err != nil {
+		t.Errorf("Expected closing twice to succeed, got %s", err)
+	}
+
+	// The file is no longer held open, so it can be removed on all platforms.
+	if err := os.Remove(filepath.Join(tempDir, testFileName)); err != nil {
+		t.Errorf("err: %s", err)
+	}
+}
+
+func TestLogFile_CloseBeforeOpen(t *testing.T) {
+	t.Parallel()
+	logFile := LogFile{fileName: testFileName, logPath: os.TempDir()}
+	if err := logFile.Close(); err != nil {
+		t.Errorf("Expected closing an unopened file to succeed, got %s", err)
+	}
+}
+
+func TestLogFile_fileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions not supported on Windows")
+	}
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterFileMode")
+	defer os.RemoveAll(tempDir)
+	logDir := filepath.Join(tempDir, "logs", "agent")
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   logDir,
+		duration:  24 * time.Hour,
+		FileMode:  0600,
+		DirMode:   0700,
+	}
+	defer logFile.Close()
+	if _, err := logFile.Write([]byte("[INFO] Hello World")); err != nil {
+		t.Fatalf("Expected the log directory to be created, got an error (%s)", err)
+	}
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dirInfo, err := os.Stat(logDir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := dirInfo.Mode().Perm(); got != 0700 {
+		t.Errorf("Expected directory mode 0700, got %o", got)
+	}
+	files, _ := ioutil.ReadDir(logDir)
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(files))
+	}
+	for _, f := range files {
+		if got := f.Mode().Perm(); got != 0600 {
+			t.Errorf("Expected %s to have mode 0600, got %o", f.Name(), got)
+		}
+	}
+}
+
+func TestNewLogFile(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterNew")
+	defer os.RemoveAll(tempDir)
+	logFile, err := NewLogFile(LogFileOptions{
+		Path:     tempDir,
+		FileName: testFileName,
+		LogLevel: "warn",
+		MaxBytes: testBytes,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer logFile.Close()
+
+	// The file is opened eagerly.
+	if _, err := os.Stat(filepath.Join(tempDir, testFileName)); err != nil {
+		t.Errorf("Expected the log file to be created, got an error (%s)", err)
+	}
+	if logFile.duration != defaultRotateDuration {
+		t.Errorf("Expected the default rotation duration, got %s", logFile.duration)
+	}
+
+	logFile.Write([]byte("[INFO] Filtered out"))
+	logFile.Write([]byte("[WARN] Hello World"))
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "[WARN] Hello World" {
+		t.Errorf("Expected only the WARN entry, got %q", bytes)
+	}
+}
+
+func TestNewLogFile_validation(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterNewValidation")
+	defer os.RemoveAll(tempDir)
+
+	// A regular file where the log directory should be makes it unwritable.
+	notDir := filepath.Join(tempDir, "file")
+	if err := ioutil.WriteFile(notDir, nil, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := map[string]LogFileOptions{
+		"empty file name":     {Path: tempDir},
+		"path in file name":   {Path: tempDir, FileName: "logs/agent.log"},
+		"negative duration":   {Path: tempDir, FileName: testFileName, Duration: -time.Second},
+		"negative max bytes":  {Path: tempDir, FileName: testFileName, MaxBytes: -1},
+		"negative max files":  {Path: tempDir, FileName: testFileName, MaxFiles: -1},
+		"invalid rotate hour": {Path: tempDir, FileName: testFileName, RotateDaily: true, RotateHour: 24},
+		"invalid time format": {Path: tempDir, FileName: testFileName, RotateTimeFormat: "2006/01/02"},
+		"negative buffer":     {Path: tempDir, FileName: testFileName, BufferSize: -1},
+		"invalid level":       {Path: tempDir, FileName: testFileName, LogLevel: "verbose"},
+		"unwritable path":     {Path: filepath.Join(notDir, "logs"), FileName: testFileName},
+	}
+	for name, opts := range cases {
+		if _, err := NewLogFile(opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLogFile_SetMinLevel(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterSetMinLevel")
+	defer os.RemoveAll(tempDir)
+	filt := LevelFilter()
+	logFile := LogFile{
+		logFilter: filt,
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+	}
+	defer logFile.Close()
+
+	logFile.Write([]byte("[DEBUG] before\n"))
+	if err := logFile.SetMinLevel("debug"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	logFile.Write([]byte("[DEBUG] lowered\n"))
+	if err := logFile.SetMinLevel("error"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	logFile.Write([]byte("[WARN] raised\n"))
+	logFile.Write([]byte("[ERR] error\n"))
+
+	if err := logFile.SetMinLevel("verbose"); err == nil {
+		t.Errorf("Expected an error for an invalid level")
+	}
+	logFile.Write([]byte("[ERR] unchanged\n"))
+
+	want := "[DEBUG] lowered\n[ERR] error\n[ERR] unchanged\n"
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != want {
+		t.Errorf("Expected %q, got %q", want, bytes)
+	}
+	if filt.MinLevel != "INFO" {
+		t.Errorf("Expected the shared filter to be unchanged, got %s", filt.MinLevel)
+	}
+}
+
+func TestLogFile_jsonLevelFilter(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterJSONLevel")
+	defer os.RemoveAll(tempDir)
+	filt := LevelFilter()
+	filt.MinLevel = logutils.LogLevel("WARN")
+	logFile := LogFile{
+		logFilter: filt,
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+	}
+	defer logFile.Close()
+
+	entries := []struct {
+		line    string
+		written bool
+	}{
+		{`{"@level":"debug","@message":"json debug"}` + "\n", false},
+		{`{"@level":"info","@message":"json info"}` + "\n", false},
+		{`{"@level":"warn","@message":"json warn"}` + "\n", true},
+		{`{"@message":"json error","@level":"error"}` + "\n", true},
+		{"[INFO] text info\n", false},
+		{"[ERR] text error\n", true},
+		{"{not json\n", true},
+	}
+	var want string
+	for _, e := range entries {
+		logFile.Write([]byte(e.line))
+		if e.written {
+			want += e.line
+		}
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != want {
+		t.Errorf("Expected %q, got %q", want, bytes)
+	}
+}
+
+func TestLogFile_deleteOldFilesScope(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterOldFilesScope")
+	defer os.RemoveAll(tempDir)
+	if err := os.Mkdir(filepath.Join(tempDir, "sub"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	old := time.Now().Add(-31 * 24 * time.Hour)
+	files := map[string]bool{
+		"agent-20200101000000.txt":     true,
+		"agent-20200101000000.txt.gz":  true,
+		"other-20200101000000.log":     false,
+		"other.log":                    false,
+		"agent-notastamp.txt":          false,
+		"sub/agent-20200101000000.txt": false,
+	}
+	for name := range files {
+		path := filepath.Join(tempDir, name)
+		if err := ioutil.WriteFile(path, []byte("old"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  "agent.txt",
+		logPath:   tempDir,
+		duration:  testDuration,
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("Hello World"))
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for name, removed := range files {
+		_, err := os.Stat(filepath.Join(tempDir, name))
+		if removed && !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", name)
+		}
+		if !removed && err != nil {
+			t.Errorf("Expected %s to survive, got an error (%s)", name, err)
+		}
+	}
+}
+
+func TestLogFile_deleteOldFilesInterleaved(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterOldFilesInterleaved")
+	defer os.RemoveAll(tempDir)
+	old := time.Now().Add(-31 * 24 * time.Hour)
+	// Young files sort between old ones, so an early exit on the first young
+	// file would leave the later old files behind.
+	files := map[string]bool{
+		"agent-20200101000000.log": true,
+		"agent-20200102000000.log": false,
+		"agent-20200103000000.log": true,
+		"agent-20200104000000.log": false,
+		"agent-20200105000000.log": true,
+	}
+	for name, isOld := range files {
+		path := filepath.Join(tempDir, name)
+		if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if isOld {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+		}
+	}
+
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  "agent.log",
+		logPath:   tempDir,
+		duration:  testDuration,
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("Hello World"))
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for name, isOld := range files {
+		_, err := os.Stat(filepath.Join(tempDir, name))
+		if isOld && !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", name)
+		}
+		if !isOld && err != nil {
+			t.Errorf("Expected %s to survive, got an error (%s)", name, err)
+		}
+	}
+}
+
+func TestLogFile_renameFailure(t *testing.T) {
+	tempDir := testutil.TempDir(t, "LogWriterRenameFailure")
+	defer os.RemoveAll(tempDir)
+	renameErr := errors.New("permission denied")
+	defer func(orig func(string, string) error) { rename = orig }(rename)
+	rename = func(string, string) error { return renameErr }
+
+	var reported []error
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+		OnError:   func(err error) { reported = append(reported, err) },
+	}
+	defer logFile.Close()
+
+	logFile.Write([]byte("Hello World"))
+	if _, err := logFile.Write([]byte("Second File")); err != nil {
+		t.Fatalf("Expected the entry to be appended, got an error (%s)", err)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], renameErr) {
+		t.Fatalf("Expected the rename error to be reported, got %v", reported)
+	}
+	if logFile.BytesWritten != 22 {
+		t.Errorf("Expected BytesWritten to keep counting, got %d", logFile.BytesWritten)
+	}
+	if err := logFile.Rotate(); !errors.Is(err, renameErr) {
+		t.Errorf("Expected Rotate to return the rename error, got %v", err)
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "Hello WorldSecond File" {
+		t.Errorf("Expected both entries in the same file, got %q", bytes)
+	}
+
+	// Rotation is retried once renaming works again
+	rename = renameFile
+	logFile.Write([]byte("Third File"))
+	want := 2
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+}
+
+func TestLogFile_pruneFailure(t *testing.T) {
+	tempDir := testutil.TempDir(t, "LogWriterPruneFailure")
+	defer os.RemoveAll(tempDir)
+	// A non-empty directory matching the rotated names can't be removed.
+	stuck := filepath.Join(tempDir, "Consul-20200101000000.log")
+	if err := os.MkdirAll(filepath.Join(stuck, "keep"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var reported []error
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+		MaxFiles:  1,
+		OnError:   func(err error) { reported = append(reported, err) },
+	}
+	defer logFile.Close()
+
+	logFile.Write([]byte("Hello World"))
+	if _, err := logFile.Write([]byte("Second File")); err != nil {
+		t.Fatalf("Expected the entry to be written, got an error (%s)", err)
+	}
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "failed to prune log files") {
+		t.Fatalf("Expected the prune error to be reported, got %v", reported)
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "Second File" {
+		t.Errorf("Expected the entry in the new file, got %q", bytes)
+	}
+}
+
+func TestLogFile_symlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need a privilege on windows")
+	}
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterSymlink")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter:   LevelFilter(),
+		fileName:    testFileName,
+		logPath:     tempDir,
+		duration:    testDuration,
+		MaxBytes:    testBytes,
+		SymlinkName: "current.log",
+	}
+	defer logFile.Close()
+
+	link := filepath.Join(tempDir, "current.log")
+	logFile.Write([]byte("Hello World"))
+	logFile.Write([]byte("Second File"))
+	if target, err := os.Readlink(link); err != nil || target != testFileName {
+		t.Errorf("Expected the link to point at %s, got %q (%v)", testFileName, target, err)
+	}
+	if bytes, _ := ioutil.ReadFile(link); string(bytes) != "Second File" {
+		t.Errorf("Expected the link to read the active file, got %q", bytes)
+	}
+	want := 3
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+}
+
+func TestLogFile_symlinkFailure(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterSymlinkFailure")
+	defer os.RemoveAll(tempDir)
+	// A directory in the way makes replacing the link fail
+	if err := os.Mkdir(filepath.Join(tempDir, "current.log"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var reported []error
+	logFile := LogFile{
+		logFilter:   LevelFilter(),
+		fileName:    testFileName,
+		logPath:     tempDir,
+		duration:    testDuration,
+		MaxBytes:    testBytes,
+		SymlinkName: "current.log",
+		OnError:     func(err error) { reported = append(reported, err) },
+	}
+	defer logFile.Close()
+
+	for _, entry := range []string{"Hello World", "Second File", "Third File!"} {
+		if _, err := logFile.Write([]byte(entry)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if len(reported) != 1 {
+		t.Errorf("Expected the failure to be reported once, got %v", reported)
+	}
+}
+
+func TestLogFile_header(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterHeader")
+	defer os.RemoveAll(tempDir)
+	filt := LevelFilter()
+	filt.MinLevel = logutils.LogLevel("WARN")
+	logFile := LogFile{
+		logFilter: filt,
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  20,
+		Header: func() []byte {
+			return []byte("# header\n")
+		},
+	}
+	defer logFile.Close()
+
+	logFile.Write([]byte("[WARN] first\n"))
+	if logFile.BytesWritten != 22 {
+		t.Errorf("Expected the header to count toward BytesWritten, got %d", logFile.BytesWritten)
+	}
+	// The header is rotated away with the first entry
+	logFile.Write([]byte("[WARN] second\n"))
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "# header\n[WARN] second\n" {
+		t.Errorf("Expected a header in the new file, got %q", bytes)
+	}
+
+	// An entry larger than MaxBytes goes after the header of an empty file
+	logFile.Write([]byte("[WARN] a very long entry\n"))
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "# header\n[WARN] a very long entry\n" {
+		t.Errorf("Expected the long entry after the header, got %q", bytes)
+	}
+	want := 3
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+
+	// Reopening a file with entries doesn't repeat the header, reopening
+	// after the file was moved away writes it to the new file
+	if err := logFile.Reopen(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "# header\n[WARN] a very long entry\n" {
+		t.Errorf("Expected a single header, got %q", bytes)
+	}
+	if err := os.Rename(filepath.Join(tempDir, testFileName), filepath.Join(tempDir, "moved.log")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := logFile.Reopen(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName)); string(bytes) != "# header\n" {
+		t.Errorf("Expected a header after reopening, got %q", bytes)
+	}
+}
+
+func TestLogFile_OnRotate(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterOnRotate")
+	defer os.RemoveAll(tempDir)
+	type rotation struct {
+		path   string
+		size   int64
+		reason RotateReason
+	}
+	var rotations []rotation
+	logFile := &LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+	}
+	logFile.OnRotate = func(path string, size int64, reason RotateReason) {
+		// The LogFile is not locked while OnRotate runs
+		logFile.Stats()
+		rotations = append(rotations, rotation{path, size, reason})
+	}
+	defer logFile.Close()
+
+	logFile.Write([]byte("Hello World"))
+	logFile.Write([]byte("Second File"))
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	logFile.Write([]byte("Third"))
+
+	if len(rotations) != 2 {
+		t.Fatalf("Expected 2 rotations, got %v", rotations)
+	}
+	for i, want := range []RotateReason{RotateSize, RotateManual} {
+		r := rotations[i]
+		if r.reason != want || r.size != 11 {
+			t.Errorf("Expected a %s rotation of 11 bytes, got %s of %d", want, r.reason, r.size)
+		}
+		if _, err := os.Stat(r.path); err != nil {
+			t.Errorf("Expected the rotated file to exist, got an error (%s)", err)
+		}
+	}
+
+	want := LogFileStats{Rotations: 2, FileSize: 5, TotalBytes: 27}
+	if got := logFile.Stats(); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestLogFile_OnRotateDuration(t *testing.T) {
+	current := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer setNow(func() time.Time { return current })()
+	tempDir := testutil.TempDir(t, "LogWriterOnRotateDuration")
+	defer os.RemoveAll(tempDir)
+	var reasons []RotateReason
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  time.Hour,
+		OnRotate: func(_ string, _ int64, reason RotateReason) {
+			reasons = append(reasons, reason)
+		},
+	}
+	defer logFile.Close()
+
+	logFile.Write([]byte("Hello World"))
+	current = current.Add(time.Hour)
+	logFile.Write([]byte("Second File"))
+	if !reflect.DeepEqual(reasons, []RotateReason{RotateDuration}) {
+		t.Errorf("Expected a duration rotation, got %v", reasons)
+	}
+}
+
+func TestLogFile_RotateOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP not supported on Windows")
+	}
+	// Not parallel, as the signal would reach other tests handling SIGHUP
+	tempDir := testutil.TempDir(t, "LogWriterRotateOnSignal")
+	defer os.RemoveAll(tempDir)
+	reasons := make(chan RotateReason, 1)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		OnRotate: func(_ string, _ int64, reason RotateReason) {
+			reasons <- reason
+		},
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("[INFO] Hello World"))
+	stop := logFile.RotateOnSignal()
+	defer stop()
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	select {
+	case reason := <-reasons:
+		if reason != RotateSignal {
+			t.Errorf("Expected a signal rotation, got %s", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a rotation after SIGHUP")
+	}
+}
+
+func TestLogFile_fallback(t *testing.T) {
+	tempDir := testutil.TempDir(t, "LogWriterFallback")
+	defer os.RemoveAll(tempDir)
+	current := time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)
+	defer setNow(func() time.Time { return current })()
+	// A regular file where the log directory should be makes it unwritable
+	logPath := filepath.Join(tempDir, "logs")
+	if err := ioutil.WriteFile(logPath, nil, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	fallback := new(strings.Builder)
+	logFile := LogFile{
+		logFilter:             LevelFilter(),
+		fileName:              testFileName,
+		logPath:               logPath,
+		duration:              testDuration,
+		FallbackAfter:         2,
+		Fallback:              fallback,
+		FallbackRetryInterval: time.Second,
+	}
+	defer logFile.Close()
+
+	if _, err := logFile.Write([]byte("first\n")); err == nil {
+		t.Errorf("Expected an error before FallbackAfter failures")
+	}
+	if _, err := logFile.Write([]byte("second\n")); err != nil {
+		t.Errorf("Expected the entry to go to the fallback, got an error (%s)", err)
+	}
+	// The file is tried again after a second, and then after two more
+	current = current.Add(time.Second)
+	logFile.Write([]byte("third\n"))
+	if err := os.Remove(logPath); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	current = current.Add(time.Second)
+	logFile.Write([]byte("fourth\n"))
+	lines := strings.Split(strings.TrimSpace(fallback.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "[ERR] logger: failed to write") || lines[1] != "second" || lines[2] != "third" || lines[3] != "fourth" {
+		t.Errorf("Expected a single notice and the entries, got %q", fallback.String())
+	}
+	if _, err := os.Stat(filepath.Join(logPath, testFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the file not to be tried before the retry interval, got %v", err)
+	}
+
+	// The file is used again once it can be written
+	current = current.Add(time.Second)
+	fallback.Reset()
+	if _, err := logFile.Write([]byte("fifth\n")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(logPath, testFileName)); string(bytes) != "fifth\n" {
+		t.Errorf("Expected the entry in the file, got %q", bytes)
+	}
+	if !strings.HasPrefix(fallback.String(), "[INFO] logger: log file") {
+		t.Errorf("Expected a recovery notice, got %q", fallback.String())
+	}
+	if logFile.BytesWritten != 6 {
+		t.Errorf("Expected only the bytes written to the file to be counted, got %d", logFile.BytesWritten)
+	}
+}
+
+func TestLogFile_idleRotation(t *testing.T) {
+	var clock sync.Mutex
+	current := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	defer setNow(func() time.Time {
+		clock.Lock()
+		defer clock.Unlock()
+		return current
+	})()
+	tempDir := testutil.TempDir(t, "LogWriterIdleRotation")
+	defer os.RemoveAll(tempDir)
+	rotated := make(chan RotateReason, 1)
+	logFile := LogFile{
+		logFilter:         LevelFilter(),
+		fileName:          testFileName,
+		logPath:           tempDir,
+		duration:          time.Hour,
+		IdleCheckInterval: 10 * time.Millisecond,
+		OnRotate: func(_ string, _ int64, reason RotateReason) {
+			rotated <- reason
+		},
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("Hello World"))
+
+	clock.Lock()
+	current = current.Add(time.Hour)
+	clock.Unlock()
+	select {
+	case reason := <-rotated:
+		if reason != RotateDuration {
+			t.Errorf("Expected a duration rotation, got %s", reason)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the idle file to be rotated")
+	}
+	want := 2
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+
+	// The new file has no entries, so it isn't rotated again
+	clock.Lock()
+	current = current.Add(time.Hour)
+	clock.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+}
+
+func TestLogFile_restart(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterRestart")
+	defer os.RemoveAll(tempDir)
+	newLogFile := func() *LogFile {
+		return &LogFile{
+			logFilter: LevelFilter(),
+			fileName:  testFileName,
+			logPath:   tempDir,
+			duration:  testDuration,
+			MaxBytes:  20,
+		}
+	}
+
+	first := newLogFile()
+	first.Write([]byte("Hello World"))
+	first.Close()
+
+	// The restarted LogFile carries on from the size of the existing file,
+	// so the combined size triggers the rotation
+	second := newLogFile()
+	defer second.Close()
+	second.Write([]byte("Second File"))
+	if second.BytesWritten != 11 {
+		t.Errorf("Expected only the new entry in the new file, got %d bytes", second.BytesWritten)
+	}
+	want := 2
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+}
+
+func TestLogFile_restartDuration(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterRestartDuration")
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, testFileName)
+	if err := ioutil.WriteFile(path, []byte("Hello World"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  time.Hour,
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("Second File"))
+	if bytes, _ := ioutil.ReadFile(path); string(bytes) != "Second File" {
+		t.Errorf("Expected the old file to be rotated, got %q", bytes)
+	}
+}
+
+func TestLogFile_CopyTruncate(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterCopyTruncate")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     testDuration,
+		MaxBytes:     testBytes,
+		MaxFiles:     1,
+		CopyTruncate: true,
+	}
+	defer logFile.Close()
+
+	path := filepath.Join(tempDir, testFileName)
+	logFile.Write([]byte("Hello World"))
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	logFile.Write([]byte("Second File"))
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !os.SameFile(before, after) {
+		t.Errorf("Expected the active file to be truncated in place")
+	}
+	if bytes, _ := ioutil.ReadFile(path); string(bytes) != "Second File" {
+		t.Errorf("Expected only the new entry in the active file, got %q", bytes)
+	}
+	if logFile.BytesWritten != 11 {
+		t.Errorf("Expected BytesWritten to restart after truncation, got %d", logFile.BytesWritten)
+	}
+
+	// Copies are pruned like renamed files
+	logFile.Write([]byte("Third File!"))
+	files, err := logFile.rotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 rotated file, got %v", files)
+	}
+	if bytes, _ := ioutil.ReadFile(files[0].path); string(bytes) != "Second File" {
+		t.Errorf("Expected the rotated copy to hold the previous entry, got %q", bytes)
+	}
+}
+
+func TestLogFile_ExclusiveLock(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterExclusiveLock")
+	defer os.RemoveAll(tempDir)
+	opts := LogFileOptions{
+		Path:          tempDir,
+		FileName:      testFileName,
+		MaxBytes:      testBytes,
+		ExclusiveLock: true,
+	}
+	first, err := NewLogFile(opts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer first.Close()
+
+	if _, err := NewLogFile(opts); err != ErrLogFileLocked {
+		t.Fatalf("Expected ErrLogFileLocked, got %v", err)
+	}
+
+	// The lock is kept across rotations
+	first.Write([]byte("Hello World"))
+	first.Write([]byte("Second File"))
+	if _, err := NewLogFile(opts); err != ErrLogFileLocked {
+		t.Fatalf("Expected ErrLogFileLocked after rotation, got %v", err)
+	}
+
+	// and released by Close
+	if err := first.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	second, err := NewLogFile(opts)
+	if err != nil {
+		t.Fatalf("Expected the lock to be released, got an error (%s)", err)
+	}
+	second.Close()
+}
+
+func TestLogFile_pruneCompressed(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterPruneCompressed")
+	defer os.RemoveAll(tempDir)
+	// The oldest file by name is the most recently modified, as compressing
+	// a file updates its modification time
+	files := []struct {
+		name string
+		size int
+	}{
+		{"Consul-20200101000000.log.gz", 10},
+		{"Consul-20200102000000.log.zst", 10},
+		{"Consul-20200103000000.log", 30},
+		{"Consul-20200104000000.log", 30},
+	}
+	for i, f := range files {
+		path := filepath.Join(tempDir, f.name)
+		if err := ioutil.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		mtime := time.Now().Add(-time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "Consul-backup.log.gz"), []byte("stray"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var reported []error
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxFiles:  4,
+		OnError:   func(err error) { reported = append(reported, err) },
+	}
+	defer logFile.Close()
+	if err := logFile.pruneFiles(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	logFile.MaxFiles = 3
+	if err := logFile.pruneFiles(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, files[0].name)); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest compressed file to be pruned")
+	}
+
+	// 70 bytes are left, the two oldest have to go to fit in 40
+	logFile.MaxTotalBytes = 40
+	if err := logFile.pruneFiles(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	got, err := logFile.rotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(got) != 1 || filepath.Base(got[0].path) != files[3].name {
+		t.Errorf("Expected only %s to be left, got %v", files[3].name, got)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "Consul-backup.log.gz")); err != nil {
+		t.Errorf("Expected the unparsable file to be left alone, got an error (%s)", err)
+	}
+	if len(reported) != 1 {
+		t.Errorf("Expected the unparsable file to be reported once, got %v", reported)
+	}
+}
+
+func TestLogFile_indexNaming(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterIndexNaming")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     testDuration,
+		MaxBytes:     testBytes,
+		MaxFiles:     3,
+		RotateNaming: RotateNamingIndex,
+	}
+	defer logFile.Close()
+
+	for _, entry := range []string{"first", "second", "third", "fourth", "fifth"} {
+		logFile.Write([]byte(entry + " entry"))
+	}
+
+	// The oldest file went past MaxFiles and was removed
+	want := map[string]string{
+		"Consul.log":   "fifth entry",
+		"Consul.log.1": "fourth entry",
+		"Consul.log.2": "third entry",
+		"Consul.log.3": "second entry",
+	}
+	files, _ := ioutil.ReadDir(tempDir)
+	if len(files) != len(want) {
+		t.Errorf("Expected %d files, got %v file(s)", len(want), len(files))
+	}
+	for name, content := range want {
+		if bytes, err := ioutil.ReadFile(filepath.Join(tempDir, name)); err != nil || string(bytes) != content {
+			t.Errorf("Expected %q in %s, got %q (%v)", content, name, bytes, err)
+		}
+	}
+}
+
+func TestLogFile_indexNamingCompressed(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterIndexNamingCompressed")
+	defer os.RemoveAll(tempDir)
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "Consul.log.1.gz"), []byte("compressed"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	logFile := LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     testDuration,
+		RotateNaming: RotateNamingIndex,
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("Hello World"))
+	if err := logFile.Rotate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, "Consul.log.2.gz")); string(bytes) != "compressed" {
+		t.Errorf("Expected the compressed file to be shifted, got %q", bytes)
+	}
+	if bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, "Consul.log.1")); string(bytes) != "Hello World" {
+		t.Errorf("Expected the rotated file at index 1, got %q", bytes)
+	}
+}
+
+func TestLogFile_LevelWrite(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterLevelWrite")
+	defer os.RemoveAll(tempDir)
+	filt := LevelFilter()
+	filt.MinLevel = logutils.LogLevel("WARN")
+	logFile := &LogFile{
+		logFilter: filt,
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+	}
+	defer logFile.Close()
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Level:       hclog.Trace,
+		Output:      logFile,
+		Color:       hclog.ForceColor,
+		DisableTime: true,
+	})
+	logger.Info("dropped")
+	logger.Warn("kept")
+
+	// The known level is used rather than the content
+	logFile.LevelWrite(hclog.Info, []byte("[ERR] dropped\n"))
+
+	bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName))
+	if strings.Contains(string(bytes), "dropped") || !strings.Contains(string(bytes), "kept") {
+		t.Errorf("Expected only the WARN entry, got %q", bytes)
+	}
+
+	// Entries without a known level are parsed
+	logFile.LevelWrite(hclog.NoLevel, []byte("[INFO] unknown\n"))
+	logFile.LevelWrite(hclog.NoLevel, []byte("[ERR] error\n"))
+	bytes, _ = ioutil.ReadFile(filepath.Join(tempDir, testFileName))
+	if strings.Contains(string(bytes), "unknown") || !strings.Contains(string(bytes), "[ERR] error") {
+		t.Errorf("Expected only the ERR entry, got %q", bytes)
+	}
+}
+
+func TestLogFile_AuditWrite(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterAuditWrite")
+	defer os.RemoveAll(tempDir)
+	filt := LevelFilter()
+	filt.MinLevel = logutils.LogLevel("ERR")
+	logFile := &LogFile{
+		logFilter: filt,
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+	}
+	defer logFile.Close()
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Output:      logFile,
+		DisableTime: true,
+	})
+	logger.Warn("dropped")
+	if err := logger.(hclog.Auditor).Audit("user login", "user", "alice"); err != nil {
+		t.Fatalf("Expected the audit entry to be written, got %v", err)
+	}
+
+	bytes, _ := ioutil.ReadFile(filepath.Join(tempDir, testFileName))
+	if string(bytes) != "[INFO]  -- user login: audit=true user=alice\n" {
+		t.Errorf("Expected only the audit entry, got %q", bytes)
+	}
+}
+
+func TestLogFile_PublishExpvar(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterExpvar")
+	defer os.RemoveAll(tempDir)
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+	}
+	defer logFile.Close()
+	logFile.PublishExpvar("logfiletest")
+	logFile.PublishExpvar("logfiletest")
+
+	logFile.Write([]byte("Hello World"))
+	logFile.Write([]byte("Second File"))
+
+	if v := expvar.Get("logfiletest.rotations"); v == nil || v.String() != "1" {
+		t.Errorf("Expected 1 rotation, got %v", v)
+	}
+	if v := expvar.Get("logfiletest.total_bytes"); v == nil || v.String() != "22" {
+		t.Errorf("Expected 22 bytes, got %v", v)
+	}
+	if expvar.Get("logfiletest.file_size") == nil {
+		t.Errorf("Expected the file size to be published")
+	}
+}
+
+func TestLogFile_CanPrune(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterCanPrune")
+	defer os.RemoveAll(tempDir)
+	names := []string{
+		"Consul-20200101000000.log",
+		"Consul-20200102000000.log.gz",
+		"Consul-20200103000000.log",
+	}
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(tempDir, name), []byte("data"), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	held := map[string]bool{names[0]: true}
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxFiles:  1,
+		CanPrune:  func(path string) bool { return !held[filepath.Base(path)] },
+	}
+	defer logFile.Close()
+
+	if err := logFile.pruneFiles(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	got, err := logFile.RotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	want := []string{filepath.Join(tempDir, names[0]), filepath.Join(tempDir, names[2])}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the held file to be kept, got %v", got)
+	}
+
+	// Once released, the file is removed by the next pruning
+	held = nil
+	if err := logFile.pruneFiles(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	got, err = logFile.RotatedFiles()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if want := want[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestLogFile_CanPruneOldFiles(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterCanPruneOld")
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "Consul-20200101000000.log")
+	if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	mtime := time.Now().Add(-2 * maxRotatedFileAge)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	logFile := LogFile{
+		fileName: testFileName,
+		logPath:  tempDir,
+		CanPrune: func(string) bool { return false },
+	}
+	logFile.fullName = filepath.Join(tempDir, testFileName)
+	if err := logFile.deleteOldFiles(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the held file to be kept, got an error (%s)", err)
+	}
+}
+
+func TestLogFile_CanPruneIndexNaming(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterCanPruneIndex")
+	defer os.RemoveAll(tempDir)
+	prune := false
+	logFile := LogFile{
+		logFilter:    LevelFilter(),
+		fileName:     testFileName,
+		logPath:      tempDir,
+		duration:     testDuration,
+		MaxBytes:     testBytes,
+		MaxFiles:     1,
+		RotateNaming: RotateNamingIndex,
+		CanPrune:     func(string) bool { return prune },
+	}
+	defer logFile.Close()
+
+	// The held file is shifted past MaxFiles instead of being removed
+	for _, entry := range []string{"first", "second", "third"} {
+		logFile.Write([]byte(entry + " entry"))
+	}
+	want := map[string]string{
+		"Consul.log":   "third entry",
+		"Consul.log.1": "second entry",
+		"Consul.log.2": "first entry",
+	}
+	files, _ := ioutil.ReadDir(tempDir)
+	if len(files) != len(want) {
+		t.Errorf("Expected %d files, got %v file(s)", len(want), len(files))
+	}
+	for name, content := range want {
+		if bytes, err := ioutil.ReadFile(filepath.Join(tempDir, name)); err != nil || string(bytes) != content {
+			t.Errorf("Expected %q in %s, got %q (%v)", content, name, bytes, err)
+		}
+	}
+
+	// and removed with the next rotation once released
+	prune = true
+	logFile.Write([]byte("fourth entry"))
+	if files, _ := ioutil.ReadDir(tempDir); len(files) != 2 {
+		t.Errorf("Expected 2 files, got %v file(s)", len(files))
+	}
+}
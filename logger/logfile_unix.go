@@ -0,0 +1,27 @@
+// +build !windows
+
+package logger
+
+import (
+	"os"
+)
+
+// fileMoved reports whether the configured path no longer refers to the file
+// being written to, because it was deleted or renamed by someone else.
+func (l *LogFile) fileMoved() bool {
+	pathInfo, err := os.Stat(l.fullName)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	fileInfo, err := l.FileInfo.Stat()
+	if err != nil {
+		return false
+	}
+	return !os.SameFile(pathInfo, fileInfo)
+}
+
+// renameFile moves a rotated file away. Files can be renamed while open on
+// unix, so other handles on it don't get in the way.
+func renameFile(from, to string) error {
+	return os.Rename(from, to)
+}
@@ -0,0 +1,46 @@
+// +build windows
+
+package logger
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// errorSharingViolation is returned by Windows when a file can't be renamed
+// because another handle, such as a log shipper's, has it open.
+const errorSharingViolation syscall.Errno = 32
+
+var (
+	// renameRetries and renameBackoff bound how long rotation waits for
+	// other handles on the file to be closed, doubling the backoff between
+	// attempts. Writes are blocked meanwhile.
+	renameRetries = 5
+	renameBackoff = 10 * time.Millisecond
+)
+
+// fileMoved reports whether the configured path no longer refers to the file
+// being written to. Windows doesn't allow deleting or renaming a file while it
+// is open without FILE_SHARE_DELETE, so only check that the path still exists.
+func (l *LogFile) fileMoved() bool {
+	_, err := os.Stat(l.fullName)
+	return os.IsNotExist(err)
+}
+
+// renameFile moves a rotated file away. The file must be closed by LogFile
+// beforehand, but another process may still have it open, so the rename is
+// retried for a while on sharing violations.
+func renameFile(from, to string) error {
+	backoff := renameBackoff
+	for i := 0; ; i++ {
+		err := os.Rename(from, to)
+		var errno syscall.Errno
+		if err == nil || i == renameRetries || !errors.As(err, &errno) || errno != errorSharingViolation {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
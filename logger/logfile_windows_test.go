@@ -0,0 +1,62 @@
+// +build windows
+
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/sdk/testutil"
+)
+
+func TestLogFile_rotateSharedHandle(t *testing.T) {
+	t.Parallel()
+	tempDir := testutil.TempDir(t, "LogWriterSharedHandle")
+	defer os.RemoveAll(tempDir)
+	var reported []error
+	logFile := LogFile{
+		logFilter: LevelFilter(),
+		fileName:  testFileName,
+		logPath:   tempDir,
+		duration:  testDuration,
+		MaxBytes:  testBytes,
+		OnError:   func(err error) { reported = append(reported, err) },
+	}
+	defer logFile.Close()
+	logFile.Write([]byte("Hello World"))
+
+	// A handle held for the whole rotation defers it, keeping the counters
+	path := filepath.Join(tempDir, testFileName)
+	other, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := logFile.Write([]byte("Second File")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(reported) != 1 {
+		t.Errorf("Expected the rename failure to be reported, got %v", reported)
+	}
+	if logFile.BytesWritten != 22 {
+		t.Errorf("Expected BytesWritten to keep counting, got %d", logFile.BytesWritten)
+	}
+
+	// A handle released while retrying lets the rotation go through
+	go func() {
+		time.Sleep(2 * renameBackoff)
+		other.Close()
+	}()
+	if _, err := logFile.Write([]byte("Third File!")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if bytes, _ := ioutil.ReadFile(path); string(bytes) != "Third File!" {
+		t.Errorf("Expected the file to be rotated, got %q", bytes)
+	}
+	want := 2
+	if got, _ := ioutil.ReadDir(tempDir); len(got) != want {
+		t.Errorf("Expected %d files, got %v file(s)", want, len(got))
+	}
+}
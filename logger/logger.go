@@ -34,6 +34,19 @@ type Config struct {
 
 	//LogRotateMaxFiles is the maximum number of past archived log files to keep
 	LogRotateMaxFiles int
+
+	//LogRotateDaily rotates logs once a day at midnight local time instead of
+	//after LogRotateDuration
+	LogRotateDaily bool
+
+	//LogSplitLevel is the minimum level of entries also written to a separate
+	//errors file next to LogFilePath, as in consul-error.log. Empty disables
+	//the errors file.
+	LogSplitLevel string
+
+	//LogSplitExclusive writes the entries at or above LogSplitLevel only to
+	//the errors file
+	LogSplitExclusive bool
 }
 
 const (
@@ -127,16 +140,36 @@ func Setup(config *Config, ui cli.Ui) (*logutils.LevelFilter, *GatedWriter, *Log
 			logRotateBytes = config.LogRotateBytes
 		}
 		logFile := &LogFile{
-			logFilter: logFilter,
-			fileName:  fileName,
-			logPath:   dir,
-			duration:  logRotateDuration,
-			MaxBytes:  logRotateBytes,
-			MaxFiles:  config.LogRotateMaxFiles,
+			logFilter:   logFilter,
+			fileName:    fileName,
+			logPath:     dir,
+			duration:    logRotateDuration,
+			MaxBytes:    logRotateBytes,
+			MaxFiles:    config.LogRotateMaxFiles,
+			RotateDaily: config.LogRotateDaily,
+		}
+		var fileWriter io.Writer = logFile
+		if config.LogSplitLevel != "" {
+			splitFile := &LogFile{
+				logFilter:   logFilter,
+				fileName:    SplitFileName(fileName),
+				logPath:     dir,
+				duration:    logRotateDuration,
+				MaxBytes:    logRotateBytes,
+				MaxFiles:    config.LogRotateMaxFiles,
+				RotateDaily: config.LogRotateDaily,
+			}
+			split, err := NewLevelSplitWriter(logFile, splitFile, config.LogSplitLevel, config.LogSplitExclusive)
+			if err != nil {
+				ui.Error(fmt.Sprintf("Invalid log split level: %v", err))
+				return nil, nil, nil, nil, false
+			}
+			fileWriter = split
 		}
-		writers = append(writers, logFile)
+		writers = append(writers, fileWriter)
 	}
 
-	logOutput = io.MultiWriter(writers...)
+	// The level of entries written by hclog is passed on to the log file
+	logOutput = multiWriter(writers)
 	return logFilter, logGate, logWriter, logOutput, true
 }
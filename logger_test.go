@@ -769,6 +769,92 @@ func (c customErrText) MarshalText() ([]byte, error) {
 	return []byte(fmt.Sprintf("text-marshaler: %s", c.Message)), nil
 }
 
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// droppingWriter drops what is written to it, as level filters do.
+type droppingWriter struct{}
+
+func (droppingWriter) Write(p []byte) (int, error) {
+	return 0, nil
+}
+
+func TestLogger_Audit(t *testing.T) {
+	t.Run("bypasses levels and excludes", func(t *testing.T) {
+		var buf bufferingBuffer
+
+		logger := New(&LoggerOptions{
+			Name:         "test",
+			Output:       &buf,
+			Level:        Off,
+			DisableTime:  true,
+			Exclude:      func(Level, string, ...interface{}) bool { return true },
+			ExcludeNames: []string{"test"},
+		})
+
+		logger.Error("dropped")
+		err := logger.(Auditor).Audit("user login", "user", "alice")
+		require.NoError(t, err)
+
+		// The output was flushed for the entry to be delivered.
+		assert.Equal(t, "[INFO]  [module=test] -- user login: audit=true user=alice\n", buf.String())
+	})
+
+	t.Run("tags json entries", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			Level:      Error,
+			JSONFormat: true,
+		})
+
+		require.NoError(t, logger.(Auditor).Audit("user login"))
+
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		assert.Equal(t, true, raw[AuditKey])
+		assert.Equal(t, "info", raw["@level"])
+	})
+
+	t.Run("returns write errors", func(t *testing.T) {
+		logger := New(&LoggerOptions{Output: failingWriter{}})
+
+		err := logger.(Auditor).Audit("user login")
+		assert.EqualError(t, err, "disk full")
+	})
+
+	t.Run("returns entries dropped by the output", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output: NewLeveledWriter(&buf, map[Level]io.Writer{Info: droppingWriter{}}),
+		})
+
+		err := logger.(Auditor).Audit("user login")
+		assert.Equal(t, io.ErrShortWrite, err)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("includes the caller location", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := NewInterceptLogger(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			IncludeLocation: true,
+		})
+
+		_, _, line, _ := runtime.Caller(0)
+		require.NoError(t, logger.(Auditor).Audit("user login"))
+
+		want := fmt.Sprintf("[INFO] [go-hclog/logger_test.go:%d] -- user login: audit=true\n", line+1)
+		assert.Equal(t, want, buf.String())
+	})
+}
+
 func BenchmarkLogger(b *testing.B) {
 	b.Run("info with 10 pairs", func(b *testing.B) {
 		var buf bytes.Buffer
@@ -794,4 +880,32 @@ func BenchmarkLogger(b *testing.B) {
 			)
 		}
 	})
+
+	b.Run("info with values of common kinds", func(b *testing.B) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Name:   "test",
+			Output: &buf,
+		})
+		at := time.Date(2024, 6, 10, 6, 13, 20, 0, time.UTC)
+		err := errors.New("connection refused")
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Info("request served",
+				"method", "GET",
+				"status", 200,
+				"bytes", int64(5120),
+				"ratio", 0.75,
+				"cached", true,
+				"took", 1500*time.Microsecond,
+				"at", at,
+				"error", err,
+				"id", Hex(0xbeef),
+				"payload", []byte("hi"),
+			)
+			buf.Reset()
+		}
+	})
 }
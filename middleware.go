@@ -0,0 +1,110 @@
+package hclog
+
+import (
+	"fmt"
+	"time"
+)
+
+// MiddlewarePanicKey is the key of the field holding the value a Middleware
+// panicked with. The entry is kept as the middleware left it, and goes through
+// the next ones.
+const MiddlewarePanicKey = "middleware_panic"
+
+// Entry is an entry as given to the Middleware of LoggerOptions, which may
+// change any of its fields.
+//
+//	Middleware: []func(e *hclog.Entry) bool{
+//		func(e *hclog.Entry) bool {
+//			e.Args = append(e.Args, "dc", "eu-west-1")
+//			return true
+//		},
+//	}
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Name    string
+	Message string
+
+	// Args are the key/value pairs of the entry, the implied arguments of the
+	// logger first, with the Field values given in place of pairs or to the
+	// methods of FieldLogger expanded. A CapturedStacktrace may end them.
+	Args []interface{}
+}
+
+// newMiddleware returns a copy of middleware without the nil functions, or nil
+// if there are none.
+func newMiddleware(middleware []func(e *Entry) bool) []func(e *Entry) bool {
+	var ms []func(e *Entry) bool
+	for _, m := range middleware {
+		if m != nil {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// entryArgs returns the Args of an Entry of the entry of args or fields, in a
+// slice of its own for the middleware to change.
+func (l *intLogger) entryArgs(args []interface{}, fields []Field) []interface{} {
+	implied := l.impliedArgs()
+	entryArgs := make([]interface{}, 0, len(implied)+len(args)+len(fields)*2)
+	entryArgs = append(entryArgs, implied...)
+	entryArgs = append(entryArgs, args...)
+	for _, f := range fields {
+		entryArgs = append(entryArgs, f.Key, f.Value())
+	}
+	return expandFields(entryArgs)
+}
+
+// logEntry passes e through the middleware and writes it, unless it is
+// dropped.
+func (l *intLogger) logEntry(e *Entry) {
+	for _, m := range l.middleware {
+		if !callMiddleware(m, e) {
+			countDropped()
+			return
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.shutdown.isStopped() || l.exclude != nil && l.exclude(e.Level, e.Message, e.Args...) {
+		countDropped()
+		return
+	}
+
+	// The implied arguments are already in the args of e, and the location
+	// is one stack frame further up.
+	sl := *l
+	sl.implied = nil
+	if sl.callerOffset > 0 {
+		sl.callerOffset++
+	}
+	if l.json {
+		sl.logJSON(e.Time, e.Name, e.Level, e.Message, nil, e.Args...)
+	} else {
+		sl.logPlain(e.Time, e.Name, e.Level, e.Message, nil, e.Args...)
+	}
+
+	l.writer.Flush(e.Level)
+}
+
+// callMiddleware calls m with e, recording the value it panics with in e.
+func callMiddleware(m func(e *Entry) bool, e *Entry) (keep bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			panicArgs := []interface{}{MiddlewarePanicKey, fmt.Sprintf("%v", v)}
+			if n := len(e.Args); n%2 == 1 {
+				if cs, ok := e.Args[n-1].(CapturedStacktrace); ok {
+					e.Args = append(append(e.Args[:n-1:n-1], panicArgs...), cs)
+					keep = true
+					return
+				}
+			}
+			e.Args = append(e.Args, panicArgs...)
+			keep = true
+		}
+	}()
+	return m(e)
+}
@@ -0,0 +1,156 @@
+package hclog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	datacenter := func(e *Entry) bool {
+		e.Args = append(e.Args, "dc", "eu-west-1")
+		return true
+	}
+	renameKeys := func(e *Entry) bool {
+		for i := 0; i < len(e.Args)-1; i += 2 {
+			if e.Args[i] == "usr" {
+				e.Args[i] = "user"
+			}
+		}
+		return true
+	}
+	dropHealthChecks := func(e *Entry) bool {
+		return e.Message != "health check"
+	}
+
+	t.Run("enriches, rewrites and drops entries", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			Middleware:  []func(e *Entry) bool{datacenter, renameKeys, dropHealthChecks},
+		})
+
+		logger.With("usr", "alice").Info("login", "attempt", 1)
+		logger.Info("health check")
+		logger.(FieldLogger).InfoF("logout", Str("usr", "bob"))
+
+		assert.Equal(t,
+			"[INFO]  -- login: user=alice attempt=1 dc=eu-west-1\n"+
+				"[INFO]  -- logout: user=bob dc=eu-west-1\n",
+			buf.String())
+	})
+
+	t.Run("applies to derived loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			JSONFormat:  true,
+			Middleware:  []func(e *Entry) bool{datacenter},
+		})
+
+		logger.Named("http").With("method", "GET").Info("served")
+		logger.StandardLogger(nil).Print("from std")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.Contains(t, lines[0], `"dc":"eu-west-1"`)
+			assert.Contains(t, lines[0], `"method":"GET"`)
+			assert.Contains(t, lines[0], `"@module":"http"`)
+			assert.Contains(t, lines[1], `"dc":"eu-west-1"`)
+		}
+	})
+
+	t.Run("runs after level filtering", func(t *testing.T) {
+		var buf bytes.Buffer
+		called := 0
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			Level:      Info,
+			Middleware: []func(e *Entry) bool{func(e *Entry) bool { called++; return true }},
+		})
+
+		logger.Debug("filtered out")
+		assert.Equal(t, 0, called)
+	})
+
+	t.Run("contains panics", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			Middleware: []func(e *Entry) bool{
+				func(e *Entry) bool { panic("boom") },
+				datacenter,
+			},
+		})
+
+		logger.Info("survived", "a", 1)
+		assert.Equal(t, "[INFO]  -- survived: a=1 middleware_panic=boom dc=eu-west-1\n", buf.String())
+	})
+
+	t.Run("rewrites but never drops audit entries", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			Middleware:  []func(e *Entry) bool{renameKeys, datacenter, func(e *Entry) bool { return false }},
+		})
+
+		err := logger.With("usr", "alice").(Auditor).Audit("deleted", "key", "a")
+		assert.NoError(t, err)
+		logger.Info("dropped")
+		assert.Equal(t, "[INFO]  -- deleted: user=alice audit=true key=a dc=eu-west-1\n", buf.String())
+	})
+
+	t.Run("reports the location of the caller", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			IncludeLocation: true,
+			Middleware:      []func(e *Entry) bool{datacenter},
+		})
+
+		logger.Info("located")
+		assert.Contains(t, buf.String(), "middleware_test.go:")
+	})
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	b.Run("none", func(b *testing.B) {
+		logger := New(&LoggerOptions{Output: ioutil.Discard, DisableTime: true})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Info("request served", "path", "/v1/kv", "status", 200)
+		}
+	})
+
+	b.Run("empty", func(b *testing.B) {
+		logger := New(&LoggerOptions{
+			Output:      ioutil.Discard,
+			DisableTime: true,
+			Middleware:  []func(e *Entry) bool{},
+		})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Info("request served", "path", "/v1/kv", "status", 200)
+		}
+	})
+
+	b.Run("one", func(b *testing.B) {
+		logger := New(&LoggerOptions{
+			Output:      ioutil.Discard,
+			DisableTime: true,
+			Middleware:  []func(e *Entry) bool{func(e *Entry) bool { return true }},
+		})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Info("request served", "path", "/v1/kv", "status", 200)
+		}
+	})
+}
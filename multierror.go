@@ -0,0 +1,97 @@
+package hclog
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxJoinedErrors bounds the number of errors written for a multi-error,
+	// the rest being counted in a last element.
+	maxJoinedErrors = 32
+
+	// maxJoinDepth bounds how deep nested multi-errors are flattened. Deeper
+	// ones are written with their Error string.
+	maxJoinDepth = 8
+)
+
+// joinedErrors returns the errors held by err, if it holds several, as from
+// errors.Join or go-multierror, recognized by an Unwrap() []error or a
+// WrappedErrors() []error method. Nested multi-errors are flattened. It returns
+// nil for other errors, and for multi-errors holding a single error, which are
+// written like other errors.
+func joinedErrors(err error) []string {
+	errs := unwrapJoined(err)
+	if errs == nil {
+		return nil
+	}
+
+	var msgs []string
+	more := 0
+	var flatten func(errs []error, depth int)
+	flatten = func(errs []error, depth int) {
+		for _, e := range errs {
+			if e == nil {
+				continue
+			}
+			if nested := unwrapJoined(e); nested != nil && depth < maxJoinDepth {
+				flatten(nested, depth+1)
+				continue
+			}
+			if len(msgs) == maxJoinedErrors {
+				more++
+				continue
+			}
+			msgs = append(msgs, e.Error())
+		}
+	}
+	flatten(errs, 1)
+
+	if more > 0 {
+		msgs = append(msgs, "... "+strconv.Itoa(more)+" more")
+	}
+	if len(msgs) < 2 {
+		return nil
+	}
+	return msgs
+}
+
+func unwrapJoined(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	case interface{ WrappedErrors() []error }:
+		return e.WrappedErrors()
+	}
+	return nil
+}
+
+// renderErrors renders the errors of a multi-error in the text format, as in
+// [timeout; "connection refused"].
+func (l *intLogger) renderErrors(msgs []string) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, msg := range msgs {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		msg = escape(l.limits.value(msg), false)
+		if strings.ContainsAny(msg, " \t\n\r") {
+			b.WriteByte('"')
+			b.WriteString(msg)
+			b.WriteByte('"')
+		} else {
+			b.WriteString(msg)
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// limitedValues returns msgs truncated to the MaxFieldBytes limit, for JSON.
+func (l *intLogger) limitedValues(msgs []string) []string {
+	for i, msg := range msgs {
+		msgs[i] = l.limits.value(msg)
+	}
+	return msgs
+}
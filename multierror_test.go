@@ -0,0 +1,102 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// joinError is a multi-error like the ones of errors.Join.
+type joinError []error
+
+func (e joinError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e joinError) Unwrap() []error { return e }
+
+// wrappedErrors is a multi-error like the ones of go-multierror.
+type wrappedErrors []error
+
+func (e wrappedErrors) Error() string          { return fmt.Sprintf("%d errors occurred", len(e)) }
+func (e wrappedErrors) WrappedErrors() []error { return e }
+
+func TestJoinedErrors(t *testing.T) {
+	a, b, c := errors.New("a"), errors.New("b"), errors.New("c")
+
+	assert.Nil(t, joinedErrors(a))
+	assert.Nil(t, joinedErrors(fmt.Errorf("wrapped: %w", joinError{a, b})))
+	assert.Nil(t, joinedErrors(joinError{a}))
+	assert.Equal(t, []string{"a", "b"}, joinedErrors(joinError{a, nil, b}))
+	assert.Equal(t, []string{"a", "b", "c"}, joinedErrors(wrappedErrors{a, joinError{b, c}}))
+
+	var many joinError
+	for i := 0; i < maxJoinedErrors+3; i++ {
+		many = append(many, a)
+	}
+	errs := joinedErrors(many)
+	assert.Len(t, errs, maxJoinedErrors+1)
+	assert.Equal(t, "... 3 more", errs[maxJoinedErrors])
+
+	var deep error = joinError{a, b}
+	for i := 0; i < maxJoinDepth; i++ {
+		deep = joinError{c, deep}
+	}
+	errs = joinedErrors(deep)
+	assert.Equal(t, "a\nb", errs[len(errs)-1])
+}
+
+func TestLogger_multiErrors(t *testing.T) {
+	err := joinError{errors.New("timeout"), joinError{errors.New("connection refused"), errors.New("eof")}}
+
+	t.Run("renders them as lists in text", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.Error("request failed", "errors", err)
+		logger.(FieldLogger).ErrorF("request failed", Err(err))
+		logger.Error("request failed", "error", fmt.Errorf("dial: %w", errors.New("timeout")))
+
+		expected := "[ERROR] -- request failed: errors=[timeout; \"connection refused\"; eof]\n" +
+			"[ERROR] -- request failed: error=[timeout; \"connection refused\"; eof]\n" +
+			"[ERROR] -- request failed: error=\"dial: timeout\"\n"
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("renders them as arrays in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		})
+
+		logger.Error("request failed", "errors", err)
+		logger.(FieldLogger).ErrorF("request failed", Err(wrappedErrors{errors.New("a"), errors.New("b")}))
+
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(lines[0], &entry))
+		assert.Equal(t, []interface{}{"timeout", "connection refused", "eof"}, entry["errors"])
+
+		entry = nil
+		require.NoError(t, json.Unmarshal(lines[1], &entry))
+		assert.Equal(t, []interface{}{"a", "b"}, entry[ErrorKey])
+	})
+}
@@ -0,0 +1,55 @@
+package hclog
+
+import "sync/atomic"
+
+// AutoNamePadding is the NamePadding padding the names of text entries to the
+// longest name written so far.
+const AutoNamePadding = -1
+
+// namePadding pads the names of text entries, as set by NamePadding.
+type namePadding struct {
+	// width is the width names are padded to, or AutoNamePadding.
+	width int
+
+	// widest is the length of the longest name written, shared with the root
+	// logger and read atomically, in AutoNamePadding mode.
+	widest *int64
+}
+
+func newNamePadding(width int) namePadding {
+	p := namePadding{width: width}
+	if width == AutoNamePadding {
+		p.widest = new(int64)
+	}
+	return p
+}
+
+// padding returns the number of spaces to write after the "[module=name] "
+// of an entry of the logger named name, or in place of it for an empty name,
+// so that the messages of the entries line up.
+func (p namePadding) padding(name string) int {
+	width := p.width
+	if p.widest != nil {
+		for {
+			widest := atomic.LoadInt64(p.widest)
+			if int64(len(name)) <= widest {
+				width = int(widest)
+				break
+			}
+			if atomic.CompareAndSwapInt64(p.widest, widest, int64(len(name))) {
+				width = len(name)
+				break
+			}
+		}
+	}
+	if width <= 0 {
+		return 0
+	}
+	if name == "" {
+		return width + len("[module=] ")
+	}
+	if len(name) >= width {
+		return 0
+	}
+	return width - len(name)
+}
@@ -0,0 +1,73 @@
+package hclog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamePadding(t *testing.T) {
+	t.Run("pads names to a width", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, NamePadding: 6})
+
+		logger.Info("root")
+		logger.Named("raft").Warn("short")
+		logger.Named("http").Named("server").Error("long")
+		assert.Equal(t,
+			"[INFO]                  -- root\n"+
+				"[WARN]  [module=raft]   -- short\n"+
+				"[ERROR] [module=http.server] -- long\n",
+			buf.String())
+	})
+
+	t.Run("pads names to the longest one written", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, NamePadding: AutoNamePadding})
+
+		logger.Info("root")
+		logger.Named("raft").Info("first")
+		logger.Named("consul").Info("wider")
+		logger.Named("raft").Info("padded")
+		logger.Info("root")
+		assert.Equal(t,
+			"[INFO]  -- root\n"+
+				"[INFO]  [module=raft] -- first\n"+
+				"[INFO]  [module=consul] -- wider\n"+
+				"[INFO]  [module=raft]   -- padded\n"+
+				"[INFO]                  -- root\n",
+			buf.String())
+	})
+
+	t.Run("leaves JSON alone", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, JSONFormat: true, NamePadding: 20})
+		logger.Named("raft").Info("entry")
+		assert.Contains(t, buf.String(), `"@module":"raft"`)
+	})
+
+	t.Run("tracks the widest name concurrently", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, NamePadding: AutoNamePadding})
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 8; i++ {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				sub := logger.Named(name)
+				for j := 0; j < 50; j++ {
+					sub.Info("entry")
+				}
+			}(strings.Repeat("n", i))
+		}
+		wg.Wait()
+
+		logger.Named("n").Info("last")
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, "[INFO]  [module=n]        -- last", lines[len(lines)-1])
+	})
+}
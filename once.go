@@ -0,0 +1,191 @@
+package hclog
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// keyTableSize bounds the number of keys remembered by Once and Every. When
+// more keys are in use, the least recently seen are forgotten, letting their
+// next entry through.
+const keyTableSize = 1024
+
+// keyTable remembers when the entries of the keys given to Once and Every
+// were last let through. It is shared by a root logger and every logger
+// derived from it, so that suppression outlives Named and With.
+type keyTable struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type keyEntry struct {
+	key  string
+	last time.Time
+}
+
+func newKeyTable(size int) *keyTable {
+	return &keyTable{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// allow reports whether an entry of key is let through at now, and records it
+// if so. An interval of zero lets through the first entry only.
+func (t *keyTable) allow(key string, interval time.Duration, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if e, ok := t.entries[key]; ok {
+		t.order.MoveToFront(e)
+		entry := e.Value.(*keyEntry)
+		if interval == 0 || now.Sub(entry.last) < interval {
+			return false
+		}
+		entry.last = now
+		return true
+	}
+
+	t.entries[key] = t.order.PushFront(&keyEntry{key: key, last: now})
+	if t.order.Len() > t.size {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.entries, oldest.Value.(*keyEntry).key)
+	}
+	return true
+}
+
+// defaultKeyTable is used by Once and Every for loggers not created by this
+// package.
+var defaultKeyTable = newKeyTable(keyTableSize)
+
+// Once returns a Logger which writes the entries of l, but only the first one
+// for key. The keys are shared by all the loggers derived from the same root
+// logger, so that a logger renamed with Named or ResetNamed is suppressed
+// too. Entries filtered out by the level don't count.
+//
+//	hclog.Once(logger, "clock-skew").Warn("clock skew detected", "skew", skew)
+func Once(l Logger, key string) Logger {
+	return newLimitedLogger(l, key, 0)
+}
+
+// Every returns a Logger which writes the entries of l at most once per
+// interval for key. Like with Once, the keys are shared by all the loggers
+// derived from the same root logger.
+func Every(l Logger, key string, interval time.Duration) Logger {
+	return newLimitedLogger(l, key, interval)
+}
+
+// limitedLogger is the Logger returned by Once and Every.
+type limitedLogger struct {
+	Logger
+
+	key      string
+	interval time.Duration
+	table    *keyTable
+}
+
+func newLimitedLogger(l Logger, key string, interval time.Duration) *limitedLogger {
+	table := defaultKeyTable
+	if kt, ok := l.(interface{ keyTable() *keyTable }); ok {
+		table = kt.keyTable()
+	}
+	return &limitedLogger{
+		Logger:   withCallerSkip(l, 1),
+		key:      key,
+		interval: interval,
+		table:    table,
+	}
+}
+
+// allow reports whether an entry at level is let through, consuming the key
+// if so.
+func (l *limitedLogger) allow(level Level) bool {
+	if lg, ok := l.Logger.(LevelGetter); ok && level < lg.GetLevel() {
+		return false
+	}
+	return l.table.allow(l.key, l.interval, time.Now())
+}
+
+func (l *limitedLogger) Log(level Level, msg string, args ...interface{}) {
+	if l.allow(level) {
+		l.Logger.Log(level, msg, args...)
+	}
+}
+
+func (l *limitedLogger) Trace(msg string, args ...interface{}) {
+	if l.allow(Trace) {
+		l.Logger.Trace(msg, args...)
+	}
+}
+
+func (l *limitedLogger) Debug(msg string, args ...interface{}) {
+	if l.allow(Debug) {
+		l.Logger.Debug(msg, args...)
+	}
+}
+
+func (l *limitedLogger) Info(msg string, args ...interface{}) {
+	if l.allow(Info) {
+		l.Logger.Info(msg, args...)
+	}
+}
+
+func (l *limitedLogger) Warn(msg string, args ...interface{}) {
+	if l.allow(Warn) {
+		l.Logger.Warn(msg, args...)
+	}
+}
+
+func (l *limitedLogger) Error(msg string, args ...interface{}) {
+	if l.allow(Error) {
+		l.Logger.Error(msg, args...)
+	}
+}
+
+func (l *limitedLogger) With(args ...interface{}) Logger {
+	return l.derive(l.Logger.With(args...))
+}
+
+func (l *limitedLogger) Named(name string) Logger {
+	return l.derive(l.Logger.Named(name))
+}
+
+func (l *limitedLogger) ResetNamed(name string) Logger {
+	return l.derive(l.Logger.ResetNamed(name))
+}
+
+// derive returns a limitedLogger for sub, derived from the logger l wraps,
+// limited by the same key.
+func (l *limitedLogger) derive(sub Logger) Logger {
+	return &limitedLogger{
+		Logger:   sub,
+		key:      l.key,
+		interval: l.interval,
+		table:    l.table,
+	}
+}
+
+// withCallerSkip returns a copy of l which reports the location of its caller
+// skip stack frames further up, for wrappers calling l on behalf of their own
+// caller. Loggers not created by this package are returned as they are.
+func withCallerSkip(l Logger, skip int) Logger {
+	switch l := l.(type) {
+	case *intLogger:
+		if l.callerOffset == 0 {
+			return l
+		}
+		sl := *l
+		sl.callerOffset += skip
+		return &sl
+	case *interceptLogger:
+		sub := *l
+		sub.Logger = withCallerSkip(l.Logger, skip)
+		return &sub
+	}
+	return l
+}
@@ -0,0 +1,108 @@
+package hclog
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnce(t *testing.T) {
+	t.Run("writes the first entry of a key", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		for i := 0; i < 3; i++ {
+			Once(logger, "skew").Warn("clock skew detected", "i", i)
+		}
+		Once(logger.Named("renamed"), "skew").Warn("clock skew detected")
+		Once(logger, "skew").Named("sub").With("a", 1).Warn("clock skew detected")
+		Once(logger, "other").Warn("other key")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, []string{
+			"[WARN]  -- clock skew detected: i=0",
+			"[WARN]  -- other key",
+		}, lines)
+	})
+
+	t.Run("filtered entries don't count", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		Once(logger, "debug").Debug("filtered")
+		logger.SetLevel(Debug)
+		Once(logger, "debug").Debug("written")
+
+		assert.Equal(t, "[DEBUG] -- written\n", buf.String())
+	})
+
+	t.Run("keeps the caller location", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			IncludeLocation: true,
+		})
+
+		_, _, line, _ := runtime.Caller(0)
+		Once(logger, "location").Info("here")
+
+		assert.Equal(t, fmt.Sprintf("[INFO] [go-hclog/once_test.go:%d] -- here\n", line+1), buf.String())
+	})
+}
+
+func TestEvery(t *testing.T) {
+	t.Run("writes once per interval", func(t *testing.T) {
+		table := newKeyTable(keyTableSize)
+		start := time.Now()
+
+		assert.True(t, table.allow("k", time.Second, start))
+		assert.False(t, table.allow("k", time.Second, start.Add(500*time.Millisecond)))
+		assert.True(t, table.allow("k", time.Second, start.Add(time.Second)))
+		assert.False(t, table.allow("k", time.Second, start.Add(1500*time.Millisecond)))
+		assert.True(t, table.allow("other", time.Second, start))
+	})
+
+	t.Run("bounds the keys", func(t *testing.T) {
+		table := newKeyTable(2)
+		now := time.Now()
+
+		assert.True(t, table.allow("a", 0, now))
+		assert.True(t, table.allow("b", 0, now))
+		assert.False(t, table.allow("a", 0, now))
+		assert.True(t, table.allow("c", 0, now))
+
+		// b was the least recently seen, and was forgotten.
+		assert.Equal(t, 2, len(table.entries))
+		assert.True(t, table.allow("b", 0, now))
+		assert.False(t, table.allow("b", 0, now))
+	})
+
+	t.Run("applies to loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := NewInterceptLogger(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		Every(logger, "tick", time.Hour).Info("tick")
+		Every(logger.Named("sub"), "tick", time.Hour).Info("tick")
+
+		assert.Equal(t, "[INFO]  -- tick\n", buf.String())
+	})
+}
@@ -0,0 +1,205 @@
+package hclog
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// optionRules are the checks run by NewWithError on the normalized options,
+// in order.
+var optionRules = []func(opts *LoggerOptions) error{
+	checkLevel,
+	checkLevelVar,
+	checkColor,
+	checkTime,
+	checkLevelOverrides,
+	checkNamePatterns,
+	checkNamePadding,
+	checkKeys,
+	checkLimits,
+	checkExit,
+}
+
+// NewWithError returns a configured logger like New, but reports misconfigured
+// options as an error rather than as odd output, or a panic, at runtime. The
+// options are normalized first, the defaults filled in the same way New does,
+// into a copy so that opts is left unchanged.
+func NewWithError(opts *LoggerOptions) (Logger, error) {
+	normalized := normalizeOptions(opts)
+	for _, rule := range optionRules {
+		if err := rule(normalized); err != nil {
+			return nil, fmt.Errorf("invalid logger options: %w", err)
+		}
+	}
+	return New(normalized), nil
+}
+
+// normalizeOptions returns a copy of opts with the defaults filled in.
+func normalizeOptions(opts *LoggerOptions) *LoggerOptions {
+	var normalized LoggerOptions
+	if opts != nil {
+		normalized = *opts
+	}
+
+	if normalized.Output == nil {
+		normalized.Output = DefaultOutput
+	}
+	if normalized.Level == NoLevel {
+		normalized.Level = DefaultLevel
+		if normalized.LevelVar != nil && normalized.LevelVar.Level() != NoLevel {
+			normalized.Level = normalized.LevelVar.Level()
+		}
+	}
+	if normalized.Mutex == nil {
+		normalized.Mutex = new(sync.Mutex)
+	}
+	return &normalized
+}
+
+// validLevel reports whether level is one of the levels of this package.
+func validLevel(level Level) bool {
+	return level >= NoLevel && level <= Off
+}
+
+// checkLevel rejects unknown levels.
+func checkLevel(opts *LoggerOptions) error {
+	if !validLevel(opts.Level) {
+		return fmt.Errorf("unknown level %d", opts.Level)
+	}
+	return nil
+}
+
+// checkLevelVar rejects a LevelVar holding an unknown level, or a level other
+// than Level, since either could be meant.
+func checkLevelVar(opts *LoggerOptions) error {
+	if opts.LevelVar == nil {
+		return nil
+	}
+	level := opts.LevelVar.Level()
+	if !validLevel(level) {
+		return fmt.Errorf("LevelVar holds unknown level %d", level)
+	}
+	if level != NoLevel && level != opts.Level {
+		return fmt.Errorf("Level is %s but LevelVar holds %s", opts.Level, level)
+	}
+	return nil
+}
+
+// checkColor rejects unknown color options, colors in JSON, which would no
+// longer parse, and AutoColor on outputs other than files, for which New
+// panics.
+func checkColor(opts *LoggerOptions) error {
+	switch opts.Color {
+	case ColorOff:
+		return nil
+	case AutoColor, ForceColor:
+	default:
+		return fmt.Errorf("unknown color option %d", opts.Color)
+	}
+
+	if opts.JSONFormat {
+		return fmt.Errorf("Color cannot be used with JSONFormat")
+	}
+	if opts.Color == AutoColor {
+		if _, ok := opts.Output.(*os.File); !ok {
+			return fmt.Errorf("AutoColor requires an *os.File Output, got %T", opts.Output)
+		}
+	}
+	return nil
+}
+
+// checkTime rejects unknown time encodings, or encodings other than the
+// default without JSONFormat, and a TimeFormat set along with DisableTime, or
+// which renders as blank.
+func checkTime(opts *LoggerOptions) error {
+	if opts.TimeEncoding > TimeEpochNanos {
+		return fmt.Errorf("unknown time encoding %d", opts.TimeEncoding)
+	}
+	if opts.TimeEncoding != TimeRFC3339 && !opts.JSONFormat {
+		return fmt.Errorf("TimeEncoding %s requires JSONFormat", opts.TimeEncoding)
+	}
+	if opts.TimeFormat == "" {
+		return nil
+	}
+	if opts.DisableTime {
+		return fmt.Errorf("TimeFormat %q cannot be used with DisableTime", opts.TimeFormat)
+	}
+	if strings.TrimSpace(time.Now().Format(opts.TimeFormat)) == "" {
+		return fmt.Errorf("TimeFormat %q renders a blank time", opts.TimeFormat)
+	}
+	return nil
+}
+
+// checkLevelOverrides rejects overrides without a name, or without a known
+// level.
+func checkLevelOverrides(opts *LoggerOptions) error {
+	for name, level := range opts.LevelOverrides {
+		if name == "" {
+			return fmt.Errorf("level override without a name")
+		}
+		if level == NoLevel || !validLevel(level) {
+			return fmt.Errorf("level override of %q has unknown level %d", name, level)
+		}
+	}
+	return nil
+}
+
+// checkNamePatterns rejects empty or malformed patterns in ExcludeNames and
+// IncludeOnlyNames, which would otherwise silently match nothing.
+func checkNamePatterns(opts *LoggerOptions) error {
+	check := func(option string, patterns []string) error {
+		for _, pattern := range patterns {
+			if pattern == "" {
+				return fmt.Errorf("empty pattern in %s", option)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("malformed pattern %q in %s: %w", pattern, option, err)
+			}
+		}
+		return nil
+	}
+
+	if err := check("ExcludeNames", opts.ExcludeNames); err != nil {
+		return err
+	}
+	return check("IncludeOnlyNames", opts.IncludeOnlyNames)
+}
+
+// checkNamePadding rejects a negative NamePadding other than AutoNamePadding.
+func checkNamePadding(opts *LoggerOptions) error {
+	if opts.NamePadding < AutoNamePadding {
+		return fmt.Errorf("negative NamePadding %d", opts.NamePadding)
+	}
+	return nil
+}
+
+// checkKeys rejects unknown key normalizations, and location keys replacing
+// the keys of the logger.
+func checkKeys(opts *LoggerOptions) error {
+	switch opts.NormalizeKeys {
+	case KeysUnchanged, KeysUnderscored:
+	default:
+		return fmt.Errorf("unknown key normalization %d", opts.NormalizeKeys)
+	}
+	if _, ok := builtinKeys[opts.LocationKey]; ok {
+		return fmt.Errorf("LocationKey %q is a key of the logger", opts.LocationKey)
+	}
+	return nil
+}
+
+// checkLimits rejects negative limits.
+func checkLimits(opts *LoggerOptions) error {
+	switch {
+	case opts.MaxMessageBytes < 0:
+		return fmt.Errorf("negative MaxMessageBytes %d", opts.MaxMessageBytes)
+	case opts.MaxFieldBytes < 0:
+		return fmt.Errorf("negative MaxFieldBytes %d", opts.MaxFieldBytes)
+	case opts.MaxFields < 0:
+		return fmt.Errorf("negative MaxFields %d", opts.MaxFields)
+	}
+	return nil
+}
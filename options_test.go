@@ -0,0 +1,116 @@
+package hclog
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWithError(t *testing.T) {
+	t.Run("returns a logger for valid options", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger, err := NewWithError(&LoggerOptions{
+			Name:        "test",
+			Output:      &buf,
+			DisableTime: true,
+		})
+		require.NoError(t, err)
+
+		logger.Info("this is test")
+		assert.Equal(t, "[INFO]  [module=test] -- this is test\n", buf.String())
+	})
+
+	t.Run("accepts nil options", func(t *testing.T) {
+		logger, err := NewWithError(nil)
+		require.NoError(t, err)
+		assert.True(t, logger.IsInfo())
+	})
+
+	t.Run("reports invalid options", func(t *testing.T) {
+		logger, err := NewWithError(&LoggerOptions{Level: Level(42)})
+		assert.EqualError(t, err, "invalid logger options: unknown level 42")
+		assert.Nil(t, logger)
+	})
+
+	t.Run("leaves the options unchanged", func(t *testing.T) {
+		opts := &LoggerOptions{}
+		_, err := NewWithError(opts)
+		require.NoError(t, err)
+		assert.Equal(t, &LoggerOptions{}, opts)
+	})
+}
+
+func TestNormalizeOptions(t *testing.T) {
+	opts := normalizeOptions(nil)
+	assert.Equal(t, DefaultLevel, opts.Level)
+	assert.Equal(t, DefaultOutput, opts.Output)
+	assert.NotNil(t, opts.Mutex)
+
+	opts = normalizeOptions(&LoggerOptions{LevelVar: NewLevelVar(Debug)})
+	assert.Equal(t, Debug, opts.Level)
+}
+
+func TestOptionRules(t *testing.T) {
+	cases := []struct {
+		name string
+		rule func(*LoggerOptions) error
+		opts LoggerOptions
+		err  string
+	}{
+		{"known level", checkLevel, LoggerOptions{Level: Warn}, ""},
+		{"unknown level", checkLevel, LoggerOptions{Level: Level(-2)}, "unknown level -2"},
+
+		{"matching LevelVar", checkLevelVar, LoggerOptions{Level: Debug, LevelVar: NewLevelVar(Debug)}, ""},
+		{"unset LevelVar", checkLevelVar, LoggerOptions{Level: Debug, LevelVar: new(LevelVar)}, ""},
+		{"conflicting LevelVar", checkLevelVar, LoggerOptions{Level: Info, LevelVar: NewLevelVar(Debug)}, "Level is info but LevelVar holds debug"},
+		{"unknown LevelVar level", checkLevelVar, LoggerOptions{Level: Info, LevelVar: NewLevelVar(Level(9))}, "LevelVar holds unknown level 9"},
+
+		{"forced color", checkColor, LoggerOptions{Color: ForceColor, Output: new(bytes.Buffer)}, ""},
+		{"auto color on a file", checkColor, LoggerOptions{Color: AutoColor, Output: os.Stderr}, ""},
+		{"auto color on a buffer", checkColor, LoggerOptions{Color: AutoColor, Output: new(bytes.Buffer)}, "AutoColor requires an *os.File Output, got *bytes.Buffer"},
+		{"color in JSON", checkColor, LoggerOptions{Color: ForceColor, JSONFormat: true}, "Color cannot be used with JSONFormat"},
+		{"unknown color", checkColor, LoggerOptions{Color: ColorOption(7)}, "unknown color option 7"},
+
+		{"time format", checkTime, LoggerOptions{TimeFormat: "15:04:05"}, ""},
+		{"time format without time", checkTime, LoggerOptions{TimeFormat: "15:04", DisableTime: true}, `TimeFormat "15:04" cannot be used with DisableTime`},
+		{"blank time format", checkTime, LoggerOptions{TimeFormat: "  "}, `TimeFormat "  " renders a blank time`},
+		{"time encoding", checkTime, LoggerOptions{TimeEncoding: TimeEpochNanos, JSONFormat: true}, ""},
+		{"unknown time encoding", checkTime, LoggerOptions{TimeEncoding: TimeEncoding(9), JSONFormat: true}, "unknown time encoding 9"},
+		{"time encoding without JSON", checkTime, LoggerOptions{TimeEncoding: TimeEpochMillis}, "TimeEncoding TimeEpochMillis requires JSONFormat"},
+
+		{"level override", checkLevelOverrides, LoggerOptions{LevelOverrides: map[string]Level{"raft": Debug}}, ""},
+		{"override without a name", checkLevelOverrides, LoggerOptions{LevelOverrides: map[string]Level{"": Debug}}, "level override without a name"},
+		{"override without a level", checkLevelOverrides, LoggerOptions{LevelOverrides: map[string]Level{"raft": NoLevel}}, `level override of "raft" has unknown level 0`},
+
+		{"name padding", checkNamePadding, LoggerOptions{NamePadding: AutoNamePadding}, ""},
+		{"negative name padding", checkNamePadding, LoggerOptions{NamePadding: -2}, "negative NamePadding -2"},
+
+		{"name patterns", checkNamePatterns, LoggerOptions{ExcludeNames: []string{"noisy-*"}, IncludeOnlyNames: []string{"raft"}}, ""},
+		{"empty pattern", checkNamePatterns, LoggerOptions{ExcludeNames: []string{""}}, "empty pattern in ExcludeNames"},
+		{"malformed pattern", checkNamePatterns, LoggerOptions{IncludeOnlyNames: []string{"raft["}}, `malformed pattern "raft[" in IncludeOnlyNames: syntax error in pattern`},
+
+		{"key normalization", checkKeys, LoggerOptions{NormalizeKeys: KeysUnderscored}, ""},
+		{"unknown key normalization", checkKeys, LoggerOptions{NormalizeKeys: KeyNormalization(5)}, "unknown key normalization 5"},
+		{"location key", checkKeys, LoggerOptions{StructuredLocation: true, LocationKey: "src"}, ""},
+		{"built-in location key", checkKeys, LoggerOptions{StructuredLocation: true, LocationKey: "@message"}, `LocationKey "@message" is a key of the logger`},
+
+		{"limits", checkLimits, LoggerOptions{MaxMessageBytes: 1024, MaxFields: 10}, ""},
+		{"negative limit", checkLimits, LoggerOptions{MaxFieldBytes: -1}, "negative MaxFieldBytes -1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := c.opts
+			err := c.rule(&opts)
+			if c.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, c.err)
+			}
+		})
+	}
+}
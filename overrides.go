@@ -0,0 +1,76 @@
+package hclog
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// levelOverrides holds the levels set for named loggers. It is shared by a
+// root logger and every logger derived from it, so that overrides changed at
+// runtime apply to all of them.
+type levelOverrides struct {
+	// count is the number of overrides, read atomically so that loggers
+	// without overrides don't take the lock.
+	count int32
+
+	mu     sync.RWMutex
+	levels map[string]Level
+}
+
+func newLevelOverrides(levels map[string]Level) *levelOverrides {
+	o := &levelOverrides{levels: make(map[string]Level, len(levels))}
+	for name, level := range levels {
+		o.set(name, level)
+	}
+	return o
+}
+
+// set sets the level of the loggers named name and their subloggers, or
+// removes the override if level is NoLevel.
+func (o *levelOverrides) set(name string, level Level) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if level == NoLevel {
+		delete(o.levels, name)
+	} else {
+		o.levels[name] = level
+	}
+	atomic.StoreInt32(&o.count, int32(len(o.levels)))
+}
+
+// snapshot returns a copy of the overrides.
+func (o *levelOverrides) snapshot() map[string]Level {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	levels := make(map[string]Level, len(o.levels))
+	for name, level := range o.levels {
+		levels[name] = level
+	}
+	return levels
+}
+
+// lookup returns the level overriding the level of the logger named name. The
+// longest override matching name or one of its parents wins, so that "raft.fsm"
+// applies to "raft.fsm.snapshot" rather than "raft".
+func (o *levelOverrides) lookup(name string) (Level, bool) {
+	if o == nil || atomic.LoadInt32(&o.count) == 0 || name == "" {
+		return NoLevel, false
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	for {
+		if level, ok := o.levels[name]; ok {
+			return level, true
+		}
+		idx := strings.LastIndexByte(name, '.')
+		if idx < 0 {
+			return NoLevel, false
+		}
+		name = name[:idx]
+	}
+}
@@ -0,0 +1,118 @@
+package hclog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelOverrides(t *testing.T) {
+	t.Run("applies the longest matching name", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			Level:       Info,
+			DisableTime: true,
+			LevelOverrides: map[string]Level{
+				"raft":     Debug,
+				"raft.fsm": Warn,
+				"http":     Error,
+			},
+		})
+
+		raft := logger.Named("raft")
+		fsm := raft.Named("fsm")
+		snapshot := fsm.Named("snapshot")
+		raftx := logger.Named("raftx")
+
+		raft.Debug("raft debug")
+		fsm.Info("fsm info")
+		fsm.Warn("fsm warn")
+		snapshot.Info("snapshot info")
+		raftx.Debug("raftx debug")
+		raftx.Info("raftx info")
+		logger.Named("http").Warn("http warn")
+		logger.Debug("root debug")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, []string{
+			"[DEBUG] [module=raft] -- raft debug",
+			"[WARN]  [module=raft.fsm] -- fsm warn",
+			"[INFO]  [module=raftx] -- raftx info",
+		}, lines)
+
+		assert.True(t, raft.IsDebug())
+		assert.False(t, fsm.IsInfo())
+		assert.False(t, logger.IsDebug())
+	})
+
+	t.Run("can be changed at runtime", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			Level:       Info,
+			DisableTime: true,
+		})
+		fsm := logger.Named("raft").Named("fsm")
+
+		fsm.Debug("before")
+		logger.(LevelOverridable).SetLevelOverride("raft", Debug)
+		fsm.Debug("raft override")
+		fsm.(LevelOverridable).SetLevelOverride("raft.fsm", Error)
+		fsm.Warn("fsm override")
+		logger.(LevelOverridable).SetLevelOverride("raft.fsm", NoLevel)
+		fsm.Debug("removed")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, []string{
+			"[DEBUG] [module=raft.fsm] -- raft override",
+			"[DEBUG] [module=raft.fsm] -- removed",
+		}, lines)
+	})
+
+	t.Run("compose with independent levels", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:            &buf,
+			Level:             Info,
+			DisableTime:       true,
+			IndependentLevels: true,
+			LevelOverrides:    map[string]Level{"raft": Debug},
+		})
+
+		raft := logger.Named("raft")
+		pinned := logger.Named("raft")
+		pinned.SetLevel(Error)
+
+		raft.Debug("override")
+		pinned.Warn("pinned")
+		pinned.Named("fsm").Warn("pinned child")
+		logger.SetLevel(Warn)
+		raft.Debug("root level changed")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Equal(t, []string{
+			"[DEBUG] [module=raft] -- override",
+			"[DEBUG] [module=raft] -- root level changed",
+		}, lines)
+	})
+
+	t.Run("apply to intercept loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := NewInterceptLogger(&LoggerOptions{
+			Output:      &buf,
+			Level:       Info,
+			DisableTime: true,
+		})
+		logger.(LevelOverridable).SetLevelOverride("raft", Debug)
+		logger.Named("raft").Debug("override")
+
+		assert.Equal(t, "[DEBUG] [module=raft] -- override\n", buf.String())
+	})
+}
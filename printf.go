@@ -0,0 +1,104 @@
+package hclog
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// PrintfLogger is implemented by loggers with printf style methods, to ease
+// porting code written for the standard library logger or logrus. The message
+// is formatted only once the level is checked, and written without key/value
+// pairs. Key/value pairs remain preferable, as they can be read back from the
+// entries. The loggers returned by New and NewInterceptLogger implement it.
+type PrintfLogger interface {
+	Tracef(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+var (
+	_ PrintfLogger = &intLogger{}
+	_ PrintfLogger = &interceptLogger{}
+)
+
+// Tracef formats and emits the message at TRACE level
+func (l *intLogger) Tracef(format string, args ...interface{}) {
+	if Trace >= l.levelFor(l.name) {
+		l.log(l.Name(), Trace, fmt.Sprintf(format, args...))
+	}
+}
+
+// Debugf formats and emits the message at DEBUG level
+func (l *intLogger) Debugf(format string, args ...interface{}) {
+	if Debug >= l.levelFor(l.name) {
+		l.log(l.Name(), Debug, fmt.Sprintf(format, args...))
+	}
+}
+
+// Infof formats and emits the message at INFO level
+func (l *intLogger) Infof(format string, args ...interface{}) {
+	if Info >= l.levelFor(l.name) {
+		l.log(l.Name(), Info, fmt.Sprintf(format, args...))
+	}
+}
+
+// Warnf formats and emits the message at WARN level
+func (l *intLogger) Warnf(format string, args ...interface{}) {
+	if Warn >= l.levelFor(l.name) {
+		l.log(l.Name(), Warn, fmt.Sprintf(format, args...))
+	}
+}
+
+// Errorf formats and emits the message at ERROR level
+func (l *intLogger) Errorf(format string, args ...interface{}) {
+	if Error >= l.levelFor(l.name) {
+		l.log(l.Name(), Error, fmt.Sprintf(format, args...))
+	}
+}
+
+// enabled reports whether an entry at level is written by the logger or may
+// be accepted by a sink, for the message to be formatted.
+func (i *interceptLogger) enabled(level Level) bool {
+	if atomic.LoadInt32(i.sinkCount) > 0 {
+		return true
+	}
+	lg, ok := i.Logger.(LevelGetter)
+	return !ok || level >= lg.GetLevel()
+}
+
+// Tracef formats and emits the message at TRACE level to log and sinks
+func (i *interceptLogger) Tracef(format string, args ...interface{}) {
+	if i.enabled(Trace) {
+		i.log(Trace, fmt.Sprintf(format, args...))
+	}
+}
+
+// Debugf formats and emits the message at DEBUG level to log and sinks
+func (i *interceptLogger) Debugf(format string, args ...interface{}) {
+	if i.enabled(Debug) {
+		i.log(Debug, fmt.Sprintf(format, args...))
+	}
+}
+
+// Infof formats and emits the message at INFO level to log and sinks
+func (i *interceptLogger) Infof(format string, args ...interface{}) {
+	if i.enabled(Info) {
+		i.log(Info, fmt.Sprintf(format, args...))
+	}
+}
+
+// Warnf formats and emits the message at WARN level to log and sinks
+func (i *interceptLogger) Warnf(format string, args ...interface{}) {
+	if i.enabled(Warn) {
+		i.log(Warn, fmt.Sprintf(format, args...))
+	}
+}
+
+// Errorf formats and emits the message at ERROR level to log and sinks
+func (i *interceptLogger) Errorf(format string, args ...interface{}) {
+	if i.enabled(Error) {
+		i.log(Error, fmt.Sprintf(format, args...))
+	}
+}
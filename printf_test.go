@@ -0,0 +1,102 @@
+package hclog
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type formatCounter struct {
+	count *int
+}
+
+func (f formatCounter) String() string {
+	*f.count++
+	return "formatted"
+}
+
+func TestPrintfLogger(t *testing.T) {
+	t.Run("formats the message", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.(PrintfLogger).Warnf("disk %d%% full on %s", 95, "/var")
+
+		assert.Equal(t, "[WARN]  -- disk 95% full on /var\n", buf.String())
+	})
+
+	t.Run("formats only entries written", func(t *testing.T) {
+		var buf bytes.Buffer
+		var count int
+
+		logger := New(&LoggerOptions{
+			Output: &buf,
+			Level:  Info,
+		})
+		intercept := NewInterceptLogger(&LoggerOptions{
+			Output: &buf,
+			Level:  Info,
+		})
+
+		logger.(PrintfLogger).Debugf("%s", formatCounter{&count})
+		intercept.(PrintfLogger).Tracef("%s", formatCounter{&count})
+		assert.Equal(t, 0, count)
+
+		logger.(PrintfLogger).Errorf("%s", formatCounter{&count})
+		intercept.(PrintfLogger).Infof("%s", formatCounter{&count})
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("formats for sinks", func(t *testing.T) {
+		var buf, sbuf bytes.Buffer
+
+		intercept := NewInterceptLogger(&LoggerOptions{
+			Output: &buf,
+			Level:  Info,
+		})
+		sink := NewSinkAdapter(&LoggerOptions{
+			Output:      &sbuf,
+			Level:       Debug,
+			DisableTime: true,
+		})
+		intercept.RegisterSink(sink)
+		defer intercept.DeregisterSink(sink)
+
+		intercept.(PrintfLogger).Debugf("debug %d", 1)
+
+		assert.Equal(t, "", buf.String())
+		assert.Equal(t, "[DEBUG] -- debug 1\n", sbuf.String())
+	})
+
+	t.Run("isn't formatted again by the standard logger bridge", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := FromStandardLogger(log.New(&buf, "", 0), &LoggerOptions{})
+		logger.(PrintfLogger).Infof("%s", "100%d")
+
+		assert.Equal(t, "[INFO]  -- 100%d\n", buf.String())
+	})
+
+	t.Run("keeps the caller location", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:          &buf,
+			DisableTime:     true,
+			IncludeLocation: true,
+		})
+
+		_, _, line, _ := runtime.Caller(0)
+		logger.(PrintfLogger).Infof("here")
+
+		assert.Equal(t, fmt.Sprintf("[INFO] [go-hclog/printf_test.go:%d] -- here\n", line+1), buf.String())
+	})
+}
@@ -0,0 +1,30 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// rawJSON returns the value written in JSON for raw: raw itself, inlined by the
+// encoder, if it is valid JSON within the MaxFieldBytes limit, or else raw as
+// a string, so that an invalid message doesn't fail the whole entry and a huge
+// one is truncated.
+func (l *intLogger) rawJSON(raw json.RawMessage) interface{} {
+	if !json.Valid(raw) {
+		return string(raw)
+	}
+	if l.limits.fieldBytes > 0 && len(raw) > l.limits.fieldBytes {
+		return compactJSON(raw)
+	}
+	return raw
+}
+
+// compactJSON returns raw without insignificant whitespace, or as it is if it
+// is invalid JSON.
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
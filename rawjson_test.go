@@ -0,0 +1,81 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_rawJSON(t *testing.T) {
+	policy := json.RawMessage(`{
+	"effect": "allow",
+	"actions": ["read", "list"]
+}`)
+
+	t.Run("inlines raw messages in JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		})
+
+		logger.Info("policy loaded", "policy", policy)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, map[string]interface{}{
+			"effect":  "allow",
+			"actions": []interface{}{"read", "list"},
+		}, entry["policy"])
+		assert.Contains(t, buf.String(), `"policy":{"effect":"allow","actions":["read","list"]}`)
+	})
+
+	t.Run("writes invalid raw messages as strings", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		})
+
+		logger.Info("policy loaded", "policy", json.RawMessage(`{"effect":`), "other", 1)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, `{"effect":`, entry["policy"])
+		assert.Equal(t, float64(1), entry["other"])
+	})
+
+	t.Run("truncates huge raw messages as strings", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:        &buf,
+			JSONFormat:    true,
+			MaxFieldBytes: 10,
+		})
+
+		logger.Info("policy loaded", "policy", policy, "small", json.RawMessage(`[1,2]`))
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, `{"effect":`+" (truncated 34 bytes)", entry["policy"])
+		assert.Equal(t, []interface{}{float64(1), float64(2)}, entry["small"])
+	})
+
+	t.Run("compacts raw messages in text", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		logger.Info("policy loaded", "policy", policy, "invalid", json.RawMessage("[1,"))
+		assert.Equal(t, "[INFO]  -- policy loaded: policy={\"effect\":\"allow\",\"actions\":[\"read\",\"list\"]} invalid=[1,\n", buf.String())
+	})
+}
@@ -0,0 +1,105 @@
+package hclog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RecoverRepanics makes RecoverAndLog panic again with the recovered value
+// once it is logged, for the panic to still crash the program.
+var RecoverRepanics = false
+
+// PanicKey is the key of the field tagging the entries written by
+// RecoverAndLog.
+const PanicKey = "panic"
+
+// RecoverAndLog recovers from a panic and logs it to l at the ERROR level, with
+// the PanicKey field, the panic value and the stack of the panic. It must be
+// deferred directly, as recover only works in deferred functions:
+//
+//	go func() {
+//		defer hclog.RecoverAndLog(logger)
+//		...
+//	}()
+//
+// Panic values which are errors are logged under the "error" key, and other
+// values under the "value" key, using the String method of fmt.Stringer
+// values. The stack is appended to the entry in text mode, and written as a
+// list of frames under the "stacktrace" key in JSON mode. A goroutine exiting
+// with runtime.Goexit isn't recovered.
+func RecoverAndLog(l Logger) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	args := []interface{}{PanicKey, true}
+	switch v := v.(type) {
+	case error:
+		args = append(args, ErrorKey, v)
+	case fmt.Stringer:
+		args = append(args, "value", v.String())
+	default:
+		args = append(args, "value", fmt.Sprintf("%v", v))
+	}
+
+	stack := panicStack(takeStacktrace())
+	if isJSON(l) {
+		args = append(args, "stacktrace", stackFrames(stack))
+	} else {
+		args = append(args, CapturedStacktrace(stack))
+	}
+	withCallerSkip(l, 2).Log(Error, "recovered from panic", args...)
+
+	if RecoverRepanics {
+		panic(v)
+	}
+}
+
+// panicStack trims the frames of the recovery from a stack taken in a
+// deferred function, so that it starts where the panic was raised.
+func panicStack(stack string) string {
+	const gopanic = "runtime.gopanic\n"
+	idx := strings.Index(stack, gopanic)
+	if idx < 0 {
+		return stack
+	}
+	rest := stack[idx+len(gopanic):]
+	if idx = strings.IndexByte(rest, '\n'); idx < 0 {
+		return stack
+	}
+	return rest[idx+1:]
+}
+
+// stackFrames splits a stack, as returned by takeStacktrace, into frames.
+func stackFrames(stack string) []map[string]interface{} {
+	lines := strings.Split(stack, "\n")
+	frames := make([]map[string]interface{}, 0, len(lines)/2)
+	for i := 0; i+1 < len(lines); i += 2 {
+		frame := map[string]interface{}{"function": lines[i]}
+		location := strings.TrimPrefix(lines[i+1], "\t")
+		if idx := strings.LastIndexByte(location, ':'); idx >= 0 {
+			if line, err := strconv.Atoi(location[idx+1:]); err == nil {
+				frame["file"] = location[:idx]
+				frame["line"] = line
+			}
+		}
+		if _, ok := frame["file"]; !ok {
+			frame["file"] = location
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// isJSON reports whether l writes JSON entries.
+func isJSON(l Logger) bool {
+	switch l := l.(type) {
+	case *intLogger:
+		return l.json
+	case *interceptLogger:
+		return isJSON(l.Logger)
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stringerValue struct{}
+
+func (stringerValue) String() string { return "stringer value" }
+
+func panicWith(l Logger, v interface{}) {
+	defer RecoverAndLog(l)
+	panic(v)
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	t.Run("logs the panic value and stack", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		panicWith(logger, "boom")
+
+		lines := strings.Split(buf.String(), "\n")
+		assert.Equal(t, "[ERROR] -- recovered from panic: panic=true value=boom", lines[0])
+		assert.Equal(t, "github.com/varnson/go-hclog.panicWith", lines[1])
+		assert.NotContains(t, buf.String(), "go-hclog.RecoverAndLog\n")
+	})
+
+	t.Run("handles errors and stringers", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+		})
+
+		panicWith(logger, errors.New("failed"))
+		panicWith(logger, stringerValue{})
+
+		assert.Contains(t, buf.String(), "recovered from panic: panic=true error=failed\n")
+		assert.Contains(t, buf.String(), "recovered from panic: panic=true value=\"stringer value\"\n")
+	})
+
+	t.Run("structures the stack in JSON mode", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+		})
+
+		panicWith(logger, "boom")
+
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		assert.Equal(t, true, raw[PanicKey])
+		assert.Equal(t, "boom", raw["value"])
+
+		frames, ok := raw["stacktrace"].([]interface{})
+		require.True(t, ok, "stacktrace is %T", raw["stacktrace"])
+		frame := frames[0].(map[string]interface{})
+		assert.Equal(t, "github.com/varnson/go-hclog.panicWith", frame["function"])
+		assert.True(t, strings.HasSuffix(frame["file"].(string), "recover_test.go"))
+		assert.IsType(t, float64(0), frame["line"])
+	})
+
+	t.Run("panics again when asked to", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{Output: &buf})
+
+		RecoverRepanics = true
+		defer func() { RecoverRepanics = false }()
+
+		assert.PanicsWithValue(t, "boom", func() { panicWith(logger, "boom") })
+		assert.Contains(t, buf.String(), "recovered from panic")
+	})
+
+	t.Run("doesn't swallow Goexit", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{Output: &buf})
+
+		var wg sync.WaitGroup
+		reached := false
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			func() {
+				defer RecoverAndLog(logger)
+				runtime.Goexit()
+			}()
+			reached = true
+		}()
+		wg.Wait()
+
+		assert.False(t, reached)
+		assert.Equal(t, "", buf.String())
+	})
+}
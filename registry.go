@@ -0,0 +1,110 @@
+package hclog
+
+import (
+	"sort"
+	"sync"
+)
+
+// LoggerInfo describes a named logger recorded by a LoggerRegistry.
+type LoggerInfo struct {
+	// Name is the full name of the logger, as returned by its Name method.
+	Name string
+
+	// Level is the level of the logger as it stands, including the level
+	// override applying to it if any.
+	Level Level
+}
+
+// LoggerRegistry is implemented by loggers which record the named loggers
+// derived from them, for tooling listing every subsystem and its level. The
+// loggers returned by New and NewInterceptLogger implement it, recording the
+// named loggers if created with RegisterNamed and nothing otherwise.
+type LoggerRegistry interface {
+	// Loggers returns the named loggers recorded, sorted by name.
+	Loggers() []LoggerInfo
+
+	// GetLogger returns the last logger created with Named or ResetNamed
+	// under name, or false if there is none.
+	GetLogger(name string) (Logger, bool)
+
+	// Release forgets the logger recorded under name, for the loggers
+	// created with names which are not reused, such as one per connection.
+	Release(name string)
+}
+
+// loggerRegistry holds the named loggers of a root logger created with
+// RegisterNamed, shared by every logger derived from it. It is keyed by name,
+// so loggers named the same, such as one per request, are only held once.
+type loggerRegistry struct {
+	mu      sync.RWMutex
+	loggers map[string]Logger
+}
+
+// newLoggerRegistry returns the registry of opts, nil unless RegisterNamed is
+// set. The registry of the logger the options were taken from is kept, so
+// that the loggers created with WithOptions record theirs in it too.
+func newLoggerRegistry(opts *LoggerOptions) *loggerRegistry {
+	if !opts.RegisterNamed {
+		return nil
+	}
+	if opts.registry != nil {
+		return opts.registry
+	}
+	return &loggerRegistry{loggers: make(map[string]Logger)}
+}
+
+// add records l under name, replacing the logger recorded before.
+func (r *loggerRegistry) add(name string, l Logger) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loggers[name] = l
+}
+
+// get returns the logger recorded under name.
+func (r *loggerRegistry) get(name string) (Logger, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	l, ok := r.loggers[name]
+	return l, ok
+}
+
+// release forgets the logger recorded under name.
+func (r *loggerRegistry) release(name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.loggers, name)
+}
+
+// list returns the loggers recorded and their levels, sorted by name. The
+// levels are read after the lock is released, since the loggers are free to
+// be derived from, and so to be added to r, meanwhile.
+func (r *loggerRegistry) list() []LoggerInfo {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	loggers := make([]Logger, 0, len(r.loggers))
+	for _, l := range r.loggers {
+		loggers = append(loggers, l)
+	}
+	r.mu.RUnlock()
+
+	infos := make([]LoggerInfo, len(loggers))
+	for i, l := range loggers {
+		infos[i] = LoggerInfo{Name: l.Name(), Level: NoLevel}
+		if lg, ok := l.(LevelGetter); ok {
+			infos[i].Level = lg.GetLevel()
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
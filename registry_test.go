@@ -0,0 +1,122 @@
+package hclog
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerRegistry(t *testing.T) {
+	t.Run("records the named loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:         &buf,
+			DisableTime:    true,
+			Level:          Info,
+			LevelOverrides: map[string]Level{"raft": Debug},
+			RegisterNamed:  true,
+		})
+		raft := logger.Named("raft")
+		raft.With("peer", 1).Named("fsm")
+		logger.ResetNamed("standalone")
+		logger.With("a", 1)
+
+		lr := logger.(LoggerRegistry)
+		assert.Equal(t, []LoggerInfo{
+			{Name: "raft", Level: Debug},
+			{Name: "raft.fsm", Level: Debug},
+			{Name: "standalone", Level: Info},
+		}, lr.Loggers())
+
+		fsm, ok := raft.(LoggerRegistry).GetLogger("raft.fsm")
+		require.True(t, ok)
+		fsm.Info("looked up")
+		assert.Equal(t, "[INFO]  [module=raft.fsm] -- looked up: peer=1\n", buf.String())
+
+		logger.SetLevel(Warn)
+		logger.(LevelOverridable).SetLevelOverride("raft", NoLevel)
+		assert.Equal(t, Warn, lr.Loggers()[0].Level)
+
+		lr.Release("raft.fsm")
+		_, ok = lr.GetLogger("raft.fsm")
+		assert.False(t, ok)
+		assert.Len(t, lr.Loggers(), 2)
+	})
+
+	t.Run("holds a logger per name", func(t *testing.T) {
+		logger := New(&LoggerOptions{Output: &bytes.Buffer{}, RegisterNamed: true})
+		for i := 0; i < 10; i++ {
+			logger.With("request", i).Named("http")
+		}
+		last := logger.With("request", "last").Named("http")
+
+		lr := logger.(LoggerRegistry)
+		assert.Len(t, lr.Loggers(), 1)
+		got, ok := lr.GetLogger("http")
+		require.True(t, ok)
+		assert.Equal(t, last, got)
+	})
+
+	t.Run("records nothing when off", func(t *testing.T) {
+		logger := New(&LoggerOptions{Output: &bytes.Buffer{}})
+		logger.Named("raft")
+
+		lr := logger.(LoggerRegistry)
+		assert.Empty(t, lr.Loggers())
+		_, ok := lr.GetLogger("raft")
+		assert.False(t, ok)
+		lr.Release("raft")
+	})
+
+	t.Run("is shared with WithOptions", func(t *testing.T) {
+		logger := New(&LoggerOptions{Output: &bytes.Buffer{}, RegisterNamed: true})
+		json := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.JSONFormat = true
+		})
+		json.Named("pipeline")
+
+		_, ok := logger.(LoggerRegistry).GetLogger("pipeline")
+		assert.True(t, ok)
+	})
+
+	t.Run("records intercept loggers", func(t *testing.T) {
+		logger := NewInterceptLogger(&LoggerOptions{Output: &bytes.Buffer{}, RegisterNamed: true})
+		var sink bytes.Buffer
+		logger.RegisterSink(NewSinkAdapter(&LoggerOptions{Output: &sink, DisableTime: true}))
+		logger.Named("raft")
+
+		got, ok := logger.(LoggerRegistry).GetLogger("raft")
+		require.True(t, ok)
+		got.Info("looked up")
+		assert.Equal(t, "[INFO]  [module=raft] -- looked up\n", sink.String())
+	})
+
+	t.Run("enumerates while loggers are named", func(t *testing.T) {
+		logger := New(&LoggerOptions{Output: &bytes.Buffer{}, RegisterNamed: true})
+		lr := logger.(LoggerRegistry)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					sub := logger.Named(fmt.Sprintf("worker%d", i)).Named(fmt.Sprint(j))
+					if j%2 == 0 {
+						lr.Release(sub.Name())
+					}
+				}
+			}(i)
+		}
+		for i := 0; i < 100; i++ {
+			lr.Loggers()
+		}
+		wg.Wait()
+
+		assert.Len(t, lr.Loggers(), 4+4*50)
+	})
+}
@@ -0,0 +1,77 @@
+package hclog
+
+import "sync/atomic"
+
+// StaleScopeKey is the key of the field added to the entries of a logger
+// returned by Scope once its scope is released.
+const StaleScopeKey = "stale_scope"
+
+// scope is the scope of a logger returned by Scope, shared with the loggers
+// derived from it.
+type scope struct {
+	released uint32
+
+	// parent is the scope of the logger Scope was called on, if any, which
+	// releasing also ends this one.
+	parent *scope
+}
+
+// release ends the scope, and is safe to call more than once.
+func (s *scope) release() {
+	atomic.StoreUint32(&s.released, 1)
+}
+
+// stale reports whether s or one of its parents was released, with an atomic
+// load per scope.
+func (s *scope) stale() bool {
+	for ; s != nil; s = s.parent {
+		if atomic.LoadUint32(&s.released) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope returns a logger implying args as With does, for the lifetime of a
+// request or task, and the function releasing it at the end:
+//
+//	logger, release := logger.(hclog.Scoper).Scope("request_id", id)
+//	defer release()
+//
+// The entries written by the logger, or by the loggers derived from it, once
+// released hold StaleScopeKey set to true, to find the loggers kept past the
+// end of their scope, as in a cache, along with outdated arguments. Checking
+// the scope costs an atomic load per entry, and none with DisableScopeChecks,
+// which makes Scope the same as With.
+func (l *intLogger) Scope(args ...interface{}) (Logger, func()) {
+	sl := l.With(args...).(*intLogger)
+	if l.noScopeChecks {
+		return sl, func() {}
+	}
+	sl.scope = &scope{parent: l.scope}
+	return sl, sl.scope.release
+}
+
+// impliedArgs returns the implied arguments of the entries of l, followed by
+// StaleScopeKey once the scope of l is released.
+func (l *intLogger) impliedArgs() []interface{} {
+	if !l.scope.stale() {
+		return l.implied
+	}
+	implied := make([]interface{}, 0, len(l.implied)+2)
+	return append(append(implied, l.implied...), StaleScopeKey, true)
+}
+
+// Scope returns a logger implying args, released by the function returned,
+// see Scoper. Registered sinks receive its entries as well. Loggers which
+// aren't Scopers are given args with With, and the release does nothing.
+func (i *interceptLogger) Scope(args ...interface{}) (Logger, func()) {
+	sub := *i
+	if s, ok := i.Logger.(Scoper); ok {
+		var release func()
+		sub.Logger, release = s.Scope(args...)
+		return &sub, release
+	}
+	sub.Logger = i.Logger.With(args...)
+	return &sub, func() {}
+}
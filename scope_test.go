@@ -0,0 +1,96 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope(t *testing.T) {
+	t.Run("flags the entries once released", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		scoped, release := logger.(Scoper).Scope("request_id", "r1")
+		derived := scoped.Named("db").With("table", "users")
+		scoped.Info("handling")
+		release()
+		scoped.Info("cached", "a", 1)
+		derived.(FieldLogger).WarnF("query", Int("rows", 2))
+		logger.Info("root")
+		release()
+
+		assert.Equal(t,
+			"[INFO]  -- handling: request_id=r1\n"+
+				"[INFO]  -- cached: request_id=r1 stale_scope=true a=1\n"+
+				"[WARN]  [module=db] -- query: request_id=r1 table=users stale_scope=true rows=2\n"+
+				"[INFO]  -- root\n",
+			buf.String())
+	})
+
+	t.Run("releases the nested scopes with their parent", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, JSONFormat: true})
+
+		outer, releaseOuter := logger.(Scoper).Scope("request_id", "r1")
+		inner, releaseInner := outer.(Scoper).Scope("step", 1)
+		defer releaseInner()
+		releaseOuter()
+		inner.Info("step", Stacktrace())
+
+		var raw map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		assert.Equal(t, true, raw[StaleScopeKey])
+		assert.Equal(t, "r1", raw["request_id"])
+		assert.Contains(t, raw, "stacktrace")
+	})
+
+	t.Run("passes the flag to the sinks", func(t *testing.T) {
+		var buf, sink bytes.Buffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: &buf, DisableTime: true})
+		logger.RegisterSink(NewSinkAdapter(&LoggerOptions{Output: &sink, DisableTime: true}))
+
+		scoped, release := logger.(Scoper).Scope("request_id", "r1")
+		release()
+		scoped.Info("cached")
+
+		assert.Equal(t, "[INFO]  -- cached: request_id=r1 stale_scope=true\n", buf.String())
+		assert.Equal(t, buf.String(), sink.String())
+	})
+
+	t.Run("is With without scope checks", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true, DisableScopeChecks: true})
+
+		scoped, release := logger.(Scoper).Scope("request_id", "r1")
+		release()
+		scoped.Info("cached")
+		assert.Nil(t, scoped.(*intLogger).scope)
+		assert.False(t, strings.Contains(buf.String(), StaleScopeKey))
+	})
+}
+
+func BenchmarkScope(b *testing.B) {
+	logger := New(&LoggerOptions{Output: ioutil.Discard, DisableTime: true})
+	scoped, release := logger.(Scoper).Scope("request_id", "r1")
+	defer release()
+	unchecked, _ := New(&LoggerOptions{Output: ioutil.Discard, DisableTime: true, DisableScopeChecks: true}).(Scoper).Scope("request_id", "r1")
+
+	for name, l := range map[string]Logger{
+		"with":      logger.With("request_id", "r1"),
+		"scope":     scoped,
+		"unchecked": unchecked,
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.Info("entry", "a", 1)
+			}
+		})
+	}
+}
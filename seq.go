@@ -0,0 +1,70 @@
+package hclog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+)
+
+const (
+	// SeqKey is the key of the sequence number written with every entry by
+	// the loggers created with Sequence.
+	SeqKey = "seq"
+
+	// EntryIDKey is the key of the random ID written with every entry by the
+	// loggers created with EntryIDs.
+	EntryIDKey = "entry_id"
+)
+
+// sequence numbers the entries of a root logger and the loggers derived from
+// it, which share next.
+type sequence struct {
+	next     *uint64
+	numbered bool
+	ids      bool
+}
+
+// newSequence returns the sequence of opts, nil if entries are neither
+// numbered nor given an ID. The counter of the logger the options were taken
+// from is kept, so that WithOptions doesn't restart the numbering.
+func newSequence(opts *LoggerOptions) *sequence {
+	if !opts.Sequence && !opts.EntryIDs {
+		return nil
+	}
+	next := opts.seqCounter
+	if next == nil {
+		next = new(uint64)
+	}
+	return &sequence{next: next, numbered: opts.Sequence, ids: opts.EntryIDs}
+}
+
+// stamp returns args preceded by the sequence number and entry ID of a new
+// entry, and args as they are if s is nil.
+func (s *sequence) stamp(args []interface{}) []interface{} {
+	if s == nil {
+		return args
+	}
+	stamped := make([]interface{}, 0, len(args)+4)
+	if s.numbered {
+		stamped = append(stamped, SeqKey, atomic.AddUint64(s.next, 1))
+	}
+	if s.ids {
+		stamped = append(stamped, EntryIDKey, newEntryID())
+	}
+	return append(stamped, args...)
+}
+
+// counter returns the counter of s, for the options of a logger.
+func (s *sequence) counter() *uint64 {
+	if s == nil {
+		return nil
+	}
+	return s.next
+}
+
+// newEntryID returns a random 128-bit ID, hex encoded.
+func newEntryID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
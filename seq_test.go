@@ -0,0 +1,183 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequence(t *testing.T) {
+	t.Run("numbers the entries of derived loggers", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			Sequence:    true,
+		})
+
+		logger.Info("first", "a", 1)
+		logger.Named("sub").With("b", 2).Info("second")
+		logger.(FieldLogger).InfoF("third", Int("c", 3))
+		logger.Debug("below the level")
+		logger.(Auditor).Audit("fourth")
+		logger.Info("fifth")
+		assert.Equal(t,
+			"[INFO]  -- first: seq=1 a=1\n"+
+				"[INFO]  [module=sub] -- second: seq=2 b=2\n"+
+				"[INFO]  -- third: seq=3 c=3\n"+
+				"[INFO]  -- fourth: seq=4 audit=true\n"+
+				"[INFO]  -- fifth: seq=5\n",
+			buf.String())
+	})
+
+	t.Run("doesn't number dropped entries", func(t *testing.T) {
+		var buf bytes.Buffer
+		excluded := new(ExcludeByMessage)
+		excluded.Add("excluded")
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			Sequence:    true,
+			Exclude:     excluded.Exclude,
+			Middleware: []func(e *Entry) bool{func(e *Entry) bool {
+				return e.Message != "dropped"
+			}},
+		})
+
+		logger.Info("excluded")
+		logger.Info("dropped")
+		logger.Info("kept")
+		assert.Equal(t, "[INFO]  -- kept: seq=1\n", buf.String())
+	})
+
+	t.Run("keeps counting across output swaps", func(t *testing.T) {
+		var first, second, third bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:      &first,
+			DisableTime: true,
+			Sequence:    true,
+		})
+
+		logger.Info("first")
+		require.NoError(t, logger.(OutputResettable).ResetOutput(&LoggerOptions{Output: &second}))
+		logger.Info("second")
+		swapped := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.Output = &third
+			opts.JSONFormat = true
+		})
+		swapped.Info("third")
+		logger.Info("fourth")
+
+		assert.Equal(t, "[INFO]  -- first: seq=1\n", first.String())
+		assert.Equal(t, "[INFO]  -- second: seq=2\n[INFO]  -- fourth: seq=4\n", second.String())
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(third.Bytes(), &entry))
+		assert.Equal(t, float64(3), entry[SeqKey])
+	})
+
+	t.Run("writes entry IDs", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			Sequence:    true,
+			EntryIDs:    true,
+		})
+
+		logger.Info("first")
+		logger.Info("second")
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		re := regexp.MustCompile(`^\[INFO\]  -- \w+: seq=\d entry_id=([0-9a-f]{32})$`)
+		first, second := re.FindStringSubmatch(lines[0]), re.FindStringSubmatch(lines[1])
+		require.NotNil(t, first, lines[0])
+		require.NotNil(t, second, lines[1])
+		assert.NotEqual(t, first[1], second[1])
+	})
+
+	t.Run("stamps entries whatever the key filter", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			DisableTime: true,
+			Sequence:    true,
+			OnlyKeys:    []string{"a"},
+		})
+
+		logger.Info("first", "a", 1, "b", 2)
+		logger.Info("second", "b", 2)
+		assert.Equal(t,
+			"[INFO]  -- first: seq=1 a=1\n"+
+				"[INFO]  -- second: seq=2\n",
+			buf.String())
+	})
+
+	t.Run("writes nothing when off", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{Output: &buf, DisableTime: true})
+
+		logger.Info("entry")
+		assert.Equal(t, "[INFO]  -- entry\n", buf.String())
+	})
+
+	t.Run("numbers concurrent entries once each", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:     &buf,
+			JSONFormat: true,
+			Sequence:   true,
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(l Logger) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.Info("entry")
+				}
+			}(logger.Named("worker"))
+		}
+		wg.Wait()
+
+		seen := make(map[float64]bool)
+		var last float64
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			seq := entry[SeqKey].(float64)
+			assert.True(t, seq > last, "Expected the entries to be written in order")
+			seen[seq], last = true, seq
+		}
+		assert.Len(t, seen, 800)
+	})
+}
+
+func BenchmarkSequence(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		opts LoggerOptions
+	}{
+		{"off", LoggerOptions{}},
+		{"seq", LoggerOptions{Sequence: true}},
+		{"seq and ids", LoggerOptions{Sequence: true, EntryIDs: true}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			opts := c.opts
+			opts.Output = ioutil.Discard
+			logger := New(&opts)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.Info("benchmark entry", "key", "value")
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package hclog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrShutdown is returned by Audit once the logger is shut down.
+var ErrShutdown = errors.New("logger is shut down")
+
+// shutdownState is shared by a root logger and every logger derived from it,
+// so that shutting down one shuts down all of them.
+type shutdownState struct {
+	// stopped is set once Shutdown is called, read atomically.
+	stopped int32
+
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// newShutdownState returns the state of Shutdown kept in opts by WithOptions,
+// or a new one.
+func newShutdownState(opts *LoggerOptions) *shutdownState {
+	if opts.shutdown != nil {
+		return opts.shutdown
+	}
+	return &shutdownState{done: make(chan struct{})}
+}
+
+// isStopped reports whether Shutdown was called.
+func (s *shutdownState) isStopped() bool {
+	return atomic.LoadInt32(&s.stopped) == 1
+}
+
+// run stops the loggers and runs work the first time it is called, and waits
+// for work to return or for ctx to be done. Later calls wait in the same way,
+// for the same result.
+func (s *shutdownState) run(ctx context.Context, work func(ctx context.Context) error) error {
+	s.once.Do(func() {
+		atomic.StoreInt32(&s.stopped, 1)
+		go func() {
+			s.err = work(ctx)
+			close(s.done)
+		}()
+	})
+	select {
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops the logger, and every logger derived from it including those
+// created with WithOptions, from writing entries, then flushes its output if
+// it implements Flushable and closes it if it implements io.Closer, unless it
+// is os.Stdout or os.Stderr. The entries being written are written first, and
+// the entries logged afterwards are dropped and counted as such by Stats,
+// while Audit returns ErrShutdown. Shutdown returns the first error, or the
+// error of ctx if it is done first. Calling it again waits for the first call
+// in the same way, and returns the same error.
+func (l *intLogger) Shutdown(ctx context.Context) error {
+	return l.shutdown.run(ctx, func(context.Context) error {
+		return l.closeOutput()
+	})
+}
+
+// closeOutput flushes and closes the output of l, once the entries being
+// written are.
+func (l *intLogger) closeOutput() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var err error
+	out := l.writer.output()
+	if f, ok := out.w.(Flushable); ok {
+		err = f.Flush()
+	}
+	if c, ok := out.w.(io.Closer); ok && c != os.Stdout && c != os.Stderr {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// closeSink closes sink, by the first of the methods it implements: Shutdown,
+// Close with a context as the sinks of the modules of this repository, Close,
+// or Flush.
+func closeSink(ctx context.Context, sink SinkAdapter) error {
+	switch s := sink.(type) {
+	case Shutdowner:
+		return s.Shutdown(ctx)
+	case interface{ Close(context.Context) error }:
+		return s.Close(ctx)
+	case io.Closer:
+		return s.Close()
+	case Flushable:
+		return s.Flush()
+	}
+	return nil
+}
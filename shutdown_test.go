@@ -0,0 +1,164 @@
+package hclog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closingBuffer is an output recording the calls to Flush and Close.
+type closingBuffer struct {
+	bytes.Buffer
+	flushed, closed int
+	closeErr        error
+}
+
+func (b *closingBuffer) Flush() error {
+	b.flushed++
+	return nil
+}
+
+func (b *closingBuffer) Close() error {
+	b.closed++
+	return b.closeErr
+}
+
+// contextSink is a sink closed with a context, like the sinks of the modules
+// of this repository.
+type contextSink struct {
+	mu       sync.Mutex
+	accepted []string
+	closed   int
+	block    chan struct{}
+}
+
+func (s *contextSink) Accept(name string, level Level, msg string, args ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accepted = append(s.accepted, msg)
+}
+
+func (s *contextSink) Close(ctx context.Context) error {
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed++
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("flushes and closes the output", func(t *testing.T) {
+		var out closingBuffer
+		logger := New(&LoggerOptions{Output: &out, DisableTime: true})
+		sub := logger.Named("sub").With("a", 1)
+		sub.Info("before")
+
+		require.NoError(t, logger.(Shutdowner).Shutdown(context.Background()))
+		assert.Equal(t, 1, out.flushed)
+		assert.Equal(t, 1, out.closed)
+
+		dropped := Stats().Dropped
+		logger.Info("after")
+		sub.Error("after")
+		sub.(FieldLogger).ErrorF("after")
+		assert.Equal(t, "[INFO]  [module=sub] -- before: a=1\n", out.String())
+		assert.Equal(t, dropped+3, Stats().Dropped)
+		assert.Equal(t, ErrShutdown, logger.(Auditor).Audit("after"))
+
+		require.NoError(t, sub.(Shutdowner).Shutdown(context.Background()))
+		assert.Equal(t, 1, out.closed)
+	})
+
+	t.Run("returns the error closing the output every time", func(t *testing.T) {
+		out := &closingBuffer{closeErr: errors.New("disk full")}
+		logger := New(&LoggerOptions{Output: out})
+
+		assert.Equal(t, out.closeErr, logger.(Shutdowner).Shutdown(context.Background()))
+		assert.Equal(t, out.closeErr, logger.(Shutdowner).Shutdown(context.Background()))
+		assert.Equal(t, 1, out.closed)
+	})
+
+	t.Run("drops the entries of middleware", func(t *testing.T) {
+		var out closingBuffer
+		logger := New(&LoggerOptions{
+			Output:      &out,
+			DisableTime: true,
+			Middleware:  []func(e *Entry) bool{func(e *Entry) bool { return true }},
+		})
+		require.NoError(t, logger.(Shutdowner).Shutdown(context.Background()))
+		logger.Info("after")
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("stops the loggers created with WithOptions", func(t *testing.T) {
+		var out, other closingBuffer
+		logger := New(&LoggerOptions{Output: &out, DisableTime: true})
+		json := logger.(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.Output = &other
+			opts.Mutex = nil
+		})
+
+		require.NoError(t, logger.(Shutdowner).Shutdown(context.Background()))
+		json.Info("after")
+		assert.Empty(t, other.String())
+		assert.Equal(t, 1, out.closed)
+		assert.Equal(t, 0, other.closed)
+		assert.Equal(t, ErrShutdown, json.(Auditor).Audit("after"))
+	})
+
+	t.Run("closes the sinks of an intercept logger", func(t *testing.T) {
+		var out, sinkOut closingBuffer
+		logger := NewInterceptLogger(&LoggerOptions{Output: &out, DisableTime: true})
+		adapter := NewSinkAdapter(&LoggerOptions{Output: &sinkOut, DisableTime: true})
+		sink := new(contextSink)
+		logger.RegisterSink(adapter)
+		logger.RegisterSink(sink)
+		sub := logger.Named("sub")
+		sub.Info("before")
+
+		require.NoError(t, sub.(Shutdowner).Shutdown(context.Background()))
+		assert.Equal(t, 1, out.closed)
+		assert.Equal(t, 1, sinkOut.closed)
+		assert.Equal(t, 1, sink.closed)
+
+		late := new(contextSink)
+		logger.RegisterSink(late)
+		logger.Info("after")
+		sub.(FieldLogger).InfoF("after")
+		assert.Equal(t, []string{"before"}, sink.accepted)
+		assert.Empty(t, late.accepted)
+		assert.Equal(t, "[INFO]  [module=sub] -- before\n", sinkOut.String())
+	})
+
+	t.Run("gives up when the context is done", func(t *testing.T) {
+		logger := NewInterceptLogger(&LoggerOptions{Output: &closingBuffer{}})
+		sink := &contextSink{block: make(chan struct{})}
+		logger.RegisterSink(sink)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, logger.(Shutdowner).Shutdown(ctx))
+
+		// The sinks are closed with the context of the first call, which
+		// gave up on them too.
+		assert.Equal(t, context.DeadlineExceeded, logger.(Shutdowner).Shutdown(context.Background()))
+		assert.Equal(t, 0, sink.closed)
+	})
+
+	t.Run("leaves the standard outputs open", func(t *testing.T) {
+		logger := New(&LoggerOptions{Level: Off})
+		require.NoError(t, logger.(Shutdowner).Shutdown(context.Background()))
+	})
+}
@@ -0,0 +1,104 @@
+package hclog
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+// stats are the counters of the loggers of this package, accessed atomically.
+var stats struct {
+	entries    [Off + 1]uint64
+	dropped    uint64
+	suppressed uint64
+	bytes      uint64
+}
+
+// LoggerStats is a snapshot of the counters of all the loggers of this
+// package, as returned by Stats.
+type LoggerStats struct {
+	// Entries counts the entries written, by level.
+	Entries map[Level]uint64
+
+	// Dropped counts the entries which passed the level of their logger but
+	// were dropped by Exclude, ExcludeNames or IncludeOnlyNames.
+	Dropped uint64
+
+	// Suppressed counts the entries demoted or dropped by SuppressErrors.
+	Suppressed uint64
+
+	// BytesWritten counts the bytes written to the outputs of the loggers.
+	BytesWritten uint64
+}
+
+// Stats returns a snapshot of the counters of all the loggers of this package.
+func Stats() LoggerStats {
+	s := LoggerStats{
+		Entries:      make(map[Level]uint64, Error-Trace+1),
+		Dropped:      atomic.LoadUint64(&stats.dropped),
+		Suppressed:   atomic.LoadUint64(&stats.suppressed),
+		BytesWritten: atomic.LoadUint64(&stats.bytes),
+	}
+	for level := Trace; level <= Error; level++ {
+		s.Entries[level] = atomic.LoadUint64(&stats.entries[level])
+	}
+	return s
+}
+
+// countWritten records an entry written at level, of n bytes.
+func countWritten(level Level, n int) {
+	if level >= NoLevel && level <= Off {
+		atomic.AddUint64(&stats.entries[level], 1)
+	}
+	atomic.AddUint64(&stats.bytes, uint64(n))
+}
+
+// countDropped records an entry dropped before being written.
+func countDropped() {
+	atomic.AddUint64(&stats.dropped, 1)
+}
+
+// countSuppressed records an entry demoted or dropped by SuppressErrors.
+func countSuppressed() {
+	atomic.AddUint64(&stats.suppressed, 1)
+}
+
+var publishLock sync.Mutex
+
+// PublishExpvar publishes the counters returned by Stats as expvar variables:
+// <prefix>.entries.<level> for each level, <prefix>.dropped,
+// <prefix>.suppressed and <prefix>.bytes_written. The variables read the
+// counters when they are served, so they are always current. Calling it again
+// with the same prefix does nothing, rather than panicking on the duplicate
+// names like expvar.Publish.
+func PublishExpvar(prefix string) {
+	for level := Trace; level <= Error; level++ {
+		level := level
+		PublishExpvarFunc(prefix+".entries."+level.String(), func() interface{} {
+			return atomic.LoadUint64(&stats.entries[level])
+		})
+	}
+	PublishExpvarFunc(prefix+".dropped", func() interface{} {
+		return atomic.LoadUint64(&stats.dropped)
+	})
+	PublishExpvarFunc(prefix+".suppressed", func() interface{} {
+		return atomic.LoadUint64(&stats.suppressed)
+	})
+	PublishExpvarFunc(prefix+".bytes_written", func() interface{} {
+		return atomic.LoadUint64(&stats.bytes)
+	})
+}
+
+// PublishExpvarFunc publishes f as the expvar variable name unless a variable
+// of that name is already published. It lets other packages, such as the
+// logger package for the statistics of its log files, publish their counters
+// along with PublishExpvar.
+func PublishExpvarFunc(name string, f func() interface{}) {
+	publishLock.Lock()
+	defer publishLock.Unlock()
+
+	if expvar.Get(name) != nil {
+		return
+	}
+	expvar.Publish(name, expvar.Func(f))
+}
@@ -0,0 +1,52 @@
+package hclog
+
+import (
+	"bytes"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	t.Run("counts entries, drops and bytes", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:       &buf,
+			Level:        Debug,
+			DisableTime:  true,
+			ExcludeNames: []string{"noisy"},
+			Exclude:      ExcludeByPrefix("skip").Exclude,
+		})
+
+		before := Stats()
+		logger.Debug("debug")
+		logger.Warn("warn")
+		logger.Warn("warn")
+		logger.Trace("below the level")
+		logger.Named("noisy").Info("dropped by name")
+		logger.Info("skip this")
+		after := Stats()
+
+		assert.Equal(t, uint64(1), after.Entries[Debug]-before.Entries[Debug])
+		assert.Equal(t, uint64(2), after.Entries[Warn]-before.Entries[Warn])
+		assert.Equal(t, before.Entries[Trace], after.Entries[Trace])
+		assert.Equal(t, uint64(2), after.Dropped-before.Dropped)
+		assert.Equal(t, uint64(buf.Len()), after.BytesWritten-before.BytesWritten)
+	})
+
+	t.Run("publishes expvar variables", func(t *testing.T) {
+		PublishExpvar("hclogtest")
+		PublishExpvar("hclogtest")
+
+		logger := New(&LoggerOptions{Output: &bytes.Buffer{}, Level: Info})
+		logger.Error("error")
+
+		v := expvar.Get("hclogtest.entries.error")
+		if assert.NotNil(t, v) {
+			assert.NotEqual(t, "0", v.String())
+		}
+		assert.NotNil(t, expvar.Get("hclogtest.dropped"))
+		assert.NotNil(t, expvar.Get("hclogtest.bytes_written"))
+	})
+}
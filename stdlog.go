@@ -2,8 +2,11 @@ package hclog
 
 import (
 	"bytes"
+	"io"
 	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Provides a io.Writer to shim the data out of *log.Logger
@@ -54,32 +57,74 @@ func (s *stdlogAdapter) dispatch(str string, level Level) {
 	}
 }
 
-// Detect, based on conventions, what log level this is.
+// levelPrefixes are the level tokens pickLevel recognizes, those of this
+// package's text format and of logutils, which writes ERR for Error.
+var levelPrefixes = []struct {
+	prefix string
+	level  Level
+}{
+	{"[DEBUG]", Debug},
+	{"[TRACE]", Trace},
+	{"[INFO]", Info},
+	{"[WARN]", Warn},
+	{"[ERROR]", Error},
+	{"[ERR]", Error},
+}
+
+// Detect, based on conventions, what log level this is. The level token
+// can follow a timestamp, as in the lines written with BracketLevelFirst or
+// by a log.Logger with LstdFlags, which is dropped with the token.
 func (s *stdlogAdapter) pickLevel(str string) (Level, string) {
-	switch {
-	case strings.HasPrefix(str, "[DEBUG]"):
-		return Debug, strings.TrimSpace(str[7:])
-	case strings.HasPrefix(str, "[TRACE]"):
-		return Trace, strings.TrimSpace(str[7:])
-	case strings.HasPrefix(str, "[INFO]"):
-		return Info, strings.TrimSpace(str[6:])
-	case strings.HasPrefix(str, "[WARN]"):
-		return Warn, strings.TrimSpace(str[7:])
-	case strings.HasPrefix(str, "[ERROR]"):
-		return Error, strings.TrimSpace(str[7:])
-	case strings.HasPrefix(str, "[ERR]"):
-		return Error, strings.TrimSpace(str[5:])
-	default:
-		return Info, str
+	rest := trimTimestamp(str)
+	for _, p := range levelPrefixes {
+		if strings.HasPrefix(rest, p.prefix) {
+			return p.level, strings.TrimSpace(rest[len(p.prefix):])
+		}
 	}
+	return Info, str
+}
+
+// trimTimestamp returns str from its first bracket, if all before it looks
+// like a timestamp followed by a space: digits and the separators of the
+// common time layouts.
+func trimTimestamp(str string) string {
+	i := strings.IndexByte(str, '[')
+	if i < 2 || str[i-1] != ' ' {
+		return str
+	}
+	digits := false
+	for _, r := range str[:i] {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case strings.ContainsRune("/:-.+TZ ", r):
+		default:
+			return str
+		}
+	}
+	if !digits {
+		return str
+	}
+	return str[i:]
 }
 
 type logWriter struct {
-	l *log.Logger
+	l      *log.Logger
+	prefix string
 }
 
 func (l *logWriter) Write(b []byte) (int, error) {
-	l.l.Println(string(bytes.TrimRight(b, " \n\t")))
+	line := string(bytes.TrimRight(b, " \n\t"))
+
+	// While a line captured by CaptureStdlog is written, the standard logger
+	// is locked, and l may be writing to it. The line goes to the output the
+	// capture replaced instead of waiting for a lock held by this very write.
+	if c := capturedStdlog(); c != nil && c.writing() {
+		c.original(l.prefix).Println(line)
+		return len(b), nil
+	}
+
+	l.l.Println(line)
 	return len(b), nil
 }
 
@@ -89,7 +134,109 @@ func FromStandardLogger(l *log.Logger, opts *LoggerOptions) Logger {
 
 	// Use the time format that log.Logger uses
 	dl.DisableTime = true
-	dl.Output = &logWriter{l}
+	dl.Output = &logWriter{l: l, prefix: l.Prefix()}
 
 	return New(&dl)
 }
+
+// stdlogCaptured holds the capture in effect on the standard logger. It is
+// kept aside rather than read back from log.Writer, which blocks while the
+// standard logger writes.
+var stdlogCaptured struct {
+	sync.Mutex
+	c *stdlogCapture
+}
+
+func capturedStdlog() *stdlogCapture {
+	stdlogCaptured.Lock()
+	defer stdlogCaptured.Unlock()
+	return stdlogCaptured.c
+}
+
+func setCapturedStdlog(c *stdlogCapture) {
+	stdlogCaptured.Lock()
+	defer stdlogCaptured.Unlock()
+	stdlogCaptured.c = c
+}
+
+// stdlogCapture is the output set on the standard logger by CaptureStdlog.
+type stdlogCapture struct {
+	w         io.Writer
+	prev      io.Writer
+	prevFlags int
+
+	// inWrite is set while a line is written to w, to tell when the Logger
+	// writes back to the capture.
+	inWrite int32
+}
+
+// Write sends b to the Logger, or to the output in use before the first of
+// the captures in effect if the Logger writes back to the capture.
+func (c *stdlogCapture) Write(b []byte) (int, error) {
+	if !atomic.CompareAndSwapInt32(&c.inWrite, 0, 1) {
+		return c.first().prev.Write(b)
+	}
+	defer atomic.StoreInt32(&c.inWrite, 0)
+	return c.w.Write(b)
+}
+
+// writing reports whether a line is being written to the Logger.
+func (c *stdlogCapture) writing() bool {
+	return atomic.LoadInt32(&c.inWrite) != 0
+}
+
+// first returns the first of the captures in effect, which replaced the
+// output in use before them.
+func (c *stdlogCapture) first() *stdlogCapture {
+	for {
+		prev, ok := c.prev.(*stdlogCapture)
+		if !ok {
+			return c
+		}
+		c = prev
+	}
+}
+
+// original returns a standard logger writing to the output in use before the
+// first of the captures in effect, with its flags.
+func (c *stdlogCapture) original(prefix string) *log.Logger {
+	first := c.first()
+	return log.New(first.prev, prefix, first.prevFlags)
+}
+
+// CaptureStdlog sends the output of the package-level functions of the log
+// package, such as log.Printf, to l, inferring the levels of the lines if
+// infer is set as with StandardLoggerOptions.InferLevels. The flags of the
+// standard logger are cleared, so that lines aren't timestamped twice.
+//
+// The returned function restores the output and flags in use before, and does
+// nothing when called again:
+//
+//	defer hclog.CaptureStdlog(logger, true)()
+//
+// Calling CaptureStdlog again captures the output for the new Logger, until
+// its restore function is called. A Logger writing back to the standard
+// logger or to its output, as made by FromStandardLogger, writes to the output
+// in use before the capture rather than to itself.
+func CaptureStdlog(l Logger, infer bool) func() {
+	prev, prevFlags := log.Writer(), log.Flags()
+
+	c := &stdlogCapture{
+		w:         l.StandardWriter(&StandardLoggerOptions{InferLevels: infer}),
+		prev:      prev,
+		prevFlags: prevFlags,
+	}
+	setCapturedStdlog(c)
+	log.SetOutput(c)
+	log.SetFlags(0)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			log.SetOutput(prev)
+			log.SetFlags(prevFlags)
+			prevCapture, _ := prev.(*stdlogCapture)
+			setCapturedStdlog(prevCapture)
+		})
+	}
+}
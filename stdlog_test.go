@@ -3,11 +3,13 @@ package hclog
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -67,6 +69,30 @@ func TestStdlogAdapter_PickLevel(t *testing.T) {
 		assert.Equal(t, Error, level)
 		assert.Equal(t, "coffee?", rest)
 	})
+
+	t.Run("picks level after a timestamp", func(t *testing.T) {
+		var s stdlogAdapter
+
+		for _, line := range []string{
+			"2024-06-10T06:13:20.000Z [WARN] raft: coffee?",
+			"2024-06-10T06:13:20.000+0200 [WARN] raft: coffee?",
+			"2024/06/10 06:13:20 [WARN] raft: coffee?",
+		} {
+			level, rest := s.pickLevel(line)
+
+			assert.Equal(t, Warn, level, line)
+			assert.Equal(t, "raft: coffee?", rest, line)
+		}
+	})
+
+	t.Run("keeps lines with text before the level", func(t *testing.T) {
+		var s stdlogAdapter
+
+		level, rest := s.pickLevel("raft: [WARN] coffee?")
+
+		assert.Equal(t, Info, level)
+		assert.Equal(t, "raft: [WARN] coffee?", rest)
+	})
 }
 
 func TestStdlogAdapter_ForceLevel(t *testing.T) {
@@ -181,3 +207,101 @@ func TestFromStandardLogger(t *testing.T) {
 	prefix := "test-stdlib-log "
 	require.Equal(t, prefix, actual[:16])
 }
+
+func TestCaptureStdlog(t *testing.T) {
+	var orig bytes.Buffer
+	defer func(w io.Writer, flags int) {
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	}(log.Writer(), log.Flags())
+	log.SetOutput(&orig)
+	log.SetFlags(log.Lshortfile)
+
+	t.Run("sends the standard logger to the logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Name:   "test",
+			Output: &buf,
+		})
+
+		restore := CaptureStdlog(logger, true)
+		log.Printf("[WARN] disk %d%% full", 90)
+		restore()
+		restore()
+		log.Print("restored")
+
+		str := buf.String()
+		dataIdx := strings.IndexByte(str, ' ')
+		assert.Equal(t, "[WARN]  [module=test] -- disk 90% full\n", str[dataIdx+1:])
+		assert.Equal(t, "stdlog_test.go:", orig.String()[:15])
+		assert.Contains(t, orig.String(), ": restored\n")
+		assert.Equal(t, log.Lshortfile, log.Flags())
+	})
+
+	t.Run("restores nested captures", func(t *testing.T) {
+		var first, second bytes.Buffer
+		restoreFirst := CaptureStdlog(New(&LoggerOptions{Output: &first, DisableTime: true}), false)
+		restoreSecond := CaptureStdlog(New(&LoggerOptions{Output: &second, DisableTime: true}), false)
+
+		log.Print("second")
+		restoreSecond()
+		log.Print("first")
+		restoreFirst()
+
+		assert.Equal(t, "[INFO]  -- second\n", second.String())
+		assert.Equal(t, "[INFO]  -- first\n", first.String())
+	})
+
+	// looped returns a writer forwarding to the output set on the standard
+	// logger by the capture, once it's made.
+	looped := func() (io.Writer, func()) {
+		var captured io.Writer
+		w := writerFunc(func(b []byte) (int, error) { return captured.Write(b) })
+		return w, func() { captured = log.Writer() }
+	}
+	printOnce := func(t *testing.T) {
+		done := make(chan struct{})
+		go func() {
+			log.Print("once")
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the write not to deadlock")
+		}
+	}
+
+	t.Run("doesn't loop through a logger writing to a standard logger", func(t *testing.T) {
+		orig.Reset()
+		w, captured := looped()
+		logger := FromStandardLogger(log.New(w, "", 0), &LoggerOptions{Name: "looped"})
+
+		restore := CaptureStdlog(logger, false)
+		defer restore()
+		captured()
+		printOnce(t)
+
+		assert.Contains(t, orig.String(), "[INFO]  [module=looped] -- once\n")
+		assert.Equal(t, 1, strings.Count(orig.String(), "\n"))
+	})
+
+	t.Run("doesn't loop through a logger writing to the captured output", func(t *testing.T) {
+		orig.Reset()
+		w, captured := looped()
+		logger := New(&LoggerOptions{Name: "looped", Output: w, DisableTime: true})
+
+		restore := CaptureStdlog(logger, false)
+		defer restore()
+		captured()
+		printOnce(t)
+
+		assert.Equal(t, "[INFO]  [module=looped] -- once\n", orig.String())
+	})
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
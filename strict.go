@@ -0,0 +1,82 @@
+package hclog
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// ArgsError is the value StrictArgs panics with, for a malformed list of
+// key/value pairs.
+type ArgsError struct {
+	// Caller is the file:line of the call given the pairs.
+	Caller string
+
+	// Problem describes what is wrong with the pairs.
+	Problem string
+}
+
+func (e *ArgsError) Error() string {
+	return fmt.Sprintf("malformed log arguments at %s: %s", e.Caller, e.Problem)
+}
+
+// checkArgs panics with an *ArgsError if args, following implied, have an odd
+// length, a key other than a string, or a key given twice. A trailing
+// CapturedStacktrace is allowed.
+func checkArgs(implied, args []interface{}) {
+	args = expandFields(args)
+	n := len(args)
+	if n%2 != 0 {
+		if _, ok := args[n-1].(CapturedStacktrace); !ok {
+			strictPanic(fmt.Sprintf("odd number of arguments, %v has no key", args[n-1]))
+		}
+		n--
+	}
+
+	seen := make(map[string]struct{}, len(implied)/2+n/2)
+	for i := 0; i+1 < len(implied); i += 2 {
+		if key, ok := implied[i].(string); ok {
+			seen[key] = struct{}{}
+		}
+	}
+	for i := 0; i < n; i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			strictPanic(fmt.Sprintf("key %v is a %T, not a string", args[i], args[i]))
+		}
+		if _, ok := seen[key]; ok {
+			strictPanic(fmt.Sprintf("duplicate key %q", key))
+		}
+		seen[key] = struct{}{}
+	}
+}
+
+// checkFields is checkArgs for the Field values given to the methods of
+// FieldLogger, of which the keys can only be duplicated.
+func checkFields(implied []interface{}, fields []Field) {
+	checkArgs(implied, fieldArgs(fields))
+}
+
+// hclogPackage is the import path of this package, for strictPanic to skip
+// its frames.
+var hclogPackage = reflect.TypeOf(ArgsError{}).PkgPath()
+
+// strictPanic panics with an *ArgsError reporting problem, at the first caller
+// outside of this package, its tests aside.
+func strictPanic(problem string) {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	caller := "unknown"
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, hclogPackage+".") || strings.HasSuffix(frame.File, "_test.go") {
+			caller = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	panic(&ArgsError{Caller: caller, Problem: problem})
+}
@@ -0,0 +1,80 @@
+package hclog
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recoverArgsError calls f and returns the *ArgsError it panics with.
+func recoverArgsError(t *testing.T, f func()) (err *ArgsError) {
+	t.Helper()
+	defer func() {
+		v := recover()
+		var ok bool
+		err, ok = v.(*ArgsError)
+		require.True(t, ok, "Expected an *ArgsError panic, got %v", v)
+	}()
+	f()
+	return nil
+}
+
+// line returns the line it is called from.
+func line() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestStrictArgs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&LoggerOptions{Output: &buf, DisableTime: true, StrictArgs: true})
+
+	cases := []struct {
+		name    string
+		log     func()
+		problem string
+	}{
+		{"odd number", func() { logger.Info("entry", "a", 1, "b") }, "odd number of arguments, b has no key"},
+		{"non-string key", func() { logger.Warn("entry", 1, 2) }, "key 1 is a int, not a string"},
+		{"duplicate key", func() { logger.Error("entry", "a", 1, Int("a", 2)) }, `duplicate key "a"`},
+		{"implied key", func() { logger.With("a", 1).Debug("entry", "a", 2) }, `duplicate key "a"`},
+		{"field key", func() { logger.(FieldLogger).InfoF("entry", Str("a", "1"), Int("a", 2)) }, `duplicate key "a"`},
+		{"odd With", func() { logger.With("a") }, "odd number of arguments, a has no key"},
+		{"intercept logger", func() {
+			NewInterceptLogger(&LoggerOptions{Output: &buf, StrictArgs: true}).Named("sub").Info("entry", errors.New("oops"), 1)
+		}, "key oops is a *errors.errorString, not a string"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := recoverArgsError(t, c.log)
+			assert.Equal(t, c.problem, err.Problem)
+			assert.Regexp(t, `strict_test\.go:\d+$`, err.Caller)
+		})
+	}
+
+	t.Run("reports the line of the call", func(t *testing.T) {
+		want := line() + 1
+		err := recoverArgsError(t, func() { logger.Info("entry", "a") })
+		assert.Regexp(t, `strict_test\.go:`+strconv.Itoa(want)+`$`, err.Caller)
+		assert.Contains(t, err.Error(), "malformed log arguments at ")
+	})
+
+	t.Run("accepts well formed pairs", func(t *testing.T) {
+		buf.Reset()
+		logger.With("a", 1).Info("entry", "b", 2, Str("c", "3"), Stacktrace())
+		logger.Trace("below the level", "a", 1)
+		assert.Contains(t, buf.String(), "[INFO]  -- entry: a=1 b=2 c=3\n")
+	})
+
+	t.Run("is off by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		lenient := New(&LoggerOptions{Output: &buf, DisableTime: true})
+		lenient.Info("entry", "a", 1, "a", 2, "b")
+		assert.Equal(t, "[INFO]  -- entry: a=1 a=2 EXTRA_VALUE_AT_END=b\n", buf.String())
+	})
+}
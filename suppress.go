@@ -0,0 +1,112 @@
+package hclog
+
+import "errors"
+
+// DropError wraps err for LoggerOptions.SuppressErrors, for the entries of
+// errors matching err to be dropped rather than demoted to Debug.
+//
+//	SuppressErrors: []error{context.Canceled, hclog.DropError(http.ErrServerClosed)},
+func DropError(err error) error {
+	return &dropError{err: err}
+}
+
+type dropError struct {
+	err error
+}
+
+func (e *dropError) Error() string { return e.err.Error() }
+
+func (e *dropError) Unwrap() error { return e.err }
+
+// errorSuppressor demotes or drops the entries holding the SuppressErrors.
+type errorSuppressor struct {
+	demote []error
+	drop   []error
+}
+
+// newErrorSuppressor returns the suppressor of errs, or nil if there are none.
+func newErrorSuppressor(errs []error) *errorSuppressor {
+	var s errorSuppressor
+	for _, err := range errs {
+		switch err := err.(type) {
+		case nil:
+		case *dropError:
+			if err.err != nil {
+				s.drop = append(s.drop, err.err)
+			}
+		default:
+			s.demote = append(s.demote, err)
+		}
+	}
+	if s.demote == nil && s.drop == nil {
+		return nil
+	}
+	return &s
+}
+
+// errors returns the errors of s as given to SuppressErrors.
+func (s *errorSuppressor) errors() []error {
+	if s == nil {
+		return nil
+	}
+	errs := append([]error(nil), s.demote...)
+	for _, err := range s.drop {
+		errs = append(errs, DropError(err))
+	}
+	return errs
+}
+
+// level returns the level of an entry at level with args and fields, which is
+// Debug if one of its error values matches a demoted error, and false if one
+// matches a dropped error. Entries already at Debug or below keep their level.
+func (s *errorSuppressor) level(level Level, args []interface{}, fields []Field) (Level, bool) {
+	if s == nil || level <= Debug && s.drop == nil {
+		return level, true
+	}
+
+	demoted := false
+	check := func(v interface{}) bool {
+		err, ok := v.(error)
+		if !ok || err == nil {
+			return true
+		}
+		for _, target := range s.drop {
+			if errors.Is(err, target) {
+				return false
+			}
+		}
+		if !demoted {
+			for _, target := range s.demote {
+				if errors.Is(err, target) {
+					demoted = true
+					break
+				}
+			}
+		}
+		return true
+	}
+
+	for _, arg := range args {
+		if f, ok := arg.(Field); ok {
+			arg = f.Value()
+		}
+		if !check(arg) {
+			countSuppressed()
+			return level, false
+		}
+	}
+	for _, f := range fields {
+		if f.typ == errorField && !check(f.iface) {
+			countSuppressed()
+			return level, false
+		}
+	}
+
+	if demoted {
+		countSuppressed()
+		if level > Debug {
+			level = Debug
+		}
+	}
+	return level, true
+}
@@ -0,0 +1,65 @@
+package hclog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppressErrors(t *testing.T) {
+	newLogger := func(buf *bytes.Buffer, level Level) Logger {
+		return New(&LoggerOptions{
+			Output:         buf,
+			Level:          level,
+			DisableTime:    true,
+			SuppressErrors: []error{context.Canceled, DropError(io.EOF)},
+		})
+	}
+
+	t.Run("demotes entries to debug", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, Debug)
+
+		logger.Error("request failed", "cause", fmt.Errorf("serving: %w", context.Canceled))
+		logger.Error("request failed", "error", errors.New("boom"))
+		assert.Equal(t,
+			"[DEBUG] -- request failed: cause=\"serving: context canceled\"\n"+
+				"[ERROR] -- request failed: error=boom\n",
+			buf.String())
+	})
+
+	t.Run("filters out demoted entries below the level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, Info)
+
+		before := Stats()
+		logger.Warn("shutting down", Err(context.Canceled))
+		logger.(FieldLogger).ErrorF("shutting down", Err(context.Canceled))
+		assert.Empty(t, buf.String())
+		assert.Equal(t, uint64(2), Stats().Suppressed-before.Suppressed)
+	})
+
+	t.Run("drops entries", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, Trace)
+
+		logger.Named("conn").Warn("read failed", "err", io.EOF)
+		logger.Trace("read failed", "err", io.EOF)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("is inherited", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, Info).(Reconfigurable).WithOptions(func(opts *LoggerOptions) {
+			opts.JSONFormat = true
+		})
+
+		logger.With("a", 1).Error("read failed", "err", io.EOF)
+		assert.Empty(t, buf.String())
+	})
+}
@@ -0,0 +1,150 @@
+package hclog
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// writeTextValue writes val as the value of a key/value pair of a text entry.
+// The numbers and booleans are appended straight to the buffer of the entry,
+// and the other common kinds are turned into strings without fmt, which is
+// left for the rest: the output is the same as fmt's %v either way, but for
+// time.Time values, written in textTimeFormat.
+func (l *intLogger) writeTextValue(val interface{}) {
+	var (
+		scratch [68]byte
+		s       string
+		raw     bool
+	)
+	if num := appendNumber(scratch[:0], val); num != nil {
+		// Numbers never need escaping or quoting, only truncating.
+		if l.limits.fieldBytes <= 0 || len(num) <= l.limits.fieldBytes {
+			l.writer.Write(num)
+			return
+		}
+		s = string(num)
+	} else {
+		s, raw = l.textValue(val)
+	}
+
+	s = l.limits.value(s)
+	if !raw {
+		s = escape(s, l.rawNewlines)
+	}
+	if !raw && strings.ContainsAny(s, " \t\n\r") {
+		l.writer.WriteByte('"')
+		l.writer.WriteString(s)
+		l.writer.WriteByte('"')
+	} else {
+		l.writer.WriteString(s)
+	}
+}
+
+// appendNumber appends val to b if it is a number or a boolean, and returns
+// nil otherwise.
+func appendNumber(b []byte, val interface{}) []byte {
+	switch st := val.(type) {
+	case int:
+		return strconv.AppendInt(b, int64(st), 10)
+	case int64:
+		return strconv.AppendInt(b, st, 10)
+	case int32:
+		return strconv.AppendInt(b, int64(st), 10)
+	case int16:
+		return strconv.AppendInt(b, int64(st), 10)
+	case int8:
+		return strconv.AppendInt(b, int64(st), 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(st), 10)
+	case uint64:
+		return strconv.AppendUint(b, st, 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(st), 10)
+	case uint16:
+		return strconv.AppendUint(b, uint64(st), 10)
+	case uint8:
+		return strconv.AppendUint(b, uint64(st), 10)
+	case float64:
+		return strconv.AppendFloat(b, st, 'g', -1, 64)
+	case float32:
+		return strconv.AppendFloat(b, float64(st), 'g', -1, 32)
+	case bool:
+		return strconv.AppendBool(b, st)
+	case Hex:
+		return strconv.AppendUint(append(b, "0x"...), uint64(st), 16)
+	case Octal:
+		return strconv.AppendUint(append(b, '0'), uint64(st), 8)
+	case Binary:
+		return strconv.AppendUint(append(b, "0b"...), uint64(st), 2)
+	}
+	return nil
+}
+
+// textValue returns val as written in text entries, but for the numbers
+// written by appendNumber, and whether it is already escaped and quoted.
+func (l *intLogger) textValue(val interface{}) (string, bool) {
+	switch st := val.(type) {
+	case string:
+		return st, false
+	case time.Duration:
+		return st.String(), false
+	case time.Time:
+		return st.Format(textTimeFormat), false
+	case Format:
+		return fmt.Sprintf(st[0].(string), st[1:]...), false
+	case json.RawMessage:
+		return compactJSON(st), false
+	case []byte:
+		return renderBytes(st), true
+	case nil:
+		return "<nil>", false
+	}
+
+	if err, ok := val.(error); ok {
+		if errs := joinedErrors(err); errs != nil {
+			return l.renderErrors(errs), true
+		}
+	}
+	if reflect.ValueOf(val).Kind() == reflect.Slice {
+		return l.renderSlice(reflect.ValueOf(val)), true
+	}
+
+	// The methods are called in the order fmt calls them in.
+	switch st := val.(type) {
+	case fmt.Formatter:
+	case error:
+		return callString(val, st.Error), false
+	case fmt.Stringer:
+		return callString(val, st.String), false
+	}
+	return fmt.Sprintf("%v", val), false
+}
+
+// callString returns the result of the Error or String method of val, or val
+// as formatted by fmt if the method panics, as when it is called on a nil
+// pointer, for fmt to handle as it does.
+func callString(val interface{}, method func() string) (s string) {
+	defer func() {
+		if recover() != nil {
+			s = fmt.Sprintf("%v", val)
+		}
+	}()
+	return method()
+}
+
+// renderBytes renders b as renderSlice does, without reflection.
+func renderBytes(b []byte) string {
+	out := make([]byte, 0, 2+len(b)*5)
+	out = append(out, '[')
+	for i, c := range b {
+		if i > 0 {
+			out = append(out, ", "...)
+		}
+		out = strconv.AppendUint(out, uint64(c), 10)
+	}
+	return string(append(out, ']'))
+}
@@ -0,0 +1,103 @@
+package hclog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nilStringer struct{ s string }
+
+func (n *nilStringer) String() string { return n.s }
+
+type sliceError []error
+
+func (e sliceError) Error() string { return "errors" }
+
+type formatterStringer struct{}
+
+func (formatterStringer) Format(f fmt.State, verb rune) { fmt.Fprint(f, "formatted") }
+func (formatterStringer) String() string                { return "stringer" }
+
+type namedInt int
+
+func (namedInt) String() string { return "named int" }
+
+// TestTextValues pins the text of the values of common kinds, as written
+// before the text values were written without fmt.
+func TestTextValues(t *testing.T) {
+	cases := []struct {
+		val  interface{}
+		want string
+	}{
+		{"plain", "plain"},
+		{"with space", `"with space"`},
+		{"tab\there", `tab\there`},
+		{"", ""},
+		{42, "42"},
+		{-7, "-7"},
+		{int8(-8), "-8"},
+		{int16(16), "16"},
+		{int32(-32), "-32"},
+		{int64(math.MinInt64), "-9223372036854775808"},
+		{uint(1), "1"},
+		{uint8(255), "255"},
+		{uint16(65535), "65535"},
+		{uint32(1 << 31), "2147483648"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{0.75, "0.75"},
+		{1e21, "1e+21"},
+		{1e20, "1e+20"},
+		{123456789.0, "1.23456789e+08"},
+		{1e-7, "1e-07"},
+		{float32(0.1), "0.1"},
+		{float32(3.4e38), "3.4e+38"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+		{math.Copysign(0, -1), "-0"},
+		{true, "true"},
+		{false, "false"},
+		{1500 * time.Microsecond, "1.5ms"},
+		{time.Duration(0), "0s"},
+		{time.Date(2024, 6, 10, 6, 13, 20, 5, time.UTC), "2024-06-10T06:13:20.000000005Z"},
+		{time.Month(3), "March"},
+		{errors.New("connection refused"), `"connection refused"`},
+		{errors.New("no-space"), "no-space"},
+		{fmt.Errorf("wrapped: %w", errors.New("inner")), `"wrapped: inner"`},
+		{sliceError{errors.New("a")}, "[a]"},
+		{Hex(0xbeef), "0xbeef"},
+		{Octal(8), "010"},
+		{Binary(5), "0b101"},
+		{[]byte("hi"), "[104, 105]"},
+		{[]byte(nil), "[]"},
+		{[]int8{-1, 2}, "[-1, 2]"},
+		{net.IPv4(10, 0, 0, 1).To4(), "[10, 0, 0, 1]"},
+		{[]string{"a b", "c"}, `["a b", c]`},
+		{[]interface{}{1, "x"}, "[1, x]"},
+		{(*nilStringer)(nil), "<nil>"},
+		{&nilStringer{"set"}, "set"},
+		{formatterStringer{}, "formatted"},
+		{namedInt(2), `"named int"`},
+		{nil, "<nil>"},
+		{struct{ A int }{1}, "{1}"},
+		{map[string]int{"a": 1}, "map[a:1]"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		New(&LoggerOptions{Output: &buf, DisableTime: true}).Info("entry", "k", c.val)
+		assert.Equal(t, "[INFO]  -- entry: k="+c.want+"\n", buf.String(), "%T %v", c.val, c.val)
+	}
+
+	t.Run("truncates numbers", func(t *testing.T) {
+		var buf bytes.Buffer
+		New(&LoggerOptions{Output: &buf, DisableTime: true, MaxFieldBytes: 4}).Info("entry", "k", 123456789, "f", 0.5)
+		assert.Equal(t, "[INFO]  -- entry: k=\"1234 (truncated 5 bytes)\" f=0.5\n", buf.String())
+	})
+}
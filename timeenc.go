@@ -0,0 +1,70 @@
+package hclog
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeEncoding is how the JSON format writes the time of the entries and
+// time.Time values.
+type TimeEncoding uint8
+
+const (
+	// TimeRFC3339 is the default encoding, writing "@timestamp" as an
+	// RFC3339 string with microseconds, and time.Time values as they marshal
+	// themselves, as RFC3339 strings with nanoseconds.
+	TimeRFC3339 TimeEncoding = iota
+
+	// TimeEpochSeconds writes times as a number of seconds since the Unix
+	// epoch, with a fraction. A float64 only holds about 16 significant
+	// digits, so the nanoseconds are rounded: times before 2^33 seconds, in
+	// 2242, are precise to the microsecond, and later times lose more
+	// digits. Use TimeEpochNanos to keep the nanoseconds.
+	TimeEpochSeconds
+
+	// TimeEpochMillis writes times as an integer number of milliseconds since
+	// the Unix epoch, truncating the rest.
+	TimeEpochMillis
+
+	// TimeEpochNanos writes times as an integer number of nanoseconds since
+	// the Unix epoch, which holds the times between 1678 and 2262.
+	TimeEpochNanos
+)
+
+// String returns the name of the encoding, as in the constants of this
+// package.
+func (e TimeEncoding) String() string {
+	switch e {
+	case TimeRFC3339:
+		return "TimeRFC3339"
+	case TimeEpochSeconds:
+		return "TimeEpochSeconds"
+	case TimeEpochMillis:
+		return "TimeEpochMillis"
+	case TimeEpochNanos:
+		return "TimeEpochNanos"
+	}
+	return fmt.Sprintf("TimeEncoding(%d)", e)
+}
+
+// timestamp returns the "@timestamp" of an entry written at t.
+func (e TimeEncoding) timestamp(t time.Time) interface{} {
+	if e == TimeRFC3339 {
+		return t.Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+	return e.value(t)
+}
+
+// value returns the time.Time value t as it is written, unchanged for
+// TimeRFC3339 so that it marshals itself.
+func (e TimeEncoding) value(t time.Time) interface{} {
+	switch e {
+	case TimeEpochSeconds:
+		return float64(t.Unix()) + float64(t.Nanosecond())/1e9
+	case TimeEpochMillis:
+		return t.Unix()*1e3 + int64(t.Nanosecond())/1e6
+	case TimeEpochNanos:
+		return t.UnixNano()
+	}
+	return t
+}
@@ -0,0 +1,101 @@
+package hclog
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeEncoding(t *testing.T) {
+	at := time.Date(2024, 6, 10, 6, 13, 20, 123456789, time.UTC)
+
+	cases := []struct {
+		encoding TimeEncoding
+		want     string
+	}{
+		{TimeRFC3339, `"2024-06-10T06:13:20.123456789Z"`},
+		{TimeEpochSeconds, `1718000000.1234567`},
+		{TimeEpochMillis, `1718000000123`},
+		{TimeEpochNanos, `1718000000123456789`},
+	}
+	for _, c := range cases {
+		t.Run(c.encoding.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&LoggerOptions{
+				Output:       &buf,
+				JSONFormat:   true,
+				TimeEncoding: c.encoding,
+			})
+
+			logger.Info("entry", "at", at)
+			logger.(FieldLogger).InfoF("entry", Time("at", at))
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 2)
+			for _, line := range lines {
+				var raw map[string]json.RawMessage
+				require.NoError(t, json.Unmarshal([]byte(line), &raw))
+				assert.Equal(t, c.want, string(raw["at"]))
+
+				var ts interface{}
+				require.NoError(t, json.Unmarshal(raw["@timestamp"], &ts))
+				if c.encoding == TimeRFC3339 {
+					assert.IsType(t, "", ts)
+				} else {
+					assert.IsType(t, float64(0), ts)
+				}
+			}
+		})
+	}
+
+	t.Run("writes the time of the entry", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&LoggerOptions{
+			Output:       &buf,
+			JSONFormat:   true,
+			TimeEncoding: TimeEpochNanos,
+		})
+
+		before := time.Now().UnixNano()
+		logger.Info("entry")
+		after := time.Now().UnixNano()
+
+		var raw map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+		var ts int64
+		require.NoError(t, json.Unmarshal(raw["@timestamp"], &ts))
+		assert.True(t, ts >= before && ts <= after, "Expected a timestamp between %d and %d, got %d", before, after, ts)
+	})
+
+	t.Run("keeps the epoch seconds precise to the microsecond until 2^33 seconds", func(t *testing.T) {
+		// drift returns how far the written seconds are from ts, in
+		// nanoseconds.
+		drift := func(ts time.Time) float64 {
+			var out bytes.Buffer
+			require.NoError(t, json.NewEncoder(&out).Encode(TimeEpochSeconds.value(ts)))
+			var seconds float64
+			require.NoError(t, json.Unmarshal(out.Bytes(), &seconds))
+			return math.Abs((seconds-float64(ts.Unix()))*1e9 - float64(ts.Nanosecond()))
+		}
+
+		// The fractions fall about halfway between two float64 values,
+		// 2^-20 seconds apart below 2^33 seconds and 2^-19 apart above.
+		for _, ts := range []time.Time{
+			at,
+			time.Unix(-1, 999999999),
+			time.Unix(1<<32, 477),
+			time.Unix(1<<33-1, 477),
+		} {
+			assert.True(t, drift(ts) < 500, "Expected %d.%09d to be written to the microsecond, drifted %.0fns",
+				ts.Unix(), ts.Nanosecond(), drift(ts))
+		}
+
+		beyond := time.Unix(1<<33, 954)
+		assert.True(t, drift(beyond) > 500, "Expected microseconds to be lost from 2^33 seconds, drifted %.0fns", drift(beyond))
+	})
+}
@@ -0,0 +1,48 @@
+package hclog
+
+import (
+	"time"
+)
+
+// DurationKey is the key of the field holding the duration measured by Time.
+const DurationKey = "duration"
+
+// TimingLogger is implemented by loggers which can time operations. The
+// loggers returned by New and NewInterceptLogger implement it.
+type TimingLogger interface {
+	// Time starts timing an operation and returns a function writing msg and
+	// args at level, with the time elapsed since in the DurationKey field,
+	// followed by the extra args given to the function. It is meant to be
+	// deferred:
+	//
+	//	defer logger.Time(hclog.Debug, "snapshot taken", "id", id)()
+	//
+	// Nothing is written when Time is called, and nothing is formatted when
+	// the function is called if level is filtered out by then. The function
+	// may be called from any goroutine.
+	Time(level Level, msg string, args ...interface{}) func(extra ...interface{})
+}
+
+// Time implements TimingLogger.
+func (l *intLogger) Time(level Level, msg string, args ...interface{}) func(extra ...interface{}) {
+	return timeFunc(l, level, msg, args)
+}
+
+// Time implements TimingLogger.
+func (i *interceptLogger) Time(level Level, msg string, args ...interface{}) func(extra ...interface{}) {
+	return timeFunc(i, level, msg, args)
+}
+
+func timeFunc(l Logger, level Level, msg string, args []interface{}) func(extra ...interface{}) {
+	start := time.Now()
+	return func(extra ...interface{}) {
+		if lg, ok := l.(LevelGetter); ok && level < lg.GetLevel() {
+			return
+		}
+		all := make([]interface{}, 0, len(args)+2+len(extra))
+		all = append(all, args...)
+		all = append(all, DurationKey, time.Since(start))
+		all = append(all, extra...)
+		withCallerSkip(l, 1).Log(level, msg, all...)
+	}
+}
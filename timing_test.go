@@ -0,0 +1,77 @@
+package hclog
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTime(t *testing.T) {
+	t.Run("writes the duration", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:      &buf,
+			Level:       Debug,
+			DisableTime: true,
+		})
+
+		done := logger.(TimingLogger).Time(Debug, "snapshot taken", "id", 1)
+		assert.Equal(t, "", buf.String())
+		time.Sleep(10 * time.Millisecond)
+		done("size", 42)
+
+		re := regexp.MustCompile(`^\[DEBUG\] -- snapshot taken: id=1 duration=(\S+) size=42\n$`)
+		m := re.FindStringSubmatch(buf.String())
+		if assert.NotNil(t, m, buf.String()) {
+			d, err := time.ParseDuration(m[1])
+			assert.NoError(t, err)
+			assert.True(t, d >= 10*time.Millisecond, d)
+		}
+	})
+
+	t.Run("skips filtered levels", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output: &buf,
+			Level:  Info,
+		})
+
+		done := logger.(TimingLogger).Time(Debug, "filtered")
+		done()
+		assert.Equal(t, "", buf.String())
+
+		// The level is checked when the function is called.
+		done = logger.(TimingLogger).Time(Debug, "written")
+		logger.SetLevel(Debug)
+		done()
+		assert.Contains(t, buf.String(), "written")
+	})
+
+	t.Run("is safe across goroutines", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output: &buf,
+			Level:  Info,
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer logger.(TimingLogger).Time(Info, "outer", "i", i)()
+				logger.Named("inner").(TimingLogger).Time(Info, "inner", "i", i)()
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 20, bytes.Count(buf.Bytes(), []byte("\n")))
+	})
+}
@@ -3,31 +3,88 @@ package hclog
 import (
 	"bytes"
 	"io"
+	"os"
+	"sync/atomic"
 )
 
+// writer buffers the entry being written by a logger, under the mutex of the
+// logger, until it is flushed to the output. The output is swapped by
+// ResetOutput, and loaded atomically so that the loggers derived from the
+// logger can take it without the mutex.
 type writer struct {
-	b     bytes.Buffer
+	b   bytes.Buffer
+	out atomic.Value
+}
+
+// output is where a writer writes. It isn't changed once given to a writer.
+type output struct {
 	w     io.Writer
 	color ColorOption
+
+	// journald prefixes each line with the syslog priority of its level,
+	// for journald to pick up.
+	journald bool
+}
+
+func newWriter(out *output) *writer {
+	w := new(writer)
+	w.out.Store(out)
+	return w
+}
+
+// output returns the output of w.
+func (w *writer) output() *output {
+	return w.out.Load().(*output)
+}
+
+// setOutput swaps the output of w. The caller must hold the mutex of the
+// logger, so that no entry is being written.
+func (w *writer) setOutput(out *output) {
+	w.out.Store(out)
 }
 
-func newWriter(w io.Writer, color ColorOption) *writer {
-	return &writer{w: w, color: color}
+// clone returns a writer of its own writing to the output of w, for a logger
+// derived from the logger of w, of which the output is swapped separately.
+func (w *writer) clone() *writer {
+	return newWriter(w.output())
 }
 
 func (w *writer) Flush(level Level) (err error) {
+	return w.flush(level, false)
+}
+
+// FlushAudit writes the entry of Audit at the INFO level, with AuditWrite if
+// the output implements AuditWriter, and reports an entry which the output
+// didn't write in full, as when a level filter drops it, as an error.
+func (w *writer) FlushAudit() error {
+	return w.flush(Info, true)
+}
+
+func (w *writer) flush(level Level, audit bool) (err error) {
 	var unwritten = w.b.Bytes()
+	out := w.output()
 
-	if w.color != ColorOff {
+	if out.color != ColorOff {
 		color := _levelToColor[level]
 		unwritten = []byte(color.Sprintf("%s", unwritten))
 	}
 
-	if lw, ok := w.w.(LevelWriter); ok {
-		_, err = lw.LevelWrite(level, unwritten)
+	if out.journald {
+		unwritten = prefixLines(unwritten, journaldPriority(level))
+	}
+
+	var n int
+	if aw, ok := out.w.(AuditWriter); ok && audit {
+		n, err = aw.AuditWrite(unwritten)
+	} else if lw, ok := out.w.(LevelWriter); ok {
+		n, err = lw.LevelWrite(level, unwritten)
 	} else {
-		_, err = w.w.Write(unwritten)
+		n, err = out.w.Write(unwritten)
+	}
+	if audit && err == nil && n < len(unwritten) {
+		err = io.ErrShortWrite
 	}
+	countWritten(level, n)
 	w.b.Reset()
 	return err
 }
@@ -49,6 +106,14 @@ type LevelWriter interface {
 	LevelWrite(level Level, p []byte) (n int, err error)
 }
 
+// AuditWriter is implemented by the outputs filtering entries by level, such
+// as the LogFile of the logger package, which write the entries of Audit
+// regardless of their filter. Audit returns io.ErrShortWrite for the entries
+// such an output drops otherwise.
+type AuditWriter interface {
+	AuditWrite(p []byte) (n int, err error)
+}
+
 // LeveledWriter writes all log messages to the standard writer,
 // except for log levels that are defined in the overrides map.
 type LeveledWriter struct {
@@ -80,3 +145,58 @@ func (lw *LeveledWriter) LevelWrite(level Level, p []byte) (int, error) {
 	}
 	return w.Write(p)
 }
+
+// NewStdSplitOutput returns an Output writing the entries below threshold to
+// os.Stdout and the others to os.Stderr, as container platforms expect of
+// normal and error output. Each entry is written to its stream in a single
+// Write, in the order it was logged, in both the text and JSON formats.
+//
+// The returned writer isn't an *os.File, so AutoColor can't be used with it.
+func NewStdSplitOutput(threshold Level) *LeveledWriter {
+	return newSplitOutput(os.Stdout, os.Stderr, threshold)
+}
+
+// newSplitOutput implements NewStdSplitOutput for the given streams.
+func newSplitOutput(stdout, stderr io.Writer, threshold Level) *LeveledWriter {
+	overrides := make(map[Level]io.Writer)
+	for level := threshold; level <= Off; level++ {
+		overrides[level] = stderr
+	}
+	return NewLeveledWriter(stdout, overrides)
+}
+
+// journaldPriority returns the syslog-style priority prefix journald reads at
+// the start of lines for level.
+func journaldPriority(level Level) string {
+	switch level {
+	case Error:
+		return "<3>"
+	case Warn:
+		return "<4>"
+	case Info:
+		return "<6>"
+	case Debug, Trace:
+		return "<7>"
+	}
+	return "<5>"
+}
+
+// prefixLines returns p with prefix at the start of each of its lines.
+func prefixLines(p []byte, prefix string) []byte {
+	lines := bytes.Count(p, []byte{'\n'})
+	if len(p) > 0 && p[len(p)-1] != '\n' {
+		lines++
+	}
+	out := make([]byte, 0, len(p)+lines*len(prefix))
+	for len(p) > 0 {
+		out = append(out, prefix...)
+		idx := bytes.IndexByte(p, '\n')
+		if idx < 0 {
+			out = append(out, p...)
+			break
+		}
+		out = append(out, p[:idx+1]...)
+		p = p[idx+1:]
+	}
+	return out
+}
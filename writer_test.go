@@ -0,0 +1,81 @@
+package hclog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// streamRecorder records the writes to it, prefixed by its name, in a list
+// shared with other recorders, to check their order.
+type streamRecorder struct {
+	name   string
+	writes *[]string
+}
+
+func (r *streamRecorder) Write(p []byte) (int, error) {
+	*r.writes = append(*r.writes, r.name+": "+string(p))
+	return len(p), nil
+}
+
+func TestNewStdSplitOutput(t *testing.T) {
+	t.Run("splits entries by severity", func(t *testing.T) {
+		var writes []string
+		stdout := &streamRecorder{name: "stdout", writes: &writes}
+		stderr := &streamRecorder{name: "stderr", writes: &writes}
+
+		logger := New(&LoggerOptions{
+			Output:      newSplitOutput(stdout, stderr, Warn),
+			Level:       Trace,
+			DisableTime: true,
+		})
+
+		logger.Debug("one")
+		logger.Warn("two")
+		logger.Info("three")
+		logger.Error("four", "multi", "line\nvalue")
+
+		expected := []string{
+			"stdout: [DEBUG] -- one\n",
+			"stderr: [WARN]  -- two\n",
+			"stdout: [INFO]  -- three\n",
+			"stderr: [ERROR] -- four: multi=line\\nvalue\n",
+		}
+		assert.Equal(t, expected, writes)
+	})
+
+	t.Run("splits JSON entries by severity", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+
+		logger := New(&LoggerOptions{
+			Output:     newSplitOutput(&stdout, &stderr, Error),
+			JSONFormat: true,
+		})
+
+		logger.Warn("warning")
+		logger.Error("failure")
+
+		assert.Contains(t, stdout.String(), `"@message":"warning"`)
+		assert.Contains(t, stderr.String(), `"@message":"failure"`)
+		assert.Equal(t, 1, strings.Count(stdout.String(), "\n"))
+		assert.Equal(t, 1, strings.Count(stderr.String(), "\n"))
+	})
+
+	t.Run("uses the standard streams", func(t *testing.T) {
+		w := NewStdSplitOutput(Warn)
+		assert.Equal(t, io.Writer(os.Stdout), w.standard)
+		assert.Equal(t, io.Writer(os.Stderr), w.overrides[Error])
+		assert.NotContains(t, w.overrides, Info)
+	})
+}
+
+func TestPrefixLines(t *testing.T) {
+	assert.Equal(t, "<6>one\n", string(prefixLines([]byte("one\n"), "<6>")))
+	assert.Equal(t, "<3>one\n<3>two\n", string(prefixLines([]byte("one\ntwo\n"), "<3>")))
+	assert.Equal(t, "<3>one\n<3>two", string(prefixLines([]byte("one\ntwo"), "<3>")))
+	assert.Equal(t, "", string(prefixLines(nil, "<3>")))
+}